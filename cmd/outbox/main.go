@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"MatchingEngine/internal/kafka"
+	"MatchingEngine/internal/outbox"
+)
+
+const usage = `usage: outbox [flags] status
+       outbox [flags] reconcile
+
+status shows how far the relay got on each topic; reconcile also reads the
+topics and checks that every event the relay is past is in them once.
+`
+
+func main() {
+	dir := flag.String("dir", "./tmp/outbox", "outbox directory")
+	broker := flag.String("broker", "localhost:9092", "Kafka broker")
+	idle := flag.Duration("idle", 2*time.Second, "stop reading a topic after this long without a message")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cursors, err := outbox.ReadCursors(*dir)
+	if err != nil {
+		log.Fatalf("cannot read outbox: %v", err)
+	}
+	topics := make([]string, 0, len(cursors))
+	for topic := range cursors {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+
+	switch flag.Arg(0) {
+	case "status":
+		for _, topic := range topics {
+			rec, err := outbox.Reconcile(*dir, topic, nil)
+			if err != nil {
+				log.Fatalf("cannot read outbox: %v", err)
+			}
+			fmt.Printf("%s\trelayed=%d\tpending=%d\n", topic, rec.Relayed, rec.Pending)
+		}
+	case "reconcile":
+		failed := false
+		for _, topic := range topics {
+			received, err := kafka.ReadOutboxEvents(context.Background(), *broker, topic, *idle)
+			if err != nil {
+				log.Fatalf("cannot read %s: %v", topic, err)
+			}
+			rec, err := outbox.Reconcile(*dir, topic, received)
+			if err != nil {
+				log.Fatalf("cannot read outbox: %v", err)
+			}
+			fmt.Println(rec)
+			if len(rec.Missing) > 0 {
+				fmt.Printf("\tmissing: %v\n", rec.Missing)
+			}
+			if len(rec.Mismatched) > 0 {
+				fmt.Printf("\tmismatched: %v\n", rec.Mismatched)
+			}
+			if len(rec.Duplicates) > 0 {
+				fmt.Printf("\tduplicated: %v\n", rec.Duplicates)
+			}
+			failed = failed || !rec.OK()
+		}
+		if failed {
+			os.Exit(1)
+		}
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
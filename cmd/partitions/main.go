@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"MatchingEngine/internal/repository"
+	"MatchingEngine/internal/util"
+)
+
+// partitions keeps the trade date partitions of executions, trade capture
+// reports and trade sides: it creates those of the next PARTITION_DAYS_AHEAD
+// days and archives or drops those older than PARTITION_RETENTION_DAYS. Run it
+// daily, e.g. from cron; the server creates the days ahead on its own, but
+// only this retires old ones.
+func main() {
+	configDir := flag.String("config", "./integration/compose", "directory of the common.env config file")
+	dryRun := flag.Bool("dry-run", false, "print the plan without changing anything")
+	flag.Parse()
+
+	config, err := util.LoadConfig(*configDir)
+	if err != nil {
+		log.Fatal("cannot load config:", err)
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, config.DBSource)
+	if err != nil {
+		log.Fatal("cannot connect to the database:", err)
+	}
+	defer pool.Close()
+
+	manager, err := repository.NewPostgresPartitionManager(pool, repository.PartitionOpts{
+		DaysAhead:     config.PartitionDaysAhead,
+		RetentionDays: config.PartitionRetention,
+		Mode:          repository.RetentionMode(config.PartitionMode),
+	})
+	if err != nil {
+		log.Fatalf("invalid partition settings: %v", err)
+	}
+
+	plan, err := manager.Plan(ctx, time.Now())
+	if err != nil {
+		log.Fatalf("cannot plan: %v", err)
+	}
+	for _, day := range plan.Create {
+		fmt.Printf("create %s\n", day.Format(time.DateOnly))
+	}
+	for _, day := range plan.Retire {
+		fmt.Printf("retire %s\n", day.Format(time.DateOnly))
+	}
+	if *dryRun {
+		return
+	}
+	if err := manager.Apply(ctx, plan); err != nil {
+		log.Fatalf("maintenance stopped: %v", err)
+	}
+	fmt.Printf("%d day(s) created, %d day(s) retired\n", len(plan.Create), len(plan.Retire))
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"MatchingEngine/internal/idgen"
+	"MatchingEngine/internal/journal"
+	"MatchingEngine/internal/model"
+	"MatchingEngine/internal/snapshot"
+	"MatchingEngine/orderBook"
+)
+
+// lineWriter writes every payload the books publish on its own line, in
+// publication order.
+type lineWriter struct {
+	w *bufio.Writer
+}
+
+func (l *lineWriter) NotifyEventAndTrade(_ model.MsgType, _ string, value json.RawMessage) error {
+	if _, err := l.w.Write(value); err != nil {
+		return err
+	}
+	return l.w.WriteByte('\n')
+}
+
+func main() {
+	journalDir := flag.String("journal", "./tmp/journal", "command journal directory")
+	snapshotDir := flag.String("snapshots", "", "snapshot directory to start from (optional)")
+	outPath := flag.String("out", "-", "file to write the execution and trade reports to, - for stdout")
+	depth := flag.Int("depth", 0, "levels per side covered by the depth checksum")
+	instance := flag.String("instance", "me1", "engine instance ID the journal was written by")
+	layout := flag.String("id-layout", string(idgen.Sequential), "ID layout of the engine: sequential or time_sortable")
+	flag.Parse()
+
+	ids, err := idgen.New(idgen.Opts{Instance: *instance, Layout: idgen.Layout(*layout)})
+	if err != nil {
+		log.Fatalf("invalid ID settings: %v", err)
+	}
+
+	var out io.Writer = os.Stdout
+	if *outPath != "-" {
+		f, err := os.Create(*outPath)
+		if err != nil {
+			log.Fatalf("cannot create output file: %v", err)
+		}
+		defer f.Close()
+		out = f
+	}
+	writer := &lineWriter{w: bufio.NewWriter(out)}
+
+	replayer := journal.NewReplayer(writer, orderBook.BookOpts{DepthLevels: *depth, IDs: ids})
+	if *snapshotDir != "" {
+		snapshots, err := snapshot.NewFileStore(*snapshotDir).LoadAll()
+		if err != nil {
+			log.Fatalf("cannot load snapshots: %v", err)
+		}
+		if err := replayer.Restore(snapshots); err != nil {
+			log.Fatalf("cannot restore snapshots: %v", err)
+		}
+	}
+
+	count, err := replayer.Replay(*journalDir)
+	if flushErr := writer.w.Flush(); flushErr != nil {
+		log.Fatalf("cannot write reports: %v", flushErr)
+	}
+	if err != nil {
+		log.Fatalf("replay stopped after %d entries: %v", count, err)
+	}
+	fmt.Fprintf(os.Stderr, "replayed %d journal entries into %d books\n", count, len(replayer.Books()))
+}
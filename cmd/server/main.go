@@ -2,21 +2,34 @@ package main
 
 import (
 	"context"
+	"expvar"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	sqlc "MatchingEngine/internal/db/sqlc"
+	"MatchingEngine/internal/fix"
 	"MatchingEngine/internal/handler"
+	"MatchingEngine/internal/idgen"
+	"MatchingEngine/internal/journal"
 	"MatchingEngine/internal/kafka"
+	"MatchingEngine/internal/outbox"
+	"MatchingEngine/internal/replica"
 	"MatchingEngine/internal/repository"
 	"MatchingEngine/internal/rmq"
 	"MatchingEngine/internal/service"
+	"MatchingEngine/internal/shard"
+	"MatchingEngine/internal/snapshot"
+	"MatchingEngine/internal/stream"
 	"MatchingEngine/internal/util"
+	"MatchingEngine/orderBook"
 )
 
 func main() {
@@ -35,7 +48,12 @@ func main() {
 	}
 	defer conn.Close()
 
-	topics := []string{config.KafkaDBUpdateTopic, config.KafkaExecutionTopic}
+	standby := config.EngineMode == "standby"
+	if !standby && config.EngineMode != "primary" {
+		log.Fatalf("Unknown ENGINE_MODE %q, expected primary or standby", config.EngineMode)
+	}
+
+	topics := []string{config.KafkaDBUpdateTopic, config.KafkaExecutionTopic, config.KafkaStatsTopic, config.KafkaMarketDataTopic, config.KafkaJournalTopic}
 	err = kafka.InitializeTopics(config.KafkaBroker, topics)
 	if err != nil {
 		log.Fatalf("Failed to initialize Kafka topics: %v", err)
@@ -43,37 +61,307 @@ func main() {
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
-	kafkaProducer := kafka.NewProducer(config.KafkaBroker, config.KafkaDBUpdateTopic, config.KafkaExecutionTopic)
-	if kafkaProducer == nil {
-		log.Fatal("Failed to initialize Kafka producer")
+
+	sigCh := make(chan os.Signal, 1) // Correctly define the channel
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		cancel()
+	}()
+
+	// The partitions of the coming days are created here as well as by
+	// cmd/partitions, which alone retires old ones: there is no default
+	// partition, and a report for a day without one cannot be written.
+	partitions, err := repository.NewPostgresPartitionManager(conn, repository.PartitionOpts{DaysAhead: config.PartitionDaysAhead})
+	if err != nil {
+		log.Fatalf("Invalid partition settings: %v", err)
+	}
+	if err := partitions.Ensure(ctx, time.Now()); err != nil {
+		log.Printf("Failed to create partitions: %v", err)
+	}
+	go ensurePartitions(ctx, partitions)
+
+	// Nothing is published until the books have caught up and this engine
+	// holds the lease.
+	gate := replica.NewGate(false)
+
+	// Reports go to the outbox first; the relay publishes them to the
+	// execution and DB topics, each at its own pace.
+	reportOutbox, err := outbox.Open(config.OutboxDir, outbox.Opts{})
+	if err != nil {
+		log.Fatalf("Failed to open outbox: %v", err)
+	}
+	defer reportOutbox.Close()
+	executionPublisher := kafka.NewOutboxPublisher(config.KafkaBroker, config.KafkaExecutionTopic)
+	defer executionPublisher.Close()
+	dbPublisher := kafka.NewOutboxPublisher(config.KafkaBroker, config.KafkaDBUpdateTopic)
+	defer dbPublisher.Close()
+	relay, err := outbox.NewRelay(reportOutbox, []outbox.Destination{executionPublisher, dbPublisher}, outbox.RelayOpts{})
+	if err != nil {
+		log.Fatalf("Failed to start outbox relay: %v", err)
 	}
+	relayDone := make(chan struct{})
+	go func() {
+		defer close(relayDone)
+		relay.Start(ctx)
+	}()
 	executionRepo := repository.NewPostgresExecutionRepository(sqlc.New(conn))
 	tradeRepo := repository.NewPostgresTradeRepository(sqlc.New(conn))
-	asyncWriter := repository.NewAsyncDBWriter(executionRepo, tradeRepo, 10)
-	execService := service.NewExecutionService(asyncWriter, kafkaProducer)
-	tradeService := service.NewTradeService(asyncWriter, kafkaProducer)
-	orderService := service.NewOrderService(kafkaProducer)
+	orderRepo := repository.NewPostgresOrderRepository(sqlc.New(conn))
+	// Writes that keep failing are spilled to DB_SPILL_DIR and replayed once
+	// the database is back.
+	asyncWriter, err := repository.NewAsyncDBWriter(executionRepo, tradeRepo, repository.NewPostgresBatchRepository(conn), repository.AsyncDBWriterOpts{
+		QueueSize:   config.DBWriterQueueSize,
+		MaxAttempts: config.DBWriteAttempts,
+		SpillDir:    config.DBSpillDir,
+	})
+	if err != nil {
+		log.Fatalf("Failed to start database writer: %v", err)
+	}
+	go asyncWriter.Start(ctx)
+	expvar.Publish("db_writer", expvar.Func(func() any { return asyncWriter.Stats() }))
+	statsPublisher := replica.NewGatedStatsPublisher(gate, kafka.NewStatsPublisher(config.KafkaBroker, config.KafkaStatsTopic))
+	// FIX sessions get their execution reports alongside Kafka; an empty
+	// FIX_ADDRESS turns the gateway off.
+	var notifier service.Notifier = reportOutbox
+	var fixReports *fix.ReportQueue
+	if config.FixAddress != "" {
+		fixReports = fix.NewReportQueue()
+		notifier = fixReports.Notifier(reportOutbox)
+	}
+	// HTTP order entry answers with the execution report of each request.
+	reportWaiter := service.NewReportWaiter()
+	notifier = reportWaiter.Notifier(notifier)
+	// WebSocket clients see the same events as Kafka; an empty STREAM_TOKENS
+	// turns the gateway off.
+	var streamHub *stream.Hub
+	if config.StreamTokens != "" {
+		tokens, err := stream.ParseTokens(config.StreamTokens)
+		if err != nil {
+			log.Fatalf("Invalid STREAM_TOKENS: %v", err)
+		}
+		streamHub = stream.NewHub(stream.Opts{Auth: tokens, BufferSize: config.StreamBufferSize})
+		notifier = streamHub.Notifier(notifier)
+	}
+	// Reports of journal entries replayed at startup that never reached the
+	// outbox are appended to it, past the closed gate.
+	recovery, err := outbox.NewRecovery(reportOutbox, replica.NewGatedNotifier(gate, notifier))
+	if err != nil {
+		log.Fatalf("Failed to read outbox: %v", err)
+	}
+	statsService := service.NewMarketStatsService(recovery, tradeRepo, statsPublisher)
+	if err := statsService.Rebuild(ctx); err != nil {
+		log.Fatalf("Failed to rebuild trade statistics: %v", err)
+	}
+	go statsService.StartPublishing(ctx, config.StatsPublishInterval)
+
+	var marketDataPublisher service.MarketDataPublisher = kafka.NewMarketDataPublisher(config.KafkaBroker, config.KafkaMarketDataTopic)
+	if streamHub != nil {
+		marketDataPublisher = streamHub.MarketDataPublisher(marketDataPublisher)
+	}
+	marketDataService := service.NewMarketDataService(replica.NewGatedMarketDataPublisher(gate, marketDataPublisher))
+
+	ids, err := idgen.New(idgen.Opts{
+		Instance:  config.EngineInstanceID,
+		Layout:    idgen.Layout(config.IDLayout),
+		StatePath: config.IDStatePath,
+	})
+	if err != nil {
+		log.Fatalf("Failed to initialize ID generator: %v", err)
+	}
+
+	journalPublisher := kafka.NewJournalPublisher(config.KafkaBroker, config.KafkaJournalTopic)
+	defer journalPublisher.Close()
+	commandJournal, err := journal.Open(config.JournalDir, journal.Opts{
+		SegmentBytes: config.JournalSegmentBytes,
+		Sink:         replica.NewGatedSink(gate, journalPublisher),
+	})
+	if err != nil {
+		log.Fatalf("Failed to open command journal: %v", err)
+	}
+	defer commandJournal.Close()
+
+	orderService := service.NewOrderService(statsService, orderBook.BookOpts{
+		MarketData:  marketDataService,
+		DepthLevels: config.MarketDataDepth,
+		Journal:     commandJournal,
+		IDs:         ids,
+	})
+	orderService.Gate = gate
+	reportWaiter.Orders = orderService
 	requestHandler := handler.NewOrderRequestHandler(orderService)
+	// Requests carrying reply_to and correlation_id get their first execution
+	// report, or a BusinessMessageReject, published back.
+	replyPublisher := rmq.NewReplyPublisher(config.RmqHost)
+	defer replyPublisher.Close()
+	requestHandler.Replies = replyPublisher
+	requestHandler.Reports = reportWaiter
+	requestHandler.ReplyTimeout = config.OrderAckTimeout
+	requestHandler.RetryAttempts = config.RmqRetryAttempts
+	requestHandler.RetryBackoff = config.RmqRetryBackoff
+
+	snapshotStore := snapshot.NewFileStore(config.SnapshotDir)
+	var shards *shard.Ownership
+	if config.ShardID != "" {
+		shards = newShardOwnership(config)
+		orderService.Ownership = shards
+		log.Printf("Running as shard %s of %v", config.ShardID, shard.ParseIDs(config.ShardIDs))
+	}
+
+	snapshotService := service.NewSnapshotService(orderService, snapshotStore)
+	restored, err := snapshotService.Restore()
+	if err != nil {
+		log.Fatalf("Failed to restore order books: %v", err)
+	}
+	// With neither snapshots nor a journal, the resting orders are rebuilt
+	// from the orders table. A standby gets them from the primary instead.
+	if restored == 0 && commandJournal.LastSeq() == 0 && !standby {
+		summary, err := service.RecoverFromDatabase(ctx, orderRepo, orderService)
+		if err != nil {
+			log.Fatalf("Refusing to start, cannot recover order books from the database: %v", err)
+		}
+		log.Print(summary)
+	}
+	lowest, highest := orderService.JournalRange()
+	if standby {
+		// The primary reported the entries a standby journaled.
+		recovery.Done()
+	}
+	recovered, err := replica.Recover(config.JournalDir, orderService, lowest)
+	if err != nil {
+		log.Fatalf("Failed to recover order books from the journal: %v", err)
+	}
+	recovery.Done()
+	log.Printf("Order books recovered up to journal sequence %d", recovered)
+
+	lease := replica.NewLease(replica.NewPgLocker(conn, config.LeaseLockID), config.LeaseInterval)
+	if standby {
+		commandJournal.AdvanceTo(recovered)
+		follower := replica.NewFollower(newReplicaSource(config), commandJournal, orderService, recovered, config.ReplicaPollInterval)
+		log.Printf("Standby following the primary from journal sequence %d", recovered)
+		if err := replica.Standby(ctx, follower, lease); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Fatalf("Standby cannot take over: %v", err)
+		}
+		if follower.LastApplied() < highest {
+			log.Fatalf("Standby cannot take over: applied up to %d, below snapshot sequence %d", follower.LastApplied(), highest)
+		}
+		log.Printf("Promoted to primary at journal sequence %d", follower.LastApplied())
+	} else {
+		commandJournal.AdvanceTo(highest)
+		log.Println("Waiting for the engine lease")
+		if err := lease.Acquire(ctx); err != nil {
+			return
+		}
+	}
+	gate.Open()
+
+	leaseLost := make(chan error, 1)
+	go func() {
+		leaseLost <- lease.Hold(ctx)
+	}()
+
+	go snapshotService.Start(ctx, config.SnapshotInterval)
 
 	consumerOpts := rmq.ConsumerOpts{
-		RabbitMQURL: config.RmqHost,
-		QueueName:   config.RmqQueueName,
-		Prefetch:    1,
+		RabbitMQURL:        config.RmqHost,
+		QueueName:          config.RmqQueueName,
+		Prefetch:           1,
+		DeadLetterExchange: config.RmqDLX,
+		ReconnectMin:       config.RmqReconnectMin,
+		ReconnectMax:       config.RmqReconnectMax,
+	}
+	if shards != nil {
+		consumerOpts.QueueName = rmq.ShardQueue(config.RmqQueueName, config.ShardID)
+		consumerOpts.Exchange = config.RmqExchange
+		consumerOpts.RoutingKey = config.ShardID
 	}
 	consumer := rmq.NewConsumer(consumerOpts, requestHandler)
+	if consumerOpts.DeadLetterExchange != "" {
+		requestHandler.DeadLetters = consumer
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /api/v1/stats/24h", handler.NewStatsHandler(statsService))
+	mux.Handle("GET /api/v1/depth", handler.NewDepthHandler(marketDataService))
+	orderEntry := handler.NewOrderEntryHandler(reportWaiter, config.OrderAckTimeout)
+	mux.HandleFunc("POST /api/v1/orders", orderEntry.NewOrder)
+	mux.HandleFunc("DELETE /api/v1/orders/{clOrdID}", orderEntry.CancelOrder)
+	mux.HandleFunc("PUT /api/v1/orders/{clOrdID}", orderEntry.AmendOrder)
+	orderQuery := handler.NewOrderQueryHandler(orderService, orderRepo, executionRepo, tradeRepo)
+	mux.HandleFunc("GET /api/v1/orders", orderQuery.ListOpenOrders)
+	mux.HandleFunc("GET /api/v1/orders/{clOrdID}", orderQuery.GetOrder)
+	mux.HandleFunc("GET /api/v1/orders/{clOrdID}/executions", orderQuery.ListOrderExecutions)
+	mux.HandleFunc("GET /api/v1/executions/{execID}", orderQuery.GetExecution)
+	mux.HandleFunc("GET /api/v1/trades", orderQuery.ListTrades)
+	historyService := service.NewHistoryService(executionRepo, tradeRepo)
+	history := handler.NewHistoryHandler(historyService)
+	mux.HandleFunc("GET /api/v1/history/executions", history.ListExecutions)
+	mux.HandleFunc("GET /api/v1/history/trades", history.ListTrades)
+	if streamHub != nil {
+		streamHub.Depth = marketDataService
+		mux.Handle("GET /api/v1/stream", streamHub)
+	}
+	if shards != nil {
+		shardService := service.NewShardService(config.ShardID, shards, orderService, snapshotStore,
+			snapshot.NewFileStore(config.ShardHandoffDir), commandJournal)
+		shardHandler := handler.NewShardHandler(shardService)
+		mux.HandleFunc("POST /api/v1/shards/release", shardHandler.Release)
+		mux.HandleFunc("POST /api/v1/shards/acquire", shardHandler.Acquire)
+	}
+	health := handler.NewHealthHandler(map[string]handler.HealthCheck{
+		"rabbitmq": consumer.Ready,
+	})
+	mux.HandleFunc("GET /healthz", health.Live)
+	mux.HandleFunc("GET /readyz", health.Ready)
+	mux.Handle("GET /debug/vars", expvar.Handler())
+	httpServer := &http.Server{Addr: config.HTTPServerAddress, Handler: mux}
 
 	go func() {
+		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Failed to start HTTP server: %v", err)
+		}
+	}()
+
+	if fixReports != nil {
+		accounts, err := fix.ParseAccounts(config.FixAccounts)
+		if err != nil {
+			log.Fatalf("Invalid FIX_ACCOUNTS: %v", err)
+		}
+		acceptor := fix.NewAcceptor(fix.Opts{
+			Address:       config.FixAddress,
+			SenderCompID:  config.FixSenderCompID,
+			StoreDir:      config.FixStoreDir,
+			TargetCompIDs: splitList(config.FixTargetCompIDs),
+			Accounts:      accounts,
+		}, orderService, fixReports)
+		acceptor.Trades = historyService
+		go func() {
+			if err := acceptor.ListenAndServe(ctx); err != nil {
+				log.Fatalf("Failed to start FIX acceptor: %v", err)
+			}
+		}()
+	}
+
+	consumerDone := make(chan struct{})
+
+	go func() {
+		defer close(consumerDone)
 		if err := consumer.Start(ctx); err != nil {
 			log.Fatalf("Failed to start consumer: %v", err)
 		}
 	}()
 
 	kafkaConsumerOpts := kafka.ConsumerOpts{
-		BrokerAddrs: config.KafkaBroker,
-		Topic:       config.KafkaDBUpdateTopic,
-		GroupID:     config.KafkaConsumerGroup,
+		BrokerAddrs:   config.KafkaBroker,
+		Topic:         config.KafkaDBUpdateTopic,
+		GroupID:       config.KafkaConsumerGroup,
+		BatchSize:     config.KafkaBatchSize,
+		FlushInterval: config.KafkaFlushInterval,
 	}
-	kafkaConsumer := kafka.NewConsumer(kafkaConsumerOpts, execService, tradeService)
+	kafkaConsumer := kafka.NewConsumer(kafkaConsumerOpts, service.NewPersistenceService(asyncWriter))
 
 	go func() {
 		if err := kafkaConsumer.Start(ctx); err != nil {
@@ -81,9 +369,89 @@ func main() {
 		}
 	}()
 
-	sigCh := make(chan os.Signal, 1) // Correctly define the channel
-	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
-	<-sigCh
+	select {
+	case <-ctx.Done():
+	case err := <-leaseLost:
+		// Another engine may take over at any moment: stop taking orders and
+		// publishing before draining.
+		log.Printf("Stopping: %v", err)
+		gate.Close()
+		cancel()
+	}
 
-	cancel()
+	<-consumerDone
+	if err := snapshotService.TakeSnapshot(); err != nil {
+		log.Printf("Failed to take shutdown snapshot: %v", err)
+	}
+	if err := httpServer.Shutdown(context.Background()); err != nil {
+		log.Printf("HTTP server shutdown error: %v", err)
+	}
+	if streamHub != nil {
+		streamHub.Close()
+	}
+	<-relayDone
+}
+
+// ensurePartitions creates the partitions of the coming days every hour, so
+// an engine running for longer than PARTITION_DAYS_AHEAD days keeps having
+// them without cmd/partitions.
+func ensurePartitions(ctx context.Context, partitions *repository.PostgresPartitionManager) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := partitions.Ensure(ctx, now); err != nil {
+				log.Printf("Failed to create partitions: %v", err)
+			}
+		}
+	}
+}
+
+// newReplicaSource returns the stream a standby follows: the journal topic,
+// or the primary's journal directory as a local stand-in.
+func newReplicaSource(config util.Config) replica.Source {
+	if config.ReplicaSource == "dir" {
+		return journal.NewDirSource(config.ReplicaSourceDir)
+	}
+	return kafka.NewJournalSource(config.KafkaBroker, config.KafkaJournalTopic)
+}
+
+// newShardOwnership builds this shard's view of the symbol assignment: the
+// static map, then consistent hashing over SHARD_IDS, with handoffs made at
+// runtime on top.
+func newShardOwnership(config util.Config) *shard.Ownership {
+	static, err := shard.ParseStaticMap(config.ShardMap)
+	if err != nil {
+		log.Fatalf("Invalid SHARD_MAP: %v", err)
+	}
+	ids := shard.ParseIDs(config.ShardIDs)
+	assignment, err := shard.NewAssignment(ids, static)
+	if err != nil {
+		log.Fatalf("Invalid shard configuration: %v", err)
+	}
+	if err := assignment.LoadOverrides(config.ShardOverridesPath); err != nil {
+		log.Fatalf("Failed to load shard overrides: %v", err)
+	}
+	ownership := &shard.Ownership{ShardID: config.ShardID, Assignment: assignment}
+	for _, id := range ids {
+		if id == config.ShardID {
+			return ownership
+		}
+	}
+	log.Fatalf("SHARD_ID %s is not listed in SHARD_IDS", config.ShardID)
+	return nil
+}
+
+// splitList splits a comma-separated setting, dropping empty entries.
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
 }
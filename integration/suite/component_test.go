@@ -65,12 +65,6 @@ func TestOrderFlowScenarios(t *testing.T) {
 				`{"35": "D","new_order": {"35": "D","11": "clOrdId-002","54": "2","55": "BTC/USDT","38": "10","44": "100","60": 1729811234567890}}`,
 			},
 			expectedEvents: []interface{}{
-				model.TradeCaptureReport{
-					MsgType: "AE",
-					Symbol:  "BTC/USDT",
-					LastQty: decimal.NewFromInt(10),
-					LastPx:  decimal.NewFromInt(100),
-				},
 				model.ExecutionReport{
 					MsgType:      "8",
 					ClOrdID:      "clOrdId-002",
@@ -101,6 +95,12 @@ func TestOrderFlowScenarios(t *testing.T) {
 					AvgPx:        decimal.NewFromInt(100),
 					TransactTime: 1729811234567890,
 				},
+				model.TradeCaptureReport{
+					MsgType: "AE",
+					Symbol:  "BTC/USDT",
+					LastQty: decimal.NewFromInt(10),
+					LastPx:  decimal.NewFromInt(100),
+				},
 			},
 		},
 		{
@@ -126,12 +126,6 @@ func TestOrderFlowScenarios(t *testing.T) {
 					AvgPx:        decimal.NewFromInt(0),
 					TransactTime: 1729811234567890,
 				},
-				model.TradeCaptureReport{
-					MsgType: "AE",
-					Symbol:  "BTC/USDT",
-					LastQty: decimal.NewFromInt(5),
-					LastPx:  decimal.NewFromInt(100),
-				},
 				model.ExecutionReport{
 					MsgType:      "8",
 					ClOrdID:      "clOrdId-004",
@@ -198,6 +192,12 @@ func TestOrderFlowScenarios(t *testing.T) {
 					AvgPx:        decimal.NewFromInt(100),
 					TransactTime: 1729811234567890,
 				},
+				model.TradeCaptureReport{
+					MsgType: "AE",
+					Symbol:  "BTC/USDT",
+					LastQty: decimal.NewFromInt(5),
+					LastPx:  decimal.NewFromInt(100),
+				},
 			},
 		},
 		{
@@ -222,12 +222,6 @@ func TestOrderFlowScenarios(t *testing.T) {
 					AvgPx:        decimal.NewFromInt(0),
 					TransactTime: 1729811234567890,
 				},
-				model.TradeCaptureReport{
-					MsgType: "AE",
-					Symbol:  "BTC/USDT",
-					LastQty: decimal.NewFromInt(5),
-					LastPx:  decimal.NewFromInt(100),
-				},
 				model.ExecutionReport{
 					MsgType:      "8",
 					ClOrdID:      "clOrdId-007",
@@ -258,6 +252,12 @@ func TestOrderFlowScenarios(t *testing.T) {
 					AvgPx:        decimal.NewFromInt(100),
 					TransactTime: 1729811234567890,
 				},
+				model.TradeCaptureReport{
+					MsgType: "AE",
+					Symbol:  "BTC/USDT",
+					LastQty: decimal.NewFromInt(5),
+					LastPx:  decimal.NewFromInt(100),
+				},
 			},
 		},
 		{
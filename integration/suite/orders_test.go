@@ -0,0 +1,148 @@
+//go:build integration
+
+package suite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sqlc "MatchingEngine/internal/db/sqlc"
+	"MatchingEngine/internal/model"
+	"MatchingEngine/internal/repository"
+)
+
+func orderReport(prefix, execID, orderID string, status model.OrderStatus, cum int64, transactTime int64, reportSeq uint64) model.ExecutionReport {
+	return model.ExecutionReport{
+		MsgType:      string(model.MsgTypeExecRpt),
+		ExecID:       prefix + execID,
+		OrderID:      prefix + orderID,
+		ClOrdID:      prefix + "cl-" + orderID,
+		Account:      prefix + "acct",
+		ExecType:     model.ExecType(status),
+		OrdStatus:    status,
+		Symbol:       prefix + "SYM",
+		Side:         model.Buy,
+		OrderQty:     decimal.NewFromInt(10),
+		LeavesQty:    decimal.NewFromInt(10 - cum),
+		CumQty:       decimal.NewFromInt(cum),
+		AvgPx:        decimal.NewFromInt(100),
+		TransactTime: transactTime,
+		ReportSeq:    reportSeq,
+	}
+}
+
+func TestOrders_KeepNewestStateWhenBatchesLandOutOfOrder(t *testing.T) {
+	pool := testPool(t)
+	prefix := "orders-" + uuid.NewString() + "-"
+	cleanBench(t, pool, prefix)
+	repo := repository.NewPostgresBatchRepository(pool)
+	queries := sqlc.New(pool)
+	ctx := context.Background()
+
+	// The fill of order 1 is written before its ack; order 2 stays open.
+	require.NoError(t, repo.SaveBatch(ctx, repository.Batch{Executions: []model.ExecutionReport{
+		orderReport(prefix, "e2", "o1", model.OrderStatusFill, 10, 200, 2),
+	}}))
+	require.NoError(t, repo.SaveBatch(ctx, repository.Batch{Executions: []model.ExecutionReport{
+		orderReport(prefix, "e1", "o1", model.OrderStatusNew, 0, 100, 1),
+		orderReport(prefix, "e3", "o2", model.OrderStatusNew, 0, 150, 3),
+	}}))
+
+	order, err := queries.GetOrder(ctx, prefix+"o1")
+	require.NoError(t, err)
+	assert.Equal(t, string(model.OrderStatusFill), order.OrdStatus)
+	assert.Equal(t, int64(100), order.CreatedTime)
+	assert.Equal(t, int64(200), order.TransactTime)
+
+	open, err := queries.ListOpenOrdersBySymbol(ctx, prefix+"SYM")
+	require.NoError(t, err)
+	require.Len(t, open, 1)
+	assert.Equal(t, prefix+"o2", open[0].OrderID)
+
+	open, err = queries.ListOpenOrdersByAccount(ctx, pgtype.Text{String: prefix + "acct", Valid: true})
+	require.NoError(t, err)
+	require.Len(t, open, 1)
+
+	order, err = queries.GetOrderByClOrdID(ctx, pgtype.Text{String: prefix + "cl-o2", Valid: true})
+	require.NoError(t, err)
+	assert.Equal(t, prefix+"o2", order.OrderID)
+}
+
+func TestOrders_RankReportsByReportSeq(t *testing.T) {
+	pool := testPool(t)
+	prefix := "ranked-" + uuid.NewString() + "-"
+	cleanBench(t, pool, prefix)
+	batches := repository.NewPostgresBatchRepository(pool)
+	executions := repository.NewPostgresExecutionRepository(sqlc.New(pool))
+	queries := sqlc.New(pool)
+	ctx := context.Background()
+
+	// Each order is acked and canceled at the same TransactTime with no
+	// fills, and the cancel is written first; only the report sequence
+	// tells the ack is older.
+	require.NoError(t, batches.SaveBatch(ctx, repository.Batch{Executions: []model.ExecutionReport{
+		orderReport(prefix, "e2", "o1", model.OrderStatusCanceled, 0, 100, 2),
+	}}))
+	require.NoError(t, batches.SaveBatch(ctx, repository.Batch{Executions: []model.ExecutionReport{
+		orderReport(prefix, "e1", "o1", model.OrderStatusNew, 0, 100, 1),
+	}}))
+	require.NoError(t, executions.SaveExecution(ctx, orderReport(prefix, "e4", "o2", model.OrderStatusCanceled, 0, 300, 4)))
+	require.NoError(t, executions.SaveExecution(ctx, orderReport(prefix, "e3", "o2", model.OrderStatusNew, 0, 250, 3)))
+
+	for id, created := range map[string]int64{"o1": 100, "o2": 250} {
+		order, err := queries.GetOrder(ctx, prefix+id)
+		require.NoError(t, err)
+		assert.Equal(t, string(model.OrderStatusCanceled), order.OrdStatus, id)
+		assert.Equal(t, created, order.CreatedTime, id)
+	}
+	open, err := queries.ListOpenOrdersBySymbol(ctx, prefix+"SYM")
+	require.NoError(t, err)
+	assert.Empty(t, open)
+}
+
+func TestOrders_ListOpenOrdersInEnginePriority(t *testing.T) {
+	pool := testPool(t)
+	prefix := "recovery-" + uuid.NewString() + "-"
+	cleanBench(t, pool, prefix)
+	executions := repository.NewPostgresExecutionRepository(sqlc.New(pool))
+	ctx := context.Background()
+
+	// Both orders are acked by one request, so only the engine sequence
+	// tells which one came first.
+	for i, id := range []string{"o2", "o1"} {
+		er := orderReport(prefix, "e-"+id, id, model.OrderStatusNew, 0, 100, uint64(i+1))
+		er.Price = decimal.NewFromInt(99)
+		er.EngineSeq = uint64(2 - i)
+		require.NoError(t, executions.SaveExecution(ctx, er))
+	}
+	// A cancel reject names no order and leaves orders alone.
+	reject := orderReport(prefix, "e-reject", "", model.OrderStatusRejected, 0, 100, 3)
+	reject.OrderID = ""
+	require.NoError(t, executions.SaveExecution(ctx, reject))
+
+	orders := repository.NewPostgresOrderRepository(sqlc.New(pool))
+	all, err := orders.ListOpenOrders(ctx)
+	require.NoError(t, err)
+	var ids []string
+	for _, order := range all {
+		if order.Symbol == prefix+"SYM" {
+			ids = append(ids, order.OrderID)
+			assert.True(t, order.Price.Equal(decimal.NewFromInt(99)))
+		}
+	}
+	assert.Equal(t, []string{prefix + "o1", prefix + "o2"}, ids)
+
+	order, err := orders.OrderByClOrdID(ctx, prefix+"cl-o1")
+	require.NoError(t, err)
+	assert.Equal(t, prefix+"o1", order.OrderID)
+	assert.Equal(t, model.OrderStatusNew, order.OrderStatus)
+	assert.True(t, order.Price.Equal(decimal.NewFromInt(99)))
+	_, err = orders.OrderByClOrdID(ctx, reject.ClOrdID)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
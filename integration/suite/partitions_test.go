@@ -0,0 +1,100 @@
+//go:build integration
+
+package suite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"MatchingEngine/internal/repository"
+	"MatchingEngine/internal/util"
+)
+
+// The test works in 1990, so that retiring its days leaves the partitions of
+// the other tests alone.
+func TestPartitions_CreateAndArchiveDays(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	prefix := "partitions-" + uuid.NewString()
+	cleanBench(t, pool, prefix)
+	first := time.Date(1990, 1, 10, 0, 0, 0, 0, time.UTC)
+	later := first.AddDate(0, 0, 10)
+	t.Cleanup(func() {
+		pool.Exec(ctx, `DROP SCHEMA IF EXISTS archive_test CASCADE`)
+		for _, table := range []string{"trade_sides", "trade_capture_reports", "executions"} {
+			pool.Exec(ctx, `DROP TABLE IF EXISTS `+table+`_p19900110`)
+			pool.Exec(ctx, `DROP TABLE IF EXISTS `+table+`_p19900120`)
+		}
+	})
+	manager, err := repository.NewPostgresPartitionManager(pool, repository.PartitionOpts{
+		RetentionDays: 5,
+		ArchiveSchema: "archive_test",
+	})
+	require.NoError(t, err)
+
+	plan, err := manager.Plan(ctx, first)
+	require.NoError(t, err)
+	assert.Equal(t, []time.Time{first}, plan.Create)
+	require.NoError(t, manager.Apply(ctx, plan))
+
+	batch := benchBatch(prefix, 0)
+	at := first.Add(12 * time.Hour).UnixNano()
+	for i := range batch.Executions {
+		batch.Executions[i].TransactTime = at
+	}
+	for i := range batch.Trades {
+		batch.Trades[i].TransactTime = at
+		batch.Trades[i].TradeDate = util.FormatDate(at)
+	}
+	require.NoError(t, repository.NewPostgresBatchRepository(pool).SaveBatch(ctx, batch))
+
+	plan, err = manager.Plan(ctx, later)
+	require.NoError(t, err)
+	assert.Equal(t, []time.Time{later}, plan.Create)
+	assert.Equal(t, []time.Time{first}, plan.Retire)
+	require.NoError(t, manager.Apply(ctx, plan))
+
+	var attached, archived int
+	require.NoError(t, pool.QueryRow(ctx, `SELECT count(*) FROM executions WHERE trade_date = '1990-01-10'`).Scan(&attached))
+	require.NoError(t, pool.QueryRow(ctx, `SELECT count(*) FROM archive_test.executions_p19900110`).Scan(&archived))
+	assert.Zero(t, attached)
+	assert.Equal(t, len(batch.Executions), archived)
+	require.NoError(t, pool.QueryRow(ctx, `SELECT count(*) FROM archive_test.trade_sides_p19900110`).Scan(&archived))
+	assert.Equal(t, 2*len(batch.Trades), archived)
+}
+
+func TestPartitions_EnsureCreatesDaysAheadOnly(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	earlier := time.Date(1991, 3, 1, 0, 0, 0, 0, time.UTC)
+	today := time.Date(1991, 3, 5, 9, 30, 0, 0, time.UTC)
+	t.Cleanup(func() {
+		for _, table := range []string{"trade_sides", "trade_capture_reports", "executions"} {
+			for _, day := range []string{"19910301", "19910305", "19910306"} {
+				pool.Exec(ctx, `DROP TABLE IF EXISTS `+table+`_p`+day)
+			}
+		}
+	})
+	manager, err := repository.NewPostgresPartitionManager(pool, repository.PartitionOpts{DaysAhead: 1, RetentionDays: 1})
+	require.NoError(t, err)
+	require.NoError(t, manager.Apply(ctx, repository.PartitionPlan{Create: []time.Time{earlier}}))
+
+	require.NoError(t, manager.Ensure(ctx, today))
+	require.NoError(t, manager.Ensure(ctx, today))
+
+	plan, err := manager.Plan(ctx, today)
+	require.NoError(t, err)
+	assert.Empty(t, plan.Create)
+	// The earlier day is past retention, which Ensure leaves to Apply.
+	assert.Contains(t, plan.Retire, earlier)
+	for _, table := range []string{"trade_sides_p19910301", "trade_sides_p19910306"} {
+		var exists bool
+		require.NoError(t, pool.QueryRow(ctx, `SELECT to_regclass($1) IS NOT NULL`, table).Scan(&exists))
+		assert.True(t, exists, table)
+	}
+}
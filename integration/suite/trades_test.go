@@ -0,0 +1,92 @@
+//go:build integration
+
+package suite
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sqlc "MatchingEngine/internal/db/sqlc"
+	"MatchingEngine/internal/model"
+	"MatchingEngine/internal/repository"
+	"MatchingEngine/internal/service"
+)
+
+func TestTrades_FetchTradeWithBothFills(t *testing.T) {
+	pool := testPool(t)
+	prefix := "trades-" + uuid.NewString()
+	cleanBench(t, pool, prefix)
+	batch := benchBatch(prefix, 0)
+	require.NoError(t, repository.NewPostgresBatchRepository(pool).SaveBatch(context.Background(), batch))
+
+	want := batch.Trades[0]
+	trade, fills, err := repository.NewPostgresTradeRepository(sqlc.New(pool)).GetTradeWithFills(context.Background(), want.TradeID)
+	require.NoError(t, err)
+	assert.Equal(t, want.TradeReportID, trade.TradeReportID)
+	assert.Equal(t, want.NoSides, trade.NoSides)
+	require.Len(t, fills, 2)
+	for _, fill := range fills {
+		assert.Equal(t, model.ExecTypeFill, fill.ExecType)
+		assert.Equal(t, want.TradeID, fill.TradeID)
+		assert.Equal(t, model.OrdTypeLimit, fill.OrdType)
+		assert.Equal(t, model.TimeInForceGTC, fill.TimeInForce)
+		assert.True(t, fill.Price.Equal(decimal.NewFromInt(100)))
+	}
+	assert.ElementsMatch(t, []model.LastLiquidityInd{model.LiquidityAdded, model.LiquidityRemoved},
+		[]model.LastLiquidityInd{fills[0].LastLiquidityInd, fills[1].LastLiquidityInd})
+
+	// A side cannot name a fill that was never stored.
+	orphan := benchBatch(prefix, 1)
+	orphan.Executions = nil
+	assert.Error(t, repository.NewPostgresBatchRepository(pool).SaveBatch(context.Background(), repository.Batch{Trades: orphan.Trades}))
+}
+
+func TestTrades_PageThroughHistory(t *testing.T) {
+	pool := testPool(t)
+	prefix := "history-" + uuid.NewString()
+	cleanBench(t, pool, prefix)
+	batch := benchBatch(prefix, 0)
+	require.NoError(t, repository.NewPostgresBatchRepository(pool).SaveBatch(context.Background(), batch))
+	history := service.NewHistoryService(
+		repository.NewPostgresExecutionRepository(sqlc.New(pool)),
+		repository.NewPostgresTradeRepository(sqlc.New(pool)))
+
+	// The whole batch shares one TransactTime, so the pages split on the IDs.
+	ts := batch.Trades[0].TransactTime
+	filter := model.HistoryFilter{Symbol: "BENCH/USDT", From: ts, To: ts + 1}
+	var ids []string
+	cursor := ""
+	for {
+		trades, next, err := history.ListTrades(context.Background(), filter, cursor, 30)
+		require.NoError(t, err)
+		for _, trade := range trades {
+			require.Len(t, trade.NoSides, 2)
+			ids = append(ids, trade.TradeReportID)
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	assert.Len(t, ids, benchTradesPerBatch)
+	assert.True(t, slices.IsSorted(ids))
+
+	want := batch.Trades[7]
+	trades, _, err := history.ListTrades(context.Background(), model.HistoryFilter{OrderID: want.NoSides[1].OrderID}, "", 0)
+	require.NoError(t, err)
+	require.Len(t, trades, 1)
+	assert.Equal(t, want.TradeReportID, trades[0].TradeReportID)
+
+	reports, next, err := history.ListExecutions(context.Background(), model.HistoryFilter{OrderID: want.NoSides[0].OrderID}, "", 0)
+	require.NoError(t, err)
+	assert.Empty(t, next)
+	require.Len(t, reports, 2)
+	// Reports of one TransactTime come in ExecID order
+	assert.Equal(t, []string{want.NoSides[0].ExecID, want.TradeReportID + "-new"}, []string{reports[0].ExecID, reports[1].ExecID})
+}
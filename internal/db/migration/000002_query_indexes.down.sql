@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS trade_sides_trade_report_id_idx;
+DROP INDEX IF EXISTS trade_capture_reports_symbol_idx;
+DROP INDEX IF EXISTS executions_cl_ord_id_idx;
+DROP INDEX IF EXISTS executions_order_id_idx;
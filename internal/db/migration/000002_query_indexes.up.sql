@@ -0,0 +1,4 @@
+CREATE INDEX executions_order_id_idx ON executions (order_id, transact_time);
+CREATE INDEX executions_cl_ord_id_idx ON executions (cl_ord_id, transact_time DESC);
+CREATE INDEX trade_capture_reports_symbol_idx ON trade_capture_reports (symbol, transact_time DESC);
+CREATE INDEX trade_sides_trade_report_id_idx ON trade_sides (trade_report_id);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS trade_sides_trade_report_id_side_key;
+CREATE INDEX trade_sides_trade_report_id_idx ON trade_sides (trade_report_id);
@@ -0,0 +1,10 @@
+-- A trade replayed by the persistence consumer must not get its sides twice.
+DELETE
+FROM trade_sides a
+    USING trade_sides b
+WHERE a.trade_report_id = b.trade_report_id
+  AND a.side = b.side
+  AND a.id > b.id;
+
+DROP INDEX IF EXISTS trade_sides_trade_report_id_idx;
+CREATE UNIQUE INDEX trade_sides_trade_report_id_side_key ON trade_sides (trade_report_id, side);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS orders;
+ALTER TABLE executions DROP COLUMN IF EXISTS account;
@@ -0,0 +1,31 @@
+ALTER TABLE executions
+    ADD COLUMN account text; -- Maps to Account
+
+-- Current state of each order, upserted with every execution report saved.
+CREATE TABLE orders
+(
+    order_id      text PRIMARY KEY, -- 37
+    cl_ord_id     text,             -- 11, of the latest report
+    account       text,             -- 1
+    symbol        text    NOT NULL, -- 55
+    side          text    NOT NULL, -- 54
+    price         numeric,          -- 44, NULL when not reported
+    order_qty     numeric NOT NULL, -- 38
+    leaves_qty    numeric NOT NULL, -- 151
+    cum_qty       numeric NOT NULL, -- 14
+    avg_px        numeric NOT NULL, -- 6
+    ord_status    text    NOT NULL, -- 39
+    created_time  bigint  NOT NULL, -- 60 of the first report
+    transact_time bigint  NOT NULL  -- 60 of the latest report
+);
+
+CREATE INDEX orders_cl_ord_id_idx ON orders (cl_ord_id);
+CREATE INDEX orders_open_symbol_idx ON orders (symbol, created_time) WHERE ord_status IN ('0', '1');
+CREATE INDEX orders_open_account_idx ON orders (account, created_time) WHERE ord_status IN ('0', '1');
+
+INSERT INTO orders (order_id, cl_ord_id, account, symbol, side, order_qty, leaves_qty, cum_qty, avg_px, ord_status,
+                    created_time, transact_time)
+SELECT DISTINCT ON (order_id) order_id, cl_ord_id, account, symbol, side, order_qty, leaves_qty, cum_qty, avg_px,
+                              ord_status, min(transact_time) OVER (PARTITION BY order_id), transact_time
+FROM executions
+ORDER BY order_id, transact_time DESC, cum_qty DESC;
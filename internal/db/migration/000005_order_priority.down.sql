@@ -0,0 +1,2 @@
+ALTER TABLE orders DROP COLUMN IF EXISTS engine_seq;
+ALTER TABLE executions DROP COLUMN IF EXISTS engine_seq;
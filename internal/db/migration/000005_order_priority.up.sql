@@ -0,0 +1,9 @@
+-- Startup recovery rebuilds the books from orders, which needs the engine
+-- sequence that gave each order its queue priority.
+ALTER TABLE executions
+    ADD COLUMN engine_seq bigint NOT NULL DEFAULT 0; -- Maps to EngineSeq
+
+-- The engine sequence of the request that placed or last replaced the order;
+-- orders at one price rest in engine sequence order.
+ALTER TABLE orders
+    ADD COLUMN engine_seq bigint NOT NULL DEFAULT 0;
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS trade_sides_exec_id_idx;
+DROP INDEX IF EXISTS executions_trade_id_idx;
+DROP INDEX IF EXISTS trade_capture_reports_trade_id_key;
+ALTER TABLE trade_sides DROP COLUMN IF EXISTS cl_ord_id, DROP COLUMN IF EXISTS exec_id;
+ALTER TABLE trade_capture_reports DROP COLUMN IF EXISTS trade_id;
+ALTER TABLE executions DROP COLUMN IF EXISTS trade_id;
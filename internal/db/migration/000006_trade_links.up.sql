@@ -0,0 +1,18 @@
+-- TradeID (1003) is stamped on a trade capture report and on the fill
+-- execution reports of both of its sides, and each trade side names its fill.
+ALTER TABLE executions
+    ADD COLUMN trade_id text; -- 1003, set on fills
+
+ALTER TABLE trade_capture_reports
+    ADD COLUMN trade_id text; -- 1003
+
+-- The engine publishes the fills before their trade and a batch writes
+-- executions before trades; the persistence consumer keeps a trade in the
+-- batch of its fills, or writes it once theirs is written.
+ALTER TABLE trade_sides
+    ADD COLUMN exec_id   text REFERENCES executions (exec_id), -- 17 of the side's fill
+    ADD COLUMN cl_ord_id text;                                 -- 11
+
+CREATE UNIQUE INDEX trade_capture_reports_trade_id_key ON trade_capture_reports (trade_id);
+CREATE INDEX executions_trade_id_idx ON executions (trade_id) WHERE trade_id IS NOT NULL;
+CREATE INDEX trade_sides_exec_id_idx ON trade_sides (exec_id);
@@ -0,0 +1,5 @@
+ALTER TABLE executions
+    DROP COLUMN IF EXISTS last_liquidity_ind,
+    DROP COLUMN IF EXISTS time_in_force,
+    DROP COLUMN IF EXISTS ord_type,
+    DROP COLUMN IF EXISTS price;
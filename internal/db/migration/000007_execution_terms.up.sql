@@ -0,0 +1,7 @@
+-- The order terms and maker/taker role reported on each execution, for fee
+-- and reporting systems.
+ALTER TABLE executions
+    ADD COLUMN price              numeric, -- 44
+    ADD COLUMN ord_type           text, -- 40
+    ADD COLUMN time_in_force      text, -- 59
+    ADD COLUMN last_liquidity_ind text; -- 851, set on fills: 1=added, 2=removed
@@ -0,0 +1,92 @@
+-- Back to the plain tables of 000007; the trade date goes back to YYYYMMDD
+-- text and executions lose it.
+ALTER TABLE trade_sides RENAME TO trade_sides_partitioned;
+ALTER TABLE trade_capture_reports RENAME TO trade_capture_reports_partitioned;
+ALTER TABLE executions RENAME TO executions_partitioned;
+
+CREATE TABLE executions
+(
+    msg_type           text    NOT NULL,
+    exec_id            text    NOT NULL,
+    order_id           text    NOT NULL,
+    cl_ord_id          text,
+    exec_type          text    NOT NULL,
+    ord_status         text    NOT NULL,
+    symbol             text    NOT NULL,
+    side               text    NOT NULL,
+    order_qty          numeric NOT NULL,
+    last_shares        numeric NOT NULL,
+    last_px            numeric NOT NULL,
+    leaves_qty         numeric NOT NULL,
+    cum_qty            numeric NOT NULL,
+    avg_px             numeric NOT NULL,
+    transact_time      bigint  NOT NULL,
+    text               text,
+    account            text,
+    price              numeric,
+    engine_seq         bigint  NOT NULL DEFAULT 0,
+    trade_id           text,
+    ord_type           text,
+    time_in_force      text,
+    last_liquidity_ind text
+);
+
+CREATE TABLE trade_capture_reports
+(
+    trade_report_id text    NOT NULL,
+    msg_type        text    NOT NULL,
+    exec_id         text    NOT NULL,
+    symbol          text    NOT NULL,
+    last_qty        NUMERIC NOT NULL,
+    last_px         NUMERIC NOT NULL,
+    trade_date      text    NOT NULL,
+    transact_time   bigint  NOT NULL,
+    trade_id        text
+);
+
+CREATE TABLE trade_sides
+(
+    id              integer  NOT NULL DEFAULT nextval('trade_sides_id_seq'),
+    trade_report_id text     NOT NULL,
+    side            SMALLINT NOT NULL,
+    order_id        text     NOT NULL,
+    exec_id         text,
+    cl_ord_id       text
+);
+
+INSERT INTO executions
+SELECT msg_type, exec_id, order_id, cl_ord_id, exec_type, ord_status, symbol, side, order_qty,
+       last_shares, last_px, leaves_qty, cum_qty, avg_px, transact_time, text, account, price,
+       engine_seq, trade_id, ord_type, time_in_force, last_liquidity_ind
+FROM executions_partitioned;
+
+INSERT INTO trade_capture_reports
+SELECT trade_report_id, msg_type, exec_id, symbol, last_qty, last_px, to_char(trade_date, 'YYYYMMDD'),
+       transact_time, trade_id
+FROM trade_capture_reports_partitioned;
+
+INSERT INTO trade_sides
+SELECT id, trade_report_id, side, order_id, exec_id, cl_ord_id
+FROM trade_sides_partitioned;
+
+ALTER SEQUENCE trade_sides_id_seq OWNED BY trade_sides.id;
+DROP TABLE trade_sides_partitioned;
+DROP TABLE trade_capture_reports_partitioned;
+DROP TABLE executions_partitioned;
+
+ALTER TABLE executions
+    ADD PRIMARY KEY (exec_id);
+ALTER TABLE trade_capture_reports
+    ADD PRIMARY KEY (trade_report_id);
+ALTER TABLE trade_sides
+    ADD PRIMARY KEY (id),
+    ADD FOREIGN KEY (trade_report_id) REFERENCES trade_capture_reports (trade_report_id) ON DELETE CASCADE,
+    ADD FOREIGN KEY (exec_id) REFERENCES executions (exec_id);
+
+CREATE INDEX executions_order_id_idx ON executions (order_id, transact_time);
+CREATE INDEX executions_cl_ord_id_idx ON executions (cl_ord_id, transact_time DESC);
+CREATE INDEX executions_trade_id_idx ON executions (trade_id) WHERE trade_id IS NOT NULL;
+CREATE INDEX trade_capture_reports_symbol_idx ON trade_capture_reports (symbol, transact_time DESC);
+CREATE UNIQUE INDEX trade_capture_reports_trade_id_key ON trade_capture_reports (trade_id);
+CREATE UNIQUE INDEX trade_sides_trade_report_id_side_key ON trade_sides (trade_report_id, side);
+CREATE INDEX trade_sides_exec_id_idx ON trade_sides (exec_id);
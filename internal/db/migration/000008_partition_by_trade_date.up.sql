@@ -0,0 +1,138 @@
+-- executions, trade_capture_reports and trade_sides are range-partitioned by
+-- trade date, one partition per UTC day, so that old days can be detached or
+-- dropped whole; cmd/partitions creates the days ahead and retires the old
+-- ones. The trade date of a row is the UTC day of its TransactTime. A trade
+-- and its two fills share a TransactTime, so a trade side, its trade and its
+-- fill always sit in the same day.
+--
+-- There is no default partition: a report for a day with no partition fails
+-- to be written, and the DB writer retries or spills it until the partition
+-- is created.
+--
+-- Keys of a partitioned table must include the partition key, so trade_date
+-- is part of every primary, unique and foreign key below.
+
+ALTER TABLE trade_sides RENAME TO trade_sides_unpartitioned;
+ALTER TABLE trade_capture_reports RENAME TO trade_capture_reports_unpartitioned;
+ALTER TABLE executions RENAME TO executions_unpartitioned;
+
+CREATE TABLE executions
+(
+    msg_type           text    NOT NULL, -- 35 (8)
+    exec_id            text    NOT NULL, -- Maps to ExecID
+    order_id           text    NOT NULL, -- Maps to OrderID
+    cl_ord_id          text,             -- Maps to ClOrdID
+    exec_type          text    NOT NULL, -- Maps to ExecType
+    ord_status         text    NOT NULL, -- Maps to OrdStatus
+    symbol             text    NOT NULL, -- Maps to Symbol
+    side               text    NOT NULL, -- Maps to Side
+    order_qty          numeric NOT NULL, -- Maps to OrderQty
+    last_shares        numeric NOT NULL, -- Maps to LastShares
+    last_px            numeric NOT NULL, -- Maps to LastPx
+    leaves_qty         numeric NOT NULL, -- Maps to LeavesQty
+    cum_qty            numeric NOT NULL, -- Maps to CumQty
+    avg_px             numeric NOT NULL, -- Maps to AvgPx
+    transact_time      bigint  NOT NULL, -- Maps to TransactTime
+    text               text,             -- Maps to Text
+    account            text,             -- Maps to Account
+    price              numeric,          -- Maps to Price
+    engine_seq         bigint  NOT NULL DEFAULT 0, -- Maps to EngineSeq
+    trade_id           text,             -- 1003, set on fills
+    ord_type           text,             -- 40
+    time_in_force      text,             -- 59
+    last_liquidity_ind text,             -- 851, set on fills: 1=added, 2=removed
+    trade_date         date    NOT NULL  -- 75, UTC day of transact_time
+) PARTITION BY RANGE (trade_date);
+
+CREATE TABLE trade_capture_reports
+(
+    trade_report_id text    NOT NULL, -- 571
+    msg_type        text    NOT NULL, -- 35 (AE)
+    exec_id         text    NOT NULL, -- 17
+    symbol          text    NOT NULL, -- 55
+    last_qty        NUMERIC NOT NULL, -- 32
+    last_px         NUMERIC NOT NULL, -- 31
+    trade_date      date    NOT NULL, -- 75, UTC day of transact_time
+    transact_time   bigint  NOT NULL, -- 60
+    trade_id        text              -- 1003
+) PARTITION BY RANGE (trade_date);
+
+-- The sides keep their IDs and the sequence that hands them out.
+CREATE TABLE trade_sides
+(
+    id              integer  NOT NULL DEFAULT nextval('trade_sides_id_seq'),
+    trade_report_id text     NOT NULL, -- 571
+    side            SMALLINT NOT NULL, -- 54: 1 = Buy, 2 = Sell
+    order_id        text     NOT NULL, -- 37
+    exec_id         text,              -- 17 of the side's fill
+    cl_ord_id       text,              -- 11
+    trade_date      date     NOT NULL  -- 75 of the trade
+) PARTITION BY RANGE (trade_date);
+
+-- A partition for every day from the oldest report through a week ahead.
+DO
+$$
+    DECLARE
+        today     date := (now() AT TIME ZONE 'UTC')::date;
+        first_day date;
+        d         date;
+        tbl       text;
+    BEGIN
+        SELECT LEAST(today,
+                     (SELECT (to_timestamp(min(transact_time) / 1e9) AT TIME ZONE 'UTC')::date FROM executions_unpartitioned),
+                     (SELECT (to_timestamp(min(transact_time) / 1e9) AT TIME ZONE 'UTC')::date FROM trade_capture_reports_unpartitioned))
+        INTO first_day;
+        FOR d IN SELECT generate_series(first_day, today + 7, interval '1 day')::date
+            LOOP
+                FOREACH tbl IN ARRAY ARRAY ['executions', 'trade_capture_reports', 'trade_sides']
+                    LOOP
+                        EXECUTE format('CREATE TABLE %I PARTITION OF %I FOR VALUES FROM (%L) TO (%L)',
+                                       tbl || '_p' || to_char(d, 'YYYYMMDD'), tbl, d, d + 1);
+                    END LOOP;
+            END LOOP;
+    END
+$$;
+
+INSERT INTO executions (msg_type, exec_id, order_id, cl_ord_id, exec_type, ord_status, symbol, side, order_qty,
+                        last_shares, last_px, leaves_qty, cum_qty, avg_px, transact_time, text, account, price,
+                        engine_seq, trade_id, ord_type, time_in_force, last_liquidity_ind, trade_date)
+SELECT msg_type, exec_id, order_id, cl_ord_id, exec_type, ord_status, symbol, side, order_qty,
+       last_shares, last_px, leaves_qty, cum_qty, avg_px, transact_time, text, account, price,
+       engine_seq, trade_id, ord_type, time_in_force, last_liquidity_ind,
+       (to_timestamp(transact_time / 1e9) AT TIME ZONE 'UTC')::date
+FROM executions_unpartitioned;
+
+INSERT INTO trade_capture_reports (trade_report_id, msg_type, exec_id, symbol, last_qty, last_px, trade_date,
+                                   transact_time, trade_id)
+SELECT trade_report_id, msg_type, exec_id, symbol, last_qty, last_px,
+       (to_timestamp(transact_time / 1e9) AT TIME ZONE 'UTC')::date, transact_time, trade_id
+FROM trade_capture_reports_unpartitioned;
+
+INSERT INTO trade_sides (id, trade_report_id, side, order_id, exec_id, cl_ord_id, trade_date)
+SELECT s.id, s.trade_report_id, s.side, s.order_id, s.exec_id, s.cl_ord_id, t.trade_date
+FROM trade_sides_unpartitioned s
+         JOIN trade_capture_reports t ON t.trade_report_id = s.trade_report_id;
+
+ALTER SEQUENCE trade_sides_id_seq OWNED BY trade_sides.id;
+DROP TABLE trade_sides_unpartitioned;
+DROP TABLE trade_capture_reports_unpartitioned;
+DROP TABLE executions_unpartitioned;
+
+ALTER TABLE executions
+    ADD PRIMARY KEY (exec_id, trade_date);
+ALTER TABLE trade_capture_reports
+    ADD PRIMARY KEY (trade_report_id, trade_date);
+ALTER TABLE trade_sides
+    ADD PRIMARY KEY (id, trade_date),
+    ADD FOREIGN KEY (trade_report_id, trade_date) REFERENCES trade_capture_reports (trade_report_id, trade_date) ON DELETE CASCADE,
+    ADD FOREIGN KEY (exec_id, trade_date) REFERENCES executions (exec_id, trade_date);
+
+CREATE INDEX executions_symbol_idx ON executions (symbol, transact_time);
+CREATE INDEX executions_order_id_idx ON executions (order_id, transact_time);
+CREATE INDEX executions_cl_ord_id_idx ON executions (cl_ord_id, transact_time DESC);
+CREATE INDEX executions_trade_id_idx ON executions (trade_id) WHERE trade_id IS NOT NULL;
+CREATE INDEX trade_capture_reports_symbol_idx ON trade_capture_reports (symbol, transact_time DESC);
+CREATE UNIQUE INDEX trade_capture_reports_trade_id_key ON trade_capture_reports (trade_id, trade_date);
+CREATE UNIQUE INDEX trade_sides_trade_report_id_side_key ON trade_sides (trade_report_id, side, trade_date);
+CREATE INDEX trade_sides_exec_id_idx ON trade_sides (exec_id);
+CREATE INDEX trade_sides_order_id_idx ON trade_sides (order_id);
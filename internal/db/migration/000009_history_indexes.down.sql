@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS trade_capture_reports_transact_time_idx;
+DROP INDEX IF EXISTS executions_account_idx;
+DROP INDEX IF EXISTS executions_transact_time_idx;
@@ -0,0 +1,3 @@
+CREATE INDEX executions_transact_time_idx ON executions (transact_time, exec_id);
+CREATE INDEX executions_account_idx ON executions (account, transact_time);
+CREATE INDEX trade_capture_reports_transact_time_idx ON trade_capture_reports (transact_time, trade_report_id);
@@ -0,0 +1,7 @@
+-- Reports of an order may be stored out of order; orders keeps the state of
+-- the one the engine sent last, ranked by the book's report sequence.
+ALTER TABLE executions
+    ADD COLUMN report_seq bigint NOT NULL DEFAULT 0; -- 5002
+
+ALTER TABLE orders
+    ADD COLUMN report_seq bigint NOT NULL DEFAULT 0;
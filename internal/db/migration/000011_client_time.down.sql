@@ -0,0 +1 @@
+ALTER TABLE executions DROP COLUMN IF EXISTS client_transact_time;
@@ -0,0 +1,4 @@
+-- An execution keeps the TransactTime the client sent with the request it
+-- answers next to the engine's own, for audit.
+ALTER TABLE executions
+    ADD COLUMN client_transact_time bigint NOT NULL DEFAULT 0; -- 5003
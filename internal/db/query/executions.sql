@@ -1,6 +1,28 @@
 -- name: CreateExecution :exec
-INSERT INTO executions (exec_id, order_id, cl_ord_id, exec_type, ord_status, symbol, side, order_qty, last_shares, last_px, leaves_qty, cum_qty, avg_px, transact_time, text, msg_type)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16);
+-- CreateExecution stores the report and upserts its order in one statement.
+-- Reports may be saved out of order, so the order only takes the state of a
+-- report with a higher ReportSeq than the one it holds.
+WITH execution AS (
+    INSERT INTO executions (exec_id, order_id, cl_ord_id, exec_type, ord_status, symbol, side, order_qty, last_shares, last_px, leaves_qty, cum_qty, avg_px, transact_time, text, msg_type, account, price, engine_seq, trade_id, ord_type, time_in_force, last_liquidity_ind, trade_date, report_seq, client_transact_time)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26)
+    ON CONFLICT (exec_id, trade_date) DO NOTHING
+)
+INSERT INTO orders (order_id, cl_ord_id, account, symbol, side, price, order_qty, leaves_qty, cum_qty, avg_px, ord_status, created_time, transact_time, engine_seq, report_seq)
+SELECT $2, $3, $17, $6, $7, $18, $8, $11, $12, $13, $5, $14, $14, $19, $25
+WHERE $2 <> ''
+ON CONFLICT (order_id) DO UPDATE
+SET cl_ord_id     = EXCLUDED.cl_ord_id,
+    account       = EXCLUDED.account,
+    price         = EXCLUDED.price,
+    order_qty     = EXCLUDED.order_qty,
+    leaves_qty    = EXCLUDED.leaves_qty,
+    cum_qty       = EXCLUDED.cum_qty,
+    avg_px        = EXCLUDED.avg_px,
+    ord_status    = EXCLUDED.ord_status,
+    transact_time = EXCLUDED.transact_time,
+    engine_seq    = EXCLUDED.engine_seq,
+    report_seq    = EXCLUDED.report_seq
+WHERE EXCLUDED.report_seq > orders.report_seq;
 
 -- name: GetExecution :one
 SELECT *
@@ -8,9 +30,26 @@ FROM executions
 WHERE exec_id = $1;
 
 -- name: ListExecutions :many
+-- A page of the executions that match the filters set, in (TransactTime,
+-- ExecID) order from just after the given key. The trade date bounds only
+-- prune partitions and must cover the TransactTime range.
 SELECT *
 FROM executions
-ORDER BY exec_id;
+WHERE trade_date BETWEEN sqlc.arg(from_date) AND sqlc.arg(to_date)
+  AND transact_time >= sqlc.arg(from_time)
+  AND transact_time < sqlc.arg(to_time)
+  AND (transact_time, exec_id) > (sqlc.arg(after_time)::bigint, sqlc.arg(after_id)::text)
+  AND (sqlc.narg(symbol)::text IS NULL OR symbol = sqlc.narg(symbol))
+  AND (sqlc.narg(order_id)::text IS NULL OR order_id = sqlc.narg(order_id))
+  AND (sqlc.narg(account)::text IS NULL OR account = sqlc.narg(account))
+ORDER BY transact_time, exec_id
+LIMIT sqlc.arg(max_rows);
+
+-- name: ListExecutionsByOrderID :many
+SELECT *
+FROM executions
+WHERE order_id = $1
+ORDER BY transact_time, exec_id;
 
 -- name: UpdateExecution :one
 UPDATE executions
@@ -0,0 +1,42 @@
+-- name: GetOrder :one
+SELECT *
+FROM orders
+WHERE order_id = $1;
+
+-- name: GetOrderByClOrdID :one
+SELECT *
+FROM orders
+WHERE cl_ord_id = $1
+ORDER BY transact_time DESC
+LIMIT 1;
+
+-- name: ListOpenOrdersBySymbol :many
+SELECT *
+FROM orders
+WHERE symbol = $1
+  AND ord_status IN ('0', '1')
+ORDER BY created_time, order_id;
+
+-- name: ListOpenOrdersByAccount :many
+SELECT *
+FROM orders
+WHERE account = $1
+  AND ord_status IN ('0', '1')
+ORDER BY created_time, order_id;
+
+-- name: ListOpenOrders :many
+-- The orders of every symbol that are not done, each symbol's in time
+-- priority. A status that is neither done nor resting is left for the caller
+-- to refuse.
+SELECT *
+FROM orders
+WHERE ord_status NOT IN ('2', '4', '8')
+ORDER BY symbol, engine_seq, created_time, order_id;
+
+-- name: BackdateOrderCreatedTime :exec
+-- Reports may be saved out of order; the earliest one saved tells when the
+-- order was created.
+UPDATE orders
+SET created_time = $2
+WHERE order_id = $1
+  AND created_time > $2;
@@ -7,17 +7,23 @@ INSERT INTO trade_capture_reports (
     last_qty,
     last_px,
     trade_date,
-    transact_time
+    transact_time,
+    trade_id
 )
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+ON CONFLICT (trade_report_id, trade_date) DO NOTHING;
 
 -- name: CreateTradeSide :exec
 INSERT INTO trade_sides (
     trade_report_id,
     side,
-    order_id
+    order_id,
+    exec_id,
+    cl_ord_id,
+    trade_date
 )
-VALUES ($1, $2, $3);
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (trade_report_id, side, trade_date) DO NOTHING;
 
 -- name: GetTrade :one
 SELECT *
@@ -29,16 +35,66 @@ SELECT *
 FROM trade_sides
 WHERE trade_report_id = $1;
 
+-- name: GetTradeWithFills :many
+-- A trade with each of its sides and that side's fill report.
+SELECT sqlc.embed(t), s.side, sqlc.embed(e)
+FROM trade_capture_reports t
+         JOIN trade_sides s ON s.trade_report_id = t.trade_report_id AND s.trade_date = t.trade_date
+         JOIN executions e ON e.exec_id = s.exec_id AND e.trade_date = s.trade_date
+WHERE t.trade_id = $1
+ORDER BY s.side;
+
 -- name: ListTrades :many
 SELECT *
 FROM trade_capture_reports
 ORDER BY trade_report_id;
 
+-- name: ListTradesSince :many
+SELECT *
+FROM trade_capture_reports
+WHERE transact_time >= $1
+ORDER BY transact_time;
+
+-- name: ListRecentTradesWithSides :many
+SELECT t.*, s.id AS side_id, s.side, s.order_id, s.exec_id AS side_exec_id, s.cl_ord_id AS side_cl_ord_id
+FROM (SELECT *
+      FROM trade_capture_reports
+      WHERE symbol = $1
+      ORDER BY transact_time DESC, trade_report_id DESC
+      LIMIT $2) t
+         JOIN trade_sides s ON t.trade_report_id = s.trade_report_id AND t.trade_date = s.trade_date
+ORDER BY t.transact_time DESC, t.trade_report_id DESC, s.id;
+
 -- name: ListTradeWithSides :many
-SELECT t.*, s.id AS side_id, s.side, s.order_id
-FROM trade_capture_reports t
-         JOIN trade_sides s ON t.trade_report_id = s.trade_report_id
-ORDER BY t.trade_report_id;
+-- A page of the trades that match the filters set, in (TransactTime,
+-- TradeReportID) order from just after the given key, with their sides. A
+-- trade is an order's or an account's when one of its sides is. The trade
+-- date bounds only prune partitions and must cover the TransactTime range.
+SELECT t.*, s.id AS side_id, s.side, s.order_id, s.exec_id AS side_exec_id, s.cl_ord_id AS side_cl_ord_id
+FROM (SELECT *
+      FROM trade_capture_reports tr
+      WHERE tr.trade_date BETWEEN sqlc.arg(from_date) AND sqlc.arg(to_date)
+        AND tr.transact_time >= sqlc.arg(from_time)
+        AND tr.transact_time < sqlc.arg(to_time)
+        AND (tr.transact_time, tr.trade_report_id) > (sqlc.arg(after_time)::bigint, sqlc.arg(after_id)::text)
+        AND (sqlc.narg(symbol)::text IS NULL OR tr.symbol = sqlc.narg(symbol))
+        AND (sqlc.narg(order_id)::text IS NULL OR EXISTS (
+            SELECT 1
+            FROM trade_sides os
+            WHERE os.trade_report_id = tr.trade_report_id
+              AND os.trade_date = tr.trade_date
+              AND os.order_id = sqlc.narg(order_id)))
+        AND (sqlc.narg(account)::text IS NULL OR EXISTS (
+            SELECT 1
+            FROM trade_sides fs
+                     JOIN executions e ON e.exec_id = fs.exec_id AND e.trade_date = fs.trade_date
+            WHERE fs.trade_report_id = tr.trade_report_id
+              AND fs.trade_date = tr.trade_date
+              AND e.account = sqlc.narg(account)))
+      ORDER BY tr.transact_time, tr.trade_report_id
+      LIMIT sqlc.arg(max_rows)) t
+         JOIN trade_sides s ON t.trade_report_id = s.trade_report_id AND t.trade_date = s.trade_date
+ORDER BY t.transact_time, t.trade_report_id, s.id;
 
 -- name: UpdateTrade :one
 UPDATE trade_capture_reports
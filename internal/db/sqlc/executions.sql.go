@@ -12,29 +12,61 @@ import (
 )
 
 const createExecution = `-- name: CreateExecution :exec
-INSERT INTO executions (exec_id, order_id, cl_ord_id, exec_type, ord_status, symbol, side, order_qty, last_shares, last_px, leaves_qty, cum_qty, avg_px, transact_time, text, msg_type)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+WITH execution AS (
+    INSERT INTO executions (exec_id, order_id, cl_ord_id, exec_type, ord_status, symbol, side, order_qty, last_shares, last_px, leaves_qty, cum_qty, avg_px, transact_time, text, msg_type, account, price, engine_seq, trade_id, ord_type, time_in_force, last_liquidity_ind, trade_date, report_seq, client_transact_time)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26)
+    ON CONFLICT (exec_id, trade_date) DO NOTHING
+)
+INSERT INTO orders (order_id, cl_ord_id, account, symbol, side, price, order_qty, leaves_qty, cum_qty, avg_px, ord_status, created_time, transact_time, engine_seq, report_seq)
+SELECT $2, $3, $17, $6, $7, $18, $8, $11, $12, $13, $5, $14, $14, $19, $25
+WHERE $2 <> ''
+ON CONFLICT (order_id) DO UPDATE
+SET cl_ord_id     = EXCLUDED.cl_ord_id,
+    account       = EXCLUDED.account,
+    price         = EXCLUDED.price,
+    order_qty     = EXCLUDED.order_qty,
+    leaves_qty    = EXCLUDED.leaves_qty,
+    cum_qty       = EXCLUDED.cum_qty,
+    avg_px        = EXCLUDED.avg_px,
+    ord_status    = EXCLUDED.ord_status,
+    transact_time = EXCLUDED.transact_time,
+    engine_seq    = EXCLUDED.engine_seq,
+    report_seq    = EXCLUDED.report_seq
+WHERE EXCLUDED.report_seq > orders.report_seq
 `
 
 type CreateExecutionParams struct {
-	ExecID       string         `json:"exec_id"`
-	OrderID      string         `json:"order_id"`
-	ClOrdID      pgtype.Text    `json:"cl_ord_id"`
-	ExecType     string         `json:"exec_type"`
-	OrdStatus    string         `json:"ord_status"`
-	Symbol       string         `json:"symbol"`
-	Side         string         `json:"side"`
-	OrderQty     pgtype.Numeric `json:"order_qty"`
-	LastShares   pgtype.Numeric `json:"last_shares"`
-	LastPx       pgtype.Numeric `json:"last_px"`
-	LeavesQty    pgtype.Numeric `json:"leaves_qty"`
-	CumQty       pgtype.Numeric `json:"cum_qty"`
-	AvgPx        pgtype.Numeric `json:"avg_px"`
-	TransactTime int64          `json:"transact_time"`
-	Text         pgtype.Text    `json:"text"`
-	MsgType      string         `json:"msg_type"`
+	ExecID             string         `json:"exec_id"`
+	OrderID            string         `json:"order_id"`
+	ClOrdID            pgtype.Text    `json:"cl_ord_id"`
+	ExecType           string         `json:"exec_type"`
+	OrdStatus          string         `json:"ord_status"`
+	Symbol             string         `json:"symbol"`
+	Side               string         `json:"side"`
+	OrderQty           pgtype.Numeric `json:"order_qty"`
+	LastShares         pgtype.Numeric `json:"last_shares"`
+	LastPx             pgtype.Numeric `json:"last_px"`
+	LeavesQty          pgtype.Numeric `json:"leaves_qty"`
+	CumQty             pgtype.Numeric `json:"cum_qty"`
+	AvgPx              pgtype.Numeric `json:"avg_px"`
+	TransactTime       int64          `json:"transact_time"`
+	Text               pgtype.Text    `json:"text"`
+	MsgType            string         `json:"msg_type"`
+	Account            pgtype.Text    `json:"account"`
+	Price              pgtype.Numeric `json:"price"`
+	EngineSeq          int64          `json:"engine_seq"`
+	TradeID            pgtype.Text    `json:"trade_id"`
+	OrdType            pgtype.Text    `json:"ord_type"`
+	TimeInForce        pgtype.Text    `json:"time_in_force"`
+	LastLiquidityInd   pgtype.Text    `json:"last_liquidity_ind"`
+	TradeDate          pgtype.Date    `json:"trade_date"`
+	ReportSeq          int64          `json:"report_seq"`
+	ClientTransactTime int64          `json:"client_transact_time"`
 }
 
+// CreateExecution stores the report and upserts its order in one statement.
+// Reports may be saved out of order, so the order only takes the state of a
+// report with a higher ReportSeq than the one it holds.
 func (q *Queries) CreateExecution(ctx context.Context, arg CreateExecutionParams) error {
 	_, err := q.db.Exec(ctx, createExecution,
 		arg.ExecID,
@@ -53,6 +85,16 @@ func (q *Queries) CreateExecution(ctx context.Context, arg CreateExecutionParams
 		arg.TransactTime,
 		arg.Text,
 		arg.MsgType,
+		arg.Account,
+		arg.Price,
+		arg.EngineSeq,
+		arg.TradeID,
+		arg.OrdType,
+		arg.TimeInForce,
+		arg.LastLiquidityInd,
+		arg.TradeDate,
+		arg.ReportSeq,
+		arg.ClientTransactTime,
 	)
 	return err
 }
@@ -60,7 +102,7 @@ func (q *Queries) CreateExecution(ctx context.Context, arg CreateExecutionParams
 const deleteExecution = `-- name: DeleteExecution :one
 DELETE
 FROM executions
-WHERE exec_id = $1 RETURNING msg_type, exec_id, order_id, cl_ord_id, exec_type, ord_status, symbol, side, order_qty, last_shares, last_px, leaves_qty, cum_qty, avg_px, transact_time, text
+WHERE exec_id = $1 RETURNING msg_type, exec_id, order_id, cl_ord_id, exec_type, ord_status, symbol, side, order_qty, last_shares, last_px, leaves_qty, cum_qty, avg_px, transact_time, text, account, price, engine_seq, trade_id, ord_type, time_in_force, last_liquidity_ind, trade_date, report_seq, client_transact_time
 `
 
 func (q *Queries) DeleteExecution(ctx context.Context, execID string) (Execution, error) {
@@ -83,12 +125,22 @@ func (q *Queries) DeleteExecution(ctx context.Context, execID string) (Execution
 		&i.AvgPx,
 		&i.TransactTime,
 		&i.Text,
+		&i.Account,
+		&i.Price,
+		&i.EngineSeq,
+		&i.TradeID,
+		&i.OrdType,
+		&i.TimeInForce,
+		&i.LastLiquidityInd,
+		&i.TradeDate,
+		&i.ReportSeq,
+		&i.ClientTransactTime,
 	)
 	return i, err
 }
 
 const getExecution = `-- name: GetExecution :one
-SELECT msg_type, exec_id, order_id, cl_ord_id, exec_type, ord_status, symbol, side, order_qty, last_shares, last_px, leaves_qty, cum_qty, avg_px, transact_time, text
+SELECT msg_type, exec_id, order_id, cl_ord_id, exec_type, ord_status, symbol, side, order_qty, last_shares, last_px, leaves_qty, cum_qty, avg_px, transact_time, text, account, price, engine_seq, trade_id, ord_type, time_in_force, last_liquidity_ind, trade_date, report_seq, client_transact_time
 FROM executions
 WHERE exec_id = $1
 `
@@ -113,18 +165,117 @@ func (q *Queries) GetExecution(ctx context.Context, execID string) (Execution, e
 		&i.AvgPx,
 		&i.TransactTime,
 		&i.Text,
+		&i.Account,
+		&i.Price,
+		&i.EngineSeq,
+		&i.TradeID,
+		&i.OrdType,
+		&i.TimeInForce,
+		&i.LastLiquidityInd,
+		&i.TradeDate,
+		&i.ReportSeq,
+		&i.ClientTransactTime,
 	)
 	return i, err
 }
 
 const listExecutions = `-- name: ListExecutions :many
-SELECT msg_type, exec_id, order_id, cl_ord_id, exec_type, ord_status, symbol, side, order_qty, last_shares, last_px, leaves_qty, cum_qty, avg_px, transact_time, text
+SELECT msg_type, exec_id, order_id, cl_ord_id, exec_type, ord_status, symbol, side, order_qty, last_shares, last_px, leaves_qty, cum_qty, avg_px, transact_time, text, account, price, engine_seq, trade_id, ord_type, time_in_force, last_liquidity_ind, trade_date, report_seq, client_transact_time
+FROM executions
+WHERE trade_date BETWEEN $1 AND $2
+  AND transact_time >= $3
+  AND transact_time < $4
+  AND (transact_time, exec_id) > ($5::bigint, $6::text)
+  AND ($7::text IS NULL OR symbol = $7)
+  AND ($8::text IS NULL OR order_id = $8)
+  AND ($9::text IS NULL OR account = $9)
+ORDER BY transact_time, exec_id
+LIMIT $10
+`
+
+type ListExecutionsParams struct {
+	FromDate  pgtype.Date `json:"from_date"`
+	ToDate    pgtype.Date `json:"to_date"`
+	FromTime  int64       `json:"from_time"`
+	ToTime    int64       `json:"to_time"`
+	AfterTime int64       `json:"after_time"`
+	AfterID   string      `json:"after_id"`
+	Symbol    pgtype.Text `json:"symbol"`
+	OrderID   pgtype.Text `json:"order_id"`
+	Account   pgtype.Text `json:"account"`
+	MaxRows   int32       `json:"max_rows"`
+}
+
+// A page of the executions that match the filters set, in (TransactTime,
+// ExecID) order from just after the given key. The trade date bounds only
+// prune partitions and must cover the TransactTime range.
+func (q *Queries) ListExecutions(ctx context.Context, arg ListExecutionsParams) ([]Execution, error) {
+	rows, err := q.db.Query(ctx, listExecutions,
+		arg.FromDate,
+		arg.ToDate,
+		arg.FromTime,
+		arg.ToTime,
+		arg.AfterTime,
+		arg.AfterID,
+		arg.Symbol,
+		arg.OrderID,
+		arg.Account,
+		arg.MaxRows,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Execution{}
+	for rows.Next() {
+		var i Execution
+		if err := rows.Scan(
+			&i.MsgType,
+			&i.ExecID,
+			&i.OrderID,
+			&i.ClOrdID,
+			&i.ExecType,
+			&i.OrdStatus,
+			&i.Symbol,
+			&i.Side,
+			&i.OrderQty,
+			&i.LastShares,
+			&i.LastPx,
+			&i.LeavesQty,
+			&i.CumQty,
+			&i.AvgPx,
+			&i.TransactTime,
+			&i.Text,
+			&i.Account,
+			&i.Price,
+			&i.EngineSeq,
+			&i.TradeID,
+			&i.OrdType,
+			&i.TimeInForce,
+			&i.LastLiquidityInd,
+			&i.TradeDate,
+			&i.ReportSeq,
+			&i.ClientTransactTime,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listExecutionsByOrderID = `-- name: ListExecutionsByOrderID :many
+SELECT msg_type, exec_id, order_id, cl_ord_id, exec_type, ord_status, symbol, side, order_qty, last_shares, last_px, leaves_qty, cum_qty, avg_px, transact_time, text, account, price, engine_seq, trade_id, ord_type, time_in_force, last_liquidity_ind, trade_date, report_seq, client_transact_time
 FROM executions
-ORDER BY exec_id
+WHERE order_id = $1
+ORDER BY transact_time, exec_id
 `
 
-func (q *Queries) ListExecutions(ctx context.Context) ([]Execution, error) {
-	rows, err := q.db.Query(ctx, listExecutions)
+func (q *Queries) ListExecutionsByOrderID(ctx context.Context, orderID string) ([]Execution, error) {
+	rows, err := q.db.Query(ctx, listExecutionsByOrderID, orderID)
 	if err != nil {
 		return nil, err
 	}
@@ -149,6 +300,16 @@ func (q *Queries) ListExecutions(ctx context.Context) ([]Execution, error) {
 			&i.AvgPx,
 			&i.TransactTime,
 			&i.Text,
+			&i.Account,
+			&i.Price,
+			&i.EngineSeq,
+			&i.TradeID,
+			&i.OrdType,
+			&i.TimeInForce,
+			&i.LastLiquidityInd,
+			&i.TradeDate,
+			&i.ReportSeq,
+			&i.ClientTransactTime,
 		); err != nil {
 			return nil, err
 		}
@@ -175,7 +336,7 @@ SET cl_ord_id     = COALESCE($2, cl_ord_id),
     avg_px        = COALESCE($12, avg_px),
     transact_time = COALESCE($13, transact_time),
     text          = COALESCE($14, text)
-WHERE exec_id = $1 RETURNING msg_type, exec_id, order_id, cl_ord_id, exec_type, ord_status, symbol, side, order_qty, last_shares, last_px, leaves_qty, cum_qty, avg_px, transact_time, text
+WHERE exec_id = $1 RETURNING msg_type, exec_id, order_id, cl_ord_id, exec_type, ord_status, symbol, side, order_qty, last_shares, last_px, leaves_qty, cum_qty, avg_px, transact_time, text, account, price, engine_seq, trade_id, ord_type, time_in_force, last_liquidity_ind, trade_date, report_seq, client_transact_time
 `
 
 type UpdateExecutionParams struct {
@@ -230,6 +391,16 @@ func (q *Queries) UpdateExecution(ctx context.Context, arg UpdateExecutionParams
 		&i.AvgPx,
 		&i.TransactTime,
 		&i.Text,
+		&i.Account,
+		&i.Price,
+		&i.EngineSeq,
+		&i.TradeID,
+		&i.OrdType,
+		&i.TimeInForce,
+		&i.LastLiquidityInd,
+		&i.TradeDate,
+		&i.ReportSeq,
+		&i.ClientTransactTime,
 	)
 	return i, err
 }
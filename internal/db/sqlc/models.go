@@ -9,22 +9,50 @@ import (
 )
 
 type Execution struct {
-	MsgType      string         `json:"msg_type"`
-	ExecID       string         `json:"exec_id"`
+	MsgType            string         `json:"msg_type"`
+	ExecID             string         `json:"exec_id"`
+	OrderID            string         `json:"order_id"`
+	ClOrdID            pgtype.Text    `json:"cl_ord_id"`
+	ExecType           string         `json:"exec_type"`
+	OrdStatus          string         `json:"ord_status"`
+	Symbol             string         `json:"symbol"`
+	Side               string         `json:"side"`
+	OrderQty           pgtype.Numeric `json:"order_qty"`
+	LastShares         pgtype.Numeric `json:"last_shares"`
+	LastPx             pgtype.Numeric `json:"last_px"`
+	LeavesQty          pgtype.Numeric `json:"leaves_qty"`
+	CumQty             pgtype.Numeric `json:"cum_qty"`
+	AvgPx              pgtype.Numeric `json:"avg_px"`
+	TransactTime       int64          `json:"transact_time"`
+	Text               pgtype.Text    `json:"text"`
+	Account            pgtype.Text    `json:"account"`
+	Price              pgtype.Numeric `json:"price"`
+	EngineSeq          int64          `json:"engine_seq"`
+	TradeID            pgtype.Text    `json:"trade_id"`
+	OrdType            pgtype.Text    `json:"ord_type"`
+	TimeInForce        pgtype.Text    `json:"time_in_force"`
+	LastLiquidityInd   pgtype.Text    `json:"last_liquidity_ind"`
+	TradeDate          pgtype.Date    `json:"trade_date"`
+	ReportSeq          int64          `json:"report_seq"`
+	ClientTransactTime int64          `json:"client_transact_time"`
+}
+
+type Order struct {
 	OrderID      string         `json:"order_id"`
 	ClOrdID      pgtype.Text    `json:"cl_ord_id"`
-	ExecType     string         `json:"exec_type"`
-	OrdStatus    string         `json:"ord_status"`
+	Account      pgtype.Text    `json:"account"`
 	Symbol       string         `json:"symbol"`
 	Side         string         `json:"side"`
+	Price        pgtype.Numeric `json:"price"`
 	OrderQty     pgtype.Numeric `json:"order_qty"`
-	LastShares   pgtype.Numeric `json:"last_shares"`
-	LastPx       pgtype.Numeric `json:"last_px"`
 	LeavesQty    pgtype.Numeric `json:"leaves_qty"`
 	CumQty       pgtype.Numeric `json:"cum_qty"`
 	AvgPx        pgtype.Numeric `json:"avg_px"`
+	OrdStatus    string         `json:"ord_status"`
+	CreatedTime  int64          `json:"created_time"`
 	TransactTime int64          `json:"transact_time"`
-	Text         pgtype.Text    `json:"text"`
+	EngineSeq    int64          `json:"engine_seq"`
+	ReportSeq    int64          `json:"report_seq"`
 }
 
 type TradeCaptureReport struct {
@@ -34,13 +62,17 @@ type TradeCaptureReport struct {
 	Symbol        string         `json:"symbol"`
 	LastQty       pgtype.Numeric `json:"last_qty"`
 	LastPx        pgtype.Numeric `json:"last_px"`
-	TradeDate     string         `json:"trade_date"`
+	TradeDate     pgtype.Date    `json:"trade_date"`
 	TransactTime  int64          `json:"transact_time"`
+	TradeID       pgtype.Text    `json:"trade_id"`
 }
 
 type TradeSide struct {
-	ID            int32  `json:"id"`
-	TradeReportID string `json:"trade_report_id"`
-	Side          int16  `json:"side"`
-	OrderID       string `json:"order_id"`
+	ID            int32       `json:"id"`
+	TradeReportID string      `json:"trade_report_id"`
+	Side          int16       `json:"side"`
+	OrderID       string      `json:"order_id"`
+	ExecID        pgtype.Text `json:"exec_id"`
+	ClOrdID       pgtype.Text `json:"cl_ord_id"`
+	TradeDate     pgtype.Date `json:"trade_date"`
 }
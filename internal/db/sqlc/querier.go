@@ -6,9 +6,14 @@ package db
 
 import (
 	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type Querier interface {
+	// Reports may be saved out of order; the earliest one saved tells when the
+	// order was created.
+	BackdateOrderCreatedTime(ctx context.Context, arg BackdateOrderCreatedTimeParams) error
 	CreateExecution(ctx context.Context, arg CreateExecutionParams) error
 	CreateTrade(ctx context.Context, arg CreateTradeParams) error
 	CreateTradeSide(ctx context.Context, arg CreateTradeSideParams) error
@@ -16,11 +21,31 @@ type Querier interface {
 	DeleteTrade(ctx context.Context, tradeReportID string) (TradeCaptureReport, error)
 	DeleteTradeSidesByTradeID(ctx context.Context, tradeReportID string) ([]TradeSide, error)
 	GetExecution(ctx context.Context, execID string) (Execution, error)
+	GetOrder(ctx context.Context, orderID string) (Order, error)
+	GetOrderByClOrdID(ctx context.Context, clOrdID pgtype.Text) (Order, error)
 	GetTrade(ctx context.Context, tradeReportID string) (TradeCaptureReport, error)
 	GetTradeSides(ctx context.Context, tradeReportID string) ([]TradeSide, error)
-	ListExecutions(ctx context.Context) ([]Execution, error)
-	ListTradeWithSides(ctx context.Context) ([]ListTradeWithSidesRow, error)
+	// A trade with each of its sides and that side's fill report.
+	GetTradeWithFills(ctx context.Context, tradeID pgtype.Text) ([]GetTradeWithFillsRow, error)
+	// A page of the executions that match the filters set, in (TransactTime,
+	// ExecID) order from just after the given key. The trade date bounds only
+	// prune partitions and must cover the TransactTime range.
+	ListExecutions(ctx context.Context, arg ListExecutionsParams) ([]Execution, error)
+	ListExecutionsByOrderID(ctx context.Context, orderID string) ([]Execution, error)
+	// The orders of every symbol that are not done, each symbol's in time
+	// priority. A status that is neither done nor resting is left for the caller
+	// to refuse.
+	ListOpenOrders(ctx context.Context) ([]Order, error)
+	ListOpenOrdersByAccount(ctx context.Context, account pgtype.Text) ([]Order, error)
+	ListOpenOrdersBySymbol(ctx context.Context, symbol string) ([]Order, error)
+	ListRecentTradesWithSides(ctx context.Context, arg ListRecentTradesWithSidesParams) ([]ListRecentTradesWithSidesRow, error)
+	// A page of the trades that match the filters set, in (TransactTime,
+	// TradeReportID) order from just after the given key, with their sides. A
+	// trade is an order's or an account's when one of its sides is. The trade
+	// date bounds only prune partitions and must cover the TransactTime range.
+	ListTradeWithSides(ctx context.Context, arg ListTradeWithSidesParams) ([]ListTradeWithSidesRow, error)
 	ListTrades(ctx context.Context) ([]TradeCaptureReport, error)
+	ListTradesSince(ctx context.Context, transactTime int64) ([]TradeCaptureReport, error)
 	UpdateExecution(ctx context.Context, arg UpdateExecutionParams) (Execution, error)
 	UpdateTrade(ctx context.Context, arg UpdateTradeParams) (TradeCaptureReport, error)
 	UpdateTradeSide(ctx context.Context, arg UpdateTradeSideParams) (TradeSide, error)
@@ -20,9 +20,11 @@ INSERT INTO trade_capture_reports (
     last_qty,
     last_px,
     trade_date,
-    transact_time
+    transact_time,
+    trade_id
 )
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+ON CONFLICT (trade_report_id, trade_date) DO NOTHING
 `
 
 type CreateTradeParams struct {
@@ -32,8 +34,9 @@ type CreateTradeParams struct {
 	Symbol        string         `json:"symbol"`
 	LastQty       pgtype.Numeric `json:"last_qty"`
 	LastPx        pgtype.Numeric `json:"last_px"`
-	TradeDate     string         `json:"trade_date"`
+	TradeDate     pgtype.Date    `json:"trade_date"`
 	TransactTime  int64          `json:"transact_time"`
+	TradeID       pgtype.Text    `json:"trade_id"`
 }
 
 func (q *Queries) CreateTrade(ctx context.Context, arg CreateTradeParams) error {
@@ -46,6 +49,7 @@ func (q *Queries) CreateTrade(ctx context.Context, arg CreateTradeParams) error
 		arg.LastPx,
 		arg.TradeDate,
 		arg.TransactTime,
+		arg.TradeID,
 	)
 	return err
 }
@@ -54,26 +58,40 @@ const createTradeSide = `-- name: CreateTradeSide :exec
 INSERT INTO trade_sides (
     trade_report_id,
     side,
-    order_id
+    order_id,
+    exec_id,
+    cl_ord_id,
+    trade_date
 )
-VALUES ($1, $2, $3)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (trade_report_id, side, trade_date) DO NOTHING
 `
 
 type CreateTradeSideParams struct {
-	TradeReportID string `json:"trade_report_id"`
-	Side          int16  `json:"side"`
-	OrderID       string `json:"order_id"`
+	TradeReportID string      `json:"trade_report_id"`
+	Side          int16       `json:"side"`
+	OrderID       string      `json:"order_id"`
+	ExecID        pgtype.Text `json:"exec_id"`
+	ClOrdID       pgtype.Text `json:"cl_ord_id"`
+	TradeDate     pgtype.Date `json:"trade_date"`
 }
 
 func (q *Queries) CreateTradeSide(ctx context.Context, arg CreateTradeSideParams) error {
-	_, err := q.db.Exec(ctx, createTradeSide, arg.TradeReportID, arg.Side, arg.OrderID)
+	_, err := q.db.Exec(ctx, createTradeSide,
+		arg.TradeReportID,
+		arg.Side,
+		arg.OrderID,
+		arg.ExecID,
+		arg.ClOrdID,
+		arg.TradeDate,
+	)
 	return err
 }
 
 const deleteTrade = `-- name: DeleteTrade :one
 DELETE FROM trade_capture_reports
 WHERE trade_report_id = $1
-    RETURNING trade_report_id, msg_type, exec_id, symbol, last_qty, last_px, trade_date, transact_time
+    RETURNING trade_report_id, msg_type, exec_id, symbol, last_qty, last_px, trade_date, transact_time, trade_id
 `
 
 func (q *Queries) DeleteTrade(ctx context.Context, tradeReportID string) (TradeCaptureReport, error) {
@@ -88,6 +106,7 @@ func (q *Queries) DeleteTrade(ctx context.Context, tradeReportID string) (TradeC
 		&i.LastPx,
 		&i.TradeDate,
 		&i.TransactTime,
+		&i.TradeID,
 	)
 	return i, err
 }
@@ -95,7 +114,7 @@ func (q *Queries) DeleteTrade(ctx context.Context, tradeReportID string) (TradeC
 const deleteTradeSidesByTradeID = `-- name: DeleteTradeSidesByTradeID :many
 DELETE FROM trade_sides
 WHERE trade_report_id = $1
-    RETURNING id, trade_report_id, side, order_id
+    RETURNING id, trade_report_id, side, order_id, exec_id, cl_ord_id, trade_date
 `
 
 func (q *Queries) DeleteTradeSidesByTradeID(ctx context.Context, tradeReportID string) ([]TradeSide, error) {
@@ -112,6 +131,9 @@ func (q *Queries) DeleteTradeSidesByTradeID(ctx context.Context, tradeReportID s
 			&i.TradeReportID,
 			&i.Side,
 			&i.OrderID,
+			&i.ExecID,
+			&i.ClOrdID,
+			&i.TradeDate,
 		); err != nil {
 			return nil, err
 		}
@@ -124,7 +146,7 @@ func (q *Queries) DeleteTradeSidesByTradeID(ctx context.Context, tradeReportID s
 }
 
 const getTrade = `-- name: GetTrade :one
-SELECT trade_report_id, msg_type, exec_id, symbol, last_qty, last_px, trade_date, transact_time
+SELECT trade_report_id, msg_type, exec_id, symbol, last_qty, last_px, trade_date, transact_time, trade_id
 FROM trade_capture_reports
 WHERE trade_report_id = $1
 `
@@ -141,12 +163,13 @@ func (q *Queries) GetTrade(ctx context.Context, tradeReportID string) (TradeCapt
 		&i.LastPx,
 		&i.TradeDate,
 		&i.TransactTime,
+		&i.TradeID,
 	)
 	return i, err
 }
 
 const getTradeSides = `-- name: GetTradeSides :many
-SELECT id, trade_report_id, side, order_id
+SELECT id, trade_report_id, side, order_id, exec_id, cl_ord_id, trade_date
 FROM trade_sides
 WHERE trade_report_id = $1
 `
@@ -165,6 +188,9 @@ func (q *Queries) GetTradeSides(ctx context.Context, tradeReportID string) ([]Tr
 			&i.TradeReportID,
 			&i.Side,
 			&i.OrderID,
+			&i.ExecID,
+			&i.ClOrdID,
+			&i.TradeDate,
 		); err != nil {
 			return nil, err
 		}
@@ -176,13 +202,188 @@ func (q *Queries) GetTradeSides(ctx context.Context, tradeReportID string) ([]Tr
 	return items, nil
 }
 
-const listTradeWithSides = `-- name: ListTradeWithSides :many
-SELECT t.trade_report_id, t.msg_type, t.exec_id, t.symbol, t.last_qty, t.last_px, t.trade_date, t.transact_time, s.id AS side_id, s.side, s.order_id
+const getTradeWithFills = `-- name: GetTradeWithFills :many
+SELECT t.trade_report_id, t.msg_type, t.exec_id, t.symbol, t.last_qty, t.last_px, t.trade_date, t.transact_time, t.trade_id, s.side, e.msg_type, e.exec_id, e.order_id, e.cl_ord_id, e.exec_type, e.ord_status, e.symbol, e.side, e.order_qty, e.last_shares, e.last_px, e.leaves_qty, e.cum_qty, e.avg_px, e.transact_time, e.text, e.account, e.price, e.engine_seq, e.trade_id, e.ord_type, e.time_in_force, e.last_liquidity_ind, e.trade_date, e.report_seq, e.client_transact_time
 FROM trade_capture_reports t
-         JOIN trade_sides s ON t.trade_report_id = s.trade_report_id
-ORDER BY t.trade_report_id
+         JOIN trade_sides s ON s.trade_report_id = t.trade_report_id AND s.trade_date = t.trade_date
+         JOIN executions e ON e.exec_id = s.exec_id AND e.trade_date = s.trade_date
+WHERE t.trade_id = $1
+ORDER BY s.side
 `
 
+type GetTradeWithFillsRow struct {
+	TradeCaptureReport TradeCaptureReport `json:"trade_capture_report"`
+	Side               int16              `json:"side"`
+	Execution          Execution          `json:"execution"`
+}
+
+// A trade with each of its sides and that side's fill report.
+func (q *Queries) GetTradeWithFills(ctx context.Context, tradeID pgtype.Text) ([]GetTradeWithFillsRow, error) {
+	rows, err := q.db.Query(ctx, getTradeWithFills, tradeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetTradeWithFillsRow{}
+	for rows.Next() {
+		var i GetTradeWithFillsRow
+		if err := rows.Scan(
+			&i.TradeCaptureReport.TradeReportID,
+			&i.TradeCaptureReport.MsgType,
+			&i.TradeCaptureReport.ExecID,
+			&i.TradeCaptureReport.Symbol,
+			&i.TradeCaptureReport.LastQty,
+			&i.TradeCaptureReport.LastPx,
+			&i.TradeCaptureReport.TradeDate,
+			&i.TradeCaptureReport.TransactTime,
+			&i.TradeCaptureReport.TradeID,
+			&i.Side,
+			&i.Execution.MsgType,
+			&i.Execution.ExecID,
+			&i.Execution.OrderID,
+			&i.Execution.ClOrdID,
+			&i.Execution.ExecType,
+			&i.Execution.OrdStatus,
+			&i.Execution.Symbol,
+			&i.Execution.Side,
+			&i.Execution.OrderQty,
+			&i.Execution.LastShares,
+			&i.Execution.LastPx,
+			&i.Execution.LeavesQty,
+			&i.Execution.CumQty,
+			&i.Execution.AvgPx,
+			&i.Execution.TransactTime,
+			&i.Execution.Text,
+			&i.Execution.Account,
+			&i.Execution.Price,
+			&i.Execution.EngineSeq,
+			&i.Execution.TradeID,
+			&i.Execution.OrdType,
+			&i.Execution.TimeInForce,
+			&i.Execution.LastLiquidityInd,
+			&i.Execution.TradeDate,
+			&i.Execution.ReportSeq,
+			&i.Execution.ClientTransactTime,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRecentTradesWithSides = `-- name: ListRecentTradesWithSides :many
+SELECT t.trade_report_id, t.msg_type, t.exec_id, t.symbol, t.last_qty, t.last_px, t.trade_date, t.transact_time, t.trade_id, s.id AS side_id, s.side, s.order_id, s.exec_id AS side_exec_id, s.cl_ord_id AS side_cl_ord_id
+FROM (SELECT trade_report_id, msg_type, exec_id, symbol, last_qty, last_px, trade_date, transact_time, trade_id
+      FROM trade_capture_reports
+      WHERE symbol = $1
+      ORDER BY transact_time DESC, trade_report_id DESC
+      LIMIT $2) t
+         JOIN trade_sides s ON t.trade_report_id = s.trade_report_id AND t.trade_date = s.trade_date
+ORDER BY t.transact_time DESC, t.trade_report_id DESC, s.id
+`
+
+type ListRecentTradesWithSidesParams struct {
+	Symbol string `json:"symbol"`
+	Limit  int32  `json:"limit"`
+}
+
+type ListRecentTradesWithSidesRow struct {
+	TradeReportID string         `json:"trade_report_id"`
+	MsgType       string         `json:"msg_type"`
+	ExecID        string         `json:"exec_id"`
+	Symbol        string         `json:"symbol"`
+	LastQty       pgtype.Numeric `json:"last_qty"`
+	LastPx        pgtype.Numeric `json:"last_px"`
+	TradeDate     pgtype.Date    `json:"trade_date"`
+	TransactTime  int64          `json:"transact_time"`
+	TradeID       pgtype.Text    `json:"trade_id"`
+	SideID        int32          `json:"side_id"`
+	Side          int16          `json:"side"`
+	OrderID       string         `json:"order_id"`
+	SideExecID    pgtype.Text    `json:"side_exec_id"`
+	SideClOrdID   pgtype.Text    `json:"side_cl_ord_id"`
+}
+
+func (q *Queries) ListRecentTradesWithSides(ctx context.Context, arg ListRecentTradesWithSidesParams) ([]ListRecentTradesWithSidesRow, error) {
+	rows, err := q.db.Query(ctx, listRecentTradesWithSides, arg.Symbol, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListRecentTradesWithSidesRow{}
+	for rows.Next() {
+		var i ListRecentTradesWithSidesRow
+		if err := rows.Scan(
+			&i.TradeReportID,
+			&i.MsgType,
+			&i.ExecID,
+			&i.Symbol,
+			&i.LastQty,
+			&i.LastPx,
+			&i.TradeDate,
+			&i.TransactTime,
+			&i.TradeID,
+			&i.SideID,
+			&i.Side,
+			&i.OrderID,
+			&i.SideExecID,
+			&i.SideClOrdID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTradeWithSides = `-- name: ListTradeWithSides :many
+SELECT t.trade_report_id, t.msg_type, t.exec_id, t.symbol, t.last_qty, t.last_px, t.trade_date, t.transact_time, t.trade_id, s.id AS side_id, s.side, s.order_id, s.exec_id AS side_exec_id, s.cl_ord_id AS side_cl_ord_id
+FROM (SELECT trade_report_id, msg_type, exec_id, symbol, last_qty, last_px, trade_date, transact_time, trade_id
+      FROM trade_capture_reports tr
+      WHERE tr.trade_date BETWEEN $1 AND $2
+        AND tr.transact_time >= $3
+        AND tr.transact_time < $4
+        AND (tr.transact_time, tr.trade_report_id) > ($5::bigint, $6::text)
+        AND ($7::text IS NULL OR tr.symbol = $7)
+        AND ($8::text IS NULL OR EXISTS (
+            SELECT 1
+            FROM trade_sides os
+            WHERE os.trade_report_id = tr.trade_report_id
+              AND os.trade_date = tr.trade_date
+              AND os.order_id = $8))
+        AND ($9::text IS NULL OR EXISTS (
+            SELECT 1
+            FROM trade_sides fs
+                     JOIN executions e ON e.exec_id = fs.exec_id AND e.trade_date = fs.trade_date
+            WHERE fs.trade_report_id = tr.trade_report_id
+              AND fs.trade_date = tr.trade_date
+              AND e.account = $9))
+      ORDER BY tr.transact_time, tr.trade_report_id
+      LIMIT $10) t
+         JOIN trade_sides s ON t.trade_report_id = s.trade_report_id AND t.trade_date = s.trade_date
+ORDER BY t.transact_time, t.trade_report_id, s.id
+`
+
+type ListTradeWithSidesParams struct {
+	FromDate  pgtype.Date `json:"from_date"`
+	ToDate    pgtype.Date `json:"to_date"`
+	FromTime  int64       `json:"from_time"`
+	ToTime    int64       `json:"to_time"`
+	AfterTime int64       `json:"after_time"`
+	AfterID   string      `json:"after_id"`
+	Symbol    pgtype.Text `json:"symbol"`
+	OrderID   pgtype.Text `json:"order_id"`
+	Account   pgtype.Text `json:"account"`
+	MaxRows   int32       `json:"max_rows"`
+}
+
 type ListTradeWithSidesRow struct {
 	TradeReportID string         `json:"trade_report_id"`
 	MsgType       string         `json:"msg_type"`
@@ -190,15 +391,33 @@ type ListTradeWithSidesRow struct {
 	Symbol        string         `json:"symbol"`
 	LastQty       pgtype.Numeric `json:"last_qty"`
 	LastPx        pgtype.Numeric `json:"last_px"`
-	TradeDate     string         `json:"trade_date"`
+	TradeDate     pgtype.Date    `json:"trade_date"`
 	TransactTime  int64          `json:"transact_time"`
+	TradeID       pgtype.Text    `json:"trade_id"`
 	SideID        int32          `json:"side_id"`
 	Side          int16          `json:"side"`
 	OrderID       string         `json:"order_id"`
+	SideExecID    pgtype.Text    `json:"side_exec_id"`
+	SideClOrdID   pgtype.Text    `json:"side_cl_ord_id"`
 }
 
-func (q *Queries) ListTradeWithSides(ctx context.Context) ([]ListTradeWithSidesRow, error) {
-	rows, err := q.db.Query(ctx, listTradeWithSides)
+// A page of the trades that match the filters set, in (TransactTime,
+// TradeReportID) order from just after the given key, with their sides. A
+// trade is an order's or an account's when one of its sides is. The trade
+// date bounds only prune partitions and must cover the TransactTime range.
+func (q *Queries) ListTradeWithSides(ctx context.Context, arg ListTradeWithSidesParams) ([]ListTradeWithSidesRow, error) {
+	rows, err := q.db.Query(ctx, listTradeWithSides,
+		arg.FromDate,
+		arg.ToDate,
+		arg.FromTime,
+		arg.ToTime,
+		arg.AfterTime,
+		arg.AfterID,
+		arg.Symbol,
+		arg.OrderID,
+		arg.Account,
+		arg.MaxRows,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -215,9 +434,12 @@ func (q *Queries) ListTradeWithSides(ctx context.Context) ([]ListTradeWithSidesR
 			&i.LastPx,
 			&i.TradeDate,
 			&i.TransactTime,
+			&i.TradeID,
 			&i.SideID,
 			&i.Side,
 			&i.OrderID,
+			&i.SideExecID,
+			&i.SideClOrdID,
 		); err != nil {
 			return nil, err
 		}
@@ -230,7 +452,7 @@ func (q *Queries) ListTradeWithSides(ctx context.Context) ([]ListTradeWithSidesR
 }
 
 const listTrades = `-- name: ListTrades :many
-SELECT trade_report_id, msg_type, exec_id, symbol, last_qty, last_px, trade_date, transact_time
+SELECT trade_report_id, msg_type, exec_id, symbol, last_qty, last_px, trade_date, transact_time, trade_id
 FROM trade_capture_reports
 ORDER BY trade_report_id
 `
@@ -253,6 +475,44 @@ func (q *Queries) ListTrades(ctx context.Context) ([]TradeCaptureReport, error)
 			&i.LastPx,
 			&i.TradeDate,
 			&i.TransactTime,
+			&i.TradeID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTradesSince = `-- name: ListTradesSince :many
+SELECT trade_report_id, msg_type, exec_id, symbol, last_qty, last_px, trade_date, transact_time, trade_id
+FROM trade_capture_reports
+WHERE transact_time >= $1
+ORDER BY transact_time
+`
+
+func (q *Queries) ListTradesSince(ctx context.Context, transactTime int64) ([]TradeCaptureReport, error) {
+	rows, err := q.db.Query(ctx, listTradesSince, transactTime)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []TradeCaptureReport{}
+	for rows.Next() {
+		var i TradeCaptureReport
+		if err := rows.Scan(
+			&i.TradeReportID,
+			&i.MsgType,
+			&i.ExecID,
+			&i.Symbol,
+			&i.LastQty,
+			&i.LastPx,
+			&i.TradeDate,
+			&i.TransactTime,
+			&i.TradeID,
 		); err != nil {
 			return nil, err
 		}
@@ -275,7 +535,7 @@ SET
     trade_date      = COALESCE($7, trade_date),
     transact_time   = COALESCE($8, transact_time)
 WHERE trade_report_id = $1
-    RETURNING trade_report_id, msg_type, exec_id, symbol, last_qty, last_px, trade_date, transact_time
+    RETURNING trade_report_id, msg_type, exec_id, symbol, last_qty, last_px, trade_date, transact_time, trade_id
 `
 
 type UpdateTradeParams struct {
@@ -285,7 +545,7 @@ type UpdateTradeParams struct {
 	Symbol        string         `json:"symbol"`
 	LastQty       pgtype.Numeric `json:"last_qty"`
 	LastPx        pgtype.Numeric `json:"last_px"`
-	TradeDate     string         `json:"trade_date"`
+	TradeDate     pgtype.Date    `json:"trade_date"`
 	TransactTime  int64          `json:"transact_time"`
 }
 
@@ -310,6 +570,7 @@ func (q *Queries) UpdateTrade(ctx context.Context, arg UpdateTradeParams) (Trade
 		&i.LastPx,
 		&i.TradeDate,
 		&i.TransactTime,
+		&i.TradeID,
 	)
 	return i, err
 }
@@ -320,7 +581,7 @@ SET
     side     = COALESCE($2, side),
     order_id = COALESCE($3, order_id)
 WHERE id = $1
-    RETURNING id, trade_report_id, side, order_id
+    RETURNING id, trade_report_id, side, order_id, exec_id, cl_ord_id, trade_date
 `
 
 type UpdateTradeSideParams struct {
@@ -337,6 +598,9 @@ func (q *Queries) UpdateTradeSide(ctx context.Context, arg UpdateTradeSideParams
 		&i.TradeReportID,
 		&i.Side,
 		&i.OrderID,
+		&i.ExecID,
+		&i.ClOrdID,
+		&i.TradeDate,
 	)
 	return i, err
 }
@@ -0,0 +1,642 @@
+package fix
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"MatchingEngine/internal/model"
+)
+
+// BusinessRejectReason <380> values.
+const (
+	businessRejectOther              = "0"
+	businessRejectUnsupportedMsgType = "3"
+)
+
+const (
+	defaultLogonTimeout = 10 * time.Second
+	// sessionQueueSize bounds the reports waiting for one session. A session
+	// whose writes stall is disconnected by the write timeout, after which
+	// its reports only go to the store, long before the queue fills; the
+	// reports that still find it full are stored without being sent.
+	sessionQueueSize = 1024
+)
+
+type OrderService interface {
+	ProcessOrderRequest(req model.OrderRequest) error
+}
+
+type Notifier interface {
+	NotifyEventAndTrade(msgType model.MsgType, key string, value json.RawMessage) error
+}
+
+type Opts struct {
+	Address      string
+	SenderCompID string
+	StoreDir     string
+	// TargetCompIDs are the counterparties allowed to log on; empty allows
+	// any.
+	TargetCompIDs []string
+	// Accounts are those each counterparty may ask for the trades of.
+	Accounts     Accounts
+	LogonTimeout time.Duration
+	Now          func() time.Time
+}
+
+// Accounts is a fixed table of counterparty CompID to the accounts it is
+// entitled to.
+type Accounts map[string][]string
+
+// ParseAccounts reads a comma-separated list of CompID=account pairs; a
+// CompID entitled to several accounts is listed once for each.
+func ParseAccounts(s string) (Accounts, error) {
+	accounts := Accounts{}
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		compID, account, ok := strings.Cut(pair, "=")
+		if !ok || compID == "" || account == "" {
+			return nil, fmt.Errorf("invalid account entry %q, expected CompID=account", pair)
+		}
+		if !slices.Contains(accounts[compID], account) {
+			accounts[compID] = append(accounts[compID], account)
+		}
+	}
+	return accounts, nil
+}
+
+// Allows reports whether compID is entitled to account.
+func (a Accounts) Allows(compID, account string) bool {
+	return slices.Contains(a[compID], account)
+}
+
+// Acceptor accepts FIX sessions, hands their orders to the order service and
+// sends each session the execution reports of the orders it entered.
+type Acceptor struct {
+	opts    Opts
+	orders  OrderService
+	store   *FileStore
+	reports *ReportQueue
+	// Trades answers TradeCaptureReportRequests; without it they are
+	// rejected.
+	Trades TradeHistory
+
+	mu       sync.Mutex
+	sessions map[string]*Session    // by counterparty CompID
+	owners   map[string]*ownedOrder // by every ClOrdID the order goes by
+	conns    map[net.Conn]struct{}
+	// answering holds the sessions with a trade request being answered.
+	answering map[*Session]bool
+}
+
+// NewAcceptor sends the sessions the execution reports arriving on reports,
+// which the order service's notifier must feed.
+func NewAcceptor(opts Opts, orders OrderService, reports *ReportQueue) *Acceptor {
+	if opts.LogonTimeout <= 0 {
+		opts.LogonTimeout = defaultLogonTimeout
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	return &Acceptor{
+		opts:      opts,
+		orders:    orders,
+		store:     NewFileStore(opts.StoreDir),
+		reports:   reports,
+		sessions:  make(map[string]*Session),
+		owners:    make(map[string]*ownedOrder),
+		conns:     make(map[net.Conn]struct{}),
+		answering: make(map[*Session]bool),
+	}
+}
+
+func (a *Acceptor) ListenAndServe(ctx context.Context) error {
+	ln, err := net.Listen("tcp", a.opts.Address)
+	if err != nil {
+		return fmt.Errorf("failed to listen for FIX sessions: %w", err)
+	}
+	return a.Serve(ctx, ln)
+}
+
+// Serve accepts connections until the context is cancelled, then logs every
+// session out and waits for its connections to end. The orders entered
+// through the sessions before a restart are restored first, so their reports
+// find the sessions again.
+func (a *Acceptor) Serve(ctx context.Context, ln net.Listener) error {
+	if err := a.restoreOwners(); err != nil {
+		ln.Close()
+		return err
+	}
+	log.Printf("FIX acceptor %s listening on %s", a.opts.SenderCompID, ln.Addr())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		a.dispatchReports(ctx)
+	}()
+
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-ctx.Done()
+		ln.Close()
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		for _, s := range a.sessions {
+			s.Logout("acceptor shutting down")
+		}
+		for conn := range a.conns {
+			conn.Close()
+		}
+	}()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() == nil {
+				err = fmt.Errorf("failed to accept FIX connection: %w", err)
+				ln.Close()
+			} else {
+				err = nil
+			}
+			<-stopped
+			wg.Wait()
+			return err
+		}
+		a.mu.Lock()
+		a.conns[conn] = struct{}{}
+		a.mu.Unlock()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.serveConn(conn)
+		}()
+	}
+}
+
+func (a *Acceptor) serveConn(conn net.Conn) {
+	defer func() {
+		conn.Close()
+		a.mu.Lock()
+		delete(a.conns, conn)
+		a.mu.Unlock()
+	}()
+	r := NewReader(conn)
+
+	conn.SetReadDeadline(a.opts.Now().Add(a.opts.LogonTimeout))
+	logon, err := r.ReadMessage()
+	if err != nil {
+		log.Printf("FIX connection from %s dropped before logon: %v", conn.RemoteAddr(), err)
+		return
+	}
+	session, heartbeat, err := a.acceptLogon(logon)
+	if err != nil {
+		log.Printf("FIX logon from %s refused: %v", conn.RemoteAddr(), err)
+		return
+	}
+	if err := session.logon(conn, logon, heartbeat); err != nil {
+		log.Printf("FIX logon of %s refused: %v", session.targetCompID, err)
+		return
+	}
+	defer session.detach(conn)
+	log.Printf("FIX session %s logged on from %s", session.ID, conn.RemoteAddr())
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go session.keepAlive(stop)
+
+	for {
+		// A counterparty silent through a TestRequest is gone.
+		conn.SetReadDeadline(a.opts.Now().Add(2*heartbeat + heartbeat/2))
+		msg, err := r.ReadMessage()
+		if errors.Is(err, ErrGarbled) {
+			log.Printf("FIX session %s: ignoring message: %v", session.ID, err)
+			continue
+		}
+		if err != nil {
+			log.Printf("FIX session %s disconnected: %v", session.ID, err)
+			return
+		}
+		if session.receive(msg) {
+			log.Printf("FIX session %s logged out", session.ID)
+			return
+		}
+	}
+}
+
+// acceptLogon checks a connection's first message and returns the session it
+// logs on to.
+func (a *Acceptor) acceptLogon(msg *Message) (*Session, time.Duration, error) {
+	if msg.MsgType() != MsgTypeLogon {
+		return nil, 0, fmt.Errorf("first message is %s, not Logon", msg.MsgType())
+	}
+	if target, _ := msg.Get(TagTargetCompID); target != a.opts.SenderCompID {
+		return nil, 0, fmt.Errorf("logon for TargetCompID %q", target)
+	}
+	sender, err := msg.Require(TagSenderCompID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !a.allowed(sender) {
+		return nil, 0, fmt.Errorf("unknown SenderCompID %q", sender)
+	}
+	hb, err := msg.Require(TagHeartBtInt)
+	if err != nil {
+		return nil, 0, err
+	}
+	seconds, err := strconv.Atoi(hb)
+	if err != nil || seconds <= 0 {
+		return nil, 0, fmt.Errorf("invalid HeartBtInt %q", hb)
+	}
+
+	session, err := a.session(sender)
+	if err != nil {
+		return nil, 0, err
+	}
+	return session, time.Duration(seconds) * time.Second, nil
+}
+
+func (a *Acceptor) allowed(compID string) bool {
+	if len(a.opts.TargetCompIDs) == 0 {
+		return true
+	}
+	for _, id := range a.opts.TargetCompIDs {
+		if id == compID {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *Acceptor) session(targetCompID string) (*Session, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if s, ok := a.sessions[targetCompID]; ok {
+		return s, nil
+	}
+	s, err := newSession(a.opts.SenderCompID, targetCompID, a.store, a.opts.Now, a.handleApplication)
+	if err != nil {
+		return nil, err
+	}
+	a.sessions[targetCompID] = s
+	return s, nil
+}
+
+// handleApplication passes an order message on to the order service. The
+// ClOrdIDs it names are tied to the session first, so its reports find the
+// way back.
+// TradeCaptureReportRequests are answered from Trades.
+func (a *Acceptor) handleApplication(s *Session, msg *Message) {
+	if msg.MsgType() == MsgTypeTradeCaptureReportReq {
+		a.handleTradeRequest(s, msg)
+		return
+	}
+	req, err := toOrderRequest(msg)
+	switch {
+	case errors.Is(err, ErrUnsupported):
+		a.businessReject(s, msg, businessRejectUnsupportedMsgType, err.Error())
+		return
+	case errors.Is(err, ErrMissingField):
+		sessionReject(s, msg, rejectRequiredTagMissing, err.Error())
+		return
+	case err != nil:
+		sessionReject(s, msg, rejectValueIncorrect, err.Error())
+		return
+	}
+
+	claimed, err := a.claim(s, msg)
+	if err != nil {
+		a.businessReject(s, msg, businessRejectOther, err.Error())
+		return
+	}
+	if err := a.orders.ProcessOrderRequest(req); err != nil {
+		a.release(claimed)
+		a.businessReject(s, msg, businessRejectOther, err.Error())
+		return
+	}
+}
+
+// ownedOrder is an order entered through a session, under every ClOrdID it
+// has gone by. Its OrderID is known once the book has reported on it. Each
+// change is stored with the session, under the first ClOrdID the order went
+// by.
+type ownedOrder struct {
+	session  *Session
+	key      string
+	orderID  string
+	clOrdIDs []string
+}
+
+// restoreOwners ties the orders stored with each session back to it.
+func (a *Acceptor) restoreOwners() error {
+	ids, err := a.store.Sessions()
+	if err != nil {
+		return err
+	}
+	prefix := BeginString + ":" + a.opts.SenderCompID + "->"
+	for _, id := range ids {
+		target, ok := strings.CutPrefix(id, prefix)
+		if !ok {
+			continue
+		}
+		s, err := a.session(target)
+		if err != nil {
+			return err
+		}
+		records, err := a.store.OwnedOrders(s.ID)
+		if err != nil {
+			return err
+		}
+		a.mu.Lock()
+		for _, rec := range records {
+			order := &ownedOrder{session: s, key: rec.Key, orderID: rec.OrderID, clOrdIDs: rec.ClOrdIDs}
+			for _, clOrdID := range rec.ClOrdIDs {
+				a.owners[clOrdID] = order
+			}
+		}
+		a.mu.Unlock()
+	}
+	return nil
+}
+
+// claim ties the ClOrdID and OrigClOrdID of a message to the session, unless
+// another session already uses them, and returns the ClOrdIDs it newly tied.
+// A cancel or replace joins the order it names, so the ClOrdIDs go together
+// when the order is done with. The ClOrdIDs are stored before the request
+// goes on.
+func (a *Acceptor) claim(s *Session, msg *Message) ([]string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	ids := []string{firstValue(msg, TagClOrdID)}
+	if orig, ok := msg.Get(TagOrigClOrdID); ok {
+		ids = append(ids, orig)
+	}
+	order := &ownedOrder{session: s}
+	for _, id := range ids {
+		owned, ok := a.owners[id]
+		if !ok {
+			continue
+		}
+		if owned.session != s {
+			return nil, fmt.Errorf("ClOrdID %s belongs to another session", id)
+		}
+		order = owned
+	}
+	var claimed []string
+	for _, id := range ids {
+		if _, ok := a.owners[id]; !ok && !slices.Contains(claimed, id) {
+			claimed = append(claimed, id)
+		}
+	}
+	if len(claimed) == 0 {
+		return nil, nil
+	}
+	if order.key == "" {
+		order.key = claimed[0]
+	}
+	if err := a.saveOwned(order, append(slices.Clone(order.clOrdIDs), claimed...)); err != nil {
+		return nil, err
+	}
+	for _, id := range claimed {
+		a.owners[id] = order
+	}
+	order.clOrdIDs = append(order.clOrdIDs, claimed...)
+	return claimed, nil
+}
+
+// release unties ClOrdIDs of a request that never reached the engine.
+func (a *Acceptor) release(ids []string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var order *ownedOrder
+	for _, id := range ids {
+		order = a.owners[id]
+		delete(a.owners, id)
+		order.clOrdIDs = slices.DeleteFunc(order.clOrdIDs, func(c string) bool { return c == id })
+	}
+	if order != nil {
+		a.saveOwnedLogged(order, order.clOrdIDs)
+	}
+}
+
+// owner returns the session a report goes to. An order that is done with
+// releases all its ClOrdIDs, as does a rejected cancel or replace naming an
+// order the session never had reported.
+func (a *Acceptor) owner(er model.ExecutionReport) (*Session, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	order, ok := a.owners[er.ClOrdID]
+	if !ok {
+		return nil, false
+	}
+	if isTerminal(er) || (er.OrderID == "" && order.orderID == "") {
+		for _, id := range order.clOrdIDs {
+			delete(a.owners, id)
+		}
+		a.saveOwnedLogged(order, nil)
+		return order.session, true
+	}
+	if order.orderID == "" {
+		order.orderID = er.OrderID
+		a.saveOwnedLogged(order, order.clOrdIDs)
+	}
+	return order.session, true
+}
+
+// saveOwned stores an order of a session as going by ids; no ids end it.
+func (a *Acceptor) saveOwned(order *ownedOrder, ids []string) error {
+	return a.store.SaveOwnedOrder(order.session.ID, ownedRecord{Key: order.key, OrderID: order.orderID, ClOrdIDs: ids})
+}
+
+// saveOwnedLogged stores an order where nothing can be refused: after a
+// failure the order is known as it was stored last, should the acceptor
+// restart.
+func (a *Acceptor) saveOwnedLogged(order *ownedOrder, ids []string) {
+	if err := a.saveOwned(order, ids); err != nil {
+		log.Printf("FIX session %s: cannot store order %s: %v", order.session.ID, order.key, err)
+	}
+}
+
+func sessionReject(s *Session, msg *Message, reason, text string) {
+	s.Send(NewMessage(MsgTypeReject).
+		Add(TagRefSeqNum, firstValue(msg, TagMsgSeqNum)).
+		Add(TagRefMsgType, msg.MsgType()).
+		Add(TagSessionRejectReason, reason).
+		Add(TagText, text))
+}
+
+func (a *Acceptor) businessReject(s *Session, msg *Message, reason, text string) {
+	s.Send(NewMessage(MsgTypeBusinessMessageReject).
+		Add(TagRefSeqNum, firstValue(msg, TagMsgSeqNum)).
+		Add(TagRefMsgType, msg.MsgType()).
+		Add(TagBusinessRejectRefID, firstValue(msg, TagClOrdID)).
+		Add(TagBusinessRejectReason, reason).
+		Add(TagText, text))
+}
+
+// ReportQueue carries execution reports from the engine to the acceptor. The
+// engine never waits on it: reports queue up in memory while the acceptor
+// catches up, and once the acceptor has stopped nothing more is queued.
+type ReportQueue struct {
+	mu      sync.Mutex
+	reports []model.ExecutionReport
+	ready   chan struct{}
+	done    chan struct{}
+	stop    sync.Once
+}
+
+func NewReportQueue() *ReportQueue {
+	return &ReportQueue{
+		ready: make(chan struct{}, 1),
+		done:  make(chan struct{}),
+	}
+}
+
+func (q *ReportQueue) close() {
+	q.stop.Do(func() { close(q.done) })
+}
+
+func (q *ReportQueue) push(er model.ExecutionReport) {
+	select {
+	case <-q.done:
+		return
+	default:
+	}
+	q.mu.Lock()
+	q.reports = append(q.reports, er)
+	q.mu.Unlock()
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// take returns the queued reports, oldest first, and empties the queue.
+func (q *ReportQueue) take() []model.ExecutionReport {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	reports := q.reports
+	q.reports = nil
+	return reports
+}
+
+// Notifier returns a notifier that forwards everything to next and also
+// queues the execution reports.
+func (q *ReportQueue) Notifier(next Notifier) Notifier {
+	return &reportNotifier{next: next, queue: q}
+}
+
+type reportNotifier struct {
+	next  Notifier
+	queue *ReportQueue
+}
+
+func (n *reportNotifier) NotifyEventAndTrade(msgType model.MsgType, key string, value json.RawMessage) error {
+	err := n.next.NotifyEventAndTrade(msgType, key, value)
+	if msgType != model.MsgTypeExecRpt {
+		return err
+	}
+	var er model.ExecutionReport
+	if decodeErr := json.Unmarshal(value, &er); decodeErr != nil {
+		log.Printf("FIX acceptor: cannot decode execution report %s: %v", key, decodeErr)
+		return err
+	}
+	n.queue.push(er)
+	return err
+}
+
+// dispatchReports hands each report to the queue of the session that owns
+// its order. Every session has its own sender, so one that is slow to read
+// holds up neither the others nor the engine; a report that finds the
+// session's queue full is stored for the session to ask for again.
+func (a *Acceptor) dispatchReports(ctx context.Context) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer a.reports.close()
+
+	queues := make(map[*Session]chan model.ExecutionReport)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-a.reports.ready:
+		}
+		for _, er := range a.reports.take() {
+			s, ok := a.owner(er)
+			if !ok {
+				continue
+			}
+			queue, ok := queues[s]
+			if !ok {
+				queue = make(chan model.ExecutionReport, sessionQueueSize)
+				queues[s] = queue
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					sendReports(ctx, s, queue)
+				}()
+			}
+			select {
+			case queue <- er:
+			default:
+				storeReport(s, er)
+			}
+		}
+	}
+}
+
+// storeReport keeps a report for a session too far behind to take it.
+func storeReport(s *Session, er model.ExecutionReport) {
+	msg, err := Marshal(er)
+	if err == nil {
+		err = s.Store(msg)
+	}
+	if err != nil {
+		log.Printf("FIX session %s: too far behind, cannot store execution report %s: %v", s.ID, er.ExecID, err)
+	}
+}
+
+func sendReports(ctx context.Context, s *Session, reports <-chan model.ExecutionReport) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case er := <-reports:
+			msg, err := Marshal(er)
+			if err != nil {
+				log.Printf("FIX session %s: cannot encode execution report %s: %v", s.ID, er.ExecID, err)
+				continue
+			}
+			if err := s.Send(msg); err != nil {
+				log.Printf("FIX session %s: execution report %s kept for resend: %v", s.ID, er.ExecID, err)
+			}
+		}
+	}
+}
+
+// isTerminal tells whether an order is done with. A rejected replace leaves
+// the order as it was, with its own status; a cancel or replace the book
+// could not match to an order is rejected without an OrderID and ends
+// nothing, as the order may well live on in another book.
+func isTerminal(er model.ExecutionReport) bool {
+	switch er.OrdStatus {
+	case model.OrderStatusFill, model.OrderStatusCanceled:
+		return true
+	case model.OrderStatusRejected:
+		return er.OrderID != ""
+	}
+	return false
+}
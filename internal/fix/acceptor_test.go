@@ -0,0 +1,411 @@
+package fix
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"MatchingEngine/internal/model"
+	"MatchingEngine/internal/service"
+	"MatchingEngine/orderBook"
+)
+
+type nopNotifier struct{}
+
+func (nopNotifier) NotifyEventAndTrade(model.MsgType, string, json.RawMessage) error { return nil }
+
+type recordingOrders struct {
+	mu       sync.Mutex
+	requests []model.OrderRequest
+}
+
+func (o *recordingOrders) ProcessOrderRequest(req model.OrderRequest) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.requests = append(o.requests, req)
+	return nil
+}
+
+func (o *recordingOrders) all() []model.OrderRequest {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]model.OrderRequest(nil), o.requests...)
+}
+
+// client is the counterparty side of a session in tests.
+type client struct {
+	t    *testing.T
+	conn net.Conn
+	r    *Reader
+	seq  uint64
+}
+
+func dial(t *testing.T, addr string, seq uint64) *client {
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return &client{t: t, conn: conn, r: NewReader(conn), seq: seq}
+}
+
+func (c *client) send(msg *Message) {
+	c.sendSeq(msg, c.seq)
+	c.seq++
+}
+
+func (c *client) sendSeq(msg *Message, seq uint64) {
+	out := NewMessage(msg.MsgType()).
+		Add(TagSenderCompID, "CLIENT").
+		Add(TagTargetCompID, "ME").
+		Add(TagMsgSeqNum, strconv.FormatUint(seq, 10)).
+		Add(TagSendingTime, time.Now().UTC().Format(sendingTimeLayout))
+	out.Fields = append(out.Fields, msg.Fields[1:]...)
+	_, err := c.conn.Write(out.Bytes())
+	require.NoError(c.t, err)
+}
+
+func (c *client) read() *Message {
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	msg, err := c.r.ReadMessage()
+	require.NoError(c.t, err)
+	return msg
+}
+
+// readType skips heartbeats and returns the next message of the given type.
+func (c *client) readType(msgType string) *Message {
+	for {
+		msg := c.read()
+		if msg.MsgType() == msgType {
+			return msg
+		}
+		require.Equal(c.t, MsgTypeHeartbeat, msg.MsgType(), "unexpected %s", msg)
+	}
+}
+
+func (c *client) logon(extra ...Field) *Message {
+	msg := NewMessage(MsgTypeLogon).Add(TagEncryptMethod, "0").Add(TagHeartBtInt, "30")
+	msg.Fields = append(msg.Fields, extra...)
+	c.send(msg)
+	return c.readType(MsgTypeLogon)
+}
+
+func startAcceptor(t *testing.T, dir string, orders OrderService, reports *ReportQueue, setup ...func(*Acceptor)) (*Acceptor, string) {
+	if reports == nil {
+		reports = NewReportQueue()
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	acceptor := NewAcceptor(Opts{SenderCompID: "ME", StoreDir: dir}, orders, reports)
+	for _, f := range setup {
+		f(acceptor)
+	}
+	done := make(chan error, 1)
+	go func() { done <- acceptor.Serve(ctx, ln) }()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return acceptor, ln.Addr().String()
+}
+
+func newOrderSingle(clOrdID, side, qty, px string) *Message {
+	return NewMessage(MsgTypeNewOrderSingle).
+		Add(TagClOrdID, clOrdID).
+		Add(TagSymbol, "BTC/USDT").
+		Add(TagSide, side).
+		Add(TagTransactTime, "20241025-00:00:00.000").
+		Add(TagOrderQty, qty).
+		Add(TagOrdType, ordTypeLimit).
+		Add(TagPrice, px)
+}
+
+func TestAcceptor_OrdersAndExecutionReports(t *testing.T) {
+	reports := NewReportQueue()
+	orders := service.NewOrderService(reports.Notifier(nopNotifier{}), orderBook.BookOpts{})
+	_, addr := startAcceptor(t, t.TempDir(), orders, reports)
+
+	c := dial(t, addr, 1)
+	logon := c.logon()
+	assert.Equal(t, "1", firstValue(logon, TagMsgSeqNum))
+
+	c.send(newOrderSingle("S1", "2", "5", "100"))
+	er := c.readType(MsgTypeExecutionReport)
+	assert.Equal(t, "S1", firstValue(er, TagClOrdID))
+	assert.Equal(t, string(model.ExecTypeNew), firstValue(er, TagExecType))
+	assert.Equal(t, "5", firstValue(er, TagLeavesQty))
+
+	c.send(NewMessage(MsgTypeOrderCancelReplace).
+		Add(TagOrigClOrdID, "S1").
+		Add(TagClOrdID, "S1R").
+		Add(TagSymbol, "BTC/USDT").
+		Add(TagSide, "2").
+		Add(TagOrderQty, "3").
+		Add(TagOrdType, ordTypeLimit).
+		Add(TagPrice, "100"))
+	er = c.readType(MsgTypeExecutionReport)
+	assert.Equal(t, string(model.ExecTypeReplaced), firstValue(er, TagExecType))
+	assert.Equal(t, "S1R", firstValue(er, TagClOrdID))
+
+	c.send(NewMessage(MsgTypeOrderCancelRequest).
+		Add(TagOrigClOrdID, "S1R").
+		Add(TagClOrdID, "C1").
+		Add(TagSymbol, "BTC/USDT").
+		Add(TagSide, "2"))
+	er = c.readType(MsgTypeExecutionReport)
+	assert.Equal(t, string(model.ExecTypeCanceled), firstValue(er, TagExecType))
+
+	// Market orders are not supported.
+	market := newOrderSingle("M1", "1", "1", "0")
+	market.Set(TagOrdType, "1")
+	c.send(market)
+	reject := c.readType(MsgTypeBusinessMessageReject)
+	assert.Equal(t, businessRejectUnsupportedMsgType, firstValue(reject, TagBusinessRejectReason))
+	assert.Equal(t, "M1", firstValue(reject, TagBusinessRejectRefID))
+
+	// A missing required field is a session-level reject.
+	c.send(NewMessage(MsgTypeNewOrderSingle).Add(TagClOrdID, "X1"))
+	reject = c.readType(MsgTypeReject)
+	assert.Equal(t, rejectRequiredTagMissing, firstValue(reject, TagSessionRejectReason))
+}
+
+func TestAcceptor_TestRequestAndLogout(t *testing.T) {
+	_, addr := startAcceptor(t, t.TempDir(), &recordingOrders{}, nil)
+	c := dial(t, addr, 1)
+	c.logon()
+
+	c.send(NewMessage(MsgTypeTestRequest).Add(TagTestReqID, "ping"))
+	hb := c.read()
+	assert.Equal(t, MsgTypeHeartbeat, hb.MsgType())
+	assert.Equal(t, "ping", firstValue(hb, TagTestReqID))
+
+	c.send(NewMessage(MsgTypeLogout))
+	assert.Equal(t, MsgTypeLogout, c.readType(MsgTypeLogout).MsgType())
+}
+
+func TestAcceptor_RequestsResendOnGap(t *testing.T) {
+	orders := &recordingOrders{}
+	_, addr := startAcceptor(t, t.TempDir(), orders, nil)
+	c := dial(t, addr, 1)
+	c.logon()
+
+	// Message 2 is lost; 3 arrives first.
+	c.sendSeq(newOrderSingle("B2", "1", "1", "100"), 3)
+	resend := c.readType(MsgTypeResendRequest)
+	assert.Equal(t, "2", firstValue(resend, TagBeginSeqNo))
+	assert.Equal(t, "0", firstValue(resend, TagEndSeqNo))
+
+	c.sendSeq(newOrderSingle("B1", "1", "1", "100"), 2)
+	gapFill := NewMessage(MsgTypeSequenceReset).Add(TagPossDupFlag, "Y").Add(TagGapFillFlag, "Y").Add(TagNewSeqNo, "4")
+	c.sendSeq(gapFill, 3)
+	c.seq = 4
+	c.send(NewMessage(MsgTypeTestRequest).Add(TagTestReqID, "sync"))
+	assert.Equal(t, "sync", firstValue(c.readType(MsgTypeHeartbeat), TagTestReqID))
+
+	requests := orders.all()
+	require.Len(t, requests, 1)
+	assert.Equal(t, "B1", requests[0].NewOrderReq.ClOrdID)
+}
+
+func TestAcceptor_PersistsSequenceAndResendsAfterReconnect(t *testing.T) {
+	dir := t.TempDir()
+	reports := NewReportQueue()
+	orders := service.NewOrderService(reports.Notifier(nopNotifier{}), orderBook.BookOpts{})
+	acceptor, addr := startAcceptor(t, dir, orders, reports)
+
+	c := dial(t, addr, 1)
+	c.logon()
+	c.send(newOrderSingle("S1", "2", "5", "100"))
+	c.readType(MsgTypeExecutionReport)
+	c.send(NewMessage(MsgTypeLogout))
+	c.readType(MsgTypeLogout)
+
+	// A fill reported while the client is away is kept for it.
+	require.NoError(t, orders.ProcessOrderRequest(model.OrderRequest{
+		MsgType: model.MsgTypeNew,
+		NewOrderReq: model.NewOrderRequest{
+			BaseOrderRequest: model.BaseOrderRequest{MsgType: model.MsgTypeNew, ClOrdID: "B1", Side: model.Buy, Symbol: "BTC/USDT"},
+			OrderQty:         decimal.NewFromInt(2),
+			Price:            decimal.NewFromInt(100),
+		},
+	}))
+	session, err := acceptor.session("CLIENT")
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return session.SeqNums().NextSender == 5 }, 5*time.Second, 10*time.Millisecond)
+
+	// The sequence numbers survive a restart of the acceptor.
+	_, addr = startAcceptor(t, dir, orders, NewReportQueue())
+	c = dial(t, addr, 4)
+	logon := c.logon()
+	assert.Equal(t, "5", firstValue(logon, TagMsgSeqNum))
+
+	// The client last saw 3, its logout reply, and asks for the rest.
+	c.send(NewMessage(MsgTypeResendRequest).Add(TagBeginSeqNo, "4").Add(TagEndSeqNo, "0"))
+	resent := c.readType(MsgTypeExecutionReport)
+	assert.Equal(t, "4", firstValue(resent, TagMsgSeqNum))
+	assert.Equal(t, "Y", firstValue(resent, TagPossDupFlag))
+	assert.NotEmpty(t, firstValue(resent, TagOrigSendingTime))
+	assert.Equal(t, string(model.ExecTypeFill), firstValue(resent, TagExecType))
+	gap := c.readType(MsgTypeSequenceReset)
+	assert.Equal(t, "5", firstValue(gap, TagMsgSeqNum))
+	assert.Equal(t, "6", firstValue(gap, TagNewSeqNo))
+}
+
+func TestAcceptor_LogsOutOnSequenceTooLow(t *testing.T) {
+	dir := t.TempDir()
+	_, addr := startAcceptor(t, dir, &recordingOrders{}, nil)
+	c := dial(t, addr, 1)
+	c.logon()
+	c.send(NewMessage(MsgTypeLogout))
+	c.readType(MsgTypeLogout)
+
+	c = dial(t, addr, 1)
+	c.send(NewMessage(MsgTypeLogon).Add(TagEncryptMethod, "0").Add(TagHeartBtInt, "30"))
+	logout := c.readType(MsgTypeLogout)
+	assert.Contains(t, firstValue(logout, TagText), "MsgSeqNum too low")
+
+	// ResetSeqNumFlag starts over.
+	c = dial(t, addr, 1)
+	logon := c.logon(Field{Tag: TagResetSeqNumFlag, Value: "Y"})
+	assert.Equal(t, "1", firstValue(logon, TagMsgSeqNum))
+	assert.Equal(t, "Y", firstValue(logon, TagResetSeqNumFlag))
+}
+
+func TestReportQueue_NeverHoldsUpTheEngine(t *testing.T) {
+	reports := NewReportQueue()
+	notifier := reports.Notifier(nopNotifier{})
+	er, err := json.Marshal(model.ExecutionReport{MsgType: MsgTypeExecutionReport, ExecID: "E1"})
+	require.NoError(t, err)
+
+	// Nobody drains the queue: reports wait for the acceptor, none dropped.
+	for i := 0; i < 2*sessionQueueSize; i++ {
+		require.NoError(t, notifier.NotifyEventAndTrade(model.MsgTypeExecRpt, "E1", er))
+	}
+	assert.Len(t, reports.take(), 2*sessionQueueSize)
+
+	// Once the acceptor has stopped, nothing more is queued.
+	reports.close()
+	require.NoError(t, notifier.NotifyEventAndTrade(model.MsgTypeExecRpt, "E1", er))
+	assert.Empty(t, reports.take())
+}
+
+func TestAcceptor_OwnershipSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	orders := &recordingOrders{}
+	reports := NewReportQueue()
+	_, addr := startAcceptor(t, dir, orders, reports)
+	report := func(execType model.ExecType, status model.OrderStatus) model.ExecutionReport {
+		return model.ExecutionReport{
+			MsgType: MsgTypeExecutionReport, ExecID: "E-" + string(execType), OrderID: "O1", ClOrdID: "S1",
+			ExecType: execType, OrdStatus: status, Symbol: "BTC/USDT", Side: model.Sell,
+		}
+	}
+
+	c := dial(t, addr, 1)
+	c.logon()
+	c.send(newOrderSingle("S1", "2", "5", "100"))
+	require.Eventually(t, func() bool { return len(orders.all()) == 1 }, 5*time.Second, 10*time.Millisecond)
+	reports.push(report(model.ExecTypeNew, model.OrderStatusNew))
+	c.readType(MsgTypeExecutionReport)
+	c.send(NewMessage(MsgTypeLogout))
+	c.readType(MsgTypeLogout)
+
+	// After a restart the fill of the resting order still finds the
+	// session, which is away and gets it on asking.
+	reports = NewReportQueue()
+	acceptor, addr := startAcceptor(t, dir, orders, reports)
+	reports.push(report(model.ExecTypeFill, model.OrderStatusFill))
+	session, err := acceptor.session("CLIENT")
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return session.SeqNums().NextSender == 5 }, 5*time.Second, 10*time.Millisecond)
+
+	c = dial(t, addr, 4)
+	c.logon()
+	c.send(NewMessage(MsgTypeResendRequest).Add(TagBeginSeqNo, "4").Add(TagEndSeqNo, "0"))
+	resent := c.readType(MsgTypeExecutionReport)
+	assert.Equal(t, "4", firstValue(resent, TagMsgSeqNum))
+	assert.Equal(t, "S1", firstValue(resent, TagClOrdID))
+	assert.Equal(t, string(model.ExecTypeFill), firstValue(resent, TagExecType))
+
+	// The fill ended the order, for this acceptor and the next.
+	acceptor.mu.Lock()
+	assert.Empty(t, acceptor.owners)
+	acceptor.mu.Unlock()
+	stored, err := NewFileStore(dir).OwnedOrders(session.ID)
+	require.NoError(t, err)
+	assert.Empty(t, stored)
+}
+
+func TestSession_StoredMessageIsResent(t *testing.T) {
+	acceptor, addr := startAcceptor(t, t.TempDir(), &recordingOrders{}, nil)
+	c := dial(t, addr, 1)
+	c.logon()
+	session, err := acceptor.session("CLIENT")
+	require.NoError(t, err)
+
+	// A report stored without being sent leaves a gap the client sees.
+	msg, err := Marshal(model.ExecutionReport{MsgType: MsgTypeExecutionReport, ExecID: "E1", ClOrdID: "S1", Symbol: "BTC/USDT"})
+	require.NoError(t, err)
+	require.NoError(t, session.Store(msg))
+	c.send(NewMessage(MsgTypeTestRequest).Add(TagTestReqID, "sync"))
+	hb := c.readType(MsgTypeHeartbeat)
+	assert.Equal(t, "3", firstValue(hb, TagMsgSeqNum))
+
+	c.send(NewMessage(MsgTypeResendRequest).Add(TagBeginSeqNo, "2").Add(TagEndSeqNo, "2"))
+	resent := c.readType(MsgTypeExecutionReport)
+	assert.Equal(t, "2", firstValue(resent, TagMsgSeqNum))
+	assert.Equal(t, "E1", firstValue(resent, TagExecID))
+	assert.Equal(t, "Y", firstValue(resent, TagPossDupFlag))
+}
+
+func TestAcceptor_OwnershipFollowsTheOrder(t *testing.T) {
+	a := NewAcceptor(Opts{SenderCompID: "ME", StoreDir: t.TempDir()}, &recordingOrders{}, NewReportQueue())
+	s, other := &Session{ID: "s"}, &Session{ID: "other"}
+	cancel := func(clOrdID, orig string) *Message {
+		return NewMessage(MsgTypeOrderCancelRequest).Add(TagOrigClOrdID, orig).Add(TagClOrdID, clOrdID)
+	}
+	report := func(clOrdID, orderID string, status model.OrderStatus) *Session {
+		owner, _ := a.owner(model.ExecutionReport{ClOrdID: clOrdID, OrderID: orderID, OrdStatus: status})
+		return owner
+	}
+
+	_, err := a.claim(s, newOrderSingle("S1", "1", "5", "100"))
+	require.NoError(t, err)
+	assert.Same(t, s, report("S1", "O1", model.OrderStatusNew))
+
+	// A cancel sent to the wrong book is rejected without an OrderID; the
+	// order lives on and its fills still find the session.
+	_, err = a.claim(s, cancel("C1", "S1"))
+	require.NoError(t, err)
+	assert.Same(t, s, report("S1", "", model.OrderStatusRejected))
+	assert.Same(t, s, report("S1", "O1", model.OrderStatusPartialFill))
+
+	_, err = a.claim(s, NewMessage(MsgTypeOrderCancelReplace).Add(TagOrigClOrdID, "S1").Add(TagClOrdID, "S1R"))
+	require.NoError(t, err)
+	assert.Same(t, s, report("S1R", "O1", model.OrderStatusPartialFill))
+	_, err = a.claim(other, cancel("C2", "S1R"))
+	assert.Error(t, err)
+
+	// The fill releases every ClOrdID the order went by.
+	assert.Same(t, s, report("S1R", "O1", model.OrderStatusFill))
+	assert.Empty(t, a.owners)
+
+	// So does the reject of a cancel naming no order.
+	_, err = a.claim(s, cancel("C3", "X"))
+	require.NoError(t, err)
+	assert.Same(t, s, report("X", "", model.OrderStatusRejected))
+	assert.Empty(t, a.owners)
+
+	// And a request the order service refused.
+	claimed, err := a.claim(s, newOrderSingle("S2", "1", "5", "100"))
+	require.NoError(t, err)
+	a.release(claimed)
+	assert.Empty(t, a.owners)
+}
@@ -0,0 +1,358 @@
+package fix
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/shopspring/decimal"
+
+	"MatchingEngine/internal/model"
+)
+
+// The codec maps structs to messages through their json tags: a field whose
+// key is a tag number is that FIX field, in struct order, and embedded
+// structs are flattened. A slice of structs is a repeating group, led by its
+// count under the slice's tag and delimited by the first field of the
+// element. Fields whose key is not a number are left out.
+
+// Header is the standard header the session puts after MsgType.
+type Header struct {
+	SenderCompID    string `json:"49"`
+	TargetCompID    string `json:"56"`
+	MsgSeqNum       uint64 `json:"34"`
+	PossDupFlag     bool   `json:"43,omitempty"`
+	SendingTime     int64  `json:"52"`
+	OrigSendingTime int64  `json:"122,omitempty"`
+}
+
+// timestampTags are the int64 epoch ns fields sent as UTCTimestamp.
+var timestampTags = map[int]bool{
+	TagSendingTime:     true,
+	TagTransactTime:    true,
+	TagOrigSendingTime: true,
+}
+
+// msgTypes supplies the MsgType of model types whose MsgType field may be
+// left empty.
+var msgTypes = map[reflect.Type]string{
+	reflect.TypeOf(model.ExecutionReport{}):           MsgTypeExecutionReport,
+	reflect.TypeOf(model.TradeCaptureReport{}):        MsgTypeTradeCaptureReport,
+	reflect.TypeOf(model.NewOrderRequest{}):           MsgTypeNewOrderSingle,
+	reflect.TypeOf(model.OrderCancelRequest{}):        MsgTypeOrderCancelRequest,
+	reflect.TypeOf(model.OrderCancelReplaceRequest{}): MsgTypeOrderCancelReplace,
+	reflect.TypeOf(model.BusinessMessageReject{}):     MsgTypeBusinessMessageReject,
+}
+
+var decimalType = reflect.TypeOf(decimal.Decimal{})
+
+type codecField struct {
+	tag       int
+	index     []int
+	omitEmpty bool
+	group     *codecStruct // element layout of a repeating group
+}
+
+type codecStruct struct {
+	fields []codecField
+	byTag  map[int]*codecField
+}
+
+var codecCache sync.Map // reflect.Type -> *codecStruct
+
+func structOf(t reflect.Type) (*codecStruct, error) {
+	if cs, ok := codecCache.Load(t); ok {
+		return cs.(*codecStruct), nil
+	}
+	cs := &codecStruct{byTag: make(map[int]*codecField)}
+	if err := cs.collect(t, nil); err != nil {
+		return nil, err
+	}
+	for i := range cs.fields {
+		cs.byTag[cs.fields[i].tag] = &cs.fields[i]
+	}
+	codecCache.Store(t, cs)
+	return cs, nil
+}
+
+func (cs *codecStruct) collect(t reflect.Type, index []int) error {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		at := append(append([]int(nil), index...), i)
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+			if err := cs.collect(sf.Type, at); err != nil {
+				return err
+			}
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		tag, err := strconv.Atoi(name)
+		if err != nil || tag <= 0 {
+			continue
+		}
+		f := codecField{tag: tag, index: at, omitEmpty: opts == "omitempty"}
+		if sf.Type.Kind() == reflect.Slice && sf.Type.Elem().Kind() == reflect.Struct {
+			group, err := structOf(sf.Type.Elem())
+			if err != nil {
+				return err
+			}
+			if len(group.fields) == 0 {
+				return fmt.Errorf("repeating group %d of %s has no FIX fields", tag, t)
+			}
+			f.group = group
+		} else if err := checkKind(sf.Type); err != nil {
+			return fmt.Errorf("field %s of %s: %w", sf.Name, t, err)
+		}
+		if _, dup := cs.byTag[tag]; dup {
+			return fmt.Errorf("tag %d appears twice in %s", tag, t)
+		}
+		cs.byTag[tag] = &f
+		cs.fields = append(cs.fields, f)
+	}
+	return nil
+}
+
+func checkKind(t reflect.Type) error {
+	if t == decimalType {
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return nil
+	}
+	return fmt.Errorf("type %s has no FIX encoding", t)
+}
+
+// Marshal encodes a struct as a message body, MsgType first.
+func Marshal(v any) (*Message, error) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("cannot encode %T as a FIX message", v)
+	}
+	cs, err := structOf(rv.Type())
+	if err != nil {
+		return nil, err
+	}
+
+	msgType := msgTypes[rv.Type()]
+	if f, ok := cs.byTag[TagMsgType]; ok {
+		if s := rv.FieldByIndex(f.index).String(); s != "" {
+			msgType = s
+		}
+	}
+	if msgType == "" {
+		return nil, fmt.Errorf("%w: %T has no MsgType", ErrMissingField, v)
+	}
+	msg := NewMessage(msgType)
+	cs.encode(msg, rv)
+	return msg, nil
+}
+
+// appendFields adds the fields of a struct to msg.
+func appendFields(msg *Message, v any) error {
+	rv := reflect.ValueOf(v)
+	cs, err := structOf(rv.Type())
+	if err != nil {
+		return err
+	}
+	cs.encode(msg, rv)
+	return nil
+}
+
+func (cs *codecStruct) encode(msg *Message, rv reflect.Value) {
+	for _, f := range cs.fields {
+		if f.tag == TagMsgType {
+			continue
+		}
+		fv := rv.FieldByIndex(f.index)
+		if f.group != nil {
+			if fv.Len() == 0 && f.omitEmpty {
+				continue
+			}
+			msg.Add(f.tag, strconv.Itoa(fv.Len()))
+			for i := 0; i < fv.Len(); i++ {
+				f.group.encode(msg, fv.Index(i))
+			}
+			continue
+		}
+		if f.omitEmpty && fv.IsZero() {
+			continue
+		}
+		if s := formatValue(f.tag, fv); s != "" {
+			msg.Add(f.tag, s)
+		}
+	}
+}
+
+func formatValue(tag int, fv reflect.Value) string {
+	if fv.Type() == decimalType {
+		return fv.Interface().(decimal.Decimal).String()
+	}
+	switch fv.Kind() {
+	case reflect.String:
+		return fv.String()
+	case reflect.Bool:
+		if fv.Bool() {
+			return "Y"
+		}
+		return "N"
+	case reflect.Int64:
+		if timestampTags[tag] {
+			return FormatTransactTime(fv.Int())
+		}
+		return strconv.FormatInt(fv.Int(), 10)
+	case reflect.Int, reflect.Int32:
+		return strconv.FormatInt(fv.Int(), 10)
+	default:
+		return strconv.FormatUint(fv.Uint(), 10)
+	}
+}
+
+// Unmarshal decodes a message into the struct v points to. Fields the struct
+// has no place for are skipped.
+func Unmarshal(msg *Message, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("cannot decode a FIX message into %T", v)
+	}
+	rv = rv.Elem()
+	cs, err := structOf(rv.Type())
+	if err != nil {
+		return err
+	}
+	_, err = cs.decode(msg.Fields, 0, rv, false)
+	return err
+}
+
+// decode fills rv from fields[i:] and returns where it stopped. A group
+// element ends at a tag it does not have or at one it already holds, which
+// is where the next element starts.
+func (cs *codecStruct) decode(fields []Field, i int, rv reflect.Value, element bool) (int, error) {
+	seen := make(map[int]bool)
+	for i < len(fields) {
+		fd := fields[i]
+		f, ok := cs.byTag[fd.Tag]
+		if element && (!ok || seen[fd.Tag]) {
+			return i, nil
+		}
+		i++
+		if !ok {
+			continue
+		}
+		seen[fd.Tag] = true
+		fv := rv.FieldByIndex(f.index)
+		if f.group == nil {
+			if err := parseValue(fd, fv); err != nil {
+				return i, err
+			}
+			continue
+		}
+
+		// Every entry starts with a field of its own, so a count beyond the
+		// fields left is garbled; it is not trusted with an allocation.
+		n, err := strconv.Atoi(fd.Value)
+		if err != nil || n < 0 || n > len(fields)-i {
+			return i, fmt.Errorf("%w: invalid group count %d=%q", ErrGarbled, fd.Tag, fd.Value)
+		}
+		delimiter := f.group.fields[0].tag
+		elems := reflect.MakeSlice(fv.Type(), n, n)
+		for k := 0; k < n; k++ {
+			if i >= len(fields) || fields[i].Tag != delimiter {
+				return i, fmt.Errorf("%w: group %d has %d of %d entries, each starting with tag %d",
+					ErrGarbled, fd.Tag, k, n, delimiter)
+			}
+			if i, err = f.group.decode(fields, i, elems.Index(k), true); err != nil {
+				return i, err
+			}
+		}
+		fv.Set(elems)
+	}
+	return i, nil
+}
+
+func parseValue(fd Field, fv reflect.Value) error {
+	bad := func(err error) error {
+		return fmt.Errorf("%w: tag %d value %q: %v", ErrGarbled, fd.Tag, fd.Value, err)
+	}
+	if fv.Type() == decimalType {
+		d, err := decimal.NewFromString(fd.Value)
+		if err != nil {
+			return bad(err)
+		}
+		fv.Set(reflect.ValueOf(d))
+		return nil
+	}
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(fd.Value)
+	case reflect.Bool:
+		switch fd.Value {
+		case "Y":
+			fv.SetBool(true)
+		case "N":
+			fv.SetBool(false)
+		default:
+			return bad(fmt.Errorf("not Y or N"))
+		}
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		if timestampTags[fd.Tag] && fv.Kind() == reflect.Int64 {
+			ns, err := ParseTransactTime(fd.Value)
+			if err != nil {
+				return bad(err)
+			}
+			fv.SetInt(ns)
+			return nil
+		}
+		n, err := strconv.ParseInt(fd.Value, 10, fv.Type().Bits())
+		if err != nil {
+			return bad(err)
+		}
+		fv.SetInt(n)
+	default:
+		n, err := strconv.ParseUint(fd.Value, 10, fv.Type().Bits())
+		if err != nil {
+			return bad(err)
+		}
+		fv.SetUint(n)
+	}
+	return nil
+}
+
+// Encode renders v as a complete message with the given header, BodyLength
+// and CheckSum.
+func Encode(h Header, v any) ([]byte, error) {
+	body, err := Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	msg := NewMessage(body.MsgType())
+	if err := appendFields(msg, h); err != nil {
+		return nil, err
+	}
+	msg.Fields = append(msg.Fields, body.Fields[1:]...)
+	return msg.Bytes(), nil
+}
+
+// Decode checks the framing and CheckSum of a complete message and decodes
+// its header and, into v, its body. The MsgType must be the one v is for.
+func Decode(raw []byte, v any) (Header, error) {
+	msg, err := Parse(raw)
+	if err != nil {
+		return Header{}, err
+	}
+	var h Header
+	if err := Unmarshal(msg, &h); err != nil {
+		return Header{}, err
+	}
+	if want := msgTypes[reflect.Indirect(reflect.ValueOf(v)).Type()]; want != "" && msg.MsgType() != want {
+		return h, fmt.Errorf("%w: MsgType %s, expected %s", ErrUnsupported, msg.MsgType(), want)
+	}
+	return h, Unmarshal(msg, v)
+}
@@ -0,0 +1,240 @@
+package fix
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"MatchingEngine/internal/model"
+)
+
+const (
+	sampleExecutionReport = "8=FIX.4.4|9=179|35=8|49=ME|56=CLIENT|34=12|52=20241025-10:00:00.123000000|" +
+		"17=E-1-7|37=O-1-3|11=S1|150=2|39=1|55=BTC/USDT|54=2|38=5|32=2|31=100.5|151=3|14=2|6=100.5|" +
+		"60=20241025-10:00:00.120000000|10=052|"
+	sampleTradeCaptureReport = "8=FIX.4.4|9=181|35=AE|49=ME|56=CLIENT|34=12|52=20241025-10:00:00.123000000|" +
+		"571=T-1-1|17=E-1-7|55=BTC/USDT|32=2|31=100.5|75=20241025|60=20241025-10:00:00.120000000|" +
+		"552=2|54=1|37=O-1-2|54=2|37=O-1-3|10=016|"
+	sampleNewOrderSingle = "8=FIX.4.4|9=132|35=D|49=CLIENT|56=ME|34=3|52=20241025-09:59:59.000000000|" +
+		"11=B1|54=1|55=ETH/USDT|60=20241025-09:59:58.500000000|40=2|38=1.25|44=2500|10=029|"
+	sampleOrderEntry = "8=FIX.4.4|9=145|35=D|49=CLIENT|56=ME|34=4|52=20241025-10:00:01.000000000|" +
+		"1=ACC-1|11=B2|54=2|55=BTC/USDT|60=20241025-10:00:00.750000000|58=hedge|38=0.5|44=101.25|10=114|"
+	sampleOrderCancelReplace = "8=FIX.4.4|9=134|35=G|49=CLIENT|56=ME|34=4|52=20241025-10:00:01.000000000|" +
+		"11=B2R|54=2|55=BTC/USDT|60=20241025-10:00:00.750000000|41=B2|38=0.4|44=101.5|10=109|"
+	sampleOrderCancel = "8=FIX.4.4|9=118|35=F|49=CLIENT|56=ME|34=4|52=20241025-10:00:01.000000000|" +
+		"11=C2|54=2|55=BTC/USDT|60=20241025-10:00:00.750000000|41=B2R|10=153|"
+)
+
+func unwire(raw []byte) []byte {
+	return []byte(strings.ReplaceAll(string(raw), "\x01", "|"))
+}
+
+func sampleTime(ms int) int64 {
+	return time.Date(2024, 10, 25, 10, 0, 0, ms*int(time.Millisecond), time.UTC).UnixNano()
+}
+
+func TestCodec_ExecutionReportRoundTrip(t *testing.T) {
+	var er model.ExecutionReport
+	h, err := Decode(wire(sampleExecutionReport), &er)
+	require.NoError(t, err)
+
+	assert.Equal(t, Header{SenderCompID: "ME", TargetCompID: "CLIENT", MsgSeqNum: 12, SendingTime: sampleTime(123)}, h)
+	assert.Equal(t, "8", er.MsgType)
+	assert.Equal(t, "E-1-7", er.ExecID)
+	assert.Equal(t, model.ExecTypeFill, er.ExecType)
+	assert.Equal(t, model.OrderStatusPartialFill, er.OrdStatus)
+	assert.Equal(t, model.Sell, er.Side)
+	assert.True(t, er.LastPx.Equal(decimal.RequireFromString("100.5")))
+	assert.True(t, er.LeavesQty.Equal(decimal.NewFromInt(3)))
+	assert.Equal(t, sampleTime(120), er.TransactTime)
+	assert.Empty(t, er.Text)
+
+	raw, err := Encode(h, er)
+	require.NoError(t, err)
+	assert.Equal(t, sampleExecutionReport, string(unwire(raw)))
+}
+
+func TestCodec_TradeCaptureReportRoundTrip(t *testing.T) {
+	var tcr model.TradeCaptureReport
+	h, err := Decode(wire(sampleTradeCaptureReport), &tcr)
+	require.NoError(t, err)
+
+	assert.Equal(t, "T-1-1", tcr.TradeReportID)
+	assert.Equal(t, "20241025", tcr.TradeDate)
+	assert.Equal(t, []model.NoSides{
+		{Side: model.Buy, OrderID: "O-1-2"},
+		{Side: model.Sell, OrderID: "O-1-3"},
+	}, tcr.NoSides)
+
+	raw, err := Encode(h, tcr)
+	require.NoError(t, err)
+	assert.Equal(t, sampleTradeCaptureReport, string(unwire(raw)))
+}
+
+func TestCodec_NewOrderRequest(t *testing.T) {
+	var req model.NewOrderRequest
+	h, err := Decode(wire(sampleNewOrderSingle), &req)
+	require.NoError(t, err)
+
+	assert.Equal(t, uint64(3), h.MsgSeqNum)
+	assert.Equal(t, model.MsgTypeNew, req.MsgType)
+	assert.Equal(t, "B1", req.ClOrdID)
+	assert.Equal(t, "ETH/USDT", req.Symbol)
+	assert.True(t, req.OrderQty.Equal(decimal.RequireFromString("1.25")))
+	assert.True(t, req.Price.Equal(decimal.NewFromInt(2500)))
+	assert.Equal(t, time.Date(2024, 10, 25, 9, 59, 58, 500_000_000, time.UTC).UnixNano(), req.TransactTime)
+
+	// OrdType <40> has no field in the model and is not encoded back.
+	raw, err := Encode(h, req)
+	require.NoError(t, err)
+	assert.NotContains(t, string(unwire(raw)), "|40=")
+	var again model.NewOrderRequest
+	_, err = Decode(raw, &again)
+	require.NoError(t, err)
+	assert.Equal(t, req, again)
+
+	// The MsgType comes from the type when the request leaves it empty.
+	msg, err := Marshal(model.NewOrderRequest{OrderQty: decimal.NewFromInt(1)})
+	require.NoError(t, err)
+	assert.Equal(t, MsgTypeNewOrderSingle, msg.MsgType())
+}
+
+// decodeOrderRequest parses an order entry message the way the acceptor does.
+func decodeOrderRequest(t *testing.T, sample string) (Header, model.OrderRequest) {
+	t.Helper()
+	msg, err := Parse(wire(sample))
+	require.NoError(t, err)
+	var h Header
+	require.NoError(t, Unmarshal(msg, &h))
+	req, err := toOrderRequest(msg)
+	require.NoError(t, err)
+	return h, req
+}
+
+func TestCodec_NewOrderSingleRoundTrip(t *testing.T) {
+	h, req := decodeOrderRequest(t, sampleOrderEntry)
+	require.Equal(t, model.MsgTypeNew, req.MsgType)
+
+	assert.Equal(t, model.NewOrderRequest{
+		BaseOrderRequest: model.BaseOrderRequest{
+			MsgType:      model.MsgTypeNew,
+			Account:      "ACC-1",
+			ClOrdID:      "B2",
+			Side:         model.Sell,
+			Symbol:       "BTC/USDT",
+			TransactTime: sampleTime(750),
+			Text:         "hedge",
+		},
+		OrderQty: decimal.RequireFromString("0.5"),
+		Price:    decimal.RequireFromString("101.25"),
+	}, req.NewOrderReq)
+
+	raw, err := Encode(h, req.NewOrderReq)
+	require.NoError(t, err)
+	assert.Equal(t, sampleOrderEntry, string(unwire(raw)))
+}
+
+func TestCodec_OrderCancelReplaceRoundTrip(t *testing.T) {
+	h, req := decodeOrderRequest(t, sampleOrderCancelReplace)
+	require.Equal(t, model.MsgTypeReplace, req.MsgType)
+	require.NotNil(t, req.ReplaceOrderReq)
+	assert.Equal(t, "B2R", req.ReplaceOrderReq.ClOrdID)
+	assert.Equal(t, "B2", req.ReplaceOrderReq.OrigClOrdID)
+	assert.True(t, req.ReplaceOrderReq.OrderQty.Equal(decimal.RequireFromString("0.4")))
+	assert.True(t, req.ReplaceOrderReq.Price.Equal(decimal.RequireFromString("101.5")))
+
+	raw, err := Encode(h, *req.ReplaceOrderReq)
+	require.NoError(t, err)
+	assert.Equal(t, sampleOrderCancelReplace, string(unwire(raw)))
+}
+
+func TestCodec_OrderCancelRoundTrip(t *testing.T) {
+	h, req := decodeOrderRequest(t, sampleOrderCancel)
+	require.Equal(t, model.MsgTypeCancel, req.MsgType)
+	assert.Equal(t, "C2", req.CancelOrderReq.ClOrdID)
+	assert.Equal(t, "B2R", req.CancelOrderReq.OrigClOrdID)
+	assert.Equal(t, model.Sell, req.CancelOrderReq.Side)
+
+	raw, err := Encode(h, req.CancelOrderReq)
+	require.NoError(t, err)
+	assert.Equal(t, sampleOrderCancel, string(unwire(raw)))
+}
+
+func TestCodec_RejectsIncompleteOrderEntry(t *testing.T) {
+	noPrice := NewMessage(MsgTypeNewOrderSingle).
+		Add(TagClOrdID, "B1").Add(TagSide, "1").Add(TagSymbol, "BTC/USDT").Add(TagOrderQty, "1")
+	_, err := toOrderRequest(noPrice)
+	assert.ErrorIs(t, err, ErrMissingField)
+
+	noOrig := NewMessage(MsgTypeOrderCancelRequest).
+		Add(TagClOrdID, "C1").Add(TagSide, "1").Add(TagSymbol, "BTC/USDT")
+	_, err = toOrderRequest(noOrig)
+	assert.ErrorIs(t, err, ErrMissingField)
+
+	market := NewMessage(MsgTypeNewOrderSingle).
+		Add(TagClOrdID, "B1").Add(TagSide, "1").Add(TagSymbol, "BTC/USDT").
+		Add(TagOrderQty, "1").Add(TagOrdType, "1").Add(TagPrice, "0")
+	_, err = toOrderRequest(market)
+	assert.ErrorIs(t, err, ErrUnsupported)
+
+	_, err = toOrderRequest(newOrderSingle("B1", "1", "lots", "100"))
+	assert.ErrorIs(t, err, ErrGarbled)
+}
+
+func TestCodec_OmitsEmptyOptionalFields(t *testing.T) {
+	msg, err := Marshal(model.TradeCaptureReport{TradeReportID: "T1", TradeDate: "20241025"})
+	require.NoError(t, err)
+	assert.Equal(t, MsgTypeTradeCaptureReport, msg.MsgType())
+	_, hasSides := msg.Get(TagNoSides)
+	assert.False(t, hasSides)
+	// Empty strings are never put on the wire.
+	_, hasSymbol := msg.Get(TagSymbol)
+	assert.False(t, hasSymbol)
+	// Required numbers are, even when zero.
+	qty, _ := msg.Get(TagLastQty)
+	assert.Equal(t, "0", qty)
+}
+
+func TestCodec_RejectsMalformedMessages(t *testing.T) {
+	var tcr model.TradeCaptureReport
+	short := NewMessage(MsgTypeTradeCaptureReport).
+		Add(TagNoSides, "2").
+		Add(TagSide, "1").Add(TagOrderID, "O1")
+	assert.ErrorIs(t, Unmarshal(short, &tcr), ErrGarbled)
+
+	misordered := NewMessage(MsgTypeTradeCaptureReport).
+		Add(TagNoSides, "1").
+		Add(TagOrderID, "O1").Add(TagSide, "1")
+	assert.ErrorIs(t, Unmarshal(misordered, &tcr), ErrGarbled)
+
+	// A count far beyond the fields there are is refused before anything
+	// is allocated for it.
+	for _, count := range []string{"1125899906842624", "1000000000", "3"} {
+		oversized := NewMessage(MsgTypeTradeCaptureReport).
+			Add(TagNoSides, count).
+			Add(TagSide, "1").Add(TagOrderID, "O1")
+		assert.ErrorIs(t, Unmarshal(oversized, &tcr), ErrGarbled, count)
+	}
+	var req tradeCaptureReportRequest
+	dates := NewMessage(MsgTypeTradeCaptureReportReq).
+		Add(TagTradeRequestID, "R1").
+		Add(TagNoDates, "1125899906842624").
+		Add(TagTransactTime, "20250101-00:00:00.000")
+	assert.ErrorIs(t, Unmarshal(dates, &req), ErrGarbled)
+
+	var er model.ExecutionReport
+	badQty := NewMessage(MsgTypeExecutionReport).Add(TagLeavesQty, "lots")
+	assert.ErrorIs(t, Unmarshal(badQty, &er), ErrGarbled)
+
+	corrupt := []byte(sampleExecutionReport)
+	corrupt[len(corrupt)-3] = '9'
+	_, err := Decode(wire(string(corrupt)), &er)
+	assert.ErrorIs(t, err, ErrGarbled)
+
+	_, err = Decode(wire(sampleTradeCaptureReport), &er)
+	assert.ErrorIs(t, err, ErrUnsupported)
+}
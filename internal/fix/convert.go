@@ -0,0 +1,59 @@
+package fix
+
+import (
+	"errors"
+	"fmt"
+
+	"MatchingEngine/internal/model"
+)
+
+// ordTypeLimit is the only OrdType <40> the engine matches.
+const ordTypeLimit = string(model.OrdTypeLimit)
+
+var ErrUnsupported = errors.New("unsupported FIX message")
+
+// requiredTags are the fields of D, F and G the engine cannot do without.
+var requiredTags = map[string][]int{
+	MsgTypeNewOrderSingle:     {TagClOrdID, TagSymbol, TagSide, TagOrderQty, TagPrice},
+	MsgTypeOrderCancelRequest: {TagClOrdID, TagOrigClOrdID, TagSymbol, TagSide},
+	MsgTypeOrderCancelReplace: {TagClOrdID, TagOrigClOrdID, TagSymbol, TagSide, TagOrderQty, TagPrice},
+}
+
+// toOrderRequest translates D, F and G into the engine's request.
+func toOrderRequest(msg *Message) (model.OrderRequest, error) {
+	required, ok := requiredTags[msg.MsgType()]
+	if !ok {
+		return model.OrderRequest{}, fmt.Errorf("%w: MsgType %s", ErrUnsupported, msg.MsgType())
+	}
+	for _, tag := range required {
+		if _, err := msg.Require(tag); err != nil {
+			return model.OrderRequest{}, err
+		}
+	}
+
+	switch msg.MsgType() {
+	case MsgTypeNewOrderSingle:
+		if ordType, ok := msg.Get(TagOrdType); ok && ordType != ordTypeLimit {
+			return model.OrderRequest{}, fmt.Errorf("%w: OrdType %s, only limit orders are accepted", ErrUnsupported, ordType)
+		}
+		var req model.NewOrderRequest
+		if err := Unmarshal(msg, &req); err != nil {
+			return model.OrderRequest{}, err
+		}
+		return model.OrderRequest{MsgType: model.MsgTypeNew, NewOrderReq: req}, nil
+
+	case MsgTypeOrderCancelRequest:
+		var req model.OrderCancelRequest
+		if err := Unmarshal(msg, &req); err != nil {
+			return model.OrderRequest{}, err
+		}
+		return model.OrderRequest{MsgType: model.MsgTypeCancel, CancelOrderReq: req}, nil
+
+	default:
+		var req model.OrderCancelReplaceRequest
+		if err := Unmarshal(msg, &req); err != nil {
+			return model.OrderRequest{}, err
+		}
+		return model.OrderRequest{MsgType: model.MsgTypeReplace, ReplaceOrderReq: &req}, nil
+	}
+}
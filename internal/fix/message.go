@@ -0,0 +1,319 @@
+// Package fix is a FIX 4.4 tag=value acceptor in front of the order service.
+package fix
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+const (
+	BeginString = "FIX.4.4"
+	soh         = '\x01'
+	// maxBodyLength bounds what a peer can make the reader allocate.
+	maxBodyLength = 1 << 20
+)
+
+// Tags used by the session layer, the order messages and the trade capture
+// report requests.
+const (
+	TagAccount              = 1
+	TagAvgPx                = 6
+	TagBeginSeqNo           = 7
+	TagBeginString          = 8
+	TagBodyLength           = 9
+	TagCheckSum             = 10
+	TagClOrdID              = 11
+	TagCumQty               = 14
+	TagEndSeqNo             = 16
+	TagExecID               = 17
+	TagLastPx               = 31
+	TagLastQty              = 32
+	TagMsgSeqNum            = 34
+	TagMsgType              = 35
+	TagNewSeqNo             = 36
+	TagOrderID              = 37
+	TagOrderQty             = 38
+	TagOrdStatus            = 39
+	TagOrdType              = 40
+	TagOrigClOrdID          = 41
+	TagPossDupFlag          = 43
+	TagPrice                = 44
+	TagRefSeqNum            = 45
+	TagSenderCompID         = 49
+	TagSendingTime          = 52
+	TagSide                 = 54
+	TagSymbol               = 55
+	TagTargetCompID         = 56
+	TagText                 = 58
+	TagTransactTime         = 60
+	TagEncryptMethod        = 98
+	TagHeartBtInt           = 108
+	TagTestReqID            = 112
+	TagOrigSendingTime      = 122
+	TagGapFillFlag          = 123
+	TagResetSeqNumFlag      = 141
+	TagExecType             = 150
+	TagLeavesQty            = 151
+	TagSubscriptionReqType  = 263
+	TagRefTagID             = 371
+	TagRefMsgType           = 372
+	TagSessionRejectReason  = 373
+	TagBusinessRejectRefID  = 379
+	TagBusinessRejectReason = 380
+	TagNoSides              = 552
+	TagTradeRequestID       = 568
+	TagTradeRequestType     = 569
+	TagTradeReportID        = 571
+	TagNoDates              = 580
+	TagTotNumTradeReports   = 748
+	TagTradeRequestResult   = 749
+	TagTradeRequestStatus   = 750
+	TagLastRptRequested     = 912
+)
+
+// Message types handled by the acceptor.
+const (
+	MsgTypeHeartbeat             = "0"
+	MsgTypeTestRequest           = "1"
+	MsgTypeResendRequest         = "2"
+	MsgTypeReject                = "3"
+	MsgTypeSequenceReset         = "4"
+	MsgTypeLogout                = "5"
+	MsgTypeExecutionReport       = "8"
+	MsgTypeLogon                 = "A"
+	MsgTypeNewOrderSingle        = "D"
+	MsgTypeOrderCancelRequest    = "F"
+	MsgTypeOrderCancelReplace    = "G"
+	MsgTypeBusinessMessageReject = "j"
+	MsgTypeTradeCaptureReportReq = "AD"
+	MsgTypeTradeCaptureReport    = "AE"
+	MsgTypeTradeCaptureReportAck = "AQ"
+)
+
+const (
+	sendingTimeLayout  = "20060102-15:04:05.000"
+	transactTimeLayout = "20060102-15:04:05.000000000"
+)
+
+var (
+	// ErrGarbled is a framed message that fails validation; the session
+	// ignores it and reads on.
+	ErrGarbled = errors.New("garbled FIX message")
+	// ErrFraming is a stream whose messages cannot be delimited any more.
+	ErrFraming = errors.New("invalid FIX framing")
+	// ErrMissingField is a message without a field its type requires.
+	ErrMissingField = errors.New("required FIX field missing")
+)
+
+type Field struct {
+	Tag   int
+	Value string
+}
+
+// Message holds the fields between BodyLength and CheckSum in wire order,
+// MsgType first.
+type Message struct {
+	Fields []Field
+}
+
+func NewMessage(msgType string) *Message {
+	return &Message{Fields: []Field{{Tag: TagMsgType, Value: msgType}}}
+}
+
+func (m *Message) MsgType() string {
+	v, _ := m.Get(TagMsgType)
+	return v
+}
+
+// Get returns the first value of a tag.
+func (m *Message) Get(tag int) (string, bool) {
+	for _, f := range m.Fields {
+		if f.Tag == tag {
+			return f.Value, true
+		}
+	}
+	return "", false
+}
+
+func (m *Message) Require(tag int) (string, error) {
+	v, ok := m.Get(tag)
+	if !ok || v == "" {
+		return "", fmt.Errorf("%w: tag %d", ErrMissingField, tag)
+	}
+	return v, nil
+}
+
+// Add appends a field.
+func (m *Message) Add(tag int, value string) *Message {
+	m.Fields = append(m.Fields, Field{Tag: tag, Value: value})
+	return m
+}
+
+// Set replaces the first value of a tag, or appends it.
+func (m *Message) Set(tag int, value string) *Message {
+	for i := range m.Fields {
+		if m.Fields[i].Tag == tag {
+			m.Fields[i].Value = value
+			return m
+		}
+	}
+	return m.Add(tag, value)
+}
+
+func (m *Message) SeqNum() (uint64, error) {
+	v, err := m.Require(TagMsgSeqNum)
+	if err != nil {
+		return 0, err
+	}
+	seq, err := strconv.ParseUint(v, 10, 64)
+	if err != nil || seq == 0 {
+		return 0, fmt.Errorf("%w: invalid MsgSeqNum %q", ErrGarbled, v)
+	}
+	return seq, nil
+}
+
+func (m *Message) flag(tag int) bool {
+	v, _ := m.Get(tag)
+	return v == "Y"
+}
+
+// Bytes encodes the message with BeginString, BodyLength and CheckSum.
+func (m *Message) Bytes() []byte {
+	var body bytes.Buffer
+	for _, f := range m.Fields {
+		body.WriteString(strconv.Itoa(f.Tag))
+		body.WriteByte('=')
+		body.WriteString(f.Value)
+		body.WriteByte(soh)
+	}
+
+	var out bytes.Buffer
+	out.WriteString("8=" + BeginString + "\x01")
+	out.WriteString("9=" + strconv.Itoa(body.Len()) + "\x01")
+	out.Write(body.Bytes())
+	fmt.Fprintf(&out, "10=%03d\x01", checksum(out.Bytes()))
+	return out.Bytes()
+}
+
+// String renders the message with | for SOH, for logs.
+func (m *Message) String() string {
+	return string(bytes.ReplaceAll(m.Bytes(), []byte{soh}, []byte{'|'}))
+}
+
+func checksum(b []byte) int {
+	sum := 0
+	for _, c := range b {
+		sum += int(c)
+	}
+	return sum % 256
+}
+
+// Reader frames messages from a stream using BodyLength and checks their
+// CheckSum.
+type Reader struct {
+	r *bufio.Reader
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+// ReadMessage returns the next message. ErrGarbled leaves the stream framed
+// for the next message; after ErrFraming the stream cannot be trusted.
+func (r *Reader) ReadMessage() (*Message, error) {
+	begin, err := r.r.ReadSlice(soh)
+	if err != nil {
+		return nil, err
+	}
+	if string(begin) != "8="+BeginString+"\x01" {
+		return nil, fmt.Errorf("%w: unexpected BeginString %q", ErrFraming, bytes.TrimRight(begin, "\x01"))
+	}
+	raw := append([]byte(nil), begin...)
+
+	lengthField, err := r.r.ReadSlice(soh)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(lengthField, []byte("9=")) {
+		return nil, fmt.Errorf("%w: BodyLength must be the second field", ErrFraming)
+	}
+	length, err := strconv.Atoi(string(lengthField[2 : len(lengthField)-1]))
+	if err != nil || length <= 0 || length > maxBodyLength {
+		return nil, fmt.Errorf("%w: invalid BodyLength %q", ErrFraming, lengthField[2:len(lengthField)-1])
+	}
+	raw = append(raw, lengthField...)
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r.r, body); err != nil {
+		return nil, err
+	}
+	raw = append(raw, body...)
+
+	trailer, err := r.r.ReadSlice(soh)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(trailer, []byte("10=")) || len(trailer) != 7 {
+		return nil, fmt.Errorf("%w: BodyLength does not end at CheckSum", ErrFraming)
+	}
+	want, err := strconv.Atoi(string(trailer[3:6]))
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid CheckSum %q", ErrGarbled, trailer[3:6])
+	}
+	if got := checksum(raw); got != want {
+		return nil, fmt.Errorf("%w: CheckSum %03d, expected %03d", ErrGarbled, want, got)
+	}
+
+	msg, err := parseBody(body)
+	if err != nil {
+		return nil, err
+	}
+	if msg.Fields[0].Tag != TagMsgType || msg.Fields[0].Value == "" {
+		return nil, fmt.Errorf("%w: MsgType must be the third field", ErrGarbled)
+	}
+	return msg, nil
+}
+
+// Parse decodes one complete message.
+func Parse(raw []byte) (*Message, error) {
+	return NewReader(bytes.NewReader(raw)).ReadMessage()
+}
+
+func parseBody(body []byte) (*Message, error) {
+	if body[len(body)-1] != soh {
+		return nil, fmt.Errorf("%w: body does not end with SOH", ErrGarbled)
+	}
+	msg := &Message{}
+	for _, field := range bytes.Split(body[:len(body)-1], []byte{soh}) {
+		tag, value, ok := bytes.Cut(field, []byte{'='})
+		if !ok {
+			return nil, fmt.Errorf("%w: field %q has no tag", ErrGarbled, field)
+		}
+		n, err := strconv.Atoi(string(tag))
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("%w: invalid tag %q", ErrGarbled, tag)
+		}
+		msg.Fields = append(msg.Fields, Field{Tag: n, Value: string(value)})
+	}
+	return msg, nil
+}
+
+// FormatTransactTime renders epoch ns as a UTCTimestamp with nanoseconds.
+func FormatTransactTime(ns int64) string {
+	return time.Unix(0, ns).UTC().Format(transactTimeLayout)
+}
+
+// ParseTransactTime accepts UTCTimestamps with no, millisecond, or finer
+// fractions.
+func ParseTransactTime(s string) (int64, error) {
+	t, err := time.Parse("20060102-15:04:05.999999999", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid UTCTimestamp %q: %w", s, err)
+	}
+	return t.UnixNano(), nil
+}
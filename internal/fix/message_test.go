@@ -0,0 +1,78 @@
+package fix
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func wire(s string) []byte {
+	return []byte(strings.ReplaceAll(s, "|", "\x01"))
+}
+
+func TestMessage_EncodesBodyLengthAndCheckSum(t *testing.T) {
+	msg := NewMessage(MsgTypeHeartbeat).
+		Add(TagSenderCompID, "ME").
+		Add(TagTargetCompID, "CLIENT").
+		Add(TagMsgSeqNum, "2").
+		Add(TagSendingTime, "20241025-00:00:00.000")
+
+	raw := msg.Bytes()
+	body := "35=0|49=ME|56=CLIENT|34=2|52=20241025-00:00:00.000|"
+	prefix := "8=FIX.4.4|9=51|"
+	require.Len(t, body, 51)
+	assert.True(t, bytes.HasPrefix(raw, wire(prefix+body)))
+	assert.Equal(t, fmt.Sprintf("10=%03d\x01", checksum(raw[:len(raw)-7])), string(raw[len(raw)-7:]))
+
+	parsed, err := Parse(raw)
+	require.NoError(t, err)
+	assert.Equal(t, msg.Fields, parsed.Fields)
+}
+
+func TestReader_FramesConsecutiveMessages(t *testing.T) {
+	first := NewMessage(MsgTypeTestRequest).Add(TagTestReqID, "a=b")
+	second := NewMessage(MsgTypeHeartbeat).Add(TagTestReqID, "x")
+	r := NewReader(bytes.NewReader(append(first.Bytes(), second.Bytes()...)))
+
+	got, err := r.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, "a=b", firstValue(got, TagTestReqID))
+	got, err = r.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, MsgTypeHeartbeat, got.MsgType())
+}
+
+func TestReader_RejectsBadMessages(t *testing.T) {
+	good := NewMessage(MsgTypeHeartbeat).Add(TagMsgSeqNum, "1").Bytes()
+
+	badSum := append([]byte(nil), good...)
+	copy(badSum[len(badSum)-4:], "999")
+	r := NewReader(bytes.NewReader(append(badSum, good...)))
+	_, err := r.ReadMessage()
+	assert.ErrorIs(t, err, ErrGarbled)
+	// The stream is still framed.
+	_, err = r.ReadMessage()
+	assert.NoError(t, err)
+
+	_, err = Parse(wire("8=FIX.4.2|9=5|35=0|10=000|"))
+	assert.ErrorIs(t, err, ErrFraming)
+	_, err = Parse(wire("8=FIX.4.4|9=3|35=0|34=1|10=000|"))
+	assert.ErrorIs(t, err, ErrFraming)
+}
+
+func TestTransactTime_RoundTrip(t *testing.T) {
+	ns := int64(1729811234567890123)
+	s := FormatTransactTime(ns)
+	assert.Equal(t, "20241024-23:07:14.567890123", s)
+	back, err := ParseTransactTime(s)
+	require.NoError(t, err)
+	assert.Equal(t, ns, back)
+
+	ms, err := ParseTransactTime("20241024-23:07:14.567")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1729811234567000000), ms)
+}
@@ -0,0 +1,452 @@
+package fix
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var ErrAlreadyLoggedOn = errors.New("session is already logged on")
+
+// SessionRejectReason <373> values.
+const (
+	rejectRequiredTagMissing = "1"
+	rejectValueIncorrect     = "5"
+	rejectCompIDProblem      = "9"
+	rejectInvalidMsgType     = "11"
+)
+
+const defaultWriteTimeout = 5 * time.Second
+
+// Session is the FIX session with one counterparty. It outlives connections:
+// application messages sent while the counterparty is away are numbered and
+// stored, and handed out again when it asks for a resend after logging on.
+type Session struct {
+	ID           string
+	senderCompID string
+	targetCompID string
+	store        *FileStore
+	now          func() time.Time
+	onApp        func(*Session, *Message)
+
+	mu             sync.Mutex
+	seq            SeqNums
+	conn           net.Conn
+	heartbeat      time.Duration
+	lastSent       time.Time
+	lastReceived   time.Time
+	testReqPending bool
+	// resendUntil is the highest MsgSeqNum seen ahead of a gap; until it is
+	// reached no further ResendRequest is sent.
+	resendUntil uint64
+}
+
+func newSession(senderCompID, targetCompID string, store *FileStore, now func() time.Time, onApp func(*Session, *Message)) (*Session, error) {
+	id := BeginString + ":" + senderCompID + "->" + targetCompID
+	seq, err := store.LoadSeqNums(id)
+	if err != nil {
+		return nil, err
+	}
+	return &Session{
+		ID:           id,
+		senderCompID: senderCompID,
+		targetCompID: targetCompID,
+		store:        store,
+		now:          now,
+		onApp:        onApp,
+		seq:          seq,
+	}, nil
+}
+
+// SeqNums returns the session's next sequence numbers.
+func (s *Session) SeqNums() SeqNums {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.seq
+}
+
+// Send numbers and sends a message. Application messages are stored first, so
+// one sent while the counterparty is away or lost with the connection can be
+// resent.
+func (s *Session) Send(msg *Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sendLocked(msg)
+}
+
+func (s *Session) sendLocked(msg *Message) error {
+	raw, err := s.storeLocked(msg)
+	if err != nil {
+		return err
+	}
+	return s.writeLocked(raw)
+}
+
+// Store numbers and stores an application message without sending it. The
+// counterparty sees the gap at the next message it gets and asks for it.
+func (s *Session) Store(msg *Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.storeLocked(msg)
+	return err
+}
+
+func (s *Session) storeLocked(msg *Message) ([]byte, error) {
+	seq := s.seq.NextSender
+	raw := s.withHeader(msg, seq).Bytes()
+
+	// A crash between the two leaves a gap, which a resend fills.
+	s.seq.NextSender++
+	if err := s.store.SaveSeqNums(s.ID, s.seq); err != nil {
+		return nil, err
+	}
+	if !isAdmin(msg.MsgType()) {
+		if err := s.store.SaveMessage(s.ID, raw); err != nil {
+			return nil, err
+		}
+	}
+	return raw, nil
+}
+
+func (s *Session) writeLocked(raw []byte) error {
+	if s.conn == nil {
+		return nil
+	}
+	s.conn.SetWriteDeadline(s.now().Add(defaultWriteTimeout))
+	if _, err := s.conn.Write(raw); err != nil {
+		log.Printf("FIX session %s: write failed, disconnecting: %v", s.ID, err)
+		s.conn.Close()
+		s.conn = nil
+		return fmt.Errorf("failed to write to %s: %w", s.targetCompID, err)
+	}
+	s.lastSent = s.now()
+	return nil
+}
+
+// withHeader returns msg with the standard header after MsgType.
+func (s *Session) withHeader(msg *Message, seq uint64) *Message {
+	out := NewMessage(msg.MsgType()).
+		Add(TagSenderCompID, s.senderCompID).
+		Add(TagTargetCompID, s.targetCompID).
+		Add(TagMsgSeqNum, strconv.FormatUint(seq, 10)).
+		Add(TagSendingTime, s.now().UTC().Format(sendingTimeLayout))
+	out.Fields = append(out.Fields, msg.Fields[1:]...)
+	return out
+}
+
+func isAdmin(msgType string) bool {
+	switch msgType {
+	case MsgTypeHeartbeat, MsgTypeTestRequest, MsgTypeResendRequest, MsgTypeReject,
+		MsgTypeSequenceReset, MsgTypeLogout, MsgTypeLogon:
+		return true
+	}
+	return false
+}
+
+// logon attaches a connection whose Logon has been validated and answers it.
+func (s *Session) logon(conn net.Conn, logon *Message, heartbeat time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn != nil {
+		return ErrAlreadyLoggedOn
+	}
+	reset := logon.flag(TagResetSeqNumFlag)
+	if reset {
+		if err := s.store.Reset(s.ID); err != nil {
+			return err
+		}
+		s.seq = initialSeqNums()
+	}
+	seq, err := logon.SeqNum()
+	if err != nil {
+		return err
+	}
+
+	s.conn = conn
+	s.heartbeat = heartbeat
+	s.lastReceived = s.now()
+	s.testReqPending = false
+	s.resendUntil = 0
+
+	if seq < s.seq.NextTarget {
+		s.logoutLocked(fmt.Sprintf("MsgSeqNum too low, expecting %d but received %d", s.seq.NextTarget, seq))
+		return fmt.Errorf("logon of %s with MsgSeqNum %d below %d", s.targetCompID, seq, s.seq.NextTarget)
+	}
+
+	reply := NewMessage(MsgTypeLogon).
+		Add(TagEncryptMethod, "0").
+		Add(TagHeartBtInt, strconv.Itoa(int(heartbeat/time.Second)))
+	if reset {
+		reply.Add(TagResetSeqNumFlag, "Y")
+	}
+	if err := s.sendLocked(reply); err != nil {
+		return err
+	}
+
+	if seq > s.seq.NextTarget {
+		return s.requestResendLocked(seq)
+	}
+	s.seq.NextTarget++
+	return s.store.SaveSeqNums(s.ID, s.seq)
+}
+
+// detach forgets the connection if it is still the session's.
+func (s *Session) detach(conn net.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == conn {
+		s.conn = nil
+	}
+}
+
+// receive processes one inbound message and reports whether the connection
+// must be closed.
+func (s *Session) receive(msg *Message) bool {
+	s.mu.Lock()
+	s.lastReceived = s.now()
+	s.testReqPending = false
+
+	sender, _ := msg.Get(TagSenderCompID)
+	target, _ := msg.Get(TagTargetCompID)
+	if sender != s.targetCompID || target != s.senderCompID {
+		s.rejectLocked(msg, rejectCompIDProblem, "CompID problem")
+		s.logoutLocked("CompID problem")
+		s.mu.Unlock()
+		return true
+	}
+	seq, err := msg.SeqNum()
+	if err != nil {
+		s.logoutLocked(err.Error())
+		s.mu.Unlock()
+		return true
+	}
+
+	msgType := msg.MsgType()
+	if msgType == MsgTypeSequenceReset && !msg.flag(TagGapFillFlag) {
+		// Reset mode ignores MsgSeqNum.
+		s.sequenceResetLocked(msg)
+		s.mu.Unlock()
+		return false
+	}
+
+	switch {
+	case seq < s.seq.NextTarget:
+		defer s.mu.Unlock()
+		if msg.flag(TagPossDupFlag) {
+			return false
+		}
+		s.logoutLocked(fmt.Sprintf("MsgSeqNum too low, expecting %d but received %d", s.seq.NextTarget, seq))
+		return true
+
+	case seq > s.seq.NextTarget:
+		defer s.mu.Unlock()
+		if err := s.requestResendLocked(seq); err != nil {
+			log.Printf("FIX session %s: %v", s.ID, err)
+		}
+		// A Logout or ResendRequest is honoured even ahead of a gap.
+		switch msgType {
+		case MsgTypeLogout:
+			s.logoutLocked("")
+			return true
+		case MsgTypeResendRequest:
+			s.resendLocked(msg)
+		}
+		return false
+	}
+
+	s.seq.NextTarget++
+	if err := s.store.SaveSeqNums(s.ID, s.seq); err != nil {
+		log.Printf("FIX session %s: %v", s.ID, err)
+	}
+
+	switch msgType {
+	case MsgTypeHeartbeat:
+	case MsgTypeTestRequest:
+		id, _ := msg.Get(TagTestReqID)
+		s.sendLocked(NewMessage(MsgTypeHeartbeat).Add(TagTestReqID, id))
+	case MsgTypeResendRequest:
+		s.resendLocked(msg)
+	case MsgTypeReject:
+		text, _ := msg.Get(TagText)
+		log.Printf("FIX session %s: counterparty rejected message: %s", s.ID, text)
+	case MsgTypeSequenceReset:
+		s.sequenceResetLocked(msg)
+	case MsgTypeLogout:
+		s.logoutLocked("")
+		s.mu.Unlock()
+		return true
+	case MsgTypeLogon:
+		s.rejectLocked(msg, rejectInvalidMsgType, "already logged on")
+	default:
+		s.mu.Unlock()
+		s.onApp(s, msg)
+		return false
+	}
+	s.mu.Unlock()
+	return false
+}
+
+// requestResendLocked asks for everything from the expected sequence on,
+// once per gap.
+func (s *Session) requestResendLocked(seen uint64) error {
+	if s.resendUntil >= s.seq.NextTarget {
+		if seen > s.resendUntil {
+			s.resendUntil = seen
+		}
+		return nil
+	}
+	s.resendUntil = seen
+	return s.sendLocked(NewMessage(MsgTypeResendRequest).
+		Add(TagBeginSeqNo, strconv.FormatUint(s.seq.NextTarget, 10)).
+		Add(TagEndSeqNo, "0"))
+}
+
+func (s *Session) sequenceResetLocked(msg *Message) {
+	v, err := msg.Require(TagNewSeqNo)
+	if err != nil {
+		s.rejectLocked(msg, rejectRequiredTagMissing, err.Error())
+		return
+	}
+	newSeq, err := strconv.ParseUint(v, 10, 64)
+	if err != nil || newSeq < s.seq.NextTarget {
+		// A gap fill from a resend already covered is harmless.
+		if !msg.flag(TagGapFillFlag) {
+			s.rejectLocked(msg, rejectValueIncorrect, "NewSeqNo below expected MsgSeqNum")
+		}
+		return
+	}
+	s.seq.NextTarget = newSeq
+	if err := s.store.SaveSeqNums(s.ID, s.seq); err != nil {
+		log.Printf("FIX session %s: %v", s.ID, err)
+	}
+}
+
+// resendLocked answers a ResendRequest: stored application messages go out
+// again as possible duplicates and everything else is covered by gap fills.
+func (s *Session) resendLocked(req *Message) {
+	begin, err1 := strconv.ParseUint(firstValue(req, TagBeginSeqNo), 10, 64)
+	end, err2 := strconv.ParseUint(firstValue(req, TagEndSeqNo), 10, 64)
+	if err1 != nil || err2 != nil || begin == 0 {
+		s.rejectLocked(req, rejectValueIncorrect, "invalid resend range")
+		return
+	}
+	last := s.seq.NextSender - 1
+	if end == 0 || end > last {
+		end = last
+	}
+	if begin > end {
+		return
+	}
+
+	next := begin
+	err := s.store.Messages(s.ID, begin, end, func(seq uint64, stored *Message) error {
+		if seq > next {
+			if err := s.gapFillLocked(next, seq); err != nil {
+				return err
+			}
+		}
+		next = seq + 1
+		return s.writeLocked(s.possDup(stored).Bytes())
+	})
+	if err == nil && next <= end {
+		err = s.gapFillLocked(next, end+1)
+	}
+	if err != nil {
+		log.Printf("FIX session %s: resend of %d-%d failed: %v", s.ID, begin, end, err)
+	}
+}
+
+func (s *Session) gapFillLocked(from, to uint64) error {
+	msg := s.withHeader(NewMessage(MsgTypeSequenceReset), from)
+	msg.Add(TagPossDupFlag, "Y").
+		Add(TagGapFillFlag, "Y").
+		Add(TagNewSeqNo, strconv.FormatUint(to, 10))
+	return s.writeLocked(msg.Bytes())
+}
+
+// possDup marks a stored message as resent, keeping its original
+// SendingTime in OrigSendingTime.
+func (s *Session) possDup(stored *Message) *Message {
+	out := &Message{}
+	for _, f := range stored.Fields {
+		switch f.Tag {
+		case TagSendingTime:
+			out.Add(TagSendingTime, s.now().UTC().Format(sendingTimeLayout)).
+				Add(TagPossDupFlag, "Y").
+				Add(TagOrigSendingTime, f.Value)
+		case TagPossDupFlag, TagOrigSendingTime:
+		default:
+			out.Add(f.Tag, f.Value)
+		}
+	}
+	return out
+}
+
+func (s *Session) rejectLocked(ref *Message, reason, text string) {
+	s.sendLocked(NewMessage(MsgTypeReject).
+		Add(TagRefSeqNum, firstValue(ref, TagMsgSeqNum)).
+		Add(TagRefMsgType, ref.MsgType()).
+		Add(TagSessionRejectReason, reason).
+		Add(TagText, text))
+}
+
+// logoutLocked sends a Logout and closes the connection.
+func (s *Session) logoutLocked(text string) {
+	msg := NewMessage(MsgTypeLogout)
+	if text != "" {
+		msg.Add(TagText, text)
+	}
+	s.sendLocked(msg)
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+}
+
+// Logout ends the current connection, if any.
+func (s *Session) Logout(text string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn != nil {
+		s.logoutLocked(text)
+	}
+}
+
+// keepAlive sends a Heartbeat after a silent interval and a TestRequest when
+// the counterparty has been silent for longer, until stop is closed. The
+// connection's read deadline ends a counterparty that stays silent.
+func (s *Session) keepAlive(stop <-chan struct{}) {
+	s.mu.Lock()
+	interval := s.heartbeat
+	s.mu.Unlock()
+
+	ticker := time.NewTicker(interval / 4)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		s.mu.Lock()
+		now := s.now()
+		if s.conn != nil && now.Sub(s.lastReceived) >= interval+interval/5 && !s.testReqPending {
+			s.testReqPending = true
+			s.sendLocked(NewMessage(MsgTypeTestRequest).Add(TagTestReqID, now.UTC().Format(sendingTimeLayout)))
+		} else if s.conn != nil && now.Sub(s.lastSent) >= interval {
+			s.sendLocked(NewMessage(MsgTypeHeartbeat))
+		}
+		s.mu.Unlock()
+	}
+}
+
+func firstValue(msg *Message, tag int) string {
+	v, _ := msg.Get(tag)
+	return v
+}
@@ -0,0 +1,283 @@
+package fix
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// SeqNums are the next sequence numbers of a session: the one this side sends
+// and the one it expects from the counterparty.
+type SeqNums struct {
+	NextSender uint64 `json:"next_sender_seq"`
+	NextTarget uint64 `json:"next_target_seq"`
+}
+
+func initialSeqNums() SeqNums {
+	return SeqNums{NextSender: 1, NextTarget: 1}
+}
+
+// FileStore keeps, per session, its sequence numbers, the application
+// messages it sent, so a reconnecting counterparty can ask for them again, and
+// the orders it entered, so their reports find it after a restart.
+type FileStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{dir: dir}
+}
+
+// LoadSeqNums returns the stored sequence numbers, starting at 1 for a new
+// session.
+func (s *FileStore) LoadSeqNums(session string) (SeqNums, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(s.path(session, ".seqnums"))
+	if errors.Is(err, os.ErrNotExist) {
+		return initialSeqNums(), nil
+	}
+	if err != nil {
+		return SeqNums{}, fmt.Errorf("failed to read sequence numbers of %s: %w", session, err)
+	}
+	var seq SeqNums
+	if err := json.Unmarshal(data, &seq); err != nil {
+		return SeqNums{}, fmt.Errorf("failed to decode sequence numbers of %s: %w", session, err)
+	}
+	return seq, nil
+}
+
+// SaveSeqNums replaces the stored sequence numbers through a synced temporary
+// file.
+func (s *FileStore) SaveSeqNums(session string, seq SeqNums) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := json.Marshal(seq)
+	if err != nil {
+		return fmt.Errorf("failed to encode sequence numbers of %s: %w", session, err)
+	}
+	if err := writeFileSynced(s.dir, s.path(session, ".seqnums"), data); err != nil {
+		return fmt.Errorf("failed to save sequence numbers of %s: %w", session, err)
+	}
+	return nil
+}
+
+// writeFileSynced replaces path through a synced temporary file in dir.
+func writeFileSynced(dir, path string, data []byte) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create FIX store directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// SaveMessage appends an encoded outbound message.
+func (s *FileStore) SaveMessage(session string, raw []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create FIX store directory: %w", err)
+	}
+	f, err := os.OpenFile(s.path(session, ".messages"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open message store of %s: %w", session, err)
+	}
+	if _, err := f.Write(raw); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to store message of %s: %w", session, err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to sync message store of %s: %w", session, err)
+	}
+	return f.Close()
+}
+
+// Messages calls fn for each stored message with MsgSeqNum in [begin, end],
+// in order. An end of 0 means no upper bound.
+func (s *FileStore) Messages(session string, begin, end uint64, fn func(seq uint64, msg *Message) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.Open(s.path(session, ".messages"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open message store of %s: %w", session, err)
+	}
+	defer f.Close()
+
+	r := NewReader(f)
+	for {
+		msg, err := r.ReadMessage()
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			// A torn last message was never sent.
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("message store of %s is corrupt: %w", session, err)
+		}
+		seq, err := msg.SeqNum()
+		if err != nil {
+			return fmt.Errorf("message store of %s is corrupt: %w", session, err)
+		}
+		if seq < begin || (end != 0 && seq > end) {
+			continue
+		}
+		if err := fn(seq, msg); err != nil {
+			return err
+		}
+	}
+}
+
+// ownedRecord is the state of an order entered through a session: every
+// ClOrdID it goes by and, once reported, its OrderID. Orders are known by the
+// first ClOrdID they went by; a record without ClOrdIDs ends the order.
+type ownedRecord struct {
+	Key      string   `json:"key"`
+	OrderID  string   `json:"order_id,omitempty"`
+	ClOrdIDs []string `json:"cl_ord_ids,omitempty"`
+}
+
+// SaveOwnedOrder appends the state of an order entered through a session.
+func (s *FileStore) SaveOwnedOrder(session string, rec ownedRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to encode order %s of %s: %w", rec.Key, session, err)
+	}
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create FIX store directory: %w", err)
+	}
+	f, err := os.OpenFile(s.path(session, ".orders"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open order store of %s: %w", session, err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to store order %s of %s: %w", rec.Key, session, err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to sync order store of %s: %w", session, err)
+	}
+	return f.Close()
+}
+
+// OwnedOrders returns the orders of a session that are not done with, and
+// rewrites its order store to hold only them.
+func (s *FileStore) OwnedOrders(session string) ([]ownedRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := s.path(session, ".orders")
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read order store of %s: %w", session, err)
+	}
+	var keys []string
+	live := make(map[string]ownedRecord)
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		var rec ownedRecord
+		if len(line) == 0 || json.Unmarshal(line, &rec) != nil {
+			// A torn last record was never acknowledged.
+			continue
+		}
+		if _, ok := live[rec.Key]; !ok {
+			keys = append(keys, rec.Key)
+		}
+		live[rec.Key] = rec
+	}
+
+	var records []ownedRecord
+	var compacted []byte
+	for _, key := range keys {
+		rec := live[key]
+		if len(rec.ClOrdIDs) == 0 {
+			continue
+		}
+		records = append(records, rec)
+		line, err := json.Marshal(rec)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode order %s of %s: %w", key, session, err)
+		}
+		compacted = append(append(compacted, line...), '\n')
+	}
+	if err := writeFileSynced(s.dir, path, compacted); err != nil {
+		return nil, fmt.Errorf("failed to compact order store of %s: %w", session, err)
+	}
+	return records, nil
+}
+
+// Sessions returns the IDs of the sessions with stored orders.
+func (s *FileStore) Sessions() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list FIX store directory: %w", err)
+	}
+	var sessions []string
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".orders")
+		if !ok {
+			continue
+		}
+		if session, err := url.PathUnescape(name); err == nil {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions, nil
+}
+
+// Reset forgets a session's messages and restarts its sequence numbers.
+func (s *FileStore) Reset(session string) error {
+	s.mu.Lock()
+	err := os.Remove(s.path(session, ".messages"))
+	s.mu.Unlock()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to reset message store of %s: %w", session, err)
+	}
+	return s.SaveSeqNums(session, initialSeqNums())
+}
+
+func (s *FileStore) path(session, suffix string) string {
+	return filepath.Join(s.dir, url.PathEscape(session)+suffix)
+}
@@ -0,0 +1,216 @@
+package fix
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"MatchingEngine/internal/model"
+)
+
+// TradeRequestType <569> values served; both mean the trades matching
+// whatever criteria the request gives.
+const (
+	tradeRequestAllTrades = "0"
+	tradeRequestMatched   = "1"
+)
+
+// TradeRequestResult <749> values.
+const (
+	tradeRequestSuccessful       = "0"
+	tradeRequestInvalidParties   = "3"
+	tradeRequestTypeNotSupported = "8"
+	tradeRequestNotAuthorized    = "9"
+	tradeRequestResultOther      = "99"
+)
+
+// TradeRequestStatus <750> values.
+const (
+	tradeRequestCompleted = "1"
+	tradeRequestRejected  = "2"
+)
+
+// subscriptionSnapshot is the only SubscriptionRequestType <263> served.
+const subscriptionSnapshot = "0"
+
+const (
+	tradeRequestPageSize = 500
+	tradeRequestTimeout  = time.Minute
+	// tradeRequestMaxTrades bounds the answer to one request, which is read
+	// whole before the first AE goes out to count it in TotNumTradeReports.
+	tradeRequestMaxTrades = 10000
+)
+
+// TradeHistory pages through the stored trades, oldest first, for
+// TradeCaptureReportRequests.
+type TradeHistory interface {
+	ListTrades(ctx context.Context, filter model.HistoryFilter, cursor string, limit int) ([]model.TradeCaptureReport, string, error)
+}
+
+// tradeCaptureReportRequest is the part of an AD the acceptor serves. The
+// TransactTimes in NoDates bound the trades: the first starts the range and
+// the second, if any, ends it, both inclusive.
+type tradeCaptureReportRequest struct {
+	TradeRequestID          string        `json:"568"`
+	TradeRequestType        string        `json:"569"`
+	SubscriptionRequestType string        `json:"263,omitempty"`
+	OrderID                 string        `json:"37,omitempty"`
+	Account                 string        `json:"1,omitempty"`
+	Symbol                  string        `json:"55,omitempty"`
+	NoDates                 []requestDate `json:"580,omitempty"`
+}
+
+type requestDate struct {
+	TransactTime int64 `json:"60"`
+}
+
+// requestedTrade is an AE sent in answer to a request; the last one of the
+// answer has LastRptRequested set.
+type requestedTrade struct {
+	TradeRequestID     string `json:"568"`
+	TotNumTradeReports int    `json:"748"`
+	LastRptRequested   bool   `json:"912,omitempty"`
+	model.TradeCaptureReport
+}
+
+// handleTradeRequest answers a TradeCaptureReportRequest with one AE per
+// matching trade, oldest first, or with a TradeCaptureReportRequestAck when
+// no trade matches or the request cannot be served. Only snapshots are
+// served, of the trades of an Account the counterparty is entitled to in
+// Opts.Accounts. The answer is sent while the session goes on reading; a
+// session gets one answer at a time.
+func (a *Acceptor) handleTradeRequest(s *Session, msg *Message) {
+	if a.Trades == nil {
+		a.businessReject(s, msg, businessRejectUnsupportedMsgType, "trade capture report requests are not served")
+		return
+	}
+	if _, err := msg.Require(TagTradeRequestID); err != nil {
+		sessionReject(s, msg, rejectRequiredTagMissing, err.Error())
+		return
+	}
+	var req tradeCaptureReportRequest
+	if err := Unmarshal(msg, &req); err != nil {
+		sessionReject(s, msg, rejectValueIncorrect, err.Error())
+		return
+	}
+
+	filter := model.HistoryFilter{Symbol: req.Symbol, OrderID: req.OrderID, Account: req.Account}
+	switch {
+	case req.TradeRequestType != tradeRequestAllTrades && req.TradeRequestType != tradeRequestMatched:
+		tradeRequestAck(s, req, tradeRequestTypeNotSupported, tradeRequestRejected,
+			fmt.Sprintf("TradeRequestType %q is not supported", req.TradeRequestType))
+		return
+	case req.SubscriptionRequestType != "" && req.SubscriptionRequestType != subscriptionSnapshot:
+		tradeRequestAck(s, req, tradeRequestResultOther, tradeRequestRejected, "only snapshots are served")
+		return
+	case len(req.NoDates) > 2:
+		tradeRequestAck(s, req, tradeRequestResultOther, tradeRequestRejected, "NoDates holds a start and an end at most")
+		return
+	case req.Account == "":
+		tradeRequestAck(s, req, tradeRequestInvalidParties, tradeRequestRejected, "Account is required")
+		return
+	case !a.opts.Accounts.Allows(s.targetCompID, req.Account):
+		tradeRequestAck(s, req, tradeRequestNotAuthorized, tradeRequestRejected,
+			fmt.Sprintf("%s is not entitled to Account %s", s.targetCompID, req.Account))
+		return
+	}
+	if len(req.NoDates) > 0 {
+		filter.From = req.NoDates[0].TransactTime
+	}
+	if len(req.NoDates) > 1 {
+		filter.To = req.NoDates[1].TransactTime + 1
+	}
+
+	if !a.startAnswering(s) {
+		tradeRequestAck(s, req, tradeRequestResultOther, tradeRequestRejected, "another trade request is being answered")
+		return
+	}
+	go func() {
+		defer a.stopAnswering(s)
+		ctx, cancel := context.WithTimeout(context.Background(), tradeRequestTimeout)
+		defer cancel()
+		a.answerTradeRequest(ctx, s, req, filter)
+	}()
+}
+
+func (a *Acceptor) startAnswering(s *Session) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.answering[s] {
+		return false
+	}
+	a.answering[s] = true
+	return true
+}
+
+func (a *Acceptor) stopAnswering(s *Session) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.answering, s)
+}
+
+func (a *Acceptor) answerTradeRequest(ctx context.Context, s *Session, req tradeCaptureReportRequest, filter model.HistoryFilter) {
+	trades, err := a.listTrades(ctx, filter)
+	if err != nil {
+		log.Printf("FIX session %s: trade request %s failed: %v", s.ID, req.TradeRequestID, err)
+		tradeRequestAck(s, req, tradeRequestResultOther, tradeRequestRejected, err.Error())
+		return
+	}
+	if len(trades) == 0 {
+		tradeRequestAck(s, req, tradeRequestSuccessful, tradeRequestCompleted, "")
+		return
+	}
+	for i, trade := range trades {
+		trade.MsgType = MsgTypeTradeCaptureReport
+		msg, err := Marshal(requestedTrade{
+			TradeRequestID:     req.TradeRequestID,
+			TotNumTradeReports: len(trades),
+			LastRptRequested:   i == len(trades)-1,
+			TradeCaptureReport: trade,
+		})
+		if err == nil {
+			err = s.Send(msg)
+		}
+		if err != nil {
+			log.Printf("FIX session %s: trade request %s stopped after %d of %d trades: %v", s.ID, req.TradeRequestID, i, len(trades), err)
+			return
+		}
+	}
+}
+
+// listTrades reads the trades matching filter a page at a time, failing if
+// there are more than tradeRequestMaxTrades.
+func (a *Acceptor) listTrades(ctx context.Context, filter model.HistoryFilter) ([]model.TradeCaptureReport, error) {
+	var all []model.TradeCaptureReport
+	cursor := ""
+	for {
+		trades, next, err := a.Trades.ListTrades(ctx, filter, cursor, tradeRequestPageSize)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, trades...)
+		if len(all) > tradeRequestMaxTrades {
+			return nil, fmt.Errorf("more than %d trades match, narrow the request", tradeRequestMaxTrades)
+		}
+		if next == "" {
+			return all, nil
+		}
+		cursor = next
+	}
+}
+
+func tradeRequestAck(s *Session, req tradeCaptureReportRequest, result, status, text string) {
+	msg := NewMessage(MsgTypeTradeCaptureReportAck).
+		Add(TagTradeRequestID, req.TradeRequestID).
+		Add(TagTradeRequestType, req.TradeRequestType).
+		Add(TagTradeRequestResult, result).
+		Add(TagTradeRequestStatus, status)
+	if status == tradeRequestCompleted {
+		msg.Add(TagTotNumTradeReports, "0")
+	}
+	if text != "" {
+		msg.Add(TagText, text)
+	}
+	s.Send(msg)
+}
@@ -0,0 +1,175 @@
+package fix
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"MatchingEngine/internal/model"
+)
+
+// pagedTrades serves its trades two to a page, whatever the limit.
+type pagedTrades struct {
+	trades []model.TradeCaptureReport
+
+	mu      sync.Mutex
+	filters []model.HistoryFilter
+}
+
+func (p *pagedTrades) ListTrades(_ context.Context, filter model.HistoryFilter, cursor string, _ int) ([]model.TradeCaptureReport, string, error) {
+	p.mu.Lock()
+	p.filters = append(p.filters, filter)
+	p.mu.Unlock()
+
+	var matching []model.TradeCaptureReport
+	for _, trade := range p.trades {
+		if filter.Symbol == "" || trade.Symbol == filter.Symbol {
+			matching = append(matching, trade)
+		}
+	}
+	start, _ := strconv.Atoi(cursor)
+	end := min(start+2, len(matching))
+	next := ""
+	if end < len(matching) {
+		next = strconv.Itoa(end)
+	}
+	return matching[start:end], next, nil
+}
+
+func testTrade(id string, ts int64) model.TradeCaptureReport {
+	return model.TradeCaptureReport{
+		TradeReportID: id,
+		ExecID:        id + "-exec",
+		Symbol:        "BTC/USDT",
+		LastQty:       decimal.NewFromInt(1),
+		LastPx:        decimal.NewFromInt(100),
+		TradeDate:     "20250101",
+		TransactTime:  ts,
+		NoSides: []model.NoSides{
+			{Side: model.Buy, OrderID: id + "-b"},
+			{Side: model.Sell, OrderID: id + "-s"},
+		},
+	}
+}
+
+func TestAcceptor_TradeCaptureReportRequest(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	history := &pagedTrades{trades: []model.TradeCaptureReport{
+		testTrade("T1", start.UnixNano()),
+		testTrade("T2", start.Add(time.Second).UnixNano()),
+		testTrade("T3", start.Add(2*time.Second).UnixNano()),
+	}}
+	_, addr := startAcceptor(t, t.TempDir(), &recordingOrders{}, nil, func(a *Acceptor) {
+		a.Trades = history
+		a.opts.Accounts = Accounts{"CLIENT": {"acct-1"}}
+	})
+
+	c := dial(t, addr, 1)
+	c.logon()
+	c.send(NewMessage(MsgTypeTradeCaptureReportReq).
+		Add(TagTradeRequestID, "R1").
+		Add(TagTradeRequestType, tradeRequestMatched).
+		Add(TagAccount, "acct-1").
+		Add(TagSymbol, "BTC/USDT").
+		Add(TagNoDates, "2").
+		Add(TagTransactTime, FormatTransactTime(start.UnixNano())).
+		Add(TagTransactTime, FormatTransactTime(start.Add(time.Hour).UnixNano())))
+	for i, id := range []string{"T1", "T2", "T3"} {
+		ae := c.readType(MsgTypeTradeCaptureReport)
+		assert.Equal(t, "R1", firstValue(ae, TagTradeRequestID))
+		assert.Equal(t, id, firstValue(ae, TagTradeReportID))
+		assert.Equal(t, "3", firstValue(ae, TagTotNumTradeReports))
+		assert.Equal(t, "2", firstValue(ae, TagNoSides))
+		last, _ := ae.Get(TagLastRptRequested)
+		assert.Equal(t, i == 2, last == "Y", id)
+	}
+	assert.Equal(t, []model.HistoryFilter{
+		{Symbol: "BTC/USDT", Account: "acct-1", From: start.UnixNano(), To: start.Add(time.Hour).UnixNano() + 1},
+		{Symbol: "BTC/USDT", Account: "acct-1", From: start.UnixNano(), To: start.Add(time.Hour).UnixNano() + 1},
+	}, history.filters)
+
+	// Nothing matches
+	c.send(NewMessage(MsgTypeTradeCaptureReportReq).
+		Add(TagTradeRequestID, "R2").
+		Add(TagTradeRequestType, tradeRequestAllTrades).
+		Add(TagAccount, "acct-1").
+		Add(TagSymbol, "ETH/USDT"))
+	ack := c.readType(MsgTypeTradeCaptureReportAck)
+	assert.Equal(t, "R2", firstValue(ack, TagTradeRequestID))
+	assert.Equal(t, tradeRequestCompleted, firstValue(ack, TagTradeRequestStatus))
+	assert.Equal(t, "0", firstValue(ack, TagTotNumTradeReports))
+
+	// Subscriptions are not served
+	c.send(NewMessage(MsgTypeTradeCaptureReportReq).
+		Add(TagTradeRequestID, "R3").
+		Add(TagTradeRequestType, tradeRequestAllTrades).
+		Add(TagAccount, "acct-1").
+		Add(TagSubscriptionReqType, "1"))
+	ack = c.readType(MsgTypeTradeCaptureReportAck)
+	assert.Equal(t, "R3", firstValue(ack, TagTradeRequestID))
+	assert.Equal(t, tradeRequestRejected, firstValue(ack, TagTradeRequestStatus))
+	assert.Equal(t, tradeRequestResultOther, firstValue(ack, TagTradeRequestResult))
+}
+
+func TestAcceptor_TradeCaptureReportRequestForeignAccount(t *testing.T) {
+	history := &pagedTrades{trades: []model.TradeCaptureReport{testTrade("T1", 1)}}
+	_, addr := startAcceptor(t, t.TempDir(), &recordingOrders{}, nil, func(a *Acceptor) {
+		a.Trades = history
+		a.opts.Accounts = Accounts{"CLIENT": {"acct-2"}, "OTHER": {"acct-1"}}
+	})
+
+	c := dial(t, addr, 1)
+	c.logon()
+	// Entering an order for an Account entitles the session to nothing.
+	c.send(newOrderSingle("O1", "1", "1", "100").Add(TagAccount, "acct-1"))
+
+	// No Account
+	c.send(NewMessage(MsgTypeTradeCaptureReportReq).
+		Add(TagTradeRequestID, "R1").
+		Add(TagTradeRequestType, tradeRequestAllTrades))
+	ack := c.readType(MsgTypeTradeCaptureReportAck)
+	assert.Equal(t, "R1", firstValue(ack, TagTradeRequestID))
+	assert.Equal(t, tradeRequestRejected, firstValue(ack, TagTradeRequestStatus))
+	assert.Equal(t, tradeRequestInvalidParties, firstValue(ack, TagTradeRequestResult))
+
+	// Another counterparty's Account
+	c.send(NewMessage(MsgTypeTradeCaptureReportReq).
+		Add(TagTradeRequestID, "R2").
+		Add(TagTradeRequestType, tradeRequestAllTrades).
+		Add(TagAccount, "acct-1"))
+	ack = c.readType(MsgTypeTradeCaptureReportAck)
+	assert.Equal(t, "R2", firstValue(ack, TagTradeRequestID))
+	assert.Equal(t, tradeRequestRejected, firstValue(ack, TagTradeRequestStatus))
+	assert.Equal(t, tradeRequestNotAuthorized, firstValue(ack, TagTradeRequestResult))
+	assert.Empty(t, history.filters)
+}
+
+func TestAcceptor_TradeCaptureReportRequestWithoutHistory(t *testing.T) {
+	_, addr := startAcceptor(t, t.TempDir(), &recordingOrders{}, nil)
+
+	c := dial(t, addr, 1)
+	c.logon()
+	c.send(NewMessage(MsgTypeTradeCaptureReportReq).
+		Add(TagTradeRequestID, "R1").
+		Add(TagTradeRequestType, tradeRequestAllTrades))
+	reject := c.readType(MsgTypeBusinessMessageReject)
+	assert.Equal(t, businessRejectUnsupportedMsgType, firstValue(reject, TagBusinessRejectReason))
+}
+
+func TestParseAccounts(t *testing.T) {
+	accounts, err := ParseAccounts(" CLIENT=acct-1, CLIENT=acct-2,OTHER=acct-3,CLIENT=acct-1,")
+	require.NoError(t, err)
+	assert.Equal(t, Accounts{"CLIENT": {"acct-1", "acct-2"}, "OTHER": {"acct-3"}}, accounts)
+	assert.True(t, accounts.Allows("CLIENT", "acct-2"))
+	assert.False(t, accounts.Allows("CLIENT", "acct-3"))
+	assert.False(t, Accounts(nil).Allows("CLIENT", "acct-1"))
+
+	_, err = ParseAccounts("CLIENT")
+	assert.Error(t, err)
+}
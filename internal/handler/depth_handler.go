@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"net/http"
+
+	"MatchingEngine/internal/model"
+)
+
+type DepthProvider interface {
+	GetDepthSnapshot(symbol string) (model.MarketDataSnapshotFullRefresh, bool)
+}
+
+type DepthHandler struct {
+	Depth DepthProvider
+}
+
+func NewDepthHandler(depth DepthProvider) *DepthHandler {
+	return &DepthHandler{
+		Depth: depth,
+	}
+}
+
+// ServeHTTP returns the full depth snapshot of the symbol given by the
+// "symbol" query parameter. Consumers use it to resync after a checksum
+// mismatch.
+func (h *DepthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	symbol := r.URL.Query().Get("symbol")
+	if symbol == "" {
+		writeError(w, http.StatusBadRequest, "missing symbol")
+		return
+	}
+
+	snapshot, ok := h.Depth.GetDepthSnapshot(symbol)
+	if !ok {
+		writeError(w, http.StatusNotFound, "no depth for symbol "+symbol)
+		return
+	}
+	writeJSON(w, http.StatusOK, snapshot)
+}
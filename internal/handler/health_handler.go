@@ -0,0 +1,43 @@
+package handler
+
+import "net/http"
+
+// HealthCheck returns nil while the dependency it checks is usable.
+type HealthCheck func() error
+
+// HealthHandler answers liveness and readiness probes. The engine is ready
+// when every check passes.
+type HealthHandler struct {
+	Checks map[string]HealthCheck
+}
+
+func NewHealthHandler(checks map[string]HealthCheck) *HealthHandler {
+	return &HealthHandler{
+		Checks: checks,
+	}
+}
+
+// Live answers as long as the process serves HTTP.
+func (h *HealthHandler) Live(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// Ready runs the checks and answers 503 with the failing ones when any fails.
+func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	status := http.StatusOK
+	checks := make(map[string]string, len(h.Checks))
+	for name, check := range h.Checks {
+		if err := check(); err != nil {
+			status = http.StatusServiceUnavailable
+			checks[name] = err.Error()
+			continue
+		}
+		checks[name] = "ok"
+	}
+
+	body := map[string]interface{}{"status": "ok", "checks": checks}
+	if status != http.StatusOK {
+		body["status"] = "unavailable"
+	}
+	writeJSON(w, status, body)
+}
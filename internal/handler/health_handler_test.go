@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthHandler_ReadyReportsFailingChecks(t *testing.T) {
+	var rabbitErr error
+	h := NewHealthHandler(map[string]HealthCheck{
+		"rabbitmq": func() error { return rabbitErr },
+		"postgres": func() error { return nil },
+	})
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", h.Live)
+	mux.HandleFunc("GET /readyz", h.Ready)
+
+	rec := serve(mux, http.MethodGet, "/readyz", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rabbitErr = errors.New("not consuming from orderRequests: connection closed")
+	rec = serve(mux, http.MethodGet, "/readyz", "")
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	var body struct {
+		Status string            `json:"status"`
+		Checks map[string]string `json:"checks"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, "unavailable", body.Status)
+	assert.Equal(t, map[string]string{
+		"rabbitmq": "not consuming from orderRequests: connection closed",
+		"postgres": "ok",
+	}, body.Checks)
+
+	// A broken dependency does not make the engine dead.
+	rec = serve(mux, http.MethodGet, "/healthz", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"MatchingEngine/internal/model"
+	"MatchingEngine/internal/service"
+)
+
+type History interface {
+	ListExecutions(ctx context.Context, filter model.HistoryFilter, cursor string, limit int) ([]model.ExecutionReport, string, error)
+	ListTrades(ctx context.Context, filter model.HistoryFilter, cursor string, limit int) ([]model.TradeCaptureReport, string, error)
+}
+
+// HistoryHandler pages through the stored executions and trades, oldest
+// first. Both take the query parameters "symbol", "order_id", "account",
+// "from" and "to" (RFC 3339 or epoch nanoseconds; "to" is exclusive),
+// "limit", and "cursor", the "next_cursor" of the previous page.
+type HistoryHandler struct {
+	History History
+}
+
+func NewHistoryHandler(history History) *HistoryHandler {
+	return &HistoryHandler{History: history}
+}
+
+type executionPage struct {
+	Executions []model.ExecutionReport `json:"executions"`
+	NextCursor string                  `json:"next_cursor,omitempty"`
+}
+
+type tradePage struct {
+	Trades     []model.TradeCaptureReport `json:"trades"`
+	NextCursor string                     `json:"next_cursor,omitempty"`
+}
+
+func (h *HistoryHandler) ListExecutions(w http.ResponseWriter, r *http.Request) {
+	filter, limit, err := historyQuery(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	reports, next, err := h.History.ListExecutions(r.Context(), filter, r.URL.Query().Get("cursor"), limit)
+	if err != nil {
+		writeHistoryError(w, err)
+		return
+	}
+	if reports == nil {
+		reports = []model.ExecutionReport{}
+	}
+	writeJSON(w, http.StatusOK, executionPage{Executions: reports, NextCursor: next})
+}
+
+// ListTrades returns trades with their sides; "order_id" and "account"
+// select the trades one of whose sides they match.
+func (h *HistoryHandler) ListTrades(w http.ResponseWriter, r *http.Request) {
+	filter, limit, err := historyQuery(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	trades, next, err := h.History.ListTrades(r.Context(), filter, r.URL.Query().Get("cursor"), limit)
+	if err != nil {
+		writeHistoryError(w, err)
+		return
+	}
+	if trades == nil {
+		trades = []model.TradeCaptureReport{}
+	}
+	writeJSON(w, http.StatusOK, tradePage{Trades: trades, NextCursor: next})
+}
+
+func historyQuery(q url.Values) (model.HistoryFilter, int, error) {
+	filter := model.HistoryFilter{
+		Symbol:  q.Get("symbol"),
+		OrderID: q.Get("order_id"),
+		Account: q.Get("account"),
+	}
+	var err error
+	if filter.From, err = parseHistoryTime(q.Get("from")); err != nil {
+		return filter, 0, fmt.Errorf("invalid from: %w", err)
+	}
+	if filter.To, err = parseHistoryTime(q.Get("to")); err != nil {
+		return filter, 0, fmt.Errorf("invalid to: %w", err)
+	}
+	limit := 0
+	if s := q.Get("limit"); s != "" {
+		if limit, err = strconv.Atoi(s); err != nil || limit <= 0 {
+			return filter, 0, fmt.Errorf("limit must be between 1 and %d", service.MaxHistoryLimit)
+		}
+	}
+	return filter, limit, nil
+}
+
+// parseHistoryTime reads an RFC 3339 time or epoch nanoseconds; empty is 0.
+func parseHistoryTime(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if ns, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return ns, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return 0, err
+	}
+	return t.UnixNano(), nil
+}
+
+func writeHistoryError(w http.ResponseWriter, err error) {
+	if errors.Is(err, service.ErrInvalidHistoryQuery) {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeError(w, http.StatusInternalServerError, err.Error())
+}
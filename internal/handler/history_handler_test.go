@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"MatchingEngine/internal/model"
+	"MatchingEngine/internal/service"
+)
+
+type fakeHistoryService struct {
+	filter model.HistoryFilter
+	cursor string
+	limit  int
+	trades []model.TradeCaptureReport
+}
+
+func (h *fakeHistoryService) ListExecutions(_ context.Context, filter model.HistoryFilter, cursor string, limit int) ([]model.ExecutionReport, string, error) {
+	h.filter, h.cursor, h.limit = filter, cursor, limit
+	if cursor == "bad" {
+		return nil, "", fmt.Errorf("%w: bad cursor", service.ErrInvalidHistoryQuery)
+	}
+	return nil, "", nil
+}
+
+func (h *fakeHistoryService) ListTrades(_ context.Context, filter model.HistoryFilter, cursor string, limit int) ([]model.TradeCaptureReport, string, error) {
+	h.filter, h.cursor, h.limit = filter, cursor, limit
+	return h.trades, "next", nil
+}
+
+func historyMux(history History) *http.ServeMux {
+	h := NewHistoryHandler(history)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/history/executions", h.ListExecutions)
+	mux.HandleFunc("GET /api/v1/history/trades", h.ListTrades)
+	return mux
+}
+
+func TestHistoryHandler_Executions(t *testing.T) {
+	history := &fakeHistoryService{}
+	mux := historyMux(history)
+
+	rec := serve(mux, http.MethodGet, "/api/v1/history/executions?symbol=BTC/USDT&account=acct-1&from=2025-01-01T00:00:00Z&to=1735776000000000000&limit=50&cursor=abc", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"executions":[]}`, rec.Body.String())
+	assert.Equal(t, model.HistoryFilter{
+		Symbol:  "BTC/USDT",
+		Account: "acct-1",
+		From:    time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC).UnixNano(),
+		To:      time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC).UnixNano(),
+	}, history.filter)
+	assert.Equal(t, "abc", history.cursor)
+	assert.Equal(t, 50, history.limit)
+
+	assert.Equal(t, http.StatusBadRequest, serve(mux, http.MethodGet, "/api/v1/history/executions?from=yesterday", "").Code)
+	assert.Equal(t, http.StatusBadRequest, serve(mux, http.MethodGet, "/api/v1/history/executions?limit=-1", "").Code)
+	assert.Equal(t, http.StatusBadRequest, serve(mux, http.MethodGet, "/api/v1/history/executions?cursor=bad", "").Code)
+}
+
+func TestHistoryHandler_Trades(t *testing.T) {
+	history := &fakeHistoryService{trades: []model.TradeCaptureReport{{TradeReportID: "tradeReport-1"}}}
+	mux := historyMux(history)
+
+	rec := serve(mux, http.MethodGet, "/api/v1/history/trades?order_id=order-1", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"next_cursor":"next"`)
+	assert.Contains(t, rec.Body.String(), `"571":"tradeReport-1"`)
+	assert.Equal(t, model.HistoryFilter{OrderID: "order-1"}, history.filter)
+	assert.Equal(t, 0, history.limit)
+}
@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"MatchingEngine/internal/model"
+	"MatchingEngine/internal/service"
+)
+
+type OrderSubmitter interface {
+	Submit(ctx context.Context, req model.OrderRequest) (model.ExecutionReport, error)
+}
+
+// OrderEntryHandler takes orders over HTTP for clients that cannot speak
+// AMQP. Each call waits for the engine's answer: the execution report comes
+// back with 201 or 200, or with 422 when the engine rejected the request.
+// 202 means the answer did not arrive within Timeout.
+type OrderEntryHandler struct {
+	Orders  OrderSubmitter
+	Timeout time.Duration
+}
+
+func NewOrderEntryHandler(orders OrderSubmitter, timeout time.Duration) *OrderEntryHandler {
+	return &OrderEntryHandler{
+		Orders:  orders,
+		Timeout: timeout,
+	}
+}
+
+// NewOrder enters the NewOrderSingle in the body, keyed by FIX tag like the
+// AMQP requests.
+func (h *OrderEntryHandler) NewOrder(w http.ResponseWriter, r *http.Request) {
+	var or model.NewOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&or); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		return
+	}
+	or.MsgType = model.MsgTypeNew
+	if or.TransactTime == 0 {
+		or.TransactTime = time.Now().UnixNano()
+	}
+	h.submit(w, r, or.ClOrdID, http.StatusCreated, model.OrderRequest{MsgType: model.MsgTypeNew, NewOrderReq: or})
+}
+
+// CancelOrder cancels the order named in the path. The "symbol" query
+// parameter is required; "cl_ord_id" names the cancel request itself.
+func (h *OrderEntryHandler) CancelOrder(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("symbol") == "" {
+		writeError(w, http.StatusBadRequest, "missing symbol")
+		return
+	}
+	cancel := model.OrderCancelRequest{
+		BaseOrderRequest: model.BaseOrderRequest{
+			MsgType:      model.MsgTypeCancel,
+			ClOrdID:      q.Get("cl_ord_id"),
+			Side:         model.Side(q.Get("side")),
+			Symbol:       q.Get("symbol"),
+			TransactTime: time.Now().UnixNano(),
+		},
+		OrigClOrdID: r.PathValue("clOrdID"),
+	}
+	h.submit(w, r, cancel.OrigClOrdID, http.StatusOK, model.OrderRequest{MsgType: model.MsgTypeCancel, CancelOrderReq: cancel})
+}
+
+// AmendOrder replaces the quantity and price of the order named in the path
+// with those in the body, which also carries the order's new ClOrdID.
+func (h *OrderEntryHandler) AmendOrder(w http.ResponseWriter, r *http.Request) {
+	var or model.OrderCancelReplaceRequest
+	if err := json.NewDecoder(r.Body).Decode(&or); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		return
+	}
+	orig := r.PathValue("clOrdID")
+	if or.OrigClOrdID != "" && or.OrigClOrdID != orig {
+		writeError(w, http.StatusBadRequest, "OrigClOrdID in the body does not match the path")
+		return
+	}
+	or.OrigClOrdID = orig
+	or.MsgType = model.MsgTypeReplace
+	if or.TransactTime == 0 {
+		or.TransactTime = time.Now().UnixNano()
+	}
+	h.submit(w, r, or.ClOrdID, http.StatusOK, model.OrderRequest{MsgType: model.MsgTypeReplace, ReplaceOrderReq: &or})
+}
+
+func (h *OrderEntryHandler) submit(w http.ResponseWriter, r *http.Request, clOrdID string, accepted int, req model.OrderRequest) {
+	ctx, cancel := context.WithTimeout(r.Context(), h.Timeout)
+	defer cancel()
+
+	er, err := h.Orders.Submit(ctx, req)
+	switch {
+	case errors.Is(err, service.ErrNoReport):
+		writeJSON(w, http.StatusAccepted, map[string]string{"cl_ord_id": clOrdID, "status": "pending"})
+	case errors.Is(err, service.ErrSymbolNotSpecified):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrSymbolNotOwned):
+		writeError(w, http.StatusMisdirectedRequest, err.Error())
+	case errors.Is(err, service.ErrChannelTimeout), errors.Is(err, service.ErrNotAccepting):
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	case er.ExecType == model.ExecTypeRejected:
+		writeJSON(w, http.StatusUnprocessableEntity, er)
+	default:
+		writeJSON(w, accepted, er)
+	}
+}
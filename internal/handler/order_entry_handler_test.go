@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"MatchingEngine/internal/model"
+	"MatchingEngine/internal/service"
+)
+
+type fakeSubmitter struct {
+	requests []model.OrderRequest
+	reply    model.ExecutionReport
+	err      error
+}
+
+func (s *fakeSubmitter) Submit(ctx context.Context, req model.OrderRequest) (model.ExecutionReport, error) {
+	s.requests = append(s.requests, req)
+	return s.reply, s.err
+}
+
+func orderEntryMux(orders OrderSubmitter) *http.ServeMux {
+	h := NewOrderEntryHandler(orders, time.Second)
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/orders", h.NewOrder)
+	mux.HandleFunc("DELETE /api/v1/orders/{clOrdID}", h.CancelOrder)
+	mux.HandleFunc("PUT /api/v1/orders/{clOrdID}", h.AmendOrder)
+	return mux
+}
+
+func serve(mux http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+	return rec
+}
+
+func TestOrderEntryHandler_NewOrder(t *testing.T) {
+	orders := &fakeSubmitter{reply: model.ExecutionReport{MsgType: "8", ClOrdID: "B1", ExecType: model.ExecTypeNew}}
+	mux := orderEntryMux(orders)
+
+	rec := serve(mux, http.MethodPost, "/api/v1/orders", `{"11":"B1","54":"1","55":"BTC/USDT","38":"2","44":"100.5"}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	var er model.ExecutionReport
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &er))
+	assert.Equal(t, model.ExecTypeNew, er.ExecType)
+
+	require.Len(t, orders.requests, 1)
+	req := orders.requests[0]
+	assert.Equal(t, model.MsgTypeNew, req.MsgType)
+	assert.Equal(t, model.MsgTypeNew, req.NewOrderReq.MsgType)
+	assert.Equal(t, "100.5", req.NewOrderReq.Price.String())
+	assert.NotZero(t, req.NewOrderReq.TransactTime)
+
+	orders.reply.ExecType = model.ExecTypeRejected
+	assert.Equal(t, http.StatusUnprocessableEntity, serve(mux, http.MethodPost, "/api/v1/orders", `{"11":"B2"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(mux, http.MethodPost, "/api/v1/orders", `{"11":`).Code)
+}
+
+func TestOrderEntryHandler_CancelAndAmend(t *testing.T) {
+	orders := &fakeSubmitter{reply: model.ExecutionReport{MsgType: "8", ExecType: model.ExecTypeCanceled}}
+	mux := orderEntryMux(orders)
+
+	assert.Equal(t, http.StatusOK, serve(mux, http.MethodDelete, "/api/v1/orders/S1?symbol=BTC/USDT&cl_ord_id=C1", "").Code)
+	assert.Equal(t, http.StatusBadRequest, serve(mux, http.MethodDelete, "/api/v1/orders/S1", "").Code)
+	require.Len(t, orders.requests, 1)
+	cancel := orders.requests[0].CancelOrderReq
+	assert.Equal(t, "S1", cancel.OrigClOrdID)
+	assert.Equal(t, "C1", cancel.ClOrdID)
+	assert.Equal(t, "BTC/USDT", cancel.Symbol)
+
+	orders.reply.ExecType = model.ExecTypeReplaced
+	rec := serve(mux, http.MethodPut, "/api/v1/orders/S1", `{"11":"S1R","54":"2","55":"BTC/USDT","38":"3","44":"101"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	require.Len(t, orders.requests, 2)
+	replace := orders.requests[1]
+	assert.Equal(t, model.MsgTypeReplace, replace.MsgType)
+	require.NotNil(t, replace.ReplaceOrderReq)
+	assert.Equal(t, "S1", replace.ReplaceOrderReq.OrigClOrdID)
+	assert.Equal(t, "S1R", replace.ReplaceOrderReq.ClOrdID)
+
+	rec = serve(mux, http.MethodPut, "/api/v1/orders/S1", `{"11":"S1R","41":"OTHER"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestOrderEntryHandler_MapsServiceErrors(t *testing.T) {
+	for err, status := range map[error]int{
+		service.ErrNoReport:           http.StatusAccepted,
+		service.ErrSymbolNotSpecified: http.StatusBadRequest,
+		service.ErrSymbolNotOwned:     http.StatusMisdirectedRequest,
+		service.ErrChannelTimeout:     http.StatusServiceUnavailable,
+	} {
+		mux := orderEntryMux(&fakeSubmitter{err: err})
+		rec := serve(mux, http.MethodPost, "/api/v1/orders", `{"11":"B1","55":"BTC/USDT"}`)
+		assert.Equal(t, status, rec.Code, err.Error())
+	}
+}
@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"MatchingEngine/internal/model"
+	"MatchingEngine/internal/repository"
+	"MatchingEngine/orderBook"
+)
+
+const (
+	defaultTradeLimit = 100
+	maxTradeLimit     = 1000
+)
+
+type OpenOrderProvider interface {
+	OpenOrders(symbol string) []orderBook.Order
+	OpenOrder(clOrdID string) (orderBook.Order, bool)
+}
+
+// StoredOrders holds the state of every order as of its last report saved.
+type StoredOrders interface {
+	OrderByClOrdID(ctx context.Context, clOrdID string) (orderBook.Order, error)
+}
+
+type ExecutionHistory interface {
+	GetExecution(ctx context.Context, execID string) (model.ExecutionReport, error)
+	ListExecutionsByOrderID(ctx context.Context, orderID string) ([]model.ExecutionReport, error)
+}
+
+type RecentTrades interface {
+	ListRecentTrades(ctx context.Context, symbol string, limit int32) ([]model.TradeCaptureReport, error)
+}
+
+// OrderQueryHandler serves orders from the books while they rest and from
+// the orders table once they are done, and executions and trades from the
+// database.
+type OrderQueryHandler struct {
+	Books      OpenOrderProvider
+	Orders     StoredOrders
+	Executions ExecutionHistory
+	Trades     RecentTrades
+}
+
+func NewOrderQueryHandler(books OpenOrderProvider, orders StoredOrders, executions ExecutionHistory, trades RecentTrades) *OrderQueryHandler {
+	return &OrderQueryHandler{
+		Books:      books,
+		Orders:     orders,
+		Executions: executions,
+		Trades:     trades,
+	}
+}
+
+// ListOpenOrders returns the resting orders of the "symbol" query parameter,
+// or of every symbol when it is omitted.
+func (h *OrderQueryHandler) ListOpenOrders(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.Books.OpenOrders(r.URL.Query().Get("symbol")))
+}
+
+// GetOrder returns the order named in the path: as it rests in the book, or
+// as its last execution report saved left it.
+func (h *OrderQueryHandler) GetOrder(w http.ResponseWriter, r *http.Request) {
+	order, err := h.order(r.Context(), r.PathValue("clOrdID"))
+	if err != nil {
+		writeLookupError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, order)
+}
+
+// ListOrderExecutions returns the execution reports of the order named in the
+// path, oldest first, including those issued under earlier ClOrdIDs.
+func (h *OrderQueryHandler) ListOrderExecutions(w http.ResponseWriter, r *http.Request) {
+	order, err := h.order(r.Context(), r.PathValue("clOrdID"))
+	if err != nil {
+		writeLookupError(w, err)
+		return
+	}
+	if order.OrderID == "" {
+		// Reports without an OrderID belong to no order.
+		writeError(w, http.StatusNotFound, "order "+order.ClOrdID+" has no OrderID")
+		return
+	}
+	reports, err := h.Executions.ListExecutionsByOrderID(r.Context(), order.OrderID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, reports)
+}
+
+func (h *OrderQueryHandler) GetExecution(w http.ResponseWriter, r *http.Request) {
+	er, err := h.Executions.GetExecution(r.Context(), r.PathValue("execID"))
+	if err != nil {
+		writeLookupError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, er)
+}
+
+// ListTrades returns the last trades of the "symbol" query parameter with
+// their sides, newest first; "limit" caps how many.
+func (h *OrderQueryHandler) ListTrades(w http.ResponseWriter, r *http.Request) {
+	symbol := r.URL.Query().Get("symbol")
+	if symbol == "" {
+		writeError(w, http.StatusBadRequest, "missing symbol")
+		return
+	}
+	limit := defaultTradeLimit
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 || n > maxTradeLimit {
+			writeError(w, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(maxTradeLimit))
+			return
+		}
+		limit = n
+	}
+
+	trades, err := h.Trades.ListRecentTrades(r.Context(), symbol, int32(limit))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if trades == nil {
+		trades = []model.TradeCaptureReport{}
+	}
+	writeJSON(w, http.StatusOK, trades)
+}
+
+func (h *OrderQueryHandler) order(ctx context.Context, clOrdID string) (orderBook.Order, error) {
+	if order, ok := h.Books.OpenOrder(clOrdID); ok {
+		return order, nil
+	}
+	return h.Orders.OrderByClOrdID(ctx, clOrdID)
+}
+
+func writeLookupError(w http.ResponseWriter, err error) {
+	if errors.Is(err, repository.ErrNotFound) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	writeError(w, http.StatusInternalServerError, err.Error())
+}
@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"MatchingEngine/internal/model"
+	"MatchingEngine/internal/repository"
+	"MatchingEngine/orderBook"
+)
+
+type fakeBooks struct {
+	orders []orderBook.Order
+}
+
+func (b *fakeBooks) OpenOrders(symbol string) []orderBook.Order {
+	var out []orderBook.Order
+	for _, o := range b.orders {
+		if symbol == "" || o.Symbol == symbol {
+			out = append(out, o)
+		}
+	}
+	return out
+}
+
+func (b *fakeBooks) OpenOrder(clOrdID string) (orderBook.Order, bool) {
+	for _, o := range b.orders {
+		if o.ClOrdID == clOrdID {
+			return o, true
+		}
+	}
+	return orderBook.Order{}, false
+}
+
+type fakeHistory struct {
+	orders  []orderBook.Order
+	reports []model.ExecutionReport
+	trades  []model.TradeCaptureReport
+	limit   int32
+}
+
+func (h *fakeHistory) GetExecution(ctx context.Context, execID string) (model.ExecutionReport, error) {
+	for _, er := range h.reports {
+		if er.ExecID == execID {
+			return er, nil
+		}
+	}
+	return model.ExecutionReport{}, fmt.Errorf("execution %s: %w", execID, repository.ErrNotFound)
+}
+
+func (h *fakeHistory) OrderByClOrdID(ctx context.Context, clOrdID string) (orderBook.Order, error) {
+	for _, o := range h.orders {
+		if o.ClOrdID == clOrdID {
+			return o, nil
+		}
+	}
+	return orderBook.Order{}, fmt.Errorf("order %s: %w", clOrdID, repository.ErrNotFound)
+}
+
+func (h *fakeHistory) ListExecutionsByOrderID(ctx context.Context, orderID string) ([]model.ExecutionReport, error) {
+	var out []model.ExecutionReport
+	for _, er := range h.reports {
+		if er.OrderID == orderID {
+			out = append(out, er)
+		}
+	}
+	return out, nil
+}
+
+func (h *fakeHistory) ListRecentTrades(ctx context.Context, symbol string, limit int32) ([]model.TradeCaptureReport, error) {
+	h.limit = limit
+	return h.trades, nil
+}
+
+func orderQueryMux(books OpenOrderProvider, history *fakeHistory) *http.ServeMux {
+	h := NewOrderQueryHandler(books, history, history, history)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/orders", h.ListOpenOrders)
+	mux.HandleFunc("GET /api/v1/orders/{clOrdID}", h.GetOrder)
+	mux.HandleFunc("GET /api/v1/orders/{clOrdID}/executions", h.ListOrderExecutions)
+	mux.HandleFunc("GET /api/v1/executions/{execID}", h.GetExecution)
+	mux.HandleFunc("GET /api/v1/trades", h.ListTrades)
+	return mux
+}
+
+func TestOrderQueryHandler_Orders(t *testing.T) {
+	books := &fakeBooks{orders: []orderBook.Order{
+		{ClOrdID: "S1", OrderID: "order-1", Symbol: "BTC/USDT", Price: decimal.NewFromInt(100)},
+		{ClOrdID: "S2", OrderID: "order-2", Symbol: "ETH/USDT", Price: decimal.NewFromInt(10)},
+	}}
+	history := &fakeHistory{
+		orders: []orderBook.Order{
+			{ClOrdID: "B1", OrderID: "order-3", Symbol: "BTC/USDT", Price: decimal.NewFromInt(101), OrderStatus: model.OrderStatusFill},
+		},
+		reports: []model.ExecutionReport{
+			{ExecID: "execution-1", OrderID: "order-1", ClOrdID: "S1", ExecType: model.ExecTypeNew},
+			{ExecID: "execution-2", OrderID: "order-3", ClOrdID: "B1", ExecType: model.ExecTypeNew},
+			{ExecID: "execution-3", OrderID: "order-3", ClOrdID: "B1", ExecType: model.ExecTypeFill, OrdStatus: model.OrderStatusFill},
+			// A later cancel reject under the same ClOrdID names no order.
+			{ExecID: "execution-4", ClOrdID: "B1", ExecType: model.ExecTypeRejected, OrdStatus: model.OrderStatusRejected},
+		},
+	}
+	mux := orderQueryMux(books, history)
+
+	var orders []orderBook.Order
+	rec := serve(mux, http.MethodGet, "/api/v1/orders?symbol=BTC/USDT", "")
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &orders))
+	require.Len(t, orders, 1)
+	assert.Equal(t, "S1", orders[0].ClOrdID)
+
+	// A resting order comes from the book, a filled one from the orders table.
+	var order orderBook.Order
+	require.NoError(t, json.Unmarshal(serve(mux, http.MethodGet, "/api/v1/orders/S2", "").Body.Bytes(), &order))
+	assert.True(t, order.Price.Equal(decimal.NewFromInt(10)))
+	require.NoError(t, json.Unmarshal(serve(mux, http.MethodGet, "/api/v1/orders/B1", "").Body.Bytes(), &order))
+	assert.Equal(t, model.OrderStatusFill, order.OrderStatus)
+	assert.True(t, order.Price.Equal(decimal.NewFromInt(101)))
+	assert.Equal(t, http.StatusNotFound, serve(mux, http.MethodGet, "/api/v1/orders/NOPE", "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(mux, http.MethodGet, "/api/v1/orders/NOPE/executions", "").Code)
+
+	var reports []model.ExecutionReport
+	require.NoError(t, json.Unmarshal(serve(mux, http.MethodGet, "/api/v1/orders/B1/executions", "").Body.Bytes(), &reports))
+	assert.Len(t, reports, 2)
+
+	rec = serve(mux, http.MethodGet, "/api/v1/executions/execution-1", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, http.StatusNotFound, serve(mux, http.MethodGet, "/api/v1/executions/execution-9", "").Code)
+}
+
+func TestOrderQueryHandler_Trades(t *testing.T) {
+	history := &fakeHistory{}
+	mux := orderQueryMux(&fakeBooks{}, history)
+
+	rec := serve(mux, http.MethodGet, "/api/v1/trades?symbol=BTC/USDT", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `[]`, rec.Body.String())
+	assert.Equal(t, int32(defaultTradeLimit), history.limit)
+
+	serve(mux, http.MethodGet, "/api/v1/trades?symbol=BTC/USDT&limit=5", "")
+	assert.Equal(t, int32(5), history.limit)
+
+	assert.Equal(t, http.StatusBadRequest, serve(mux, http.MethodGet, "/api/v1/trades", "").Code)
+	assert.Equal(t, http.StatusBadRequest, serve(mux, http.MethodGet, "/api/v1/trades?symbol=X&limit=0", "").Code)
+}
@@ -7,9 +7,12 @@ import (
 
 	"github.com/shopspring/decimal"
 	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 
 	"MatchingEngine/internal/model"
+	"MatchingEngine/internal/service"
 )
 
 type mockAcknowledger struct{}
@@ -64,7 +67,7 @@ func TestHandleOrderMessage_ValidOrder(t *testing.T) {
 
 func TestHandleOrderMessage_InvalidJSON(t *testing.T) {
 	mockOrderService := new(MockOrderService)
-	h := NewOrderRequestHandler( mockOrderService)
+	h := NewOrderRequestHandler(mockOrderService)
 
 	msg := amqp.Delivery{
 		Body:         []byte("{invalid json"),
@@ -73,3 +76,130 @@ func TestHandleOrderMessage_InvalidJSON(t *testing.T) {
 
 	h.HandleOrderMessage(msg)
 }
+
+type recordingReplier struct {
+	replyTo, correlationID string
+	body                   json.RawMessage
+}
+
+func (r *recordingReplier) Reply(replyTo, correlationID string, body json.RawMessage) error {
+	r.replyTo, r.correlationID, r.body = replyTo, correlationID, body
+	return nil
+}
+
+func TestHandleOrderMessage_InvalidJSONIsAnsweredWithBusinessReject(t *testing.T) {
+	replies := &recordingReplier{}
+	h := NewOrderRequestHandler(new(MockOrderService))
+	h.Replies = replies
+	h.Reports = service.NewReportWaiter()
+
+	h.HandleOrderMessage(amqp.Delivery{
+		Body:          []byte("{invalid json"),
+		ReplyTo:       "replies",
+		CorrelationId: "req-1",
+		Acknowledger:  &mockAcknowledger{},
+	})
+
+	assert.Equal(t, "replies", replies.replyTo)
+	assert.Equal(t, "req-1", replies.correlationID)
+	var rej model.BusinessMessageReject
+	require.NoError(t, json.Unmarshal(replies.body, &rej))
+	assert.Equal(t, string(model.MsgTypeBusinessRej), rej.MsgType)
+	assert.Equal(t, model.BusinessRejectOther, rej.BusinessRejectReason)
+}
+
+type recordingAcknowledger struct {
+	acked, nacked, requeued int
+}
+
+func (a *recordingAcknowledger) Ack(uint64, bool) error {
+	a.acked++
+	return nil
+}
+
+func (a *recordingAcknowledger) Nack(_ uint64, _ bool, requeue bool) error {
+	a.nacked++
+	if requeue {
+		a.requeued++
+	}
+	return nil
+}
+
+func (a *recordingAcknowledger) Reject(uint64, bool) error { return nil }
+
+type recordingDeadLetterer struct {
+	reason  string
+	cause   error
+	retries int
+}
+
+func (d *recordingDeadLetterer) DeadLetter(_ amqp.Delivery, reason string, cause error, retries int) error {
+	d.reason, d.cause, d.retries = reason, cause, retries
+	return nil
+}
+
+func TestHandleOrderMessage_RetriesChannelTimeoutThenDeadLetters(t *testing.T) {
+	orderReq := model.OrderRequest{
+		MsgType:     model.MsgTypeNew,
+		NewOrderReq: model.NewOrderRequest{BaseOrderRequest: model.BaseOrderRequest{ClOrdID: "cl1", Symbol: "BTC/USDT"}},
+	}
+	body, _ := json.Marshal(orderReq)
+
+	// Recovers on the last retry.
+	orders := new(MockOrderService)
+	orders.On("ProcessOrderRequest", mock.Anything).Return(service.ErrChannelTimeout).Twice()
+	orders.On("ProcessOrderRequest", mock.Anything).Return(nil).Once()
+	deadLetters := &recordingDeadLetterer{}
+	h := NewOrderRequestHandler(orders)
+	h.RetryAttempts, h.RetryBackoff, h.DeadLetters = 2, time.Millisecond, deadLetters
+
+	ack := &recordingAcknowledger{}
+	h.HandleOrderMessage(amqp.Delivery{Body: body, Acknowledger: ack})
+	orders.AssertNumberOfCalls(t, "ProcessOrderRequest", 3)
+	assert.Equal(t, 1, ack.acked)
+	assert.Empty(t, deadLetters.reason)
+
+	// Gives up after the last retry.
+	orders = new(MockOrderService)
+	orders.On("ProcessOrderRequest", mock.Anything).Return(service.ErrChannelTimeout)
+	h.OrderService = orders
+	ack = &recordingAcknowledger{}
+	h.HandleOrderMessage(amqp.Delivery{Body: body, Acknowledger: ack})
+	orders.AssertNumberOfCalls(t, "ProcessOrderRequest", 3)
+	assert.Equal(t, "order processing error", deadLetters.reason)
+	assert.ErrorIs(t, deadLetters.cause, service.ErrChannelTimeout)
+	assert.Equal(t, 2, deadLetters.retries)
+	assert.Equal(t, 1, ack.acked)
+	assert.Zero(t, ack.nacked)
+
+	// Other failures are not retried, and without a dead-letter exchange the
+	// message is nacked.
+	orders = new(MockOrderService)
+	orders.On("ProcessOrderRequest", mock.Anything).Return(service.ErrSymbolNotSpecified)
+	h.OrderService, h.DeadLetters = orders, nil
+	ack = &recordingAcknowledger{}
+	h.HandleOrderMessage(amqp.Delivery{Body: body, Acknowledger: ack})
+	orders.AssertNumberOfCalls(t, "ProcessOrderRequest", 1)
+	assert.Equal(t, 1, ack.nacked)
+}
+
+func TestHandleOrderMessage_RequeuesWhenNotAccepting(t *testing.T) {
+	orderReq := model.OrderRequest{
+		MsgType:     model.MsgTypeNew,
+		NewOrderReq: model.NewOrderRequest{BaseOrderRequest: model.BaseOrderRequest{ClOrdID: "cl1", Symbol: "BTC/USDT"}},
+	}
+	body, _ := json.Marshal(orderReq)
+
+	orders := new(MockOrderService)
+	orders.On("ProcessOrderRequest", mock.Anything).Return(service.ErrNotAccepting)
+	deadLetters := &recordingDeadLetterer{}
+	h := NewOrderRequestHandler(orders)
+	h.DeadLetters = deadLetters
+
+	ack := &recordingAcknowledger{}
+	h.HandleOrderMessage(amqp.Delivery{Body: body, Acknowledger: ack})
+	orders.AssertNumberOfCalls(t, "ProcessOrderRequest", 1)
+	assert.Equal(t, 1, ack.requeued)
+	assert.Zero(t, ack.acked)
+	assert.Empty(t, deadLetters.reason)
+}
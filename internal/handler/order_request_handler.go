@@ -1,25 +1,64 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"log"
+	"time"
 
 	"github.com/streadway/amqp"
 
 	"MatchingEngine/internal/model"
+	"MatchingEngine/internal/service"
+)
+
+const (
+	defaultReplyTimeout = 5 * time.Second
+	defaultRetryBackoff = 100 * time.Millisecond
 )
 
 type OrderService interface {
 	ProcessOrderRequest(req model.OrderRequest) error
 }
 
+// Replier publishes the answer to a request that named a reply queue.
+type Replier interface {
+	Reply(replyTo, correlationID string, body json.RawMessage) error
+}
+
+// DeadLetterer keeps a message the handler gives up on, with why.
+type DeadLetterer interface {
+	DeadLetter(msg amqp.Delivery, reason string, cause error, retries int) error
+}
+
+type ReportSubmitter interface {
+	Enter(req model.OrderRequest) (*service.PendingReport, error)
+}
+
 type OrderRequestHandler struct {
-	OrderService     OrderService
+	OrderService OrderService
+
+	// With Replies and Reports set, a request carrying reply_to and
+	// correlation_id is answered with its first execution report, or with a
+	// BusinessMessageReject when the engine could not take it. ReplyTimeout
+	// bounds the wait for the report.
+	Replies      Replier
+	Reports      ReportSubmitter
+	ReplyTimeout time.Duration
+
+	// A request refused with ErrChannelTimeout is tried RetryAttempts more
+	// times, waiting RetryBackoff and then twice as long each time, before
+	// it fails. A failed message goes to DeadLetters when set, and is nacked
+	// without requeue otherwise.
+	RetryAttempts int
+	RetryBackoff  time.Duration
+	DeadLetters   DeadLetterer
 }
 
 func NewOrderRequestHandler(orderService OrderService) *OrderRequestHandler {
 	return &OrderRequestHandler{
-		OrderService:     orderService,
+		OrderService: orderService,
 	}
 }
 
@@ -27,16 +66,24 @@ func (h *OrderRequestHandler) HandleOrderMessage(msg amqp.Delivery) {
 	var req model.OrderRequest
 	if err := json.Unmarshal(msg.Body, &req); err != nil {
 		log.Printf("failed to decode order request: %v | message: %s", err, string(msg.Body))
-		h.handleFailure(msg, "invalid JSON format")
+		if h.wantsReply(msg) {
+			h.reply(msg, businessReject(req, model.BusinessRejectOther, "invalid JSON format: "+err.Error()))
+		}
+		h.handleFailure(msg, "invalid JSON format", err, 0)
 		return
 	}
 
 	log.Printf("Received order request: %+v", req)
 
-	err := h.OrderService.ProcessOrderRequest(req)
+	if h.wantsReply(msg) {
+		h.enter(msg, req)
+		return
+	}
+
+	retries, err := h.retry(func() error { return h.OrderService.ProcessOrderRequest(req) })
 	if err != nil {
 		log.Printf("failed to process order request: %v | message: %s", err, string(msg.Body))
-		h.handleFailure(msg, "order processing error")
+		h.handleFailure(msg, "order processing error", err, retries)
 		return
 	}
 
@@ -46,7 +93,118 @@ func (h *OrderRequestHandler) HandleOrderMessage(msg amqp.Delivery) {
 	}
 }
 
-func (h *OrderRequestHandler) handleFailure(msg amqp.Delivery, reason string) {
+func (h *OrderRequestHandler) wantsReply(msg amqp.Delivery) bool {
+	return h.Replies != nil && h.Reports != nil && msg.ReplyTo != "" && msg.CorrelationId != ""
+}
+
+// enter hands the request over in delivery order, then waits for its report
+// off the consumer goroutine.
+func (h *OrderRequestHandler) enter(msg amqp.Delivery, req model.OrderRequest) {
+	var pending *service.PendingReport
+	retries, err := h.retry(func() (err error) {
+		pending, err = h.Reports.Enter(req)
+		return err
+	})
+	if err != nil {
+		log.Printf("failed to process order request: %v | message: %s", err, string(msg.Body))
+		if !errors.Is(err, service.ErrNotAccepting) {
+			h.reply(msg, businessReject(req, rejectReason(err), err.Error()))
+		}
+		h.handleFailure(msg, "order processing error", err, retries)
+		return
+	}
+	if err := msg.Ack(false); err != nil {
+		log.Printf("failed to acknowledge message: %v", err)
+	}
+
+	go func() {
+		timeout := h.ReplyTimeout
+		if timeout <= 0 {
+			timeout = defaultReplyTimeout
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		er, err := pending.Wait(ctx)
+		if err != nil {
+			h.reply(msg, businessReject(req, model.BusinessRejectApplicationNotAvailable,
+				"no execution report within "+timeout.String()+", the request may still be processed"))
+			return
+		}
+		h.reply(msg, er)
+	}()
+}
+
+func (h *OrderRequestHandler) reply(msg amqp.Delivery, v any) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("failed to encode reply to %s: %v", msg.CorrelationId, err)
+		return
+	}
+	if err := h.Replies.Reply(msg.ReplyTo, msg.CorrelationId, body); err != nil {
+		log.Printf("failed to reply to %s on %s: %v", msg.CorrelationId, msg.ReplyTo, err)
+	}
+}
+
+func businessReject(req model.OrderRequest, reason model.BusinessRejectReason, text string) model.BusinessMessageReject {
+	return model.BusinessMessageReject{
+		MsgType:              string(model.MsgTypeBusinessRej),
+		RefMsgType:           string(req.MsgType),
+		BusinessRejectRefID:  req.ClOrdID(),
+		BusinessRejectReason: reason,
+		Text:                 text,
+		TransactTime:         time.Now().UnixNano(),
+	}
+}
+
+func rejectReason(err error) model.BusinessRejectReason {
+	switch {
+	case errors.Is(err, service.ErrSymbolNotSpecified):
+		return model.BusinessRejectMissingField
+	case errors.Is(err, service.ErrChannelTimeout), errors.Is(err, service.ErrSymbolNotOwned), errors.Is(err, service.ErrNotAccepting):
+		return model.BusinessRejectApplicationNotAvailable
+	}
+	return model.BusinessRejectOther
+}
+
+// retry runs process until it succeeds, fails for good, or runs out of
+// retries, and returns the retries it took.
+func (h *OrderRequestHandler) retry(process func() error) (int, error) {
+	backoff := h.RetryBackoff
+	if backoff <= 0 {
+		backoff = defaultRetryBackoff
+	}
+	err := process()
+	retries := 0
+	for ; errors.Is(err, service.ErrChannelTimeout) && retries < h.RetryAttempts; retries++ {
+		log.Printf("retrying order request in %s: %v", backoff, err)
+		time.Sleep(backoff)
+		backoff *= 2
+		err = process()
+	}
+	return retries, err
+}
+
+func (h *OrderRequestHandler) handleFailure(msg amqp.Delivery, reason string, cause error, retries int) {
+	if errors.Is(cause, service.ErrNotAccepting) {
+		// The engine taking over handles it.
+		log.Printf("requeueing message the engine is not accepting: %s", string(msg.Body))
+		if err := msg.Nack(false, true); err != nil {
+			log.Printf("failed to negatively acknowledge message: %v", err)
+		}
+		return
+	}
+	if h.DeadLetters != nil {
+		err := h.DeadLetters.DeadLetter(msg, reason, cause, retries)
+		if err == nil {
+			log.Printf("dead-lettered message due to: %s | body: %s", reason, string(msg.Body))
+			if err := msg.Ack(false); err != nil {
+				log.Printf("failed to acknowledge dead-lettered message: %v", err)
+			}
+			return
+		}
+		log.Printf("failed to dead-letter message: %v", err)
+	}
 	log.Printf("nacking message due to: %s | body: %s", reason, string(msg.Body))
 	if err := msg.Nack(false, false); err != nil {
 		log.Printf("failed to negatively acknowledge message: %v", err)
@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"net/http"
+)
+
+type ShardManager interface {
+	Release(symbol, to string) error
+	Acquire(symbol string) error
+}
+
+// ShardHandler exposes the symbol handoff between shards. A move is a release
+// on the old shard followed by an acquire on the new one.
+type ShardHandler struct {
+	Shards ShardManager
+}
+
+func NewShardHandler(shards ShardManager) *ShardHandler {
+	return &ShardHandler{
+		Shards: shards,
+	}
+}
+
+// Release hands the "symbol" query parameter over to the shard named by "to".
+func (h *ShardHandler) Release(w http.ResponseWriter, r *http.Request) {
+	symbol := r.URL.Query().Get("symbol")
+	to := r.URL.Query().Get("to")
+	if symbol == "" || to == "" {
+		writeError(w, http.StatusBadRequest, "missing symbol or target shard")
+		return
+	}
+	if err := h.Shards.Release(symbol, to); err != nil {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"symbol": symbol, "shard": to})
+}
+
+// Acquire takes over the "symbol" query parameter once it has been released.
+func (h *ShardHandler) Acquire(w http.ResponseWriter, r *http.Request) {
+	symbol := r.URL.Query().Get("symbol")
+	if symbol == "" {
+		writeError(w, http.StatusBadRequest, "missing symbol")
+		return
+	}
+	if err := h.Shards.Acquire(symbol); err != nil {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"symbol": symbol})
+}
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"MatchingEngine/internal/model"
+)
+
+type StatisticsProvider interface {
+	GetStatistics(symbol string) (model.TradeStatistics, bool)
+	ListStatistics() []model.TradeStatistics
+}
+
+type StatsHandler struct {
+	Stats StatisticsProvider
+}
+
+func NewStatsHandler(stats StatisticsProvider) *StatsHandler {
+	return &StatsHandler{
+		Stats: stats,
+	}
+}
+
+// ServeHTTP returns the rolling statistics of the symbol given by the "symbol"
+// query parameter, or of every symbol when it is omitted.
+func (h *StatsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	symbol := r.URL.Query().Get("symbol")
+	if symbol == "" {
+		writeJSON(w, http.StatusOK, h.Stats.ListStatistics())
+		return
+	}
+
+	stats, ok := h.Stats.GetStatistics(symbol)
+	if !ok {
+		writeError(w, http.StatusNotFound, "no trades for symbol "+symbol)
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("failed to write response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,225 @@
+// Package idgen generates the OrderID, ExecID and TradeReportID values of the
+// engine. IDs are "<prefix>-<instance>-<counter>", where the counter is
+// monotonic for the instance and survives restarts, so two engines with
+// different instance IDs never collide and one engine never repeats itself.
+package idgen
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Layout selects the shape of the counter part of an ID.
+type Layout string
+
+const (
+	// Sequential IDs look like execution-me1-000000000042.
+	Sequential Layout = "sequential"
+	// TimeSortable IDs carry the issue time in epoch ms ahead of the counter,
+	// execution-me1-1729811234567-000000000042, so they sort by time across
+	// instances.
+	TimeSortable Layout = "time_sortable"
+
+	// DefaultBlockSize is how many IDs are reserved per write of the state file.
+	DefaultBlockSize = 1000
+
+	// A block that cannot be reserved is retried after reserveRetryMin,
+	// doubling up to reserveRetryMax.
+	reserveRetryMin = 10 * time.Millisecond
+	reserveRetryMax = time.Second
+)
+
+var ErrInvalidInstance = errors.New("instance ID must be non-empty and must not contain '-'")
+
+type Opts struct {
+	Instance  string
+	Layout    Layout
+	StatePath string // File holding the reserved counter; empty keeps it in memory
+	BlockSize uint64
+	Now       func() time.Time
+}
+
+// Generator hands out IDs from a counter reserved in blocks: the end of the
+// current block is fsynced to StatePath before any ID in it is issued, so a
+// restart resumes after every ID that may have been used. It is safe for
+// concurrent use.
+type Generator struct {
+	instance  string
+	layout    Layout
+	statePath string
+	blockSize uint64
+	now       func() time.Time
+
+	mu     sync.Mutex
+	next   uint64
+	limit  uint64
+	lastMs int64
+}
+
+func New(opts Opts) (*Generator, error) {
+	if opts.Instance == "" || strings.Contains(opts.Instance, "-") {
+		return nil, ErrInvalidInstance
+	}
+	switch opts.Layout {
+	case "":
+		opts.Layout = Sequential
+	case Sequential, TimeSortable:
+	default:
+		return nil, fmt.Errorf("unknown ID layout %q", opts.Layout)
+	}
+	if opts.BlockSize == 0 {
+		opts.BlockSize = DefaultBlockSize
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+
+	g := &Generator{
+		instance:  opts.Instance,
+		layout:    opts.Layout,
+		statePath: opts.StatePath,
+		blockSize: opts.BlockSize,
+		now:       opts.Now,
+		next:      1,
+	}
+	if g.statePath != "" {
+		reserved, err := readState(g.statePath)
+		if err != nil {
+			return nil, err
+		}
+		if reserved > 0 {
+			g.next = reserved
+		}
+	}
+	g.limit = g.next
+	if err := g.reserve(); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// NewEphemeral returns an in-memory generator with a random instance ID, for
+// books that were not given one.
+func NewEphemeral() *Generator {
+	var b [4]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(fmt.Sprintf("idgen: cannot read random instance ID: %v", err))
+	}
+	g, err := New(Opts{Instance: hex.EncodeToString(b[:])})
+	if err != nil {
+		panic(err)
+	}
+	return g
+}
+
+func (g *Generator) Instance() string {
+	return g.instance
+}
+
+// NextID issues the next ID. When the next block cannot be reserved it waits,
+// retrying, until it is: an ID past the persisted limit would be issued again
+// after a crash.
+func (g *Generator) NextID(prefix string) string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for backoff := reserveRetryMin; g.next >= g.limit; backoff = min(2*backoff, reserveRetryMax) {
+		if err := g.reserve(); err != nil {
+			log.Printf("Error reserving ID block for instance %s, retrying in %s: %v", g.instance, backoff, err)
+			time.Sleep(backoff)
+		}
+	}
+	counter := g.next
+	g.next++
+
+	if g.layout == TimeSortable {
+		ms := g.now().UnixMilli()
+		if ms < g.lastMs {
+			ms = g.lastMs
+		}
+		g.lastMs = ms
+		return fmt.Sprintf("%s-%s-%013d-%012d", prefix, g.instance, ms, counter)
+	}
+	return fmt.Sprintf("%s-%s-%012d", prefix, g.instance, counter)
+}
+
+// CommandID derives the n-th ID of the journaled command seq stamped at ts.
+// It uses no generator state, so replaying a journal reproduces the IDs.
+func (g *Generator) CommandID(prefix string, seq uint64, ts int64, n int) string {
+	if g.layout == TimeSortable {
+		return fmt.Sprintf("%s-%s-%013d-%012d.%d", prefix, g.instance, time.Unix(0, ts).UnixMilli(), seq, n)
+	}
+	return fmt.Sprintf("%s-%s-%012d.%d", prefix, g.instance, seq, n)
+}
+
+// reserve persists the end of the next block before it is used. Must be
+// called with g.mu held, or before g is shared.
+func (g *Generator) reserve() error {
+	limit := g.limit + g.blockSize
+	if g.statePath != "" {
+		if err := writeState(g.statePath, limit); err != nil {
+			return err
+		}
+	}
+	g.limit = limit
+	return nil
+}
+
+func readState(path string) (uint64, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read ID state: %w", err)
+	}
+	reserved, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid ID state in %s: %w", path, err)
+	}
+	return reserved, nil
+}
+
+// writeState replaces the state file through a synced temporary file.
+func writeState(path string, reserved uint64) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create ID state directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create ID state file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.WriteString(strconv.FormatUint(reserved, 10) + "\n"); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write ID state: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync ID state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close ID state: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace ID state: %w", err)
+	}
+
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("failed to open ID state directory: %w", err)
+	}
+	defer d.Close()
+	return d.Sync()
+}
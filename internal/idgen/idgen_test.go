@@ -0,0 +1,130 @@
+package idgen
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerator_Sequential(t *testing.T) {
+	g, err := New(Opts{Instance: "me1"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "execution-me1-000000000001", g.NextID("execution"))
+	assert.Equal(t, "order-me1-000000000002", g.NextID("order"))
+}
+
+func TestGenerator_InvalidOpts(t *testing.T) {
+	_, err := New(Opts{})
+	assert.ErrorIs(t, err, ErrInvalidInstance)
+	_, err = New(Opts{Instance: "me-1"})
+	assert.ErrorIs(t, err, ErrInvalidInstance)
+	_, err = New(Opts{Instance: "me1", Layout: "random"})
+	assert.Error(t, err)
+}
+
+func TestGenerator_ResumesAfterRestart(t *testing.T) {
+	state := filepath.Join(t.TempDir(), "ids", "counter")
+	g, err := New(Opts{Instance: "me1", StatePath: state, BlockSize: 3})
+	require.NoError(t, err)
+
+	seen := make(map[string]bool)
+	for i := 0; i < 5; i++ {
+		seen[g.NextID("execution")] = true
+	}
+
+	// A crash loses the rest of the reserved block, never reuses it.
+	g, err = New(Opts{Instance: "me1", StatePath: state, BlockSize: 3})
+	require.NoError(t, err)
+	next := g.NextID("execution")
+	assert.False(t, seen[next])
+	assert.Equal(t, "execution-me1-000000000007", next)
+}
+
+func TestGenerator_WaitsForTheReservation(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "ids")
+	state := filepath.Join(dir, "counter")
+	g, err := New(Opts{Instance: "me1", StatePath: state, BlockSize: 2})
+	require.NoError(t, err)
+	g.NextID("execution")
+	g.NextID("execution")
+
+	// The state directory cannot be created while a file is in its way.
+	require.NoError(t, os.RemoveAll(dir))
+	require.NoError(t, os.WriteFile(dir, nil, 0o644))
+	issued := make(chan string)
+	go func() { issued <- g.NextID("execution") }()
+	select {
+	case id := <-issued:
+		t.Fatalf("issued %s without reserving it", id)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	require.NoError(t, os.Remove(dir))
+	select {
+	case id := <-issued:
+		assert.Equal(t, "execution-me1-000000000003", id)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no ID once the reservation could be written")
+	}
+	reserved, err := readState(state)
+	require.NoError(t, err)
+	assert.Greater(t, reserved, uint64(3))
+}
+
+func TestGenerator_TimeSortable(t *testing.T) {
+	now := time.UnixMilli(1729811234567)
+	g, err := New(Opts{Instance: "me1", Layout: TimeSortable, Now: func() time.Time { return now }})
+	require.NoError(t, err)
+
+	first := g.NextID("execution")
+	assert.Equal(t, "execution-me1-1729811234567-000000000001", first)
+
+	now = now.Add(-time.Second) // Wall clock stepped back
+	second := g.NextID("execution")
+	assert.Equal(t, "execution-me1-1729811234567-000000000002", second)
+	assert.Less(t, first, second)
+}
+
+func TestGenerator_CommandID(t *testing.T) {
+	g, err := New(Opts{Instance: "me1"})
+	require.NoError(t, err)
+	assert.Equal(t, "execution-me1-000000000042.3", g.CommandID("execution", 42, 0, 3))
+
+	g, err = New(Opts{Instance: "me1", Layout: TimeSortable})
+	require.NoError(t, err)
+	ts := time.UnixMilli(1729811234567).UnixNano()
+	assert.Equal(t, "execution-me1-1729811234567-000000000042.3", g.CommandID("execution", 42, ts, 3))
+}
+
+func TestGenerator_ConcurrentUnique(t *testing.T) {
+	g, err := New(Opts{Instance: "me1", StatePath: filepath.Join(t.TempDir(), "counter"), BlockSize: 10})
+	require.NoError(t, err)
+
+	const workers, perWorker = 16, 500
+	ids := make(chan string, workers*perWorker)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				ids <- g.NextID("execution")
+			}
+		}()
+	}
+	wg.Wait()
+	close(ids)
+
+	seen := make(map[string]bool, workers*perWorker)
+	for id := range ids {
+		assert.False(t, seen[id], "duplicate ID %s", id)
+		seen[id] = true
+	}
+	assert.Len(t, seen, workers*perWorker)
+}
@@ -0,0 +1,50 @@
+package journal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+)
+
+// DirSource reads the journal directory of another engine, e.g. on a shared
+// volume. It stands in for the replicated journal topic.
+type DirSource struct {
+	dir string
+}
+
+func NewDirSource(dir string) *DirSource {
+	return &DirSource{dir: dir}
+}
+
+// ReadFrom calls fn for every entry after seq that is in the journal now. A
+// record still being written at the end of the newest segment is left for
+// the next call.
+func (s *DirSource) ReadFrom(ctx context.Context, after uint64, fn func(Entry) error) error {
+	segments, err := listSegments(s.dir)
+	if err != nil {
+		return err
+	}
+
+	for i, segment := range segments {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if i+1 < len(segments) && segments[i+1].first <= after+1 {
+			continue // Every entry of this segment is at or below after
+		}
+		_, _, err := scanSegment(filepath.Join(s.dir, segment.name), segment.first, func(e Entry) error {
+			if e.Seq <= after {
+				return nil
+			}
+			return fn(e)
+		})
+		if errors.Is(err, ErrCorrupt) && i == len(segments)-1 {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read journal segment %s: %w", segment.name, err)
+		}
+	}
+	return nil
+}
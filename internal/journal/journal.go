@@ -0,0 +1,391 @@
+package journal
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"MatchingEngine/internal/model"
+)
+
+const (
+	segmentSuffix = ".wal"
+	headerSize    = 8 // uint32 payload length + uint32 CRC32-C of the payload
+
+	// DefaultSegmentBytes is the size after which a segment is rotated.
+	DefaultSegmentBytes = 64 << 20
+	maxRecordBytes      = 16 << 20
+)
+
+var (
+	ErrCorrupt = errors.New("journal record is corrupt")
+	ErrClosed  = errors.New("journal is closed")
+
+	crcTable = crc32.MakeTable(crc32.Castagnoli)
+)
+
+// Entry is one journaled request.
+type Entry struct {
+	Seq       uint64             `json:"seq"`
+	Timestamp int64              `json:"ts"` // Epoch ns, engine time
+	Request   model.OrderRequest `json:"request"`
+}
+
+// Sink receives every entry once it is on disk, in sequence order, e.g. to
+// replicate the journal to a standby.
+type Sink interface {
+	Publish(entry Entry) error
+}
+
+type Opts struct {
+	SegmentBytes int64
+	Now          func() time.Time
+	Sink         Sink
+}
+
+// Journal is an append-only log of every request accepted by the engine's
+// books. Records are written to segment files named after the first sequence
+// they hold; every append is fsynced before it returns. It is safe for
+// concurrent use, all books share one sequence.
+type Journal struct {
+	dir          string
+	segmentBytes int64
+	now          func() time.Time
+	sink         Sink
+
+	mu   sync.Mutex
+	seq  uint64
+	file *os.File
+	size int64
+}
+
+// Open recovers the last sequence from the newest segment, truncating a
+// record torn by a crash, and opens it for appending.
+func Open(dir string, opts Opts) (*Journal, error) {
+	if opts.SegmentBytes <= 0 {
+		opts.SegmentBytes = DefaultSegmentBytes
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create journal directory: %w", err)
+	}
+
+	j := &Journal{dir: dir, segmentBytes: opts.SegmentBytes, now: opts.Now, sink: opts.Sink}
+
+	segments, err := listSegments(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(segments) == 0 {
+		return j, nil
+	}
+
+	last := segments[len(segments)-1]
+	path := filepath.Join(dir, last.name)
+	seq, valid, err := scanSegment(path, last.first, nil)
+	if err != nil && !errors.Is(err, ErrCorrupt) {
+		return nil, err
+	}
+	if err != nil {
+		log.Printf("Truncating torn journal record in %s at offset %d: %v", last.name, valid, err)
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal segment: %w", err)
+	}
+	if err := f.Truncate(valid); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to truncate journal segment: %w", err)
+	}
+	if _, err := f.Seek(valid, io.SeekStart); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to seek journal segment: %w", err)
+	}
+
+	j.file = f
+	j.size = valid
+	j.seq = seq
+	if seq == 0 {
+		j.seq = last.first - 1
+	}
+	return j, nil
+}
+
+// Append stamps req with the next sequence number and the current time and
+// returns once the record is on disk.
+func (j *Journal) Append(req model.OrderRequest) (uint64, int64, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	entry := Entry{Seq: j.seq + 1, Timestamp: j.now().UnixNano(), Request: req}
+	if err := j.write(entry); err != nil {
+		return 0, 0, err
+	}
+	return entry.Seq, entry.Timestamp, nil
+}
+
+// Write records an entry sequenced elsewhere, e.g. by the primary a standby
+// follows. It must be the next entry of the journal; an empty journal
+// accepts any sequence.
+func (j *Journal) Write(entry Entry) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.seq > 0 && entry.Seq != j.seq+1 {
+		return fmt.Errorf("journal entry %d does not follow %d", entry.Seq, j.seq)
+	}
+	return j.write(entry)
+}
+
+// AdvanceTo moves the sequence of an empty journal up to seq, so entries
+// appended after restoring books from snapshots are not ignored as already
+// applied.
+func (j *Journal) AdvanceTo(seq uint64) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.seq == 0 {
+		j.seq = seq
+	}
+}
+
+// write must be called with j.mu held.
+func (j *Journal) write(entry Entry) error {
+	if j.dir == "" {
+		return ErrClosed
+	}
+	record, err := encodeRecord(entry)
+	if err != nil {
+		return err
+	}
+
+	if j.file == nil || (j.size > 0 && j.size+int64(len(record)) > j.segmentBytes) {
+		if err := j.rotate(entry.Seq); err != nil {
+			return err
+		}
+	}
+
+	if _, err := j.file.Write(record); err != nil {
+		return fmt.Errorf("failed to write journal record %d: %w", entry.Seq, err)
+	}
+	if err := j.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync journal record %d: %w", entry.Seq, err)
+	}
+
+	j.size += int64(len(record))
+	j.seq = entry.Seq
+
+	// The entry is durable and will be processed, so a replication failure
+	// must not fail the append; the follower detects the gap.
+	if j.sink != nil {
+		if err := j.sink.Publish(entry); err != nil {
+			log.Printf("Error replicating journal entry %d: %v", entry.Seq, err)
+		}
+	}
+	return nil
+}
+
+// LastSeq returns the sequence of the last record written.
+func (j *Journal) LastSeq() uint64 {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.seq
+}
+
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.dir = ""
+	if j.file == nil {
+		return nil
+	}
+	err := j.file.Close()
+	j.file = nil
+	return err
+}
+
+// rotate closes the current segment and starts a new one whose first record
+// is firstSeq.
+func (j *Journal) rotate(firstSeq uint64) error {
+	if j.file != nil {
+		if err := j.file.Sync(); err != nil {
+			return fmt.Errorf("failed to sync journal segment: %w", err)
+		}
+		if err := j.file.Close(); err != nil {
+			return fmt.Errorf("failed to close journal segment: %w", err)
+		}
+		j.file = nil
+	}
+
+	path := filepath.Join(j.dir, segmentName(firstSeq))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create journal segment: %w", err)
+	}
+	if err := syncDir(j.dir); err != nil {
+		f.Close()
+		return err
+	}
+	j.file = f
+	j.size = 0
+	return nil
+}
+
+// Read calls fn for every entry in the journal in sequence order. A torn
+// record at the end of the newest segment is treated as the end of the
+// journal; corruption anywhere else is an error.
+func Read(dir string, fn func(Entry) error) error {
+	segments, err := listSegments(dir)
+	if err != nil {
+		return err
+	}
+
+	var next uint64
+	for i, segment := range segments {
+		if i > 0 && segment.first != next {
+			return fmt.Errorf("journal segment %s starts at %d, expected %d", segment.name, segment.first, next)
+		}
+		last, _, err := scanSegment(filepath.Join(dir, segment.name), segment.first, fn)
+		if err != nil && (!errors.Is(err, ErrCorrupt) || i != len(segments)-1) {
+			return fmt.Errorf("failed to read journal segment %s: %w", segment.name, err)
+		}
+		next = segment.first
+		if last > 0 {
+			next = last + 1
+		}
+	}
+	return nil
+}
+
+// scanSegment reads the records of one segment, checking that they are
+// numbered from first onwards. It returns the last valid sequence (0 for an
+// empty segment) and the offset just past the last valid record.
+func scanSegment(path string, first uint64, fn func(Entry) error) (uint64, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to open journal segment: %w", err)
+	}
+	defer f.Close()
+
+	var (
+		last   uint64
+		offset int64
+		header [headerSize]byte
+	)
+	for {
+		if _, err := io.ReadFull(f, header[:]); err != nil {
+			if errors.Is(err, io.EOF) {
+				return last, offset, nil
+			}
+			return last, offset, fmt.Errorf("%w: short header at offset %d", ErrCorrupt, offset)
+		}
+		length := binary.BigEndian.Uint32(header[0:4])
+		if length == 0 || length > maxRecordBytes {
+			return last, offset, fmt.Errorf("%w: invalid length %d at offset %d", ErrCorrupt, length, offset)
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(f, payload); err != nil {
+			return last, offset, fmt.Errorf("%w: short payload at offset %d", ErrCorrupt, offset)
+		}
+		if crc32.Checksum(payload, crcTable) != binary.BigEndian.Uint32(header[4:8]) {
+			return last, offset, fmt.Errorf("%w: checksum mismatch at offset %d", ErrCorrupt, offset)
+		}
+
+		var entry Entry
+		if err := json.Unmarshal(payload, &entry); err != nil {
+			return last, offset, fmt.Errorf("%w: %v at offset %d", ErrCorrupt, err, offset)
+		}
+		want := first
+		if last > 0 {
+			want = last + 1
+		}
+		if entry.Seq != want {
+			return last, offset, fmt.Errorf("journal record at offset %d has sequence %d, expected %d", offset, entry.Seq, want)
+		}
+
+		if fn != nil {
+			if err := fn(entry); err != nil {
+				return last, offset, err
+			}
+		}
+		last = entry.Seq
+		offset += headerSize + int64(length)
+	}
+}
+
+func encodeRecord(entry Entry) ([]byte, error) {
+	payload, err := json.Marshal(entry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal journal entry %d: %w", entry.Seq, err)
+	}
+	if len(payload) > maxRecordBytes {
+		return nil, fmt.Errorf("journal entry %d is %d bytes, over the %d limit", entry.Seq, len(payload), maxRecordBytes)
+	}
+	record := make([]byte, headerSize+len(payload))
+	binary.BigEndian.PutUint32(record[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(record[4:8], crc32.Checksum(payload, crcTable))
+	copy(record[headerSize:], payload)
+	return record, nil
+}
+
+type segment struct {
+	name  string
+	first uint64
+}
+
+func segmentName(firstSeq uint64) string {
+	return fmt.Sprintf("%020d%s", firstSeq, segmentSuffix)
+}
+
+// listSegments returns the segments of dir ordered by first sequence. A
+// missing directory is an empty journal.
+func listSegments(dir string) ([]segment, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read journal directory: %w", err)
+	}
+
+	var segments []segment
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, segmentSuffix) {
+			continue
+		}
+		first, err := strconv.ParseUint(strings.TrimSuffix(name, segmentSuffix), 10, 64)
+		if err != nil || first == 0 {
+			return nil, fmt.Errorf("unexpected journal segment name %s", name)
+		}
+		segments = append(segments, segment{name: name, first: first})
+	}
+	sort.Slice(segments, func(i, k int) bool { return segments[i].first < segments[k].first })
+	return segments, nil
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("failed to open journal directory: %w", err)
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("failed to sync journal directory: %w", err)
+	}
+	return nil
+}
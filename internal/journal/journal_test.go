@@ -0,0 +1,224 @@
+package journal
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"MatchingEngine/internal/model"
+)
+
+func newOrder(clOrdID string, side model.Side, px, qty int64) model.OrderRequest {
+	return model.OrderRequest{
+		MsgType: model.MsgTypeNew,
+		NewOrderReq: model.NewOrderRequest{
+			BaseOrderRequest: model.BaseOrderRequest{
+				MsgType:      model.MsgTypeNew,
+				ClOrdID:      clOrdID,
+				Side:         side,
+				Symbol:       "BTC/USDT",
+				TransactTime: 1729811234567890,
+			},
+			OrderQty: decimal.NewFromInt(qty),
+			Price:    decimal.NewFromInt(px),
+		},
+	}
+}
+
+func cancelOrder(origClOrdID string) model.OrderRequest {
+	return model.OrderRequest{
+		MsgType: model.MsgTypeCancel,
+		CancelOrderReq: model.OrderCancelRequest{
+			BaseOrderRequest: model.BaseOrderRequest{
+				MsgType: model.MsgTypeCancel,
+				ClOrdID: "cancel-" + origClOrdID,
+				Symbol:  "BTC/USDT",
+			},
+			OrigClOrdID: origClOrdID,
+		},
+	}
+}
+
+func testClock() func() time.Time {
+	ts := time.Unix(1729811234, 0)
+	return func() time.Time {
+		ts = ts.Add(time.Millisecond)
+		return ts
+	}
+}
+
+func readAll(t *testing.T, dir string) []Entry {
+	var entries []Entry
+	require.NoError(t, Read(dir, func(e Entry) error {
+		entries = append(entries, e)
+		return nil
+	}))
+	return entries
+}
+
+func TestJournal_AppendAndRead(t *testing.T) {
+	dir := t.TempDir()
+	j, err := Open(dir, Opts{Now: testClock()})
+	require.NoError(t, err)
+
+	seq, ts, err := j.Append(newOrder("B1", model.Buy, 100, 1))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), seq)
+	assert.Equal(t, time.Unix(1729811234, 0).Add(time.Millisecond).UnixNano(), ts)
+
+	seq, _, err = j.Append(cancelOrder("B1"))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), seq)
+	require.NoError(t, j.Close())
+
+	entries := readAll(t, dir)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "B1", entries[0].Request.NewOrderReq.ClOrdID)
+	assert.True(t, entries[0].Request.NewOrderReq.Price.Equal(decimal.NewFromInt(100)))
+	assert.Equal(t, "B1", entries[1].Request.CancelOrderReq.OrigClOrdID)
+
+	_, _, err = j.Append(cancelOrder("B1"))
+	assert.ErrorIs(t, err, ErrClosed)
+}
+
+func TestJournal_RotatesAndResumesSequence(t *testing.T) {
+	dir := t.TempDir()
+	j, err := Open(dir, Opts{SegmentBytes: 300})
+	require.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		_, _, err := j.Append(newOrder("B", model.Buy, 100, 1))
+		require.NoError(t, err)
+	}
+	require.NoError(t, j.Close())
+
+	segments, err := listSegments(dir)
+	require.NoError(t, err)
+	assert.Greater(t, len(segments), 1)
+	assert.Equal(t, uint64(1), segments[0].first)
+
+	j, err = Open(dir, Opts{SegmentBytes: 300})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(5), j.LastSeq())
+	seq, _, err := j.Append(newOrder("B", model.Buy, 100, 1))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(6), seq)
+	require.NoError(t, j.Close())
+
+	entries := readAll(t, dir)
+	require.Len(t, entries, 6)
+	for i, e := range entries {
+		assert.Equal(t, uint64(i+1), e.Seq)
+	}
+}
+
+func TestJournal_TruncatesTornTail(t *testing.T) {
+	dir := t.TempDir()
+	j, err := Open(dir, Opts{})
+	require.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		_, _, err := j.Append(newOrder("B", model.Buy, 100, 1))
+		require.NoError(t, err)
+	}
+	require.NoError(t, j.Close())
+
+	path := filepath.Join(dir, segmentName(1))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.NoError(t, os.Truncate(path, info.Size()-5))
+
+	assert.Len(t, readAll(t, dir), 1)
+
+	j, err = Open(dir, Opts{})
+	require.NoError(t, err)
+	seq, _, err := j.Append(newOrder("B", model.Buy, 100, 1))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), seq)
+	require.NoError(t, j.Close())
+	assert.Len(t, readAll(t, dir), 2)
+}
+
+func TestJournal_RejectsCorruptClosedSegment(t *testing.T) {
+	dir := t.TempDir()
+	j, err := Open(dir, Opts{SegmentBytes: 300})
+	require.NoError(t, err)
+	for i := 0; i < 4; i++ {
+		_, _, err := j.Append(newOrder("B", model.Buy, 100, 1))
+		require.NoError(t, err)
+	}
+	require.NoError(t, j.Close())
+
+	path := filepath.Join(dir, segmentName(1))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	data[headerSize+10] ^= 0xff
+	require.NoError(t, os.WriteFile(path, data, 0o644))
+
+	err = Read(dir, func(Entry) error { return nil })
+	assert.ErrorIs(t, err, ErrCorrupt)
+}
+
+type recordingSink struct {
+	entries []Entry
+}
+
+func (s *recordingSink) Publish(entry Entry) error {
+	s.entries = append(s.entries, entry)
+	return nil
+}
+
+func TestJournal_WriteFollowsForeignSequence(t *testing.T) {
+	sink := &recordingSink{}
+	j, err := Open(t.TempDir(), Opts{Sink: sink})
+	require.NoError(t, err)
+	defer j.Close()
+
+	require.NoError(t, j.Write(Entry{Seq: 41, Timestamp: 1, Request: newOrder("B1", model.Buy, 100, 1)}))
+	assert.Error(t, j.Write(Entry{Seq: 43, Request: newOrder("B2", model.Buy, 100, 1)}))
+
+	seq, _, err := j.Append(newOrder("B3", model.Buy, 100, 1))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(42), seq)
+
+	require.Len(t, sink.entries, 2)
+	assert.Equal(t, uint64(41), sink.entries[0].Seq)
+	assert.Equal(t, uint64(42), sink.entries[1].Seq)
+}
+
+func TestJournal_AdvanceToOnlyMovesEmptyJournal(t *testing.T) {
+	j, err := Open(t.TempDir(), Opts{})
+	require.NoError(t, err)
+	defer j.Close()
+
+	j.AdvanceTo(10)
+	seq, _, err := j.Append(newOrder("B1", model.Buy, 100, 1))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(11), seq)
+
+	j.AdvanceTo(20)
+	assert.Equal(t, uint64(11), j.LastSeq())
+}
+
+func TestDirSource_ReadsAfterSequence(t *testing.T) {
+	dir := t.TempDir()
+	j, err := Open(dir, Opts{SegmentBytes: 300})
+	require.NoError(t, err)
+	for i := 0; i < 6; i++ {
+		_, _, err := j.Append(newOrder("B", model.Buy, 100, 1))
+		require.NoError(t, err)
+	}
+	require.NoError(t, j.Close())
+
+	var seqs []uint64
+	err = NewDirSource(dir).ReadFrom(context.Background(), 3, func(e Entry) error {
+		seqs = append(seqs, e.Seq)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{4, 5, 6}, seqs)
+}
@@ -0,0 +1,79 @@
+package journal
+
+import (
+	"fmt"
+
+	"MatchingEngine/orderBook"
+)
+
+// Replayer feeds journal entries through order books, optionally starting
+// from snapshots. Books are driven synchronously on the caller's goroutine
+// and publish to notifier, so the reports they produce can be compared with
+// the ones published live.
+type Replayer struct {
+	notifier orderBook.Notifier
+	opts     orderBook.BookOpts
+	books    map[string]*orderBook.OrderBook
+}
+
+// NewReplayer creates a replayer. For the replay to reproduce the engine's
+// reports, opts.IDs must use the engine's instance ID and layout.
+func NewReplayer(notifier orderBook.Notifier, opts orderBook.BookOpts) *Replayer {
+	opts.Journal = nil
+	return &Replayer{
+		notifier: notifier,
+		opts:     opts,
+		books:    make(map[string]*orderBook.OrderBook),
+	}
+}
+
+// Restore seeds the replay with snapshots. Entries a snapshot already covers
+// are skipped for its book.
+func (r *Replayer) Restore(snapshots []orderBook.BookSnapshot) error {
+	for _, snapshot := range snapshots {
+		if _, exists := r.books[snapshot.Symbol]; exists {
+			return fmt.Errorf("order book for %s already exists", snapshot.Symbol)
+		}
+		opts := r.opts
+		opts.Symbol = snapshot.Symbol
+		book, err := orderBook.RestoreOrderBook(r.notifier, opts, snapshot)
+		if err != nil {
+			return fmt.Errorf("failed to restore order book for %s: %w", snapshot.Symbol, err)
+		}
+		r.books[snapshot.Symbol] = book
+	}
+	return nil
+}
+
+// Apply routes one entry to the book of its symbol.
+func (r *Replayer) Apply(entry Entry) error {
+	symbol := entry.Request.Symbol()
+	if symbol == "" {
+		return fmt.Errorf("journal entry %d has no symbol", entry.Seq)
+	}
+	book, ok := r.books[symbol]
+	if !ok {
+		opts := r.opts
+		opts.Symbol = symbol
+		book = orderBook.NewOrderBook(r.notifier, opts)
+		r.books[symbol] = book
+	}
+	book.Apply(entry.Seq, entry.Timestamp, entry.Request)
+	return nil
+}
+
+// Replay applies every entry of the journal in dir and returns how many were
+// read.
+func (r *Replayer) Replay(dir string) (int, error) {
+	count := 0
+	err := Read(dir, func(entry Entry) error {
+		count++
+		return r.Apply(entry)
+	})
+	return count, err
+}
+
+// Books returns the replayed books by symbol.
+func (r *Replayer) Books() map[string]*orderBook.OrderBook {
+	return r.books
+}
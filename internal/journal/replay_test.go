@@ -0,0 +1,122 @@
+package journal
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"MatchingEngine/internal/idgen"
+	"MatchingEngine/internal/model"
+	"MatchingEngine/orderBook"
+)
+
+type capturingNotifier struct {
+	mu       sync.Mutex
+	payloads [][]byte
+}
+
+func (c *capturingNotifier) NotifyEventAndTrade(_ model.MsgType, _ string, value json.RawMessage) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.payloads = append(c.payloads, append([]byte(nil), value...))
+	return nil
+}
+
+func (c *capturingNotifier) Payloads() [][]byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([][]byte(nil), c.payloads...)
+}
+
+func liveSession(t *testing.T, dir string, notifier *capturingNotifier, reqs []model.OrderRequest) *orderBook.OrderBook {
+	j, err := Open(dir, Opts{SegmentBytes: 512})
+	require.NoError(t, err)
+	defer j.Close()
+
+	book := orderBook.NewOrderBook(notifier, orderBook.BookOpts{Symbol: "BTC/USDT", Journal: j})
+	ch := book.Start()
+	for _, req := range reqs {
+		ch <- req
+	}
+	book.Exec(func(*orderBook.OrderBook) {})
+	return book
+}
+
+func TestReplay_ReproducesReports(t *testing.T) {
+	dir := t.TempDir()
+	live := &capturingNotifier{}
+	liveSession(t, dir, live, []model.OrderRequest{
+		newOrder("S1", model.Sell, 101, 5),
+		newOrder("S2", model.Sell, 102, 5),
+		newOrder("B1", model.Buy, 102, 7),
+		cancelOrder("S2"),
+		cancelOrder("UNKNOWN"),
+	})
+	require.NotEmpty(t, live.Payloads())
+
+	replayed := &capturingNotifier{}
+	count, err := NewReplayer(replayed, orderBook.BookOpts{}).Replay(dir)
+	require.NoError(t, err)
+	assert.Equal(t, 5, count)
+	assert.Equal(t, live.Payloads(), replayed.Payloads())
+}
+
+func TestReplay_FromSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	live := &capturingNotifier{}
+	book := liveSession(t, dir, live, []model.OrderRequest{
+		newOrder("S1", model.Sell, 101, 5),
+		newOrder("S2", model.Sell, 102, 5),
+	})
+	var snapshot orderBook.BookSnapshot
+	book.Exec(func(b *orderBook.OrderBook) { snapshot = b.Snapshot(1) })
+	assert.Equal(t, uint64(2), snapshot.JournalSeq)
+	before := len(live.Payloads())
+
+	// Restart from the snapshot and keep journaling.
+	j, err := Open(dir, Opts{SegmentBytes: 512})
+	require.NoError(t, err)
+	restarted, err := orderBook.RestoreOrderBook(live, orderBook.BookOpts{Journal: j}, snapshot)
+	require.NoError(t, err)
+	ch := restarted.Start()
+	ch <- newOrder("B1", model.Buy, 102, 7)
+	ch <- cancelOrder("S2")
+	restarted.Exec(func(*orderBook.OrderBook) {})
+	require.NoError(t, j.Close())
+
+	replayed := &capturingNotifier{}
+	replayer := NewReplayer(replayed, orderBook.BookOpts{})
+	require.NoError(t, replayer.Restore([]orderBook.BookSnapshot{snapshot}))
+	count, err := replayer.Replay(dir)
+	require.NoError(t, err)
+	assert.Equal(t, 4, count)
+	assert.Equal(t, live.Payloads()[before:], replayed.Payloads())
+	assert.Equal(t, uint64(4), replayer.Books()["BTC/USDT"].JournalSeq())
+}
+
+func TestReplay_UsesGeneratorLayout(t *testing.T) {
+	dir := t.TempDir()
+	j, err := Open(dir, Opts{})
+	require.NoError(t, err)
+	liveIDs, err := idgen.New(idgen.Opts{Instance: "me1"})
+	require.NoError(t, err)
+
+	live := &capturingNotifier{}
+	book := orderBook.NewOrderBook(live, orderBook.BookOpts{Symbol: "BTC/USDT", Journal: j, IDs: liveIDs})
+	ch := book.Start()
+	ch <- newOrder("S1", model.Sell, 101, 5)
+	ch <- newOrder("B1", model.Buy, 101, 5)
+	book.Exec(func(*orderBook.OrderBook) {})
+	require.NoError(t, j.Close())
+	assert.Contains(t, string(live.Payloads()[0]), `"37":"order-me1-000000000001.1"`)
+
+	replayIDs, err := idgen.New(idgen.Opts{Instance: "me1"})
+	require.NoError(t, err)
+	replayed := &capturingNotifier{}
+	_, err = NewReplayer(replayed, orderBook.BookOpts{IDs: replayIDs}).Replay(dir)
+	require.NoError(t, err)
+	assert.Equal(t, live.Payloads(), replayed.Payloads())
+}
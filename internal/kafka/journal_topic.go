@@ -0,0 +1,97 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	kafka "github.com/segmentio/kafka-go"
+
+	"MatchingEngine/internal/journal"
+)
+
+// JournalPublisher replicates journal entries to a single-partition topic,
+// the sequenced input stream a standby engine follows.
+type JournalPublisher struct {
+	writer *kafka.Writer
+}
+
+func NewJournalPublisher(brokerAddr string, topic string) *JournalPublisher {
+	return &JournalPublisher{
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(brokerAddr),
+			Topic:        topic,
+			Balancer:     &kafka.LeastBytes{},
+			RequiredAcks: kafka.RequireAll,
+		},
+	}
+}
+
+func (p *JournalPublisher) Publish(entry journal.Entry) error {
+	value, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal journal entry %d: %w", entry.Seq, err)
+	}
+	return p.writer.WriteMessages(context.Background(), kafka.Message{
+		Key:   []byte(strconv.FormatUint(entry.Seq, 10)),
+		Value: value,
+	})
+}
+
+func (p *JournalPublisher) Close() error {
+	return p.writer.Close()
+}
+
+// JournalSource reads the journal topic from its first offset. The topic's
+// retention must reach back to the snapshots a standby starts from.
+type JournalSource struct {
+	reader      *kafka.Reader
+	idleTimeout time.Duration
+}
+
+func NewJournalSource(brokerAddr string, topic string) *JournalSource {
+	return &JournalSource{
+		reader: kafka.NewReader(kafka.ReaderConfig{
+			Brokers:     []string{brokerAddr},
+			Topic:       topic,
+			Partition:   0,
+			StartOffset: kafka.FirstOffset,
+		}),
+		idleTimeout: time.Second,
+	}
+}
+
+// ReadFrom calls fn for every entry after seq, returning once no message has
+// arrived for the idle timeout.
+func (s *JournalSource) ReadFrom(ctx context.Context, after uint64, fn func(journal.Entry) error) error {
+	for {
+		fetchCtx, cancel := context.WithTimeout(ctx, s.idleTimeout)
+		msg, err := s.reader.FetchMessage(fetchCtx)
+		cancel()
+		if err != nil {
+			if ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+				return nil
+			}
+			return fmt.Errorf("failed to fetch journal entry: %w", err)
+		}
+
+		var entry journal.Entry
+		if err := json.Unmarshal(msg.Value, &entry); err != nil {
+			return fmt.Errorf("invalid journal entry at offset %d: %w", msg.Offset, err)
+		}
+		if entry.Seq <= after {
+			continue
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+		after = entry.Seq
+	}
+}
+
+func (s *JournalSource) Close() error {
+	return s.reader.Close()
+}
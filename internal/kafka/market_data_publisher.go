@@ -0,0 +1,31 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+
+	kafka "github.com/segmentio/kafka-go"
+)
+
+type MarketDataPublisher struct {
+	writer *kafka.Writer
+}
+
+func NewMarketDataPublisher(brokerAddr string, topic string) *MarketDataPublisher {
+	return &MarketDataPublisher{
+		writer: &kafka.Writer{
+			Addr:     kafka.TCP(brokerAddr),
+			Topic:    topic,
+			Balancer: &kafka.Hash{},
+		},
+	}
+}
+
+// PublishMarketData keys the message by symbol so depth updates of a symbol
+// are consumed in sequence order.
+func (p *MarketDataPublisher) PublishMarketData(symbol string, value json.RawMessage) error {
+	return p.writer.WriteMessages(context.Background(), kafka.Message{
+		Key:   []byte(symbol),
+		Value: value,
+	})
+}
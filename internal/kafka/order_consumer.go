@@ -2,6 +2,7 @@ package kafka
 
 import (
 	"MatchingEngine/internal/model"
+	"MatchingEngine/internal/repository"
 	"context"
 	"encoding/json"
 	"log"
@@ -14,29 +15,68 @@ type ConsumerOpts struct {
 	BrokerAddrs string
 	Topic       string
 	GroupID     string
+	// A batch is flushed once it holds BatchSize messages or its first
+	// message is FlushInterval old, whichever comes first.
+	BatchSize     int
+	FlushInterval time.Duration
 }
 
 type MessageHandler interface {
 	HandleExecutionReport(message []byte) error
 }
 
-type ExecutionService interface {
-	SaveExecutionAsync(order model.ExecutionReport)
+// BatchService writes a batch of reports to the database in one transaction,
+// calling done with the outcome. SaveBatchAsync blocks while the writer is
+// full, which stops the consumer fetching until it catches up.
+type BatchService interface {
+	SaveBatchAsync(ctx context.Context, batch repository.Batch, done func(error))
 }
 
-type TradeService interface {
-	SaveTradeAsync(trade model.TradeCaptureReport)
+// MessageReader is the part of *kafka.Reader the consumer uses.
+type MessageReader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
 }
 
+const (
+	defaultBatchSize     = 500
+	defaultFlushInterval = 200 * time.Millisecond
+	// Batches written at the same time; fetching waits beyond that.
+	maxBatchesInFlight = 4
+
+	writeRetryMin = 100 * time.Millisecond
+	writeRetryMax = 10 * time.Second
+)
+
+// Consumer persists the reports of the DB topic in batches. Offsets are
+// committed in order, each once the batches up to it are in the database,
+// so a crash redelivers what was not written; the writes are idempotent on
+// ExecID and TradeReportID, so a redelivered report is harmless. A trade's
+// sides reference its fills, which the engine publishes just before it: a
+// batch is cut so that a trade goes with its fills, and one whose fills went
+// in an earlier batch is only written once that batch is.
 type Consumer struct {
-	opts         ConsumerOpts
-	reader       *kafka.Reader
-	executionSvc ExecutionService
-	tradeSvc     TradeService
-	batch        *MessageBatch
+	opts    ConsumerOpts
+	reader  MessageReader
+	batches BatchService
+}
+
+// pendingBatch is a flushed batch waiting for its write. stored is closed
+// once it and every batch before it are written.
+type pendingBatch struct {
+	messages []kafka.Message
+	batch    repository.Batch
+	written  chan error
+	stored   chan struct{}
 }
 
-func NewConsumer(opts ConsumerOpts, executionService ExecutionService, tradeService TradeService) *Consumer {
+func NewConsumer(opts ConsumerOpts, batches BatchService) *Consumer {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultBatchSize
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = defaultFlushInterval
+	}
 	reader := kafka.NewReader(kafka.ReaderConfig{
 		Brokers:     []string{opts.BrokerAddrs},
 		Topic:       opts.Topic,
@@ -45,113 +85,236 @@ func NewConsumer(opts ConsumerOpts, executionService ExecutionService, tradeServ
 	})
 
 	return &Consumer{
-		opts:         opts,
-		reader:       reader,
-		executionSvc: executionService,
-		tradeSvc:     tradeService,
-		batch:        NewMessageBatch(),
+		opts:    opts,
+		reader:  reader,
+		batches: batches,
 	}
 }
 
 func (c *Consumer) Start(ctx context.Context) error {
 	log.Printf("Kafka consumer started for topic:%s, groupID:%s", c.opts.Topic, c.opts.GroupID)
 
-	// Start a message collection goroutine
-	go c.collectMessages(ctx)
+	messages := make(chan kafka.Message)
+	go c.collectMessages(ctx, messages)
 
-	// Start periodic processing of collected messages
-	go c.processBatchPeriodically(ctx)
+	pending := make(chan *pendingBatch, maxBatchesInFlight)
+	committed := make(chan struct{})
+	go func() {
+		defer close(committed)
+		c.commitBatches(ctx, pending)
+	}()
 
-	<-ctx.Done()
+	c.batchMessages(ctx, messages, pending)
+	<-committed
 	return nil
 }
 
-func (c *Consumer) collectMessages(ctx context.Context) {
+func (c *Consumer) collectMessages(ctx context.Context, messages chan<- kafka.Message) {
 	for {
+		msg, err := c.reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				log.Println("Context cancelled, stopping message collection")
+				return
+			}
+			log.Printf("Error fetching message: %v", err)
+			continue
+		}
+
 		select {
+		case messages <- msg:
 		case <-ctx.Done():
-			log.Println("Stopping message collection due to context cancellation")
 			return
-		default:
-			msg, err := c.reader.FetchMessage(ctx)
-			if err != nil {
-				if ctx.Err() != nil {
-					log.Println("Context cancelled, stopping message collection")
-					return
-				}
-				log.Printf("Error fetching message: %v", err)
-				continue
-			}
-
-			// Add a message to the batch instead of processing immediately
-			c.batch.AddMessage(msg.Value)
-
-			// commit the message to mark it as processed
-			if err := c.reader.CommitMessages(ctx, msg); err != nil {
-				log.Printf("Error committing message: %v", err)
-			}
 		}
 	}
 }
 
-func (c *Consumer) processBatchPeriodically(ctx context.Context) {
-	ticker := time.NewTicker(3 * time.Minute)
-	defer ticker.Stop()
+// batchMessages gathers messages into batches and hands them over for
+// writing, blocking while maxBatchesInFlight are being written.
+func (c *Consumer) batchMessages(ctx context.Context, messages <-chan kafka.Message, pending chan<- *pendingBatch) {
+	defer close(pending)
+
+	timer := time.NewTimer(c.opts.FlushInterval)
+	timer.Stop()
+	var batch []kafka.Message
+	var last *pendingBatch
+	flush := func() bool {
+		timer.Stop()
+		n := tradeBoundary(batch)
+		p := c.write(ctx, batch[:n], last)
+		batch = append([]kafka.Message(nil), batch[n:]...)
+		if len(batch) > 0 {
+			timer.Reset(c.opts.FlushInterval)
+		}
+		last = p
+		select {
+		case pending <- p:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
 
 	for {
 		select {
 		case <-ctx.Done():
-			log.Println("Stopping batch processing due to context cancellation")
 			return
-		case <-ticker.C:
-			messages := c.batch.GetAndClearMessages()
-			if len(messages) > 0 {
-				log.Printf("Processing batch of %d messages", len(messages))
-				for _, msg := range messages {
-					if err := c.handleReports(msg); err != nil {
-						log.Printf("Error handling message: %v", err)
-					}
-				}
+		case msg := <-messages:
+			batch = append(batch, msg)
+			if len(batch) == 1 {
+				timer.Reset(c.opts.FlushInterval)
+			}
+			if len(batch) >= c.opts.BatchSize && !flush() {
+				return
+			}
+		case <-timer.C:
+			if len(batch) > 0 && !flush() {
+				return
 			}
 		}
 	}
 }
 
-func (c *Consumer) handleReports(message []byte) error {
+// write decodes the messages and queues their reports for writing, once prev
+// is stored if a trade among them has a fill in an earlier batch.
+func (c *Consumer) write(ctx context.Context, messages []kafka.Message, prev *pendingBatch) *pendingBatch {
+	p := &pendingBatch{messages: messages, written: make(chan error, 1), stored: make(chan struct{})}
+	for _, msg := range messages {
+		c.handleReports(msg.Value, &p.batch)
+	}
+	if prev != nil && awaitsFills(p.batch) {
+		select {
+		case <-prev.stored:
+		case <-ctx.Done():
+			return p
+		}
+	}
+	c.submit(ctx, p)
+	return p
+}
+
+// reportLink is the part of a report that ties a fill to its trade.
+type reportLink struct {
+	MsgType string `json:"35"`
+	TradeID string `json:"1003"`
+}
+
+// tradeBoundary returns how many of messages to flush so that no trade is
+// split from its fills: those before the first fill whose trade has not
+// arrived, or all of them if that fill is the first message.
+func tradeBoundary(messages []kafka.Message) int {
+	links := make([]reportLink, len(messages))
+	trades := make(map[string]bool)
+	for i, msg := range messages {
+		if err := json.Unmarshal(msg.Value, &links[i]); err != nil {
+			links[i] = reportLink{}
+			continue
+		}
+		if links[i].MsgType == string(model.MsgTypeTradeReport) && links[i].TradeID != "" {
+			trades[links[i].TradeID] = true
+		}
+	}
+	for i, link := range links {
+		if link.MsgType == string(model.MsgTypeExecRpt) && link.TradeID != "" && !trades[link.TradeID] {
+			if i == 0 {
+				break
+			}
+			return i
+		}
+	}
+	return len(messages)
+}
+
+// awaitsFills tells whether a trade in batch has a fill outside it.
+func awaitsFills(batch repository.Batch) bool {
+	execIDs := make(map[string]bool, len(batch.Executions))
+	for _, er := range batch.Executions {
+		execIDs[er.ExecID] = true
+	}
+	for _, trade := range batch.Trades {
+		for _, side := range trade.NoSides {
+			if side.ExecID != "" && !execIDs[side.ExecID] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *Consumer) submit(ctx context.Context, p *pendingBatch) {
+	if p.batch.Len() == 0 {
+		p.written <- nil
+		return
+	}
+	c.batches.SaveBatchAsync(ctx, p.batch, func(err error) { p.written <- err })
+}
+
+// commitBatches waits for each batch in turn, writing it again until it goes
+// through, and commits its offsets. A batch cut short by ctx is left
+// uncommitted and redelivered on the next start.
+func (c *Consumer) commitBatches(ctx context.Context, pending <-chan *pendingBatch) {
+	for p := range pending {
+		backoff := writeRetryMin
+		for {
+			var err error
+			select {
+			case <-ctx.Done():
+				return
+			case err = <-p.written:
+			}
+			if err == nil {
+				break
+			}
+			log.Printf("Failed to write batch of %d messages, retrying in %s: %v", len(p.messages), backoff, err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, writeRetryMax)
+			c.submit(ctx, p)
+		}
+
+		close(p.stored)
+		if err := c.reader.CommitMessages(ctx, p.messages...); err != nil {
+			log.Printf("Error committing messages: %v", err)
+		}
+	}
+}
+
+// handleReports adds the report in message to the batch. A message that is
+// not a report is logged and skipped.
+func (c *Consumer) handleReports(message []byte, batch *repository.Batch) {
 	var raw map[string]interface{}
 	if err := json.Unmarshal(message, &raw); err != nil {
 		log.Printf("Invalid execution report JSON: %v | message: %s", err, string(message))
-		return nil
+		return
 	}
 
 	msgType, ok := raw["35"].(string)
 	if !ok {
 		log.Printf("Missing or invalid MsgType in message: %s", string(message))
-		return nil
+		return
 	}
 
 	switch msgType {
 	case string(model.MsgTypeTradeReport):
 		var tradeCaptureReport model.TradeCaptureReport
 		if err := unmarshalAndLogError(message, &tradeCaptureReport); err != nil {
-			return nil
+			return
 		}
-		log.Printf("received trade report : %+v", tradeCaptureReport)
-		c.tradeSvc.SaveTradeAsync(tradeCaptureReport)
+		batch.Trades = append(batch.Trades, tradeCaptureReport)
 
 	case string(model.MsgTypeExecRpt):
 		var execReport model.ExecutionReport
 		if err := unmarshalAndLogError(message, &execReport); err != nil {
-			return nil
+			return
 		}
-		log.Printf("received execution report: %+v", execReport)
-		c.executionSvc.SaveExecutionAsync(execReport)
+		batch.Executions = append(batch.Executions, execReport)
 
 	default:
 		log.Printf("Unknown MsgType: %s | message: %s", msgType, string(message))
 	}
-
-	return nil
 }
 
 func unmarshalAndLogError(message []byte, v interface{}) error {
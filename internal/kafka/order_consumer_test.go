@@ -0,0 +1,282 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	kafka "github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"MatchingEngine/internal/repository"
+)
+
+type fakeReader struct {
+	messages chan kafka.Message
+
+	mu        sync.Mutex
+	committed []int64
+}
+
+func newFakeReader() *fakeReader {
+	return &fakeReader{messages: make(chan kafka.Message)}
+}
+
+func (r *fakeReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	select {
+	case msg := <-r.messages:
+		return msg, nil
+	case <-ctx.Done():
+		return kafka.Message{}, ctx.Err()
+	}
+}
+
+func (r *fakeReader) CommitMessages(_ context.Context, msgs ...kafka.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, m := range msgs {
+		r.committed = append(r.committed, m.Offset)
+	}
+	return nil
+}
+
+func (r *fakeReader) commits() []int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]int64(nil), r.committed...)
+}
+
+// flakyBatches fails the first writes, and records the batches written.
+type flakyBatches struct {
+	mu       sync.Mutex
+	failures int
+	written  []repository.Batch
+}
+
+func (s *flakyBatches) SaveBatchAsync(_ context.Context, batch repository.Batch, done func(error)) {
+	go func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.failures > 0 {
+			s.failures--
+			done(errors.New("connection reset"))
+			return
+		}
+		s.written = append(s.written, batch)
+		done(nil)
+	}()
+}
+
+func (s *flakyBatches) batches() []repository.Batch {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]repository.Batch(nil), s.written...)
+}
+
+// newTestConsumer reads from a fakeReader; the topic only satisfies
+// kafka.NewReader.
+func newTestConsumer(opts ConsumerOpts, batches BatchService) (*Consumer, *fakeReader) {
+	opts.BrokerAddrs, opts.Topic = "localhost:9092", "dbUpdateTopic"
+	reader := newFakeReader()
+	c := NewConsumer(opts, batches)
+	c.reader = reader
+	return c, reader
+}
+
+func startTestConsumer(t *testing.T, opts ConsumerOpts, batches BatchService) *fakeReader {
+	c, reader := newTestConsumer(opts, batches)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.NoError(t, c.Start(ctx))
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return reader
+}
+
+func execMessage(offset int64) kafka.Message {
+	return kafka.Message{Offset: offset, Value: []byte(fmt.Sprintf(`{"35":"8","17":"exec-%d"}`, offset))}
+}
+
+func TestConsumer_FlushesFullBatchesAndCommitsAfterWrite(t *testing.T) {
+	batches := &flakyBatches{failures: 1}
+	reader := startTestConsumer(t, ConsumerOpts{BatchSize: 3, FlushInterval: time.Hour}, batches)
+
+	reader.messages <- execMessage(10)
+	reader.messages <- kafka.Message{Offset: 11, Value: []byte(`not json`)}
+	reader.messages <- kafka.Message{Offset: 12, Value: []byte(`{"35":"AE","571":"trade-1"}`)}
+
+	// The first write fails and is retried; nothing is committed before.
+	require.Eventually(t, func() bool { return len(reader.commits()) == 3 }, 5*time.Second, time.Millisecond)
+	written := batches.batches()
+	require.Len(t, written, 1)
+	assert.Len(t, written[0].Executions, 1)
+	assert.Equal(t, "trade-1", written[0].Trades[0].TradeReportID)
+	assert.Equal(t, []int64{10, 11, 12}, reader.commits())
+}
+
+func TestConsumer_FlushesPartialBatchAfterInterval(t *testing.T) {
+	batches := &flakyBatches{}
+	reader := startTestConsumer(t, ConsumerOpts{BatchSize: 100, FlushInterval: 20 * time.Millisecond}, batches)
+
+	reader.messages <- execMessage(1)
+	reader.messages <- execMessage(2)
+	require.Eventually(t, func() bool { return len(reader.commits()) == 2 }, 5*time.Second, time.Millisecond)
+
+	reader.messages <- execMessage(3)
+	require.Eventually(t, func() bool { return len(reader.commits()) == 3 }, 5*time.Second, time.Millisecond)
+	assert.Len(t, batches.batches(), 2)
+}
+
+func TestConsumer_LeavesUnwrittenBatchUncommitted(t *testing.T) {
+	batches := &flakyBatches{failures: 1000}
+	c, reader := newTestConsumer(ConsumerOpts{BatchSize: 1}, batches)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.NoError(t, c.Start(ctx))
+	}()
+
+	reader.messages <- execMessage(10)
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	<-done
+
+	assert.Empty(t, batches.batches())
+	assert.Empty(t, reader.commits())
+}
+
+// blockingBatches holds SaveBatchAsync like a writer with a full queue.
+type blockingBatches struct {
+	release chan struct{}
+}
+
+func (s *blockingBatches) SaveBatchAsync(ctx context.Context, _ repository.Batch, done func(error)) {
+	select {
+	case <-s.release:
+		done(nil)
+	case <-ctx.Done():
+		done(ctx.Err())
+	}
+}
+
+func TestConsumer_StopsFetchingWhileWriterIsFull(t *testing.T) {
+	batches := &blockingBatches{release: make(chan struct{})}
+	reader := startTestConsumer(t, ConsumerOpts{BatchSize: 1}, batches)
+
+	// The first message is stuck in the writer and the second waits to be
+	// batched; the third is not fetched.
+	reader.messages <- execMessage(1)
+	reader.messages <- execMessage(2)
+	select {
+	case reader.messages <- execMessage(3):
+		t.Fatal("fetched a message while the writer was full")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(batches.release)
+	reader.messages <- execMessage(3)
+	require.Eventually(t, func() bool { return len(reader.commits()) == 3 }, 5*time.Second, time.Millisecond)
+}
+
+func fillMessage(offset int64, tradeID string) kafka.Message {
+	return kafka.Message{Offset: offset, Value: []byte(fmt.Sprintf(`{"35":"8","17":"exec-%d","1003":%q}`, offset, tradeID))}
+}
+
+func tradeMessage(offset int64, tradeID string, execIDs ...string) kafka.Message {
+	sides := ""
+	for i, id := range execIDs {
+		if i > 0 {
+			sides += ","
+		}
+		sides += fmt.Sprintf(`{"17":%q}`, id)
+	}
+	return kafka.Message{Offset: offset, Value: []byte(fmt.Sprintf(`{"35":"AE","571":"report-%s","1003":%q,"552":[%s]}`, tradeID, tradeID, sides))}
+}
+
+func TestConsumer_KeepsTradesWithTheirFills(t *testing.T) {
+	batches := &flakyBatches{}
+	reader := startTestConsumer(t, ConsumerOpts{BatchSize: 3, FlushInterval: time.Hour}, batches)
+
+	// The batch is full before the trade of the two fills arrives; they
+	// wait for it in the next batch.
+	reader.messages <- execMessage(1)
+	reader.messages <- fillMessage(2, "T1")
+	reader.messages <- fillMessage(3, "T1")
+	reader.messages <- tradeMessage(4, "T1", "exec-2", "exec-3")
+	reader.messages <- execMessage(5)
+
+	require.Eventually(t, func() bool { return len(reader.commits()) == 4 }, 5*time.Second, time.Millisecond)
+	written := batches.batches()
+	require.Len(t, written, 2)
+	// Batches are written concurrently and may land in either order.
+	if len(written[0].Trades) > 0 {
+		written[0], written[1] = written[1], written[0]
+	}
+	assert.Len(t, written[0].Executions, 1)
+	assert.Empty(t, written[0].Trades)
+	assert.Len(t, written[1].Executions, 2)
+	require.Len(t, written[1].Trades, 1)
+	assert.Equal(t, "T1", written[1].Trades[0].TradeID)
+	assert.Equal(t, []int64{1, 2, 3, 4}, reader.commits())
+}
+
+// heldBatches holds the first batch until released and records the trades
+// of every batch when it is submitted.
+type heldBatches struct {
+	release chan struct{}
+
+	mu        sync.Mutex
+	submitted [][]string
+}
+
+func (s *heldBatches) SaveBatchAsync(_ context.Context, batch repository.Batch, done func(error)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var trades []string
+	for _, trade := range batch.Trades {
+		trades = append(trades, trade.TradeID)
+	}
+	s.submitted = append(s.submitted, trades)
+	if len(s.submitted) > 1 {
+		done(nil)
+		return
+	}
+	go func() {
+		<-s.release
+		done(nil)
+	}()
+}
+
+func (s *heldBatches) trades() [][]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([][]string(nil), s.submitted...)
+}
+
+func TestConsumer_WritesTradeAfterTheBatchOfItsFills(t *testing.T) {
+	batches := &heldBatches{release: make(chan struct{})}
+	reader := startTestConsumer(t, ConsumerOpts{BatchSize: 2, FlushInterval: time.Hour}, batches)
+
+	// Nothing but the fills fits in the first batch, so the trade follows
+	// in the second, which waits until the first is written.
+	reader.messages <- fillMessage(1, "T1")
+	reader.messages <- fillMessage(2, "T1")
+	reader.messages <- tradeMessage(3, "T1", "exec-1", "exec-2")
+	reader.messages <- execMessage(4)
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, batches.trades(), 1)
+
+	close(batches.release)
+	require.Eventually(t, func() bool { return len(reader.commits()) == 4 }, 5*time.Second, time.Millisecond)
+	assert.Equal(t, [][]string{nil, {"T1"}}, batches.trades())
+}
@@ -2,58 +2,91 @@ package kafka
 
 import (
 	"context"
-	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
 
 	kafka "github.com/segmentio/kafka-go"
-)
 
-type Producer struct {
-	dbWriter        *kafka.Writer
-	executionWriter *kafka.Writer
-}
+	"MatchingEngine/internal/outbox"
+)
 
-type EventAndTradeNotifier interface {
-	NotifyEventAndTrade(orderID string, value json.RawMessage) error
+// OutboxPublisher relays the outbox to one topic, the execution topic or the
+// DB topic. Each report keeps its ExecID or TradeReportID as message key, so
+// a consumer recognises a report it was sent twice, and carries its outbox
+// sequence for reconciliation.
+type OutboxPublisher struct {
+	writer *kafka.Writer
 }
 
-func NewProducer(brokerAddr string, dbTopic string, executionTopic string) *Producer {
-	return &Producer{
-		dbWriter: &kafka.Writer{
-			Addr:     kafka.TCP(brokerAddr),
-			Topic:    dbTopic,
-			Balancer: &kafka.LeastBytes{},
-		},
-		executionWriter: &kafka.Writer{
-			Addr:     kafka.TCP(brokerAddr),
-			Topic:    executionTopic,
-			Balancer: &kafka.LeastBytes{},
+func NewOutboxPublisher(brokerAddr string, topic string) *OutboxPublisher {
+	return &OutboxPublisher{
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(brokerAddr),
+			Topic:        topic,
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: kafka.RequireAll,
+			BatchTimeout: 10 * time.Millisecond,
 		},
 	}
 }
 
-func (p *Producer) NotifyEventAndTrade(key string, value json.RawMessage) error {
-	// Publish to the execution notification topic
-	log.Printf("Publishing message to Execution topic: Key=%s, Value=%s", key, string(value))
-	err := p.executionWriter.WriteMessages(context.Background(), kafka.Message{
-		Key:   []byte(key),
-		Value: value,
-	})
-	if err != nil {
-		log.Println("Failed to publish message to Execution topic:", err)
-		return err
+func (p *OutboxPublisher) Name() string {
+	return p.writer.Topic
+}
+
+func (p *OutboxPublisher) Publish(ctx context.Context, events []outbox.Event) error {
+	msgs := make([]kafka.Message, len(events))
+	for i, e := range events {
+		msgs[i] = kafka.Message{
+			Key:     []byte(e.Key),
+			Value:   e.Value,
+			Headers: []kafka.Header{{Key: outbox.SeqHeader, Value: []byte(strconv.FormatUint(e.Seq, 10))}},
+		}
+	}
+	if err := p.writer.WriteMessages(ctx, msgs...); err != nil {
+		return fmt.Errorf("failed to publish to %s: %w", p.writer.Topic, err)
 	}
+	return nil
+}
+
+func (p *OutboxPublisher) Close() error {
+	return p.writer.Close()
+}
 
-	// Publish to the database update topic
-	log.Printf("Publishing message to DB topic: Key=%s, Value=%s", key, string(value))
-	err = p.dbWriter.WriteMessages(context.Background(), kafka.Message{
-		Key:   []byte(key),
-		Value: value,
+// ReadOutboxEvents returns the sequence and key of every relayed message
+// still in the topic, reading until no message has arrived for idleTimeout.
+// Topics are created with a single partition.
+func ReadOutboxEvents(ctx context.Context, brokerAddr, topic string, idleTimeout time.Duration) ([]outbox.Event, error) {
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     []string{brokerAddr},
+		Topic:       topic,
+		Partition:   0,
+		StartOffset: kafka.FirstOffset,
 	})
-	if err != nil {
-		log.Println("Failed to publish message to DB topic:", err)
-		return err
-	}
+	defer reader.Close()
 
-	return nil
+	var events []outbox.Event
+	for {
+		fetchCtx, cancel := context.WithTimeout(ctx, idleTimeout)
+		msg, err := reader.FetchMessage(fetchCtx)
+		cancel()
+		if err != nil {
+			if ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+				return events, nil
+			}
+			return nil, fmt.Errorf("failed to read %s: %w", topic, err)
+		}
+		for _, h := range msg.Headers {
+			if h.Key != outbox.SeqHeader {
+				continue
+			}
+			seq, err := strconv.ParseUint(string(h.Value), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s header at offset %d of %s: %w", outbox.SeqHeader, msg.Offset, topic, err)
+			}
+			events = append(events, outbox.Event{Seq: seq, Key: string(msg.Key)})
+		}
+	}
 }
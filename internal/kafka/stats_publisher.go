@@ -0,0 +1,31 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+
+	kafka "github.com/segmentio/kafka-go"
+)
+
+type StatsPublisher struct {
+	writer *kafka.Writer
+}
+
+func NewStatsPublisher(brokerAddr string, topic string) *StatsPublisher {
+	return &StatsPublisher{
+		writer: &kafka.Writer{
+			Addr:     kafka.TCP(brokerAddr),
+			Topic:    topic,
+			Balancer: &kafka.Hash{},
+		},
+	}
+}
+
+// PublishStatistics keys the message by symbol so each symbol's statistics stay
+// ordered within a partition.
+func (p *StatsPublisher) PublishStatistics(symbol string, value json.RawMessage) error {
+	return p.writer.WriteMessages(context.Background(), kafka.Message{
+		Key:   []byte(symbol),
+		Value: value,
+	})
+}
@@ -0,0 +1,166 @@
+package marketdata
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"MatchingEngine/internal/model"
+)
+
+var hundred = decimal.NewFromInt(100)
+
+type tradePoint struct {
+	price decimal.Decimal
+	qty   decimal.Decimal
+	ts    int64
+}
+
+// tradeQueue is a FIFO of trade points backed by a slice that is compacted
+// once the consumed head grows past half of it.
+type tradeQueue struct {
+	items []tradePoint
+	head  int
+}
+
+func (q *tradeQueue) len() int {
+	return len(q.items) - q.head
+}
+
+func (q *tradeQueue) front() tradePoint {
+	return q.items[q.head]
+}
+
+func (q *tradeQueue) back() tradePoint {
+	return q.items[len(q.items)-1]
+}
+
+func (q *tradeQueue) pushBack(p tradePoint) {
+	q.items = append(q.items, p)
+}
+
+func (q *tradeQueue) popBack() {
+	q.items = q.items[:len(q.items)-1]
+}
+
+func (q *tradeQueue) popFront() {
+	q.items[q.head] = tradePoint{}
+	q.head++
+	if q.head == len(q.items) {
+		q.items = q.items[:0]
+		q.head = 0
+	} else if q.head >= 64 && q.head*2 >= len(q.items) {
+		n := copy(q.items, q.items[q.head:])
+		q.items = q.items[:n]
+		q.head = 0
+	}
+}
+
+// RollingWindow keeps every trade of a symbol executed within the last window
+// duration and maintains the aggregates incrementally. High and low are tracked
+// with monotonic queues so that eviction stays amortised O(1) per trade.
+type RollingWindow struct {
+	symbol   string
+	window   int64
+	trades   tradeQueue
+	highs    tradeQueue // prices non-increasing from front to back
+	lows     tradeQueue // prices non-decreasing from front to back
+	volume   decimal.Decimal
+	notional decimal.Decimal
+	lastPx   decimal.Decimal
+	lastTs   int64
+}
+
+func NewRollingWindow(symbol string, window time.Duration) *RollingWindow {
+	return &RollingWindow{
+		symbol:   symbol,
+		window:   window.Nanoseconds(),
+		volume:   decimal.Zero,
+		notional: decimal.Zero,
+		lastPx:   decimal.Zero,
+	}
+}
+
+// Add records a trade executed at ts (epoch ns). Trades are expected in
+// execution order; a timestamp older than the previous trade is clamped to it
+// so the window never evicts out of order.
+func (w *RollingWindow) Add(price, qty decimal.Decimal, ts int64) {
+	if ts < w.lastTs {
+		ts = w.lastTs
+	}
+	w.lastTs = ts
+
+	p := tradePoint{price: price, qty: qty, ts: ts}
+	w.trades.pushBack(p)
+	for w.highs.len() > 0 && w.highs.back().price.LessThan(price) {
+		w.highs.popBack()
+	}
+	w.highs.pushBack(p)
+	for w.lows.len() > 0 && w.lows.back().price.GreaterThan(price) {
+		w.lows.popBack()
+	}
+	w.lows.pushBack(p)
+
+	w.volume = w.volume.Add(qty)
+	w.notional = w.notional.Add(price.Mul(qty))
+	w.lastPx = price
+}
+
+// evict drops every trade that is no longer inside (now-window, now].
+func (w *RollingWindow) evict(now int64) {
+	cutoff := now - w.window
+	for w.trades.len() > 0 && w.trades.front().ts <= cutoff {
+		p := w.trades.front()
+		w.volume = w.volume.Sub(p.qty)
+		w.notional = w.notional.Sub(p.price.Mul(p.qty))
+		w.trades.popFront()
+	}
+	for w.highs.len() > 0 && w.highs.front().ts <= cutoff {
+		w.highs.popFront()
+	}
+	for w.lows.len() > 0 && w.lows.front().ts <= cutoff {
+		w.lows.popFront()
+	}
+	if w.trades.len() == 0 {
+		// Avoid carrying rounding residue into the next window.
+		w.volume = decimal.Zero
+		w.notional = decimal.Zero
+	}
+}
+
+// Stats evicts expired trades and returns the statistics as of now (epoch ns).
+// LastPx is the last traded price even when it has left the window.
+func (w *RollingWindow) Stats(now int64) model.TradeStatistics {
+	w.evict(now)
+
+	stats := model.TradeStatistics{
+		Symbol:             w.symbol,
+		WindowStart:        now - w.window,
+		WindowEnd:          now,
+		OpenPx:             decimal.Zero,
+		HighPx:             decimal.Zero,
+		LowPx:              decimal.Zero,
+		LastPx:             w.lastPx,
+		Vwap:               decimal.Zero,
+		Volume:             w.volume,
+		Notional:           w.notional,
+		TradeCount:         w.trades.len(),
+		PriceChange:        decimal.Zero,
+		PriceChangePercent: decimal.Zero,
+	}
+	if stats.TradeCount == 0 {
+		return stats
+	}
+
+	stats.OpenPx = w.trades.front().price
+	stats.HighPx = w.highs.front().price
+	stats.LowPx = w.lows.front().price
+	if w.volume.IsPositive() {
+		stats.Vwap = w.notional.Div(w.volume)
+	}
+	stats.PriceChange = stats.LastPx.Sub(stats.OpenPx)
+	if !stats.OpenPx.IsZero() {
+		stats.PriceChangePercent = stats.PriceChange.Div(stats.OpenPx).Mul(hundred).Round(4)
+	}
+	return stats
+}
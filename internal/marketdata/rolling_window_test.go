@@ -0,0 +1,103 @@
+package marketdata
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+
+	"MatchingEngine/internal/model"
+)
+
+var base = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC).UnixNano()
+
+func at(d time.Duration) int64 {
+	return base + d.Nanoseconds()
+}
+
+func TestRollingWindow_Aggregates(t *testing.T) {
+	w := NewRollingWindow("BTC/USDT", 24*time.Hour)
+	w.Add(decimal.NewFromInt(100), decimal.NewFromInt(2), at(0))
+	w.Add(decimal.NewFromInt(120), decimal.NewFromInt(1), at(time.Hour))
+	w.Add(decimal.NewFromInt(90), decimal.NewFromInt(1), at(2*time.Hour))
+	w.Add(decimal.NewFromInt(110), decimal.NewFromInt(1), at(3*time.Hour))
+
+	stats := w.Stats(at(4 * time.Hour))
+
+	assert.Equal(t, "BTC/USDT", stats.Symbol)
+	assert.Equal(t, 4, stats.TradeCount)
+	assert.True(t, stats.OpenPx.Equal(decimal.NewFromInt(100)))
+	assert.True(t, stats.HighPx.Equal(decimal.NewFromInt(120)))
+	assert.True(t, stats.LowPx.Equal(decimal.NewFromInt(90)))
+	assert.True(t, stats.LastPx.Equal(decimal.NewFromInt(110)))
+	assert.True(t, stats.Volume.Equal(decimal.NewFromInt(5)))
+	assert.True(t, stats.Notional.Equal(decimal.NewFromInt(520)))
+	assert.True(t, stats.Vwap.Equal(decimal.NewFromInt(104)))
+	assert.True(t, stats.PriceChange.Equal(decimal.NewFromInt(10)))
+	assert.True(t, stats.PriceChangePercent.Equal(decimal.NewFromInt(10)))
+}
+
+func TestRollingWindow_EvictsExactlyPerTrade(t *testing.T) {
+	w := NewRollingWindow("BTC/USDT", 24*time.Hour)
+	w.Add(decimal.NewFromInt(150), decimal.NewFromInt(1), at(0))
+	w.Add(decimal.NewFromInt(80), decimal.NewFromInt(1), at(time.Minute))
+	w.Add(decimal.NewFromInt(100), decimal.NewFromInt(3), at(2*time.Minute))
+
+	// One nanosecond before the first trade leaves the window.
+	stats := w.Stats(at(24*time.Hour) - 1)
+	assert.Equal(t, 3, stats.TradeCount)
+	assert.True(t, stats.HighPx.Equal(decimal.NewFromInt(150)))
+
+	stats = w.Stats(at(24 * time.Hour))
+	assert.Equal(t, 2, stats.TradeCount)
+	assert.True(t, stats.OpenPx.Equal(decimal.NewFromInt(80)))
+	assert.True(t, stats.HighPx.Equal(decimal.NewFromInt(100)))
+	assert.True(t, stats.LowPx.Equal(decimal.NewFromInt(80)))
+	assert.True(t, stats.Volume.Equal(decimal.NewFromInt(4)))
+
+	stats = w.Stats(at(24*time.Hour + time.Minute))
+	assert.Equal(t, 1, stats.TradeCount)
+	assert.True(t, stats.LowPx.Equal(decimal.NewFromInt(100)))
+	assert.True(t, stats.Vwap.Equal(decimal.NewFromInt(100)))
+}
+
+func TestRollingWindow_EmptyWindowKeepsLastPx(t *testing.T) {
+	w := NewRollingWindow("BTC/USDT", time.Hour)
+	w.Add(decimal.NewFromInt(100), decimal.NewFromInt(1), at(0))
+
+	stats := w.Stats(at(2 * time.Hour))
+
+	assert.Equal(t, 0, stats.TradeCount)
+	assert.True(t, stats.Volume.IsZero())
+	assert.True(t, stats.Vwap.IsZero())
+	assert.True(t, stats.LastPx.Equal(decimal.NewFromInt(100)))
+}
+
+func TestRollingWindow_ManyTradesCompactsQueue(t *testing.T) {
+	w := NewRollingWindow("BTC/USDT", time.Minute)
+	for i := 0; i < 1000; i++ {
+		w.Add(decimal.NewFromInt(int64(1000-i)), decimal.NewFromInt(1), at(time.Duration(i)*time.Second))
+	}
+
+	stats := w.Stats(at(999 * time.Second))
+
+	assert.Equal(t, 60, stats.TradeCount)
+	assert.True(t, stats.HighPx.Equal(decimal.NewFromInt(60)))
+	assert.True(t, stats.LowPx.Equal(decimal.NewFromInt(1)))
+	assert.Less(t, len(w.trades.items), 1000)
+}
+
+func TestStatistics_RecordAndAll(t *testing.T) {
+	s := NewStatistics(24 * time.Hour)
+	s.Record(model.TradeCaptureReport{Symbol: "ETH/USDT", LastPx: decimal.NewFromInt(10), LastQty: decimal.NewFromInt(1), TransactTime: at(0)})
+	s.Record(model.TradeCaptureReport{Symbol: "BTC/USDT", LastPx: decimal.NewFromInt(20), LastQty: decimal.NewFromInt(1), TransactTime: at(0)})
+
+	_, ok := s.Get("SOL/USDT", at(time.Minute))
+	assert.False(t, ok)
+
+	all := s.All(at(time.Minute))
+	assert.Len(t, all, 2)
+	assert.Equal(t, "BTC/USDT", all[0].Symbol)
+	assert.Equal(t, "ETH/USDT", all[1].Symbol)
+}
@@ -0,0 +1,64 @@
+package marketdata
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"MatchingEngine/internal/model"
+)
+
+// Statistics tracks a RollingWindow per symbol. It is safe for concurrent use
+// by the order book goroutines and the readers.
+type Statistics struct {
+	window  time.Duration
+	mu      sync.Mutex
+	windows map[string]*RollingWindow
+}
+
+func NewStatistics(window time.Duration) *Statistics {
+	return &Statistics{
+		window:  window,
+		windows: make(map[string]*RollingWindow),
+	}
+}
+
+func (s *Statistics) Record(trade model.TradeCaptureReport) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w, ok := s.windows[trade.Symbol]
+	if !ok {
+		w = NewRollingWindow(trade.Symbol, s.window)
+		s.windows[trade.Symbol] = w
+	}
+	w.Add(trade.LastPx, trade.LastQty, trade.TransactTime)
+}
+
+// Get returns the statistics of a symbol as of now (epoch ns). The boolean is
+// false if the symbol has never traded.
+func (s *Statistics) Get(symbol string, now int64) (model.TradeStatistics, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w, ok := s.windows[symbol]
+	if !ok {
+		return model.TradeStatistics{}, false
+	}
+	return w.Stats(now), true
+}
+
+// All returns the statistics of every traded symbol, ordered by symbol.
+func (s *Statistics) All(now int64) []model.TradeStatistics {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all := make([]model.TradeStatistics, 0, len(s.windows))
+	for _, w := range s.windows {
+		all = append(all, w.Stats(now))
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].Symbol < all[j].Symbol
+	})
+	return all
+}
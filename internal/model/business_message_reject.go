@@ -0,0 +1,22 @@
+package model
+
+// BusinessRejectReason FIX <380>
+type BusinessRejectReason string
+
+const (
+	BusinessRejectOther                   BusinessRejectReason = "0"
+	BusinessRejectUnsupportedMsgType      BusinessRejectReason = "3"
+	BusinessRejectApplicationNotAvailable BusinessRejectReason = "4"
+	BusinessRejectMissingField            BusinessRejectReason = "5" // Conditionally required field missing
+)
+
+// BusinessMessageReject represents a FIX j message: a request the engine
+// could not take, so no execution report will answer it.
+type BusinessMessageReject struct {
+	MsgType              string               `json:"35"`            // always "j"
+	RefMsgType           string               `json:"372,omitempty"` // MsgType of the refused request, when known
+	BusinessRejectRefID  string               `json:"379,omitempty"` // ClOrdID of the refused request, when known
+	BusinessRejectReason BusinessRejectReason `json:"380"`
+	Text                 string               `json:"58,omitempty"`
+	TransactTime         int64                `json:"60"` // Epoch ns
+}
@@ -3,20 +3,29 @@ package model
 import "github.com/shopspring/decimal"
 
 type ExecutionReport struct {
-	MsgType      string          `json:"35"`           // always "8"
-	ExecID       string          `json:"17"`           // ExecID
-	OrderID      string          `json:"37"`           // OrderID
-	ClOrdID      string          `json:"11,omitempty"` // ClOrdID
-	ExecType     ExecType        `json:"150"`          // ExecType
-	OrdStatus    OrderStatus     `json:"39"`           // OrdStatus
-	Symbol       string          `json:"55"`           // Symbol
-	Side         Side            `json:"54"`           // Side
-	OrderQty     decimal.Decimal `json:"38"`           // OrderQty
-	LastShares   decimal.Decimal `json:"32"`           // LastShares
-	LastPx       decimal.Decimal `json:"31"`           // LastPx
-	LeavesQty    decimal.Decimal `json:"151"`          // LeavesQty
-	CumQty       decimal.Decimal `json:"14"`           // CumQty
-	AvgPx        decimal.Decimal `json:"6"`            // AvgPx
-	TransactTime int64           `json:"60"`           // TransactTime
-	Text         string          `json:"58,omitempty"` // Text
+	MsgType          string           `json:"35"`             // always "8"
+	ExecID           string           `json:"17"`             // ExecID
+	OrderID          string           `json:"37"`             // OrderID
+	ClOrdID          string           `json:"11,omitempty"`   // ClOrdID
+	Account          string           `json:"1,omitempty"`    // Account
+	ExecType         ExecType         `json:"150"`            // ExecType
+	OrdStatus        OrderStatus      `json:"39"`             // OrdStatus
+	Symbol           string           `json:"55"`             // Symbol
+	Side             Side             `json:"54"`             // Side
+	Price            decimal.Decimal  `json:"44,omitempty"`   // Price
+	OrdType          OrdType          `json:"40,omitempty"`   // OrdType
+	TimeInForce      TimeInForce      `json:"59,omitempty"`   // TimeInForce
+	OrderQty         decimal.Decimal  `json:"38"`             // OrderQty
+	LastShares       decimal.Decimal  `json:"32"`             // LastShares
+	LastPx           decimal.Decimal  `json:"31"`             // LastPx
+	LeavesQty        decimal.Decimal  `json:"151"`            // LeavesQty
+	CumQty           decimal.Decimal  `json:"14"`             // CumQty
+	AvgPx            decimal.Decimal  `json:"6"`              // AvgPx
+	TransactTime     int64            `json:"60"`             // TransactTime
+	ClientTime       int64            `json:"5003,omitempty"` // ClientTransactTime, the client TransactTime <60> of the request that produced the report (user-defined)
+	Text             string           `json:"58,omitempty"`   // Text
+	TradeID          string           `json:"1003,omitempty"` // TradeID, set on fills
+	LastLiquidityInd LastLiquidityInd `json:"851,omitempty"`  // LastLiquidityInd, set on fills
+	EngineSeq        uint64           `json:"5001,omitempty"` // EngineSeq, the order's queue priority (user-defined)
+	ReportSeq        uint64           `json:"5002,omitempty"` // ReportSeq, ranks the reports of an order (user-defined)
 }
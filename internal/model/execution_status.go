@@ -6,6 +6,7 @@ const (
 	ExecTypeNew      ExecType = "0"
 	ExecTypeFill     ExecType = "2"
 	ExecTypeCanceled ExecType = "4"
+	ExecTypeReplaced ExecType = "5"
 	ExecTypeRejected ExecType = "8"
 )
 
@@ -19,3 +20,12 @@ const (
 	OrderStatusRejected    OrderStatus = "8"
 	OrderStatusPendingNew  OrderStatus = "A"
 )
+
+// LastLiquidityInd FIX LastLiquidityInd <851>, set on fills: whether the order
+// rested on the book (maker) or took liquidity from it (taker).
+type LastLiquidityInd string
+
+const (
+	LiquidityAdded   LastLiquidityInd = "1"
+	LiquidityRemoved LastLiquidityInd = "2"
+)
@@ -0,0 +1,12 @@
+package model
+
+// HistoryFilter selects stored executions or trades. Empty fields match
+// everything; a trade matches an order or an account when one of its sides
+// does.
+type HistoryFilter struct {
+	Symbol  string
+	OrderID string
+	Account string
+	From    int64 // TransactTime, epoch ns, inclusive
+	To      int64 // TransactTime, epoch ns, exclusive; 0 is no bound
+}
@@ -0,0 +1,53 @@
+package model
+
+import "github.com/shopspring/decimal"
+
+// MDUpdateAction FIX <279>
+type MDUpdateAction string
+
+const (
+	MDUpdateActionNew    MDUpdateAction = "0"
+	MDUpdateActionChange MDUpdateAction = "1"
+	MDUpdateActionDelete MDUpdateAction = "2"
+)
+
+// MDEntryType FIX <269>
+type MDEntryType string
+
+const (
+	MDEntryTypeBid   MDEntryType = "0"
+	MDEntryTypeOffer MDEntryType = "1"
+)
+
+// MDEntry is one price level of a NoMDEntries <268> repeating group. Size is
+// the aggregated LeavesQty resting at Px.
+type MDEntry struct {
+	UpdateAction MDUpdateAction  `json:"279,omitempty"` // Only on incremental refreshes
+	EntryType    MDEntryType     `json:"269"`
+	Px           decimal.Decimal `json:"270"`
+	Size         decimal.Decimal `json:"271"`
+}
+
+// MarketDataIncrementalRefresh represents a FIX X message carrying the price
+// levels changed by one order book event.
+type MarketDataIncrementalRefresh struct {
+	MsgType      string    `json:"35"`       // always "X"
+	Symbol       string    `json:"55"`       // Symbol
+	SeqNum       uint64    `json:"seq_num"`  // Per-symbol depth sequence, starts at 1
+	TransactTime int64     `json:"60"`       // Epoch ns
+	Depth        int       `json:"depth"`    // Levels per side covered by Checksum
+	Checksum     uint32    `json:"checksum"` // CRC32 of the top Depth levels after applying Entries
+	Entries      []MDEntry `json:"268"`      // NoMDEntries
+}
+
+// MarketDataSnapshotFullRefresh represents a FIX W message with every price
+// level of the book as of SeqNum, bids best first followed by offers best first.
+type MarketDataSnapshotFullRefresh struct {
+	MsgType      string    `json:"35"` // always "W"
+	Symbol       string    `json:"55"`
+	SeqNum       uint64    `json:"seq_num"`
+	TransactTime int64     `json:"60"`
+	Depth        int       `json:"depth"`
+	Checksum     uint32    `json:"checksum"`
+	Entries      []MDEntry `json:"268"`
+}
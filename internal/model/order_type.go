@@ -0,0 +1,15 @@
+package model
+
+// OrdType FIX OrdType <40>
+type OrdType string
+
+const (
+	OrdTypeLimit OrdType = "2" // The only type the engine matches
+)
+
+// TimeInForce FIX TimeInForce <59>
+type TimeInForce string
+
+const (
+	TimeInForceGTC TimeInForce = "1" // Good Till Cancel: orders rest until filled or canceled
+)
@@ -10,21 +10,80 @@ import (
 type MsgType string
 
 const (
-	MsgTypeNew         MsgType = "D"  // New Order - Single
-	MsgTypeCancel      MsgType = "F"  // Order Cancel Request
-	MsgTypeExecRpt     MsgType = "8"  // Execution Report
-	MsgTypeTradeReport MsgType = "AE" // Trade Capture Report
+	MsgTypeNew          MsgType = "D"  // New Order - Single
+	MsgTypeCancel       MsgType = "F"  // Order Cancel Request
+	MsgTypeReplace      MsgType = "G"  // Order Cancel/Replace Request
+	MsgTypeExecRpt      MsgType = "8"  // Execution Report
+	MsgTypeTradeReport  MsgType = "AE" // Trade Capture Report
+	MsgTypeMDSnapshot   MsgType = "W"  // Market Data - Snapshot/Full Refresh
+	MsgTypeMDIncRefresh MsgType = "X"  // Market Data - Incremental Refresh
+	MsgTypeBusinessRej  MsgType = "j"  // Business Message Reject
 )
 
 type OrderRequest struct {
-	MsgType        MsgType            `json:"35"`
-	NewOrderReq    NewOrderRequest    `json:"new_order,omitempty"`
-	CancelOrderReq OrderCancelRequest `json:"cancel_order,omitempty"`
+	MsgType         MsgType                    `json:"35"`
+	NewOrderReq     NewOrderRequest            `json:"new_order,omitempty"`
+	CancelOrderReq  OrderCancelRequest         `json:"cancel_order,omitempty"`
+	ReplaceOrderReq *OrderCancelReplaceRequest `json:"replace_order,omitempty"`
+}
+
+// ClOrdID returns the ClOrdID of the wrapped request, or "" for an unknown
+// type.
+func (r OrderRequest) ClOrdID() string {
+	switch r.MsgType {
+	case MsgTypeNew:
+		return r.NewOrderReq.ClOrdID
+	case MsgTypeCancel:
+		return r.CancelOrderReq.ClOrdID
+	case MsgTypeReplace:
+		if r.ReplaceOrderReq == nil {
+			return ""
+		}
+		return r.ReplaceOrderReq.ClOrdID
+	default:
+		return ""
+	}
+}
+
+// Symbol returns the symbol of the wrapped request, or "" for an unknown type.
+func (r OrderRequest) Symbol() string {
+	switch r.MsgType {
+	case MsgTypeNew:
+		return r.NewOrderReq.Symbol
+	case MsgTypeCancel:
+		return r.CancelOrderReq.Symbol
+	case MsgTypeReplace:
+		if r.ReplaceOrderReq == nil {
+			return ""
+		}
+		return r.ReplaceOrderReq.Symbol
+	default:
+		return ""
+	}
+}
+
+// TransactTime returns the TransactTime <60> the client sent with the wrapped
+// request, or 0 for an unknown type.
+func (r OrderRequest) TransactTime() int64 {
+	switch r.MsgType {
+	case MsgTypeNew:
+		return r.NewOrderReq.TransactTime
+	case MsgTypeCancel:
+		return r.CancelOrderReq.TransactTime
+	case MsgTypeReplace:
+		if r.ReplaceOrderReq == nil {
+			return 0
+		}
+		return r.ReplaceOrderReq.TransactTime
+	default:
+		return 0
+	}
 }
 
 // BaseOrderRequest Common fields across different FIX messages
 type BaseOrderRequest struct {
 	MsgType      MsgType `json:"35"`
+	Account      string  `json:"1,omitempty"`  // FIX <1> - Account the order is entered for
 	ClOrdID      string  `json:"11"`           // FIX <11> - Unique client order ID
 	Side         Side    `json:"54"`           // FIX <54> - 1=Buy, 2=Sell
 	Symbol       string  `json:"55"`           // FIX <55> - Symbol
@@ -43,6 +102,29 @@ type OrderCancelRequest struct {
 	OrigClOrdID string `json:"41"` // FIX <41> - Original client order ID
 }
 
+// OrderCancelReplaceRequest amends the quantity and price of a resting order.
+// The order is known by ClOrdID from then on.
+type OrderCancelReplaceRequest struct {
+	BaseOrderRequest
+	OrigClOrdID string          `json:"41"` // FIX <41> - Original client order ID
+	OrderQty    decimal.Decimal `json:"38"` // FIX <38> - New total quantity, fills included
+	Price       decimal.Decimal `json:"44"` // FIX <44> - New limit price
+}
+
+func (or *OrderCancelReplaceRequest) ValidateReplace() error {
+	switch {
+	case or.ClOrdID == "":
+		return errors.New("missing client order ID")
+	case or.OrigClOrdID == "":
+		return errors.New("missing original client order ID")
+	case or.OrderQty.IsZero() || or.OrderQty.IsNegative():
+		return errors.New("invalid order quantity")
+	case or.Price.IsNegative():
+		return errors.New("invalid price")
+	}
+	return nil
+}
+
 func (or *NewOrderRequest) ValidateNewOrder() error {
 	switch {
 	case or.ClOrdID == "":
@@ -9,6 +9,8 @@ import (
 type NoSides struct {
 	Side    Side   `json:"54"` // Side: 1 = Buy, 2 = Sell
 	OrderID string `json:"37"`
+	ClOrdID string `json:"11,omitempty"`
+	ExecID  string `json:"17,omitempty"` // ExecID of the side's fill report
 }
 
 // TradeCaptureReport represents a FIX AE message (Trade Capture Report)
@@ -16,6 +18,7 @@ type TradeCaptureReport struct {
 	MsgType       string          `json:"35"`            // MsgType = AE (Trade Capture Report)
 	TradeReportID string          `json:"571"`           // Unique ID for this Trade Report
 	ExecID        string          `json:"17"`            // Execution ID
+	TradeID       string          `json:"1003"`          // Shared with the fill reports of both sides
 	Symbol        string          `json:"55"`            // Ticker Symbol
 	LastQty       decimal.Decimal `json:"32"`            // Quantity traded
 	LastPx        decimal.Decimal `json:"31"`            // Price traded
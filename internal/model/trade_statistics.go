@@ -0,0 +1,20 @@
+package model
+
+import "github.com/shopspring/decimal"
+
+// TradeStatistics is a rolling-window summary of the trades executed on a symbol.
+type TradeStatistics struct {
+	Symbol             string          `json:"symbol"`
+	WindowStart        int64           `json:"window_start"` // Epoch ns, inclusive
+	WindowEnd          int64           `json:"window_end"`   // Epoch ns
+	OpenPx             decimal.Decimal `json:"open_px"`
+	HighPx             decimal.Decimal `json:"high_px"`
+	LowPx              decimal.Decimal `json:"low_px"`
+	LastPx             decimal.Decimal `json:"last_px"`
+	Vwap               decimal.Decimal `json:"vwap"`
+	Volume             decimal.Decimal `json:"volume"`
+	Notional           decimal.Decimal `json:"notional"`
+	TradeCount         int             `json:"trade_count"`
+	PriceChange        decimal.Decimal `json:"price_change"`
+	PriceChangePercent decimal.Decimal `json:"price_change_percent"`
+}
@@ -0,0 +1,471 @@
+package outbox
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"MatchingEngine/internal/model"
+)
+
+const (
+	segmentSuffix = ".outbox"
+	// uint32 payload length + uint32 CRC32-C of the payload + uint64 sequence
+	headerSize = 16
+
+	// DefaultSegmentBytes is the size after which a segment is rotated.
+	DefaultSegmentBytes = 16 << 20
+	maxRecordBytes      = 16 << 20
+)
+
+var (
+	ErrCorrupt = errors.New("outbox record is corrupt")
+	ErrClosed  = errors.New("outbox is closed")
+
+	crcTable = crc32.MakeTable(crc32.Castagnoli)
+)
+
+// Event is one execution or trade capture report bound for the execution and
+// DB topics. Key is the report's ExecID or TradeReportID, which consumers
+// use to drop a report relayed twice.
+type Event struct {
+	Seq   uint64
+	Key   string
+	Value json.RawMessage
+}
+
+type record struct {
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+type Opts struct {
+	SegmentBytes int64
+}
+
+// Outbox is the durable log every report is appended to before it is
+// published: the notifier the books report to returns once the report is on
+// disk, and a Relay publishes it to each topic on its own. Like the command
+// journal, it is a series of fsynced segment files named after the first
+// sequence they hold; segments every topic has received are trimmed.
+type Outbox struct {
+	path         string
+	dir          string // Empty once closed
+	segmentBytes int64
+	appended     chan struct{}
+
+	mu   sync.Mutex
+	seq  uint64
+	file *os.File
+	size int64
+}
+
+// Open recovers the last sequence from the newest segment, truncating a
+// record torn by a crash, and opens it for appending.
+func Open(dir string, opts Opts) (*Outbox, error) {
+	if opts.SegmentBytes <= 0 {
+		opts.SegmentBytes = DefaultSegmentBytes
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create outbox directory: %w", err)
+	}
+
+	o := &Outbox{path: dir, dir: dir, segmentBytes: opts.SegmentBytes, appended: make(chan struct{}, 1)}
+
+	segments, err := listSegments(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(segments) == 0 {
+		return o, nil
+	}
+
+	last := segments[len(segments)-1]
+	path := filepath.Join(dir, last.name)
+	seq, valid, err := scanSegment(path, nil)
+	if err != nil && !errors.Is(err, ErrCorrupt) {
+		return nil, err
+	}
+	if err != nil {
+		log.Printf("Truncating torn outbox record in %s at offset %d: %v", last.name, valid, err)
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open outbox segment: %w", err)
+	}
+	if err := f.Truncate(valid); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to truncate outbox segment: %w", err)
+	}
+	if _, err := f.Seek(valid, io.SeekStart); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to seek outbox segment: %w", err)
+	}
+
+	o.file = f
+	o.size = valid
+	o.seq = seq
+	if seq == 0 {
+		o.seq = last.first - 1
+	}
+	return o, nil
+}
+
+// NotifyEventAndTrade appends the report, so the outbox can stand at the end
+// of the notifier chain where the Kafka producer used to.
+func (o *Outbox) NotifyEventAndTrade(_ model.MsgType, key string, value json.RawMessage) error {
+	_, err := o.Append(key, value)
+	return err
+}
+
+// Append returns the sequence of the report once it is on disk.
+func (o *Outbox) Append(key string, value json.RawMessage) (uint64, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.dir == "" {
+		return 0, ErrClosed
+	}
+	event := Event{Seq: o.seq + 1, Key: key, Value: value}
+	rec, err := encodeRecord(event)
+	if err != nil {
+		return 0, err
+	}
+
+	if o.file == nil || (o.size > 0 && o.size+int64(len(rec)) > o.segmentBytes) {
+		if err := o.rotate(event.Seq); err != nil {
+			return 0, err
+		}
+	}
+	if _, err := o.file.Write(rec); err != nil {
+		return 0, fmt.Errorf("failed to write outbox record %d: %w", event.Seq, err)
+	}
+	if err := o.file.Sync(); err != nil {
+		return 0, fmt.Errorf("failed to sync outbox record %d: %w", event.Seq, err)
+	}
+	o.size += int64(len(rec))
+	o.seq = event.Seq
+
+	select {
+	case o.appended <- struct{}{}:
+	default:
+	}
+	return event.Seq, nil
+}
+
+// Appended is signalled after appends, for a relay waiting on new events.
+func (o *Outbox) Appended() <-chan struct{} {
+	return o.appended
+}
+
+// LastSeq returns the sequence of the last event appended.
+func (o *Outbox) LastSeq() uint64 {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.seq
+}
+
+// Dir returns the directory the outbox lives in.
+func (o *Outbox) Dir() string {
+	return o.path
+}
+
+func (o *Outbox) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.dir = ""
+	if o.file == nil {
+		return nil
+	}
+	err := o.file.Close()
+	o.file = nil
+	return err
+}
+
+// rotate must be called with o.mu held.
+func (o *Outbox) rotate(firstSeq uint64) error {
+	if o.file != nil {
+		if err := o.file.Close(); err != nil {
+			return fmt.Errorf("failed to close outbox segment: %w", err)
+		}
+		o.file = nil
+	}
+
+	f, err := os.OpenFile(filepath.Join(o.dir, segmentName(firstSeq)), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create outbox segment: %w", err)
+	}
+	if err := syncDir(o.dir); err != nil {
+		f.Close()
+		return err
+	}
+	o.file = f
+	o.size = 0
+	return nil
+}
+
+// Trim removes the segments holding only events up to seq. The segment being
+// appended to is kept.
+func (o *Outbox) Trim(seq uint64) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	segments, err := listSegments(o.path)
+	if err != nil {
+		return err
+	}
+	for i := 0; i+1 < len(segments); i++ {
+		if segments[i+1].first-1 > seq {
+			break
+		}
+		if err := os.Remove(filepath.Join(o.path, segments[i].name)); err != nil {
+			return fmt.Errorf("failed to remove outbox segment: %w", err)
+		}
+	}
+	return nil
+}
+
+// Read calls fn for every event after seq still in the outbox at dir, in
+// sequence order. A torn record at the end is treated as the end.
+func Read(dir string, after uint64, fn func(Event) error) error {
+	r := NewReader(dir, after)
+	defer r.Close()
+	for {
+		events, err := r.Next(256)
+		if err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return nil
+		}
+		for _, e := range events {
+			if err := fn(e); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Reader reads an outbox from a sequence on, following appends and
+// rotations. Events already trimmed are skipped.
+type Reader struct {
+	dir    string
+	next   uint64
+	file   *os.File
+	offset int64
+}
+
+func NewReader(dir string, after uint64) *Reader {
+	return &Reader{dir: dir, next: after + 1}
+}
+
+// Next returns up to max events, or none when it has caught up.
+func (r *Reader) Next(max int) ([]Event, error) {
+	if r.file == nil {
+		if err := r.open(); err != nil || r.file == nil {
+			return nil, err
+		}
+	}
+
+	var events []Event
+	for len(events) < max {
+		event, size, err := readRecord(r.file)
+		if err != nil {
+			// A partial record is one being appended: go back and try later.
+			if _, seekErr := r.file.Seek(r.offset, io.SeekStart); seekErr != nil {
+				return events, fmt.Errorf("failed to seek outbox segment: %w", seekErr)
+			}
+			if !errors.Is(err, io.EOF) && !errors.Is(err, ErrCorrupt) {
+				return events, err
+			}
+			// The writer moves to a new segment named after the next event.
+			if _, statErr := os.Stat(filepath.Join(r.dir, segmentName(r.next))); statErr == nil {
+				r.file.Close()
+				r.file = nil
+				if err := r.open(); err != nil {
+					return events, err
+				}
+				continue
+			}
+			return events, nil
+		}
+		r.offset += size
+		if event.Seq < r.next {
+			continue
+		}
+		if event.Seq != r.next {
+			return events, fmt.Errorf("outbox record has sequence %d, expected %d", event.Seq, r.next)
+		}
+		events = append(events, event)
+		r.next++
+	}
+	return events, nil
+}
+
+// open opens the segment holding r.next, or the oldest one when r.next has
+// been trimmed, and leaves r.file nil when there is nothing to read.
+func (r *Reader) open() error {
+	segments, err := listSegments(r.dir)
+	if err != nil || len(segments) == 0 {
+		return err
+	}
+	i := sort.Search(len(segments), func(i int) bool { return segments[i].first > r.next }) - 1
+	if i < 0 {
+		i = 0
+		r.next = segments[0].first
+	}
+	f, err := os.Open(filepath.Join(r.dir, segments[i].name))
+	if err != nil {
+		return fmt.Errorf("failed to open outbox segment: %w", err)
+	}
+	r.file = f
+	r.offset = 0
+	return nil
+}
+
+func (r *Reader) Close() error {
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+// scanSegment reads the records of one segment. It returns the last valid
+// sequence (0 for an empty segment) and the offset just past it.
+func scanSegment(path string, fn func(Event) error) (uint64, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to open outbox segment: %w", err)
+	}
+	defer f.Close()
+
+	var (
+		last   uint64
+		offset int64
+	)
+	for {
+		event, size, err := readRecord(f)
+		if errors.Is(err, io.EOF) {
+			return last, offset, nil
+		}
+		if err != nil {
+			return last, offset, fmt.Errorf("%w at offset %d", err, offset)
+		}
+		if last > 0 && event.Seq != last+1 {
+			return last, offset, fmt.Errorf("outbox record at offset %d has sequence %d, expected %d", offset, event.Seq, last+1)
+		}
+		if fn != nil {
+			if err := fn(event); err != nil {
+				return last, offset, err
+			}
+		}
+		last = event.Seq
+		offset += size
+	}
+}
+
+// readRecord returns io.EOF at a clean end and ErrCorrupt for a short or
+// damaged record.
+func readRecord(f io.Reader) (Event, int64, error) {
+	var header [headerSize]byte
+	if _, err := io.ReadFull(f, header[:]); err != nil {
+		if errors.Is(err, io.EOF) {
+			return Event{}, 0, io.EOF
+		}
+		return Event{}, 0, fmt.Errorf("%w: short header", ErrCorrupt)
+	}
+	length := binary.BigEndian.Uint32(header[0:4])
+	if length == 0 || length > maxRecordBytes {
+		return Event{}, 0, fmt.Errorf("%w: invalid length %d", ErrCorrupt, length)
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(f, payload); err != nil {
+		return Event{}, 0, fmt.Errorf("%w: short payload", ErrCorrupt)
+	}
+	if crc32.Checksum(payload, crcTable) != binary.BigEndian.Uint32(header[4:8]) {
+		return Event{}, 0, fmt.Errorf("%w: checksum mismatch", ErrCorrupt)
+	}
+	var rec record
+	if err := json.Unmarshal(payload, &rec); err != nil {
+		return Event{}, 0, fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+	event := Event{Seq: binary.BigEndian.Uint64(header[8:16]), Key: rec.Key, Value: rec.Value}
+	return event, headerSize + int64(length), nil
+}
+
+func encodeRecord(event Event) ([]byte, error) {
+	payload, err := json.Marshal(record{Key: event.Key, Value: event.Value})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal outbox event %d: %w", event.Seq, err)
+	}
+	if len(payload) > maxRecordBytes {
+		return nil, fmt.Errorf("outbox event %d is %d bytes, over the %d limit", event.Seq, len(payload), maxRecordBytes)
+	}
+	rec := make([]byte, headerSize+len(payload))
+	binary.BigEndian.PutUint32(rec[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(rec[4:8], crc32.Checksum(payload, crcTable))
+	binary.BigEndian.PutUint64(rec[8:16], event.Seq)
+	copy(rec[headerSize:], payload)
+	return rec, nil
+}
+
+type segment struct {
+	name  string
+	first uint64
+}
+
+func segmentName(firstSeq uint64) string {
+	return fmt.Sprintf("%020d%s", firstSeq, segmentSuffix)
+}
+
+// listSegments returns the segments of dir ordered by first sequence.
+func listSegments(dir string) ([]segment, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read outbox directory: %w", err)
+	}
+
+	var segments []segment
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, segmentSuffix) {
+			continue
+		}
+		first, err := strconv.ParseUint(strings.TrimSuffix(name, segmentSuffix), 10, 64)
+		if err != nil || first == 0 {
+			return nil, fmt.Errorf("unexpected outbox segment name %s", name)
+		}
+		segments = append(segments, segment{name: name, first: first})
+	}
+	sort.Slice(segments, func(i, k int) bool { return segments[i].first < segments[k].first })
+	return segments, nil
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("failed to open outbox directory: %w", err)
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("failed to sync outbox directory: %w", err)
+	}
+	return nil
+}
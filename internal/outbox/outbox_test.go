@@ -0,0 +1,114 @@
+package outbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func appendEvents(t *testing.T, o *Outbox, from, to int) {
+	t.Helper()
+	for i := from; i <= to; i++ {
+		_, err := o.Append(fmt.Sprintf("exec-%d", i), json.RawMessage(fmt.Sprintf(`{"17":"exec-%d"}`, i)))
+		require.NoError(t, err)
+	}
+}
+
+func readAll(t *testing.T, dir string, after uint64) []Event {
+	t.Helper()
+	var events []Event
+	require.NoError(t, Read(dir, after, func(e Event) error {
+		events = append(events, e)
+		return nil
+	}))
+	return events
+}
+
+func TestOutbox_AppendReopenAndTornTail(t *testing.T) {
+	dir := t.TempDir()
+	o, err := Open(dir, Opts{})
+	require.NoError(t, err)
+	appendEvents(t, o, 1, 3)
+	require.NoError(t, o.Close())
+
+	// A crash in the middle of the next append leaves half a record.
+	segment := filepath.Join(dir, segmentName(1))
+	f, err := os.OpenFile(segment, os.O_APPEND|os.O_WRONLY, 0o644)
+	require.NoError(t, err)
+	_, err = f.Write([]byte{0, 0, 0, 42, 1, 2})
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	o, err = Open(dir, Opts{})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), o.LastSeq())
+	seq, err := o.Append("exec-4", json.RawMessage(`{"17":"exec-4"}`))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), seq)
+	require.NoError(t, o.Close())
+
+	events := readAll(t, dir, 2)
+	require.Len(t, events, 2)
+	assert.Equal(t, uint64(3), events[0].Seq)
+	assert.Equal(t, "exec-4", events[1].Key)
+	assert.JSONEq(t, `{"17":"exec-4"}`, string(events[1].Value))
+}
+
+func TestReader_FollowsAppendsAcrossSegments(t *testing.T) {
+	dir := t.TempDir()
+	o, err := Open(dir, Opts{SegmentBytes: 100})
+	require.NoError(t, err)
+	defer o.Close()
+
+	r := NewReader(dir, 0)
+	defer r.Close()
+	events, err := r.Next(10)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	appendEvents(t, o, 1, 2)
+	events, err = r.Next(10)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+
+	// Every record fills a segment of its own from here on.
+	appendEvents(t, o, 3, 6)
+	events, err = r.Next(3)
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	assert.Equal(t, uint64(3), events[0].Seq)
+	events, err = r.Next(3)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, uint64(6), events[0].Seq)
+}
+
+func TestOutbox_TrimKeepsUnrelayedSegments(t *testing.T) {
+	dir := t.TempDir()
+	o, err := Open(dir, Opts{SegmentBytes: 100})
+	require.NoError(t, err)
+	defer o.Close()
+	appendEvents(t, o, 1, 5)
+
+	require.NoError(t, o.Trim(3))
+	segments, err := listSegments(dir)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), segments[0].first)
+
+	events := readAll(t, dir, 0)
+	require.Len(t, events, 2)
+	assert.Equal(t, uint64(4), events[0].Seq)
+
+	// The segment being appended to stays, even when everything is relayed.
+	require.NoError(t, o.Trim(5))
+	segments, err = listSegments(dir)
+	require.NoError(t, err)
+	require.Len(t, segments, 1)
+	appendEvents(t, o, 6, 6)
+	assert.Equal(t, uint64(6), o.LastSeq())
+}
@@ -0,0 +1,83 @@
+package outbox
+
+import "fmt"
+
+// SeqHeader carries an event's outbox sequence on the messages relayed to a
+// topic, which is what reconciliation matches them by.
+const SeqHeader = "outbox-seq"
+
+// Reconciliation compares the events the outbox still holds with the ones a
+// topic received.
+type Reconciliation struct {
+	Destination string
+	Relayed     uint64 // Cursor of the destination
+	Pending     uint64 // Appended but not relayed yet
+	Checked     int    // Outbox events up to the cursor compared
+	// Missing are events the cursor is past that the topic does not have,
+	// Duplicates the ones it has more than once and Mismatched the ones it
+	// has under another key.
+	Missing    []uint64
+	Duplicates []uint64
+	Mismatched []uint64
+}
+
+// OK tells whether the topic has every relayed event under its key.
+// Duplicates are fine, consumers drop them.
+func (r Reconciliation) OK() bool {
+	return len(r.Missing) == 0 && len(r.Mismatched) == 0
+}
+
+func (r Reconciliation) String() string {
+	return fmt.Sprintf("%s: relayed up to %d, %d pending, %d checked, %d missing, %d duplicated, %d mismatched",
+		r.Destination, r.Relayed, r.Pending, r.Checked, len(r.Missing), len(r.Duplicates), len(r.Mismatched))
+}
+
+// Reconcile compares the outbox at dir with the events the destination named
+// name received, given with their sequence and key. Duplicates are expected
+// after a relay crashed between publishing and saving its cursor; consumers
+// drop them by key.
+func Reconcile(dir, name string, received []Event) (Reconciliation, error) {
+	cursors, err := ReadCursors(dir)
+	if err != nil {
+		return Reconciliation{}, err
+	}
+	rec := Reconciliation{Destination: name, Relayed: cursors[name]}
+
+	type seen struct {
+		count int
+		key   string
+	}
+	topic := make(map[uint64]seen, len(received))
+	for _, e := range received {
+		s := topic[e.Seq]
+		s.count++
+		s.key = e.Key
+		topic[e.Seq] = s
+	}
+
+	var last uint64
+	err = Read(dir, 0, func(e Event) error {
+		last = e.Seq
+		if e.Seq > rec.Relayed {
+			return nil
+		}
+		rec.Checked++
+		s := topic[e.Seq]
+		switch {
+		case s.count == 0:
+			rec.Missing = append(rec.Missing, e.Seq)
+		case s.key != e.Key:
+			rec.Mismatched = append(rec.Mismatched, e.Seq)
+		case s.count > 1:
+			rec.Duplicates = append(rec.Duplicates, e.Seq)
+		}
+		return nil
+	})
+	if err != nil {
+		return Reconciliation{}, err
+	}
+	if last > rec.Relayed {
+		rec.Pending = last - rec.Relayed
+	}
+	return rec, nil
+}
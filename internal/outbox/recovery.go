@@ -0,0 +1,69 @@
+package outbox
+
+import (
+	"encoding/json"
+	"sync"
+
+	"MatchingEngine/internal/model"
+)
+
+// Notifier is what the books report to.
+type Notifier interface {
+	NotifyEventAndTrade(msgType model.MsgType, key string, value json.RawMessage) error
+}
+
+// Recovery stands in front of the gate while the command journal is replayed
+// at startup, when the gate drops every report. The books report after the
+// journal append, so a crash in between loses the reports of the entries
+// being processed; replaying those entries produces them again, under the
+// same keys. Recovery appends every replayed report whose key the outbox does
+// not hold. A report of a segment trimmed long ago is relayed once more, and
+// consumers drop it by key. Once Done, it only forwards to next.
+type Recovery struct {
+	outbox *Outbox
+	next   Notifier
+
+	mu   sync.Mutex
+	keys map[string]bool // Keys the outbox holds; nil once done
+}
+
+// NewRecovery reads the keys of the events the outbox holds.
+func NewRecovery(o *Outbox, next Notifier) (*Recovery, error) {
+	keys := make(map[string]bool)
+	err := Read(o.Dir(), 0, func(e Event) error {
+		keys[e.Key] = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Recovery{outbox: o, next: next, keys: keys}, nil
+}
+
+func (r *Recovery) NotifyEventAndTrade(msgType model.MsgType, key string, value json.RawMessage) error {
+	if err := r.recover(key, value); err != nil {
+		return err
+	}
+	return r.next.NotifyEventAndTrade(msgType, key, value)
+}
+
+func (r *Recovery) recover(key string, value json.RawMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.keys == nil || r.keys[key] {
+		return nil
+	}
+	if _, err := r.outbox.Append(key, value); err != nil {
+		return err
+	}
+	r.keys[key] = true
+	return nil
+}
+
+// Done ends the replay: from then on the reports reach the outbox through
+// the gate.
+func (r *Recovery) Done() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.keys = nil
+}
@@ -0,0 +1,220 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	cursorsFile = "cursors.json"
+
+	defaultBatchSize = 100
+	defaultRetryMin  = 100 * time.Millisecond
+	defaultRetryMax  = 10 * time.Second
+)
+
+// Destination is a topic the outbox is relayed to. Publish must deliver every
+// event or fail; a failed batch is published again, so a topic can see an
+// event more than once but never miss one.
+type Destination interface {
+	Name() string
+	Publish(ctx context.Context, events []Event) error
+}
+
+type RelayOpts struct {
+	BatchSize int
+	// A failed batch is retried after RetryMin, doubling up to RetryMax.
+	RetryMin time.Duration
+	RetryMax time.Duration
+}
+
+// Relay publishes the outbox to each destination from its own cursor, so a
+// topic that is down holds back neither the outbox nor the other topics. The
+// cursors are kept next to the segments and advance only after a batch is
+// acknowledged.
+type Relay struct {
+	outbox *Outbox
+	dests  []Destination
+	opts   RelayOpts
+
+	mu      sync.Mutex
+	cursors map[string]uint64
+}
+
+func NewRelay(outbox *Outbox, dests []Destination, opts RelayOpts) (*Relay, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultBatchSize
+	}
+	if opts.RetryMin <= 0 {
+		opts.RetryMin = defaultRetryMin
+	}
+	if opts.RetryMax < opts.RetryMin {
+		opts.RetryMax = max(defaultRetryMax, opts.RetryMin)
+	}
+	cursors, err := ReadCursors(outbox.Dir())
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range dests {
+		if _, ok := cursors[d.Name()]; !ok {
+			cursors[d.Name()] = 0
+		}
+	}
+	return &Relay{outbox: outbox, dests: dests, opts: opts, cursors: cursors}, nil
+}
+
+// Start relays until ctx ends. What is left is relayed on the next start.
+func (r *Relay) Start(ctx context.Context) error {
+	// Every destination waits on the outbox's signal through its own channel.
+	wakeups := make([]chan struct{}, len(r.dests))
+	var wg sync.WaitGroup
+	for i, d := range r.dests {
+		wakeups[i] = make(chan struct{}, 1)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.relay(ctx, d, wakeups[i])
+		}()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return nil
+		case <-r.outbox.Appended():
+			for _, w := range wakeups {
+				select {
+				case w <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}
+}
+
+func (r *Relay) relay(ctx context.Context, dest Destination, wakeup <-chan struct{}) {
+	reader := NewReader(r.outbox.Dir(), r.Cursor(dest.Name()))
+	defer reader.Close()
+
+	for {
+		events, err := reader.Next(r.opts.BatchSize)
+		if err != nil {
+			log.Printf("Error reading outbox for %s: %v", dest.Name(), err)
+		}
+		if len(events) == 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-wakeup:
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+		if !r.publish(ctx, dest, events) {
+			return
+		}
+		if err := r.advance(dest.Name(), events[len(events)-1].Seq); err != nil {
+			log.Printf("Error saving outbox cursor for %s: %v", dest.Name(), err)
+		}
+	}
+}
+
+// publish retries the batch until it goes through, and reports false when
+// ctx ends first.
+func (r *Relay) publish(ctx context.Context, dest Destination, events []Event) bool {
+	backoff := r.opts.RetryMin
+	for {
+		err := dest.Publish(ctx, events)
+		if err == nil {
+			return true
+		}
+		if ctx.Err() != nil {
+			return false
+		}
+		log.Printf("Failed to relay outbox events %d-%d to %s, retrying in %s: %v",
+			events[0].Seq, events[len(events)-1].Seq, dest.Name(), backoff, err)
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, r.opts.RetryMax)
+	}
+}
+
+// advance saves the cursor and trims the segments every destination is past.
+func (r *Relay) advance(name string, seq uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.cursors[name] = seq
+	if err := writeCursors(r.outbox.Dir(), r.cursors); err != nil {
+		return err
+	}
+	lowest := seq
+	for _, d := range r.dests {
+		lowest = min(lowest, r.cursors[d.Name()])
+	}
+	return r.outbox.Trim(lowest)
+}
+
+// Cursor returns the last sequence relayed to the destination.
+func (r *Relay) Cursor(name string) uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cursors[name]
+}
+
+// ReadCursors returns the last sequence relayed to each destination of the
+// outbox at dir.
+func ReadCursors(dir string) (map[string]uint64, error) {
+	data, err := os.ReadFile(filepath.Join(dir, cursorsFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]uint64{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read outbox cursors: %w", err)
+	}
+	cursors := map[string]uint64{}
+	if err := json.Unmarshal(data, &cursors); err != nil {
+		return nil, fmt.Errorf("invalid outbox cursors: %w", err)
+	}
+	return cursors, nil
+}
+
+// writeCursors replaces the cursors file, so a crash leaves the old or the
+// new cursors but never a mix.
+func writeCursors(dir string, cursors map[string]uint64) error {
+	data, err := json.Marshal(cursors)
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(dir, cursorsFile+".tmp")
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to write outbox cursors: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write outbox cursors: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to sync outbox cursors: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write outbox cursors: %w", err)
+	}
+	if err := os.Rename(tmp, filepath.Join(dir, cursorsFile)); err != nil {
+		return fmt.Errorf("failed to write outbox cursors: %w", err)
+	}
+	return syncDir(dir)
+}
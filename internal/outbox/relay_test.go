@@ -0,0 +1,164 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTopic records what it is sent, failing while down.
+type fakeTopic struct {
+	name string
+
+	mu       sync.Mutex
+	down     bool
+	attempts int
+	received []Event
+}
+
+func (t *fakeTopic) Name() string { return t.name }
+
+func (t *fakeTopic) Publish(_ context.Context, events []Event) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.attempts++
+	if t.down {
+		return errors.New("broker unavailable")
+	}
+	for _, e := range events {
+		t.received = append(t.received, Event{Seq: e.Seq, Key: e.Key})
+	}
+	return nil
+}
+
+func (t *fakeTopic) setDown(down bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.down = down
+}
+
+func (t *fakeTopic) count() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.received)
+}
+
+func (t *fakeTopic) publishAttempts() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.attempts
+}
+
+func startRelay(t *testing.T, o *Outbox, topics ...*fakeTopic) (*Relay, func()) {
+	dests := make([]Destination, len(topics))
+	for i, topic := range topics {
+		dests[i] = topic
+	}
+	relay, err := NewRelay(o, dests, RelayOpts{BatchSize: 2, RetryMin: time.Millisecond, RetryMax: 2 * time.Millisecond})
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.NoError(t, relay.Start(ctx))
+	}()
+	return relay, func() {
+		cancel()
+		<-done
+	}
+}
+
+func TestRelay_TopicDownHoldsBackOnlyItself(t *testing.T) {
+	dir := t.TempDir()
+	o, err := Open(dir, Opts{SegmentBytes: 100})
+	require.NoError(t, err)
+	defer o.Close()
+
+	executions := &fakeTopic{name: "executionTopic"}
+	db := &fakeTopic{name: "dbUpdateTopic", down: true}
+	relay, stop := startRelay(t, o, executions, db)
+
+	appendEvents(t, o, 1, 5)
+	require.Eventually(t, func() bool { return executions.count() == 5 }, 5*time.Second, time.Millisecond)
+	require.Eventually(t, func() bool { return db.publishAttempts() > 3 }, 5*time.Second, time.Millisecond)
+	assert.Equal(t, uint64(5), relay.Cursor("executionTopic"))
+	assert.Equal(t, uint64(0), relay.Cursor("dbUpdateTopic"))
+
+	// Nothing the DB topic still needs has been trimmed.
+	events := readAll(t, dir, 0)
+	require.Len(t, events, 5)
+
+	db.setDown(false)
+	require.Eventually(t, func() bool { return db.count() == 5 }, 5*time.Second, time.Millisecond)
+	require.Eventually(t, func() bool { return relay.Cursor("dbUpdateTopic") == 5 }, 5*time.Second, time.Millisecond)
+	stop()
+
+	assert.Equal(t, executions.received, db.received)
+	segments, err := listSegments(dir)
+	require.NoError(t, err)
+	assert.Len(t, segments, 1)
+}
+
+func TestRelay_ResumesFromCursorsAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	o, err := Open(dir, Opts{})
+	require.NoError(t, err)
+
+	executions := &fakeTopic{name: "executionTopic"}
+	_, stop := startRelay(t, o, executions)
+	appendEvents(t, o, 1, 3)
+	require.Eventually(t, func() bool { return executions.count() == 3 }, 5*time.Second, time.Millisecond)
+	stop()
+
+	// Appended while the relay was stopped, e.g. with the broker down.
+	appendEvents(t, o, 4, 5)
+	require.NoError(t, o.Close())
+
+	o, err = Open(dir, Opts{})
+	require.NoError(t, err)
+	defer o.Close()
+	relay, stop := startRelay(t, o, executions)
+	defer stop()
+	require.Eventually(t, func() bool { return relay.Cursor("executionTopic") == 5 }, 5*time.Second, time.Millisecond)
+
+	seqs := make([]uint64, 0, 5)
+	for _, e := range executions.received {
+		seqs = append(seqs, e.Seq)
+	}
+	assert.Equal(t, []uint64{1, 2, 3, 4, 5}, seqs)
+}
+
+func TestReconcile(t *testing.T) {
+	dir := t.TempDir()
+	o, err := Open(dir, Opts{})
+	require.NoError(t, err)
+	defer o.Close()
+	appendEvents(t, o, 1, 5)
+	require.NoError(t, writeCursors(dir, map[string]uint64{"executionTopic": 4}))
+
+	received := []Event{
+		{Seq: 1, Key: "exec-1"},
+		{Seq: 2, Key: "exec-2"},
+		{Seq: 2, Key: "exec-2"},
+		{Seq: 3, Key: "exec-9"},
+	}
+	rec, err := Reconcile(dir, "executionTopic", received)
+	require.NoError(t, err)
+	assert.False(t, rec.OK())
+	assert.Equal(t, uint64(4), rec.Relayed)
+	assert.Equal(t, uint64(1), rec.Pending)
+	assert.Equal(t, 4, rec.Checked)
+	assert.Equal(t, []uint64{4}, rec.Missing)
+	assert.Equal(t, []uint64{2}, rec.Duplicates)
+	assert.Equal(t, []uint64{3}, rec.Mismatched)
+
+	received = append(received[:3], Event{Seq: 3, Key: "exec-3"}, Event{Seq: 4, Key: "exec-4"})
+	rec, err = Reconcile(dir, "executionTopic", received)
+	require.NoError(t, err)
+	assert.True(t, rec.OK())
+}
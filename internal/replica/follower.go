@@ -0,0 +1,106 @@
+package replica
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"MatchingEngine/internal/journal"
+	"MatchingEngine/internal/model"
+)
+
+var ErrSequenceGap = errors.New("journal sequence gap")
+
+// Source delivers the primary's journal entries.
+type Source interface {
+	// ReadFrom calls fn for every entry after seq that is available now, in
+	// sequence order, and returns once it has reached the end.
+	ReadFrom(ctx context.Context, after uint64, fn func(journal.Entry) error) error
+}
+
+// EntryWriter records followed entries in the standby's own journal so that
+// its sequence continues from the primary's after promotion.
+type EntryWriter interface {
+	Write(entry journal.Entry) error
+}
+
+type Applier interface {
+	Apply(seq uint64, ts int64, req model.OrderRequest) error
+}
+
+// Follower keeps a standby's books in step with the primary by applying the
+// primary's journal entries in sequence.
+type Follower struct {
+	source  Source
+	journal EntryWriter
+	books   Applier
+	poll    time.Duration
+	last    uint64
+}
+
+// NewFollower creates a follower that resumes after seq, the lowest journal
+// sequence applied by any of the standby's books.
+func NewFollower(source Source, journal EntryWriter, books Applier, after uint64, poll time.Duration) *Follower {
+	if poll <= 0 {
+		poll = time.Second
+	}
+	return &Follower{
+		source:  source,
+		journal: journal,
+		books:   books,
+		poll:    poll,
+		last:    after,
+	}
+}
+
+// Run follows the source until the context is cancelled. A gap or a failure
+// to apply an entry stops it, as the standby can no longer take over safely.
+func (f *Follower) Run(ctx context.Context) error {
+	ticker := time.NewTicker(f.poll)
+	defer ticker.Stop()
+
+	for {
+		if err := f.CatchUp(ctx); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// CatchUp applies every entry available now. On promotion it runs once more
+// after Run has stopped, so the new primary resumes from the last entry the
+// old one journaled.
+func (f *Follower) CatchUp(ctx context.Context) error {
+	return f.source.ReadFrom(ctx, f.last, f.apply)
+}
+
+// LastApplied returns the sequence of the last entry applied.
+func (f *Follower) LastApplied() uint64 {
+	return f.last
+}
+
+func (f *Follower) apply(entry journal.Entry) error {
+	if entry.Seq <= f.last {
+		return nil
+	}
+	if entry.Seq != f.last+1 {
+		return fmt.Errorf("%w: expected %d got %d", ErrSequenceGap, f.last+1, entry.Seq)
+	}
+	if err := f.journal.Write(entry); err != nil {
+		return fmt.Errorf("failed to journal entry %d: %w", entry.Seq, err)
+	}
+	if err := f.books.Apply(entry.Seq, entry.Timestamp, entry.Request); err != nil {
+		log.Printf("Skipping journal entry %d: %v", entry.Seq, err)
+	}
+	f.last = entry.Seq
+	return nil
+}
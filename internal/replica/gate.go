@@ -0,0 +1,115 @@
+package replica
+
+import (
+	"encoding/json"
+	"sync/atomic"
+
+	"MatchingEngine/internal/journal"
+	"MatchingEngine/internal/model"
+)
+
+// Gate holds back everything a standby would otherwise publish. It is opened
+// on promotion and closed again when the engine loses its lease, as another
+// engine may be promoted from then on.
+type Gate struct {
+	open atomic.Bool
+}
+
+func NewGate(open bool) *Gate {
+	g := &Gate{}
+	g.open.Store(open)
+	return g
+}
+
+func (g *Gate) Open() {
+	g.open.Store(true)
+}
+
+func (g *Gate) Close() {
+	g.open.Store(false)
+}
+
+func (g *Gate) IsOpen() bool {
+	return g.open.Load()
+}
+
+type Notifier interface {
+	NotifyEventAndTrade(msgType model.MsgType, orderID string, value json.RawMessage) error
+}
+
+type MarketDataPublisher interface {
+	PublishMarketData(symbol string, value json.RawMessage) error
+}
+
+type StatsPublisher interface {
+	PublishStatistics(symbol string, value json.RawMessage) error
+}
+
+// GatedNotifier drops execution and trade reports while the gate is closed.
+type GatedNotifier struct {
+	gate *Gate
+	next Notifier
+}
+
+func NewGatedNotifier(gate *Gate, next Notifier) *GatedNotifier {
+	return &GatedNotifier{gate: gate, next: next}
+}
+
+func (n *GatedNotifier) NotifyEventAndTrade(msgType model.MsgType, key string, value json.RawMessage) error {
+	if !n.gate.IsOpen() {
+		return nil
+	}
+	return n.next.NotifyEventAndTrade(msgType, key, value)
+}
+
+// GatedMarketDataPublisher drops depth messages while the gate is closed.
+type GatedMarketDataPublisher struct {
+	gate *Gate
+	next MarketDataPublisher
+}
+
+func NewGatedMarketDataPublisher(gate *Gate, next MarketDataPublisher) *GatedMarketDataPublisher {
+	return &GatedMarketDataPublisher{gate: gate, next: next}
+}
+
+func (p *GatedMarketDataPublisher) PublishMarketData(symbol string, value json.RawMessage) error {
+	if !p.gate.IsOpen() {
+		return nil
+	}
+	return p.next.PublishMarketData(symbol, value)
+}
+
+// GatedStatsPublisher drops statistics while the gate is closed.
+type GatedStatsPublisher struct {
+	gate *Gate
+	next StatsPublisher
+}
+
+func NewGatedStatsPublisher(gate *Gate, next StatsPublisher) *GatedStatsPublisher {
+	return &GatedStatsPublisher{gate: gate, next: next}
+}
+
+func (p *GatedStatsPublisher) PublishStatistics(symbol string, value json.RawMessage) error {
+	if !p.gate.IsOpen() {
+		return nil
+	}
+	return p.next.PublishStatistics(symbol, value)
+}
+
+// GatedSink stops a standby from replicating the entries it follows back to
+// the journal topic.
+type GatedSink struct {
+	gate *Gate
+	next journal.Sink
+}
+
+func NewGatedSink(gate *Gate, next journal.Sink) *GatedSink {
+	return &GatedSink{gate: gate, next: next}
+}
+
+func (s *GatedSink) Publish(entry journal.Entry) error {
+	if !s.gate.IsOpen() {
+		return nil
+	}
+	return s.next.Publish(entry)
+}
@@ -0,0 +1,139 @@
+package replica
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrLeaseLost = errors.New("engine lease lost")
+
+// Locker is a session-level lock: it is held until released or until the
+// session that took it ends.
+type Locker interface {
+	TryLock(ctx context.Context) (bool, error)
+	// Check fails once the session holding the lock is gone.
+	Check(ctx context.Context) error
+	Unlock(ctx context.Context) error
+}
+
+// Lease makes one engine the primary. The holder keeps checking its session
+// and must stop publishing as soon as the lease is lost; a standby waits for
+// the lease and is promoted when it gets it.
+type Lease struct {
+	locker   Locker
+	interval time.Duration
+}
+
+func NewLease(locker Locker, interval time.Duration) *Lease {
+	if interval <= 0 {
+		interval = 2 * time.Second
+	}
+	return &Lease{locker: locker, interval: interval}
+}
+
+// Acquire blocks until the lease is held or the context is cancelled.
+func (l *Lease) Acquire(ctx context.Context) error {
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+
+	for {
+		ok, err := l.locker.TryLock(ctx)
+		switch {
+		case err != nil:
+			log.Printf("Error trying to acquire engine lease: %v", err)
+		case ok:
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Hold checks the lease until the context is cancelled, then releases it. It
+// returns ErrLeaseLost as soon as a check fails.
+func (l *Lease) Hold(ctx context.Context) error {
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := l.locker.Unlock(context.Background()); err != nil {
+				log.Printf("Error releasing engine lease: %v", err)
+			}
+			return nil
+		case <-ticker.C:
+			if err := l.locker.Check(ctx); err != nil && ctx.Err() == nil {
+				return fmt.Errorf("%w: %v", ErrLeaseLost, err)
+			}
+		}
+	}
+}
+
+// PgLocker takes a Postgres advisory lock on a connection it keeps out of the
+// pool, so the lock lives exactly as long as that session.
+type PgLocker struct {
+	pool *pgxpool.Pool
+	key  int64
+	conn *pgxpool.Conn
+}
+
+func NewPgLocker(pool *pgxpool.Pool, key int64) *PgLocker {
+	return &PgLocker{pool: pool, key: key}
+}
+
+func (l *PgLocker) TryLock(ctx context.Context) (bool, error) {
+	if l.conn == nil {
+		conn, err := l.pool.Acquire(ctx)
+		if err != nil {
+			return false, fmt.Errorf("failed to acquire lease connection: %w", err)
+		}
+		l.conn = conn
+	}
+
+	var locked bool
+	if err := l.conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", l.key).Scan(&locked); err != nil {
+		l.discard()
+		return false, fmt.Errorf("failed to try advisory lock: %w", err)
+	}
+	return locked, nil
+}
+
+func (l *PgLocker) Check(ctx context.Context) error {
+	if l.conn == nil {
+		return errors.New("no lease connection")
+	}
+	if err := l.conn.Ping(ctx); err != nil {
+		l.discard()
+		return err
+	}
+	return nil
+}
+
+func (l *PgLocker) Unlock(ctx context.Context) error {
+	if l.conn == nil {
+		return nil
+	}
+	defer l.discard()
+	_, err := l.conn.Exec(ctx, "SELECT pg_advisory_unlock($1)", l.key)
+	return err
+}
+
+// discard closes the session rather than returning it to the pool, which
+// also drops any advisory lock it still holds.
+func (l *PgLocker) discard() {
+	if l.conn == nil {
+		return
+	}
+	l.conn.Conn().Close(context.Background())
+	l.conn.Release()
+	l.conn = nil
+}
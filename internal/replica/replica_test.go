@@ -0,0 +1,274 @@
+package replica
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"MatchingEngine/internal/journal"
+	"MatchingEngine/internal/model"
+	"MatchingEngine/internal/outbox"
+	"MatchingEngine/internal/service"
+	"MatchingEngine/orderBook"
+)
+
+type fakeLocker struct {
+	mu       sync.Mutex
+	grantAt  int // TryLock succeeds from this attempt on, 0 never
+	attempts int
+	checkErr error
+	unlocked bool
+}
+
+func (l *fakeLocker) TryLock(context.Context) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.attempts++
+	return l.grantAt > 0 && l.attempts >= l.grantAt, nil
+}
+
+func (l *fakeLocker) Check(context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.checkErr
+}
+
+func (l *fakeLocker) Unlock(context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.unlocked = true
+	return nil
+}
+
+func (l *fakeLocker) grant() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.grantAt = l.attempts + 1
+}
+
+type countingNotifier struct {
+	mu    sync.Mutex
+	count int
+}
+
+func (n *countingNotifier) NotifyEventAndTrade(model.MsgType, string, json.RawMessage) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.count++
+	return nil
+}
+
+func (n *countingNotifier) Count() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.count
+}
+
+type sliceSource struct {
+	entries []journal.Entry
+}
+
+func (s *sliceSource) ReadFrom(_ context.Context, after uint64, fn func(journal.Entry) error) error {
+	for _, e := range s.entries {
+		if e.Seq <= after {
+			continue
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newOrder(clOrdID string, side model.Side, px, qty int64) model.OrderRequest {
+	return model.OrderRequest{
+		MsgType: model.MsgTypeNew,
+		NewOrderReq: model.NewOrderRequest{
+			BaseOrderRequest: model.BaseOrderRequest{
+				MsgType: model.MsgTypeNew,
+				ClOrdID: clOrdID,
+				Side:    side,
+				Symbol:  "BTC/USDT",
+			},
+			OrderQty: decimal.NewFromInt(qty),
+			Price:    decimal.NewFromInt(px),
+		},
+	}
+}
+
+func openJournal(t *testing.T) *journal.Journal {
+	j, err := journal.Open(t.TempDir(), journal.Opts{})
+	require.NoError(t, err)
+	t.Cleanup(func() { j.Close() })
+	return j
+}
+
+func TestFollower_AppliesInSequenceWithoutPublishing(t *testing.T) {
+	gate := NewGate(false)
+	published := &countingNotifier{}
+	orders := service.NewOrderService(NewGatedNotifier(gate, published), orderBook.BookOpts{})
+	own := openJournal(t)
+
+	source := &sliceSource{entries: []journal.Entry{
+		{Seq: 1, Timestamp: 10, Request: newOrder("S1", model.Sell, 100, 5)},
+		{Seq: 2, Timestamp: 20, Request: newOrder("B1", model.Buy, 100, 2)},
+	}}
+	follower := NewFollower(source, own, orders, 0, time.Millisecond)
+	require.NoError(t, follower.CatchUp(context.Background()))
+
+	assert.Equal(t, uint64(2), follower.LastApplied())
+	assert.Equal(t, uint64(2), own.LastSeq())
+	lowest, highest := orders.JournalRange()
+	assert.Equal(t, uint64(2), lowest)
+	assert.Equal(t, uint64(2), highest)
+	assert.Zero(t, published.Count())
+
+	// After promotion new requests continue the primary's sequence.
+	gate.Open()
+	seq, _, err := own.Append(newOrder("B2", model.Buy, 100, 1))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), seq)
+}
+
+func TestFollower_StopsOnGap(t *testing.T) {
+	source := &sliceSource{entries: []journal.Entry{
+		{Seq: 1, Request: newOrder("S1", model.Sell, 100, 5)},
+		{Seq: 3, Request: newOrder("S2", model.Sell, 100, 5)},
+	}}
+	orders := service.NewOrderService(&countingNotifier{}, orderBook.BookOpts{})
+	follower := NewFollower(source, openJournal(t), orders, 0, time.Millisecond)
+
+	err := follower.Run(context.Background())
+	assert.ErrorIs(t, err, ErrSequenceGap)
+	assert.Equal(t, uint64(1), follower.LastApplied())
+}
+
+func TestStandby_CatchesUpOnPromotion(t *testing.T) {
+	primaryDir := t.TempDir()
+	primary, err := journal.Open(primaryDir, journal.Opts{})
+	require.NoError(t, err)
+	defer primary.Close()
+	_, _, err = primary.Append(newOrder("S1", model.Sell, 100, 5))
+	require.NoError(t, err)
+
+	orders := service.NewOrderService(&countingNotifier{}, orderBook.BookOpts{})
+	follower := NewFollower(journal.NewDirSource(primaryDir), openJournal(t), orders, 0, time.Millisecond)
+	locker := &fakeLocker{}
+	lease := NewLease(locker, time.Millisecond)
+
+	promoted := make(chan error, 1)
+	go func() { promoted <- Standby(context.Background(), follower, lease) }()
+
+	// The primary journals one more request and goes away.
+	_, _, err = primary.Append(newOrder("S2", model.Sell, 101, 5))
+	require.NoError(t, err)
+	locker.grant()
+
+	select {
+	case err := <-promoted:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("standby was not promoted")
+	}
+	assert.Equal(t, uint64(2), follower.LastApplied())
+}
+
+func TestStandby_ReturnsWhenCancelled(t *testing.T) {
+	orders := service.NewOrderService(&countingNotifier{}, orderBook.BookOpts{})
+	follower := NewFollower(&sliceSource{}, openJournal(t), orders, 0, time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	err := Standby(ctx, follower, NewLease(&fakeLocker{}, time.Millisecond))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestLease_HoldDetectsLoss(t *testing.T) {
+	locker := &fakeLocker{grantAt: 1, checkErr: errors.New("connection reset")}
+	lease := NewLease(locker, time.Millisecond)
+	require.NoError(t, lease.Acquire(context.Background()))
+
+	err := lease.Hold(context.Background())
+	assert.ErrorIs(t, err, ErrLeaseLost)
+}
+
+func TestLease_HoldReleasesOnShutdown(t *testing.T) {
+	locker := &fakeLocker{grantAt: 1}
+	lease := NewLease(locker, time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.NoError(t, lease.Hold(ctx))
+	assert.True(t, locker.unlocked)
+}
+
+func TestRecover_ReappliesJournalTail(t *testing.T) {
+	dir := t.TempDir()
+	j, err := journal.Open(dir, journal.Opts{})
+	require.NoError(t, err)
+	for _, req := range []model.OrderRequest{newOrder("S1", model.Sell, 100, 5), newOrder("S2", model.Sell, 101, 5)} {
+		_, _, err := j.Append(req)
+		require.NoError(t, err)
+	}
+	require.NoError(t, j.Close())
+
+	orders := service.NewOrderService(&countingNotifier{}, orderBook.BookOpts{})
+	last, err := Recover(dir, orders, 1)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), last)
+
+	snapshots := orders.Snapshots(0)
+	require.Len(t, snapshots, 1)
+	assert.Contains(t, snapshots[0].OrderIndex, "S2")
+	assert.NotContains(t, snapshots[0].OrderIndex, "S1")
+}
+
+func TestRecover_AppendsReportsTheOutboxMissed(t *testing.T) {
+	dir, outboxDir := t.TempDir(), t.TempDir()
+	j, err := journal.Open(dir, journal.Opts{})
+	require.NoError(t, err)
+	box, err := outbox.Open(outboxDir, outbox.Opts{})
+	require.NoError(t, err)
+
+	orders := service.NewOrderService(box, orderBook.BookOpts{Journal: j})
+	require.NoError(t, orders.ProcessOrderRequest(newOrder("S1", model.Sell, 100, 5)))
+	require.Eventually(t, func() bool { return box.LastSeq() == 1 }, 5*time.Second, 10*time.Millisecond)
+	// The engine dies between journaling S2 and reporting it.
+	_, _, err = j.Append(newOrder("S2", model.Sell, 101, 5))
+	require.NoError(t, err)
+	require.NoError(t, j.Close())
+	require.NoError(t, box.Close())
+
+	box, err = outbox.Open(outboxDir, outbox.Opts{})
+	require.NoError(t, err)
+	defer box.Close()
+	gate := NewGate(false)
+	recovery, err := outbox.NewRecovery(box, NewGatedNotifier(gate, box))
+	require.NoError(t, err)
+	_, err = Recover(dir, service.NewOrderService(recovery, orderBook.BookOpts{}), 0)
+	require.NoError(t, err)
+	recovery.Done()
+
+	// S1 is not appended twice; S2 is appended under the ExecID it would
+	// have had.
+	var events []outbox.Event
+	require.NoError(t, outbox.Read(outboxDir, 0, func(e outbox.Event) error {
+		events = append(events, e)
+		return nil
+	}))
+	require.Len(t, events, 2)
+	var er model.ExecutionReport
+	require.NoError(t, json.Unmarshal(events[1].Value, &er))
+	assert.Equal(t, "S2", er.ClOrdID)
+	assert.Equal(t, er.ExecID, events[1].Key)
+
+	require.NoError(t, recovery.NotifyEventAndTrade(model.MsgTypeExecRpt, "late", json.RawMessage(`{}`)))
+	assert.Equal(t, uint64(2), box.LastSeq())
+}
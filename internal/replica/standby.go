@@ -0,0 +1,72 @@
+package replica
+
+import (
+	"context"
+	"fmt"
+
+	"MatchingEngine/internal/journal"
+)
+
+// Recover re-applies the entries of the engine's own journal after seq, the
+// lowest sequence its restored books have applied, e.g. after a crash between
+// two snapshots. Books skip entries they already hold. Run it before the gate
+// opens so nothing is published twice. It returns the last sequence read, or
+// after when there is none.
+func Recover(dir string, books Applier, after uint64) (uint64, error) {
+	last := after
+	err := journal.Read(dir, func(entry journal.Entry) error {
+		if entry.Seq <= after {
+			return nil
+		}
+		if err := books.Apply(entry.Seq, entry.Timestamp, entry.Request); err != nil {
+			return fmt.Errorf("failed to recover journal entry %d: %w", entry.Seq, err)
+		}
+		last = entry.Seq
+		return nil
+	})
+	return last, err
+}
+
+// Standby follows the primary until this engine acquires the lease, then
+// applies whatever the primary journaled before it went away. It returns nil
+// once the engine may be promoted, or an error if it must not take over.
+func Standby(ctx context.Context, follower *Follower, lease *Lease) error {
+	followCtx, stopFollowing := context.WithCancel(ctx)
+	defer stopFollowing()
+
+	followed := make(chan error, 1)
+	go func() {
+		followed <- follower.Run(followCtx)
+	}()
+
+	acquireCtx, stopAcquiring := context.WithCancel(ctx)
+	defer stopAcquiring()
+
+	acquired := make(chan error, 1)
+	go func() {
+		acquired <- lease.Acquire(acquireCtx)
+	}()
+
+	select {
+	case err := <-followed:
+		stopAcquiring()
+		if err == nil {
+			err = ctx.Err()
+		}
+		if lockErr := <-acquired; lockErr == nil {
+			if unlockErr := lease.locker.Unlock(context.Background()); unlockErr != nil {
+				return fmt.Errorf("%w; also failed to release lease: %v", err, unlockErr)
+			}
+		}
+		return err
+	case err := <-acquired:
+		stopFollowing()
+		if followErr := <-followed; followErr != nil {
+			return followErr
+		}
+		if err != nil {
+			return err
+		}
+		return follower.CatchUp(ctx)
+	}
+}
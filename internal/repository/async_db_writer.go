@@ -2,41 +2,132 @@ package repository
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"os"
+	"sync/atomic"
 	"time"
 )
 
 type AsyncDBWriterInterface interface {
-	EnqueueTask(task DBTask)
+	// EnqueueTask blocks while the queue is full, which holds back whoever
+	// produces the tasks. A task still waiting when ctx ends is completed
+	// with ctx.Err().
+	EnqueueTask(ctx context.Context, task DBTask)
 }
 
+type AsyncDBWriterOpts struct {
+	QueueSize int
+	Workers   int
+	// A task is tried MaxAttempts times, waiting from RetryMin up to RetryMax
+	// in between, before it is spilled. Once Start returns, a task is spilled
+	// at its first failure.
+	MaxAttempts  int
+	RetryMin     time.Duration
+	RetryMax     time.Duration
+	WriteTimeout time.Duration
+	// SpillDir keeps the tasks that ran out of attempts until the database
+	// takes them, tried every ReplayInterval. Without it such tasks fail.
+	SpillDir       string
+	ReplayInterval time.Duration
+}
+
+// AsyncDBWriterStats are the writer's metrics.
+type AsyncDBWriterStats struct {
+	QueueDepth    int   `json:"queue_depth"`
+	QueueCapacity int   `json:"queue_capacity"`
+	Retries       int64 `json:"retries"`
+	DeadLettered  int64 `json:"dead_lettered"`
+	Replayed      int64 `json:"replayed"`
+	Spilled       int64 `json:"spilled"`
+}
+
+const (
+	defaultWriterQueueSize   = 10
+	defaultWriterWorkers     = 5
+	defaultWriteAttempts     = 5
+	defaultWriteRetryMin     = 100 * time.Millisecond
+	defaultWriteRetryMax     = 5 * time.Second
+	defaultWriteTimeout      = 5 * time.Second
+	defaultSpillReplayPeriod = 10 * time.Second
+	// Replay stops after this many failures in a row and leaves the rest
+	// spilled, as the database is most likely still down.
+	maxReplayFailures = 3
+)
+
 type AsyncDBWriter struct {
+	opts        AsyncDBWriterOpts
 	taskChannel chan DBTask
 	execRepo    *PostgresExecutionRepository
 	tradeRepo   *PostgresTradeRepository
-	retryCount  int
-	timeout     time.Duration
+	batchRepo   BatchRepository
+	spill       *spillFile
+	// stop ends the retries of the workers when Start returns.
+	stop context.CancelFunc
+
+	retries      atomic.Int64
+	deadLettered atomic.Int64
+	replayed     atomic.Int64
 }
 
-func NewAsyncDBWriter(execRepo *PostgresExecutionRepository, tradeRepo *PostgresTradeRepository, bufferSize int) *AsyncDBWriter {
+func NewAsyncDBWriter(execRepo *PostgresExecutionRepository, tradeRepo *PostgresTradeRepository, batchRepo BatchRepository, opts AsyncDBWriterOpts) (*AsyncDBWriter, error) {
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = defaultWriterQueueSize
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = defaultWriterWorkers
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = defaultWriteAttempts
+	}
+	if opts.RetryMin <= 0 {
+		opts.RetryMin = defaultWriteRetryMin
+	}
+	if opts.RetryMax <= 0 {
+		opts.RetryMax = defaultWriteRetryMax
+	}
+	if opts.WriteTimeout <= 0 {
+		opts.WriteTimeout = defaultWriteTimeout
+	}
+	if opts.ReplayInterval <= 0 {
+		opts.ReplayInterval = defaultSpillReplayPeriod
+	}
+	ctx, stop := context.WithCancel(context.Background())
 	writer := &AsyncDBWriter{
-		taskChannel: make(chan DBTask, bufferSize),
+		opts:        opts,
+		stop:        stop,
+		taskChannel: make(chan DBTask, opts.QueueSize),
 		execRepo:    execRepo,
 		tradeRepo:   tradeRepo,
-		retryCount:  3,
-		timeout:     100 * time.Millisecond,
+		batchRepo:   batchRepo,
+	}
+	if opts.SpillDir != "" {
+		spill, err := openSpillFile(opts.SpillDir)
+		if err != nil {
+			stop()
+			return nil, err
+		}
+		writer.spill = spill
 	}
-	go writer.startWorkerPool(5)
-	return writer
+	go writer.startWorkerPool(ctx, opts.Workers)
+	return writer, nil
 }
 
-func (w *AsyncDBWriter) startWorkerPool(workerCount int) {
+func (w *AsyncDBWriter) startWorkerPool(ctx context.Context, workerCount int) {
 	for i := 0; i < workerCount; i++ {
 		go func(workerID int) {
 			for task := range w.taskChannel {
-				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-				err := w.executeTask(ctx, task)
-				cancel()
+				err := w.executeWithRetry(ctx, task)
+				if err != nil && w.spill != nil {
+					if spillErr := w.spill.append(task); spillErr != nil {
+						log.Printf("[Worker %d] Failed to spill task: %v", workerID, spillErr)
+					} else {
+						log.Printf("[Worker %d] Spilled task for replay: %v", workerID, err)
+						w.deadLettered.Add(1)
+						err = nil
+					}
+				}
+				task.Complete(err)
 
 				if err != nil {
 					log.Printf("[Worker %d] Failed to execute task: %v", workerID, err)
@@ -46,24 +137,135 @@ func (w *AsyncDBWriter) startWorkerPool(workerCount int) {
 	}
 }
 
+// executeWithRetry runs the task until it succeeds, is out of attempts or
+// ctx ends.
+func (w *AsyncDBWriter) executeWithRetry(ctx context.Context, task DBTask) error {
+	backoff := w.opts.RetryMin
+	for attempt := 1; ; attempt++ {
+		err := w.execute(ctx, task)
+		if err == nil || attempt >= w.opts.MaxAttempts {
+			return err
+		}
+		w.retries.Add(1)
+		log.Printf("Write attempt %d failed, retrying in %s: %v", attempt, backoff, err)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("gave up after %d attempts: %w", attempt, err)
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, w.opts.RetryMax)
+	}
+}
+
+func (w *AsyncDBWriter) execute(ctx context.Context, task DBTask) error {
+	ctx, cancel := context.WithTimeout(ctx, w.opts.WriteTimeout)
+	defer cancel()
+	return w.executeTask(ctx, task)
+}
+
 func (w *AsyncDBWriter) executeTask(ctx context.Context, task DBTask) error {
 	// Type assertion to determine the repository type
 	if execTask, ok := task.(SaveExecutionTask); ok {
 		return execTask.Execute(ctx, w.execRepo)
 	} else if tradeTask, ok := task.(SaveTradeTask); ok {
 		return tradeTask.Execute(ctx, w.tradeRepo)
+	} else if batchTask, ok := task.(SaveBatchTask); ok {
+		return batchTask.Execute(ctx, w.batchRepo)
 	}
 	return nil
 }
 
-func (w *AsyncDBWriter) EnqueueTask(task DBTask) {
-	for attempt := 0; attempt < w.retryCount; attempt++ {
+func (w *AsyncDBWriter) EnqueueTask(ctx context.Context, task DBTask) {
+	select {
+	case w.taskChannel <- task:
+		return
+	default:
+	}
+	log.Printf("Database write queue is full, waiting")
+	select {
+	case w.taskChannel <- task:
+	case <-ctx.Done():
+		task.Complete(ctx.Err())
+	}
+}
+
+// Start replays the spilled tasks every ReplayInterval until ctx ends. The
+// writes being retried then give up and are spilled.
+func (w *AsyncDBWriter) Start(ctx context.Context) error {
+	defer w.stop()
+	if w.spill == nil {
+		<-ctx.Done()
+		return nil
+	}
+	defer w.spill.close()
+
+	ticker := time.NewTicker(w.opts.ReplayInterval)
+	defer ticker.Stop()
+	for {
+		w.replay(ctx)
 		select {
-		case w.taskChannel <- task:
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// replay writes the spilled tasks again. Those that fail are spilled anew;
+// after maxReplayFailures in a row the rest are spilled without trying.
+func (w *AsyncDBWriter) replay(ctx context.Context) {
+	path, err := w.spill.take()
+	if err != nil {
+		log.Printf("Failed to take spilled tasks: %v", err)
+		return
+	}
+	if path == "" {
+		return
+	}
+	tasks, err := readSpill(path)
+	if err != nil {
+		log.Printf("Failed to read spilled tasks: %v", err)
+		return
+	}
+
+	var written, failures int
+	for _, task := range tasks {
+		if ctx.Err() != nil {
+			// The file is replayed again on the next start.
+			return
+		}
+		if failures < maxReplayFailures {
+			if err := w.execute(ctx, task); err == nil {
+				written++
+				failures = 0
+				w.replayed.Add(1)
+				w.spill.replayed(1)
+				continue
+			}
+			failures++
+		}
+		if err := w.spill.requeue(task); err != nil {
+			log.Printf("Failed to spill task again, keeping %s: %v", path, err)
 			return
-		case <-time.After(w.timeout):
-			log.Printf("Enqueue attempt %d timed out", attempt+1)
 		}
 	}
-	log.Println("Task channel is full after retries, dropping task")
+	if err := os.Remove(path); err != nil {
+		log.Printf("Failed to remove replayed spill file: %v", err)
+	}
+	log.Printf("Replayed %d of %d spilled tasks", written, len(tasks))
+}
+
+// Stats returns the writer's metrics.
+func (w *AsyncDBWriter) Stats() AsyncDBWriterStats {
+	stats := AsyncDBWriterStats{
+		QueueDepth:    len(w.taskChannel),
+		QueueCapacity: cap(w.taskChannel),
+		Retries:       w.retries.Load(),
+		DeadLettered:  w.deadLettered.Load(),
+		Replayed:      w.replayed.Load(),
+	}
+	if w.spill != nil {
+		stats.Spilled = w.spill.count()
+	}
+	return stats
 }
@@ -0,0 +1,216 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"MatchingEngine/internal/model"
+)
+
+// fakeBatchRepo fails while down and records the batches written.
+type fakeBatchRepo struct {
+	mu      sync.Mutex
+	down    bool
+	block   chan struct{}
+	written []Batch
+}
+
+func (r *fakeBatchRepo) SaveBatch(_ context.Context, batch Batch) error {
+	if r.block != nil {
+		<-r.block
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.down {
+		return errors.New("connection refused")
+	}
+	r.written = append(r.written, batch)
+	return nil
+}
+
+func (r *fakeBatchRepo) setDown(down bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.down = down
+}
+
+func (r *fakeBatchRepo) batches() []Batch {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Batch(nil), r.written...)
+}
+
+// execIDs lists the executions of the batches; decoded decimals do not
+// compare equal to zero values.
+func execIDs(batches ...Batch) []string {
+	var ids []string
+	for _, batch := range batches {
+		for _, er := range batch.Executions {
+			ids = append(ids, er.ExecID)
+		}
+	}
+	return ids
+}
+
+func batchOf(execIDs ...string) Batch {
+	var batch Batch
+	for _, id := range execIDs {
+		batch.Executions = append(batch.Executions, model.ExecutionReport{ExecID: id})
+	}
+	return batch
+}
+
+// enqueue queues the batch and returns the outcome of its write.
+func enqueue(ctx context.Context, w *AsyncDBWriter, batch Batch) <-chan error {
+	done := make(chan error, 1)
+	w.EnqueueTask(ctx, SaveBatchTask{Batch: batch, Done: func(err error) { done <- err }})
+	return done
+}
+
+func testWriterOpts(spillDir string) AsyncDBWriterOpts {
+	return AsyncDBWriterOpts{
+		QueueSize:      1,
+		Workers:        1,
+		MaxAttempts:    3,
+		RetryMin:       time.Millisecond,
+		RetryMax:       2 * time.Millisecond,
+		SpillDir:       spillDir,
+		ReplayInterval: 5 * time.Millisecond,
+	}
+}
+
+func TestAsyncDBWriter_EnqueueBlocksWhileQueueIsFull(t *testing.T) {
+	repo := &fakeBatchRepo{block: make(chan struct{})}
+	w, err := NewAsyncDBWriter(nil, nil, repo, testWriterOpts(""))
+	require.NoError(t, err)
+
+	// One task is being written and one fills the queue.
+	first := enqueue(context.Background(), w, batchOf("exec-1"))
+	require.Eventually(t, func() bool { return w.Stats().QueueDepth == 0 }, 5*time.Second, time.Millisecond)
+	second := enqueue(context.Background(), w, batchOf("exec-2"))
+	assert.Equal(t, 1, w.Stats().QueueDepth)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	third := enqueue(ctx, w, batchOf("exec-3"))
+	assert.ErrorIs(t, <-third, context.DeadlineExceeded)
+
+	close(repo.block)
+	assert.NoError(t, <-first)
+	assert.NoError(t, <-second)
+	assert.Len(t, repo.batches(), 2)
+}
+
+func TestAsyncDBWriter_RetriesFailedWrites(t *testing.T) {
+	repo := &fakeBatchRepo{down: true}
+	w, err := NewAsyncDBWriter(nil, nil, repo, testWriterOpts(""))
+	require.NoError(t, err)
+
+	// Without a spill dir the write fails once it is out of attempts.
+	assert.Error(t, <-enqueue(context.Background(), w, batchOf("exec-1")))
+	assert.Equal(t, int64(2), w.Stats().Retries)
+
+	go func() {
+		time.Sleep(time.Millisecond)
+		repo.setDown(false)
+	}()
+	w.opts.MaxAttempts = 1000
+	assert.NoError(t, <-enqueue(context.Background(), w, batchOf("exec-2")))
+	assert.Len(t, repo.batches(), 1)
+}
+
+func TestAsyncDBWriter_SpillsAndReplaysOnceTheDatabaseRecovers(t *testing.T) {
+	dir := t.TempDir()
+	repo := &fakeBatchRepo{down: true}
+	w, err := NewAsyncDBWriter(nil, nil, repo, testWriterOpts(dir))
+	require.NoError(t, err)
+
+	// A spilled task counts as done: it is on disk.
+	assert.NoError(t, <-enqueue(context.Background(), w, batchOf("exec-1")))
+	assert.NoError(t, <-enqueue(context.Background(), w, batchOf("exec-2", "exec-3")))
+	stats := w.Stats()
+	assert.Equal(t, int64(2), stats.DeadLettered)
+	assert.Equal(t, int64(2), stats.Spilled)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.NoError(t, w.Start(ctx))
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// Replays while the database is down leave the tasks spilled.
+	time.Sleep(20 * time.Millisecond)
+	assert.Empty(t, repo.batches())
+	assert.Equal(t, int64(2), w.Stats().Spilled)
+
+	repo.setDown(false)
+	require.Eventually(t, func() bool { return w.Stats().Spilled == 0 }, 5*time.Second, time.Millisecond)
+	assert.Len(t, repo.batches(), 2)
+	assert.Equal(t, []string{"exec-1", "exec-2", "exec-3"}, execIDs(repo.batches()...))
+	assert.Equal(t, int64(2), w.Stats().Replayed)
+}
+
+func TestAsyncDBWriter_SpillsRetriesCutShortByShutdown(t *testing.T) {
+	dir := t.TempDir()
+	repo := &fakeBatchRepo{down: true}
+	opts := testWriterOpts(dir)
+	opts.MaxAttempts = 1000
+	opts.RetryMin, opts.RetryMax = time.Hour, time.Hour
+	opts.ReplayInterval = time.Hour
+	w, err := NewAsyncDBWriter(nil, nil, repo, opts)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.NoError(t, w.Start(ctx))
+	}()
+
+	written := enqueue(context.Background(), w, batchOf("exec-1"))
+	require.Eventually(t, func() bool { return w.Stats().Retries == 1 }, 5*time.Second, time.Millisecond)
+	cancel()
+	<-done
+
+	// The task waiting out its backoff is on disk instead.
+	select {
+	case err := <-written:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the retry outlived the writer")
+	}
+	assert.Equal(t, int64(1), w.Stats().Spilled)
+}
+
+func TestSpillFile_SurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	spill, err := openSpillFile(dir)
+	require.NoError(t, err)
+	require.NoError(t, spill.append(SaveBatchTask{Batch: batchOf("exec-1")}))
+	require.NoError(t, spill.append(SaveExecutionTask{Execution: model.ExecutionReport{ExecID: "exec-2"}}))
+	require.NoError(t, spill.close())
+
+	spill, err = openSpillFile(dir)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), spill.count())
+	path, err := spill.take()
+	require.NoError(t, err)
+	tasks, err := readSpill(path)
+	require.NoError(t, err)
+	require.Len(t, tasks, 2)
+	require.IsType(t, SaveBatchTask{}, tasks[0])
+	assert.Equal(t, []string{"exec-1"}, execIDs(tasks[0].(SaveBatchTask).Batch))
+	require.IsType(t, SaveExecutionTask{}, tasks[1])
+	assert.Equal(t, "exec-2", tasks[1].(SaveExecutionTask).Execution.ExecID)
+}
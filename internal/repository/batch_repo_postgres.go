@@ -0,0 +1,211 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+
+	"MatchingEngine/internal/model"
+)
+
+// Batch is the reports the persistence consumer flushes together.
+type Batch struct {
+	Executions []model.ExecutionReport
+	Trades     []model.TradeCaptureReport
+}
+
+func (b Batch) Len() int {
+	return len(b.Executions) + len(b.Trades)
+}
+
+type BatchRepository interface {
+	SaveBatch(ctx context.Context, batch Batch) error
+}
+
+// TxBeginner is satisfied by *pgxpool.Pool and *pgx.Conn.
+type TxBeginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// COPY cannot skip rows that already exist, so a batch is copied into
+// staging tables dropped at commit and moved over with ON CONFLICT DO
+// NOTHING, which keeps a redelivered batch harmless.
+var (
+	createStagingTables = []string{
+		`CREATE TEMP TABLE executions_staging (LIKE executions INCLUDING DEFAULTS) ON COMMIT DROP`,
+		`CREATE TEMP TABLE trade_capture_reports_staging (LIKE trade_capture_reports INCLUDING DEFAULTS) ON COMMIT DROP`,
+		`CREATE TEMP TABLE trade_sides_staging (trade_report_id text, side smallint, order_id text, exec_id text, cl_ord_id text, trade_date date) ON COMMIT DROP`,
+	}
+	moveStagedRows = []string{
+		`INSERT INTO executions (` + executionColumnList + `)
+SELECT ` + executionColumnList + ` FROM executions_staging
+ON CONFLICT (exec_id, trade_date) DO NOTHING`,
+		`INSERT INTO trade_capture_reports (` + tradeColumnList + `)
+SELECT ` + tradeColumnList + ` FROM trade_capture_reports_staging
+ON CONFLICT (trade_report_id, trade_date) DO NOTHING`,
+		`INSERT INTO trade_sides (` + tradeSideColumnList + `)
+SELECT ` + tradeSideColumnList + ` FROM trade_sides_staging
+ON CONFLICT (trade_report_id, side, trade_date) DO NOTHING`,
+		// The latest report of each order in the batch; rows are locked in
+		// order_id order so concurrent batches do not deadlock.
+		`INSERT INTO orders (` + orderColumnList + `)
+SELECT DISTINCT ON (order_id) order_id, cl_ord_id, account, symbol, side, price, order_qty, leaves_qty, cum_qty,
+       avg_px, ord_status, transact_time, transact_time, engine_seq, report_seq
+FROM executions_staging
+WHERE order_id <> ''
+ORDER BY order_id, report_seq DESC
+` + upsertOrder,
+		`UPDATE orders
+SET created_time = staged.created_time
+FROM (SELECT order_id, min(transact_time) AS created_time FROM executions_staging GROUP BY order_id) staged
+WHERE orders.order_id = staged.order_id
+  AND orders.created_time > staged.created_time`,
+	}
+
+	// upsertOrder keeps the newest state of an order: batches are written
+	// concurrently and may land out of order, so an order only takes the
+	// state of a report with a higher ReportSeq than the one it holds. The
+	// earliest report saved sets created_time, which moveStagedRows moves
+	// back once the order is upserted.
+	upsertOrder = `ON CONFLICT (order_id) DO UPDATE
+SET cl_ord_id     = EXCLUDED.cl_ord_id,
+    account       = EXCLUDED.account,
+    price         = EXCLUDED.price,
+    order_qty     = EXCLUDED.order_qty,
+    leaves_qty    = EXCLUDED.leaves_qty,
+    cum_qty       = EXCLUDED.cum_qty,
+    avg_px        = EXCLUDED.avg_px,
+    ord_status    = EXCLUDED.ord_status,
+    transact_time = EXCLUDED.transact_time,
+    engine_seq    = EXCLUDED.engine_seq,
+    report_seq    = EXCLUDED.report_seq
+WHERE EXCLUDED.report_seq > orders.report_seq`
+
+	executionColumns = []string{"exec_id", "order_id", "cl_ord_id", "exec_type", "ord_status", "symbol", "side",
+		"order_qty", "last_shares", "last_px", "leaves_qty", "cum_qty", "avg_px", "transact_time", "text", "msg_type", "account", "price", "engine_seq", "trade_id",
+		"ord_type", "time_in_force", "last_liquidity_ind", "trade_date", "report_seq", "client_transact_time"}
+	executionColumnList = strings.Join(executionColumns, ", ")
+	tradeColumns        = []string{"trade_report_id", "msg_type", "exec_id", "symbol", "last_qty", "last_px", "trade_date", "transact_time", "trade_id"}
+	tradeColumnList     = strings.Join(tradeColumns, ", ")
+	tradeSideColumns    = []string{"trade_report_id", "side", "order_id", "exec_id", "cl_ord_id", "trade_date"}
+	tradeSideColumnList = strings.Join(tradeSideColumns, ", ")
+	orderColumnList     = "order_id, cl_ord_id, account, symbol, side, price, order_qty, leaves_qty, cum_qty, avg_px, ord_status, created_time, transact_time, engine_seq, report_seq"
+)
+
+// PostgresBatchRepository writes a batch of reports with COPY, in one
+// transaction, and upserts the orders they report on.
+type PostgresBatchRepository struct {
+	db TxBeginner
+}
+
+func NewPostgresBatchRepository(db TxBeginner) *PostgresBatchRepository {
+	return &PostgresBatchRepository{db: db}
+}
+
+func (r *PostgresBatchRepository) SaveBatch(ctx context.Context, batch Batch) error {
+	executions, err := executionRows(batch.Executions)
+	if err != nil {
+		return err
+	}
+	trades, sides, err := tradeRows(batch.Trades)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin batch: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for _, stmt := range createStagingTables {
+		if _, err := tx.Exec(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to create staging table: %w", err)
+		}
+	}
+	for _, c := range []struct {
+		table   string
+		columns []string
+		rows    [][]any
+	}{
+		{"executions_staging", executionColumns, executions},
+		{"trade_capture_reports_staging", tradeColumns, trades},
+		{"trade_sides_staging", tradeSideColumns, sides},
+	} {
+		if len(c.rows) == 0 {
+			continue
+		}
+		if _, err := tx.CopyFrom(ctx, pgx.Identifier{c.table}, c.columns, pgx.CopyFromRows(c.rows)); err != nil {
+			return fmt.Errorf("failed to copy into %s: %w", c.table, err)
+		}
+	}
+	for _, stmt := range moveStagedRows {
+		if _, err := tx.Exec(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to insert staged rows: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit batch: %w", err)
+	}
+	return nil
+}
+
+func executionRows(reports []model.ExecutionReport) ([][]any, error) {
+	rows := make([][]any, 0, len(reports))
+	for _, er := range reports {
+		row := []any{er.ExecID, er.OrderID, stringToPgText(er.ClOrdID), string(er.ExecType), string(er.OrdStatus),
+			er.Symbol, string(er.Side)}
+		for _, f := range []struct {
+			name  string
+			value decimal.Decimal
+		}{
+			{"OrderQty", er.OrderQty},
+			{"LastShares", er.LastShares},
+			{"LastPx", er.LastPx},
+			{"LeavesQty", er.LeavesQty},
+			{"CumQty", er.CumQty},
+			{"AvgPx", er.AvgPx},
+		} {
+			num, err := decimalToPgNumeric(f.value)
+			if err != nil {
+				return nil, fmt.Errorf("conversion failed for %s (exec=%s): %w", f.name, er.ExecID, err)
+			}
+			row = append(row, num)
+		}
+		price, err := decimalToPgNumeric(er.Price)
+		if err != nil {
+			return nil, fmt.Errorf("conversion failed for Price (exec=%s): %w", er.ExecID, err)
+		}
+		rows = append(rows, append(row, er.TransactTime, stringToPgText(er.Text), er.MsgType, stringToPgText(er.Account), price, int64(er.EngineSeq),
+			optionalPgText(er.TradeID), optionalPgText(string(er.OrdType)), optionalPgText(string(er.TimeInForce)),
+			optionalPgText(string(er.LastLiquidityInd)), tradeDate(er.TransactTime), int64(er.ReportSeq), er.ClientTime))
+	}
+	return rows, nil
+}
+
+func tradeRows(reports []model.TradeCaptureReport) ([][]any, [][]any, error) {
+	trades := make([][]any, 0, len(reports))
+	sides := make([][]any, 0, 2*len(reports))
+	for _, trade := range reports {
+		lastQty, err := decimalToPgNumeric(trade.LastQty)
+		if err != nil {
+			return nil, nil, fmt.Errorf("conversion failed for LastQty (trade=%s): %w", trade.TradeReportID, err)
+		}
+		lastPx, err := decimalToPgNumeric(trade.LastPx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("conversion failed for LastPx (trade=%s): %w", trade.TradeReportID, err)
+		}
+		date := tradeDate(trade.TransactTime)
+		trades = append(trades, []any{trade.TradeReportID, trade.MsgType, trade.ExecID, trade.Symbol,
+			lastQty, lastPx, date, trade.TransactTime, optionalPgText(trade.TradeID)})
+		for _, side := range trade.NoSides {
+			sides = append(sides, []any{trade.TradeReportID, mapSideToInt16(side.Side), side.OrderID,
+				optionalPgText(side.ExecID), optionalPgText(side.ClOrdID), date})
+		}
+	}
+	return trades, sides, nil
+}
@@ -9,10 +9,14 @@ import (
 
 type DBTask interface {
 	Execute(ctx context.Context, repo interface{}) error
+	// Complete is called with the outcome of the task: nil once it is
+	// written or spilled for replay, the error otherwise.
+	Complete(err error)
 }
 
 type SaveExecutionTask struct {
 	Execution model.ExecutionReport
+	Done      func(error) // Optional
 }
 
 func (t SaveExecutionTask) Execute(ctx context.Context, repo interface{}) error {
@@ -24,8 +28,15 @@ func (t SaveExecutionTask) Execute(ctx context.Context, repo interface{}) error
 	return err
 }
 
+func (t SaveExecutionTask) Complete(err error) {
+	if t.Done != nil {
+		t.Done(err)
+	}
+}
+
 type SaveTradeTask struct {
 	Trade model.TradeCaptureReport
+	Done  func(error) // Optional
 }
 
 func (t SaveTradeTask) Execute(ctx context.Context, repo interface{}) error {
@@ -36,3 +47,29 @@ func (t SaveTradeTask) Execute(ctx context.Context, repo interface{}) error {
 	err := tradeRepo.SaveTrade(ctx, t.Trade)
 	return err
 }
+
+func (t SaveTradeTask) Complete(err error) {
+	if t.Done != nil {
+		t.Done(err)
+	}
+}
+
+// SaveBatchTask writes a batch of reports in one transaction.
+type SaveBatchTask struct {
+	Batch Batch
+	Done  func(error) // Optional
+}
+
+func (t SaveBatchTask) Execute(ctx context.Context, repo interface{}) error {
+	batchRepo, ok := repo.(BatchRepository)
+	if !ok {
+		return fmt.Errorf("invalid repository type")
+	}
+	return batchRepo.SaveBatch(ctx, t.Batch)
+}
+
+func (t SaveBatchTask) Complete(err error) {
+	if t.Done != nil {
+		t.Done(err)
+	}
+}
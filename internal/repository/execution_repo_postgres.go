@@ -2,9 +2,12 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
+	"time"
 
-	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/shopspring/decimal"
 
@@ -12,8 +15,15 @@ import (
 	"MatchingEngine/internal/model"
 )
 
+// ErrNotFound is a lookup that matched no row.
+var ErrNotFound = errors.New("not found")
+
 type OrderQueries interface {
+	BackdateOrderCreatedTime(ctx context.Context, params sqlc.BackdateOrderCreatedTimeParams) error
 	CreateExecution(ctx context.Context, params sqlc.CreateExecutionParams) error
+	GetExecution(ctx context.Context, execID string) (sqlc.Execution, error)
+	ListExecutions(ctx context.Context, params sqlc.ListExecutionsParams) ([]sqlc.Execution, error)
+	ListExecutionsByOrderID(ctx context.Context, orderID string) ([]sqlc.Execution, error)
 }
 
 type PostgresExecutionRepository struct {
@@ -32,8 +42,21 @@ func decimalToPgNumeric(d decimal.Decimal) (pgtype.Numeric, error) {
 	return num, nil
 }
 
+func pgNumericToDecimal(num pgtype.Numeric) (decimal.Decimal, error) {
+	if !num.Valid {
+		return decimal.Zero, nil
+	}
+	if num.NaN || num.InfinityModifier != pgtype.Finite {
+		return decimal.Decimal{}, fmt.Errorf("failed to convert pgtype.Numeric to decimal: non-finite value")
+	}
+	return decimal.NewFromBigInt(num.Int, num.Exp), nil
+}
+
+// SaveExecution stores the report under the engine's ExecID and brings its
+// order's row in orders up to date, then moves the order's created_time back
+// if the report is older than any saved before it. Saving a report again is a
+// no-op, so the persistence consumer can redeliver.
 func (r *PostgresExecutionRepository) SaveExecution(ctx context.Context, execReport model.ExecutionReport) error {
-	executionId := uuid.NewString()
 	orderQty, err := decimalToPgNumeric(execReport.OrderQty)
 	if err != nil {
 		return fmt.Errorf("conversion failed for OrderQty: %w", err)
@@ -54,37 +77,194 @@ func (r *PostgresExecutionRepository) SaveExecution(ctx context.Context, execRep
 	if err != nil {
 		return fmt.Errorf("conversion failed for AvgPx: %w", err)
 	}
+	price, err := decimalToPgNumeric(execReport.Price)
+	if err != nil {
+		return fmt.Errorf("conversion failed for Price: %w", err)
+	}
 
 	params := sqlc.CreateExecutionParams{
-		ExecID:       executionId,
-		OrderID:      execReport.OrderID,
-		ClOrdID:      stringToPgText(execReport.ClOrdID),
-		ExecType:     string(execReport.ExecType),
-		OrdStatus:    string(execReport.OrdStatus),
-		Symbol:       execReport.Symbol,
-		Side:         string(execReport.Side),
-		OrderQty:     orderQty,
-		LastShares:   decimalToPgNumericOrZero(execReport.LastShares),
-		LastPx:       lastPx,
-		LeavesQty:    leavesQty,
-		CumQty:       cumQty,
-		AvgPx:        avgPx,
-		TransactTime: execReport.TransactTime,
-		Text:         stringToPgText(execReport.Text),
-		MsgType:      execReport.MsgType,
+		ExecID:             execReport.ExecID,
+		OrderID:            execReport.OrderID,
+		ClOrdID:            stringToPgText(execReport.ClOrdID),
+		ExecType:           string(execReport.ExecType),
+		OrdStatus:          string(execReport.OrdStatus),
+		Symbol:             execReport.Symbol,
+		Side:               string(execReport.Side),
+		OrderQty:           orderQty,
+		LastShares:         decimalToPgNumericOrZero(execReport.LastShares),
+		LastPx:             lastPx,
+		LeavesQty:          leavesQty,
+		CumQty:             cumQty,
+		AvgPx:              avgPx,
+		TransactTime:       execReport.TransactTime,
+		Text:               stringToPgText(execReport.Text),
+		MsgType:            execReport.MsgType,
+		Account:            stringToPgText(execReport.Account),
+		Price:              price,
+		EngineSeq:          int64(execReport.EngineSeq),
+		TradeID:            optionalPgText(execReport.TradeID),
+		OrdType:            optionalPgText(string(execReport.OrdType)),
+		TimeInForce:        optionalPgText(string(execReport.TimeInForce)),
+		LastLiquidityInd:   optionalPgText(string(execReport.LastLiquidityInd)),
+		TradeDate:          tradeDate(execReport.TransactTime),
+		ReportSeq:          int64(execReport.ReportSeq),
+		ClientTransactTime: execReport.ClientTime,
 	}
 
 	if err := r.queries.CreateExecution(ctx, params); err != nil {
 		return fmt.Errorf("create execution failed: %w", err)
 	}
+	if execReport.OrderID != "" {
+		backdate := sqlc.BackdateOrderCreatedTimeParams{OrderID: execReport.OrderID, CreatedTime: execReport.TransactTime}
+		if err := r.queries.BackdateOrderCreatedTime(ctx, backdate); err != nil {
+			return fmt.Errorf("backdate order failed: %w", err)
+		}
+	}
 
 	return nil
 }
 
+func (r *PostgresExecutionRepository) GetExecution(ctx context.Context, execID string) (model.ExecutionReport, error) {
+	row, err := r.queries.GetExecution(ctx, execID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.ExecutionReport{}, fmt.Errorf("execution %s: %w", execID, ErrNotFound)
+	}
+	if err != nil {
+		return model.ExecutionReport{}, fmt.Errorf("failed to get execution %s: %w", execID, err)
+	}
+	return executionFromRow(row)
+}
+
+// ListExecutionsByOrderID returns the execution reports of an order, oldest
+// first.
+func (r *PostgresExecutionRepository) ListExecutionsByOrderID(ctx context.Context, orderID string) ([]model.ExecutionReport, error) {
+	rows, err := r.queries.ListExecutionsByOrderID(ctx, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list executions of order %s: %w", orderID, err)
+	}
+	reports := make([]model.ExecutionReport, 0, len(rows))
+	for _, row := range rows {
+		er, err := executionFromRow(row)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, er)
+	}
+	return reports, nil
+}
+
+// ListExecutions returns up to limit execution reports matching filter, in
+// history order from just after the given key.
+func (r *PostgresExecutionRepository) ListExecutions(ctx context.Context, filter model.HistoryFilter, after HistoryKey, limit int32) ([]model.ExecutionReport, error) {
+	to := historyEnd(filter)
+	rows, err := r.queries.ListExecutions(ctx, sqlc.ListExecutionsParams{
+		FromDate:  tradeDate(filter.From),
+		ToDate:    tradeDate(to - 1),
+		FromTime:  filter.From,
+		ToTime:    to,
+		AfterTime: after.TransactTime,
+		AfterID:   after.ID,
+		Symbol:    optionalPgText(filter.Symbol),
+		OrderID:   optionalPgText(filter.OrderID),
+		Account:   optionalPgText(filter.Account),
+		MaxRows:   limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list executions: %w", err)
+	}
+	reports := make([]model.ExecutionReport, 0, len(rows))
+	for _, row := range rows {
+		er, err := executionFromRow(row)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, er)
+	}
+	return reports, nil
+}
+
+// HistoryKey is the position of an execution or a trade in history order:
+// its TransactTime, then its ExecID or TradeReportID. The zero key comes
+// before everything.
+type HistoryKey struct {
+	TransactTime int64
+	ID           string
+}
+
+// historyEnd is the exclusive TransactTime bound of filter.
+func historyEnd(filter model.HistoryFilter) int64 {
+	if filter.To <= 0 {
+		return math.MaxInt64
+	}
+	return filter.To
+}
+
+func executionFromRow(row sqlc.Execution) (model.ExecutionReport, error) {
+	er := model.ExecutionReport{
+		MsgType:          row.MsgType,
+		ExecID:           row.ExecID,
+		OrderID:          row.OrderID,
+		ClOrdID:          row.ClOrdID.String,
+		Account:          row.Account.String,
+		ExecType:         model.ExecType(row.ExecType),
+		OrdStatus:        model.OrderStatus(row.OrdStatus),
+		Symbol:           row.Symbol,
+		Side:             model.Side(row.Side),
+		TransactTime:     row.TransactTime,
+		ClientTime:       row.ClientTransactTime,
+		Text:             row.Text.String,
+		EngineSeq:        uint64(row.EngineSeq),
+		ReportSeq:        uint64(row.ReportSeq),
+		TradeID:          row.TradeID.String,
+		OrdType:          model.OrdType(row.OrdType.String),
+		TimeInForce:      model.TimeInForce(row.TimeInForce.String),
+		LastLiquidityInd: model.LastLiquidityInd(row.LastLiquidityInd.String),
+	}
+	for _, f := range []struct {
+		name string
+		num  pgtype.Numeric
+		dst  *decimal.Decimal
+	}{
+		{"Price", row.Price, &er.Price},
+		{"OrderQty", row.OrderQty, &er.OrderQty},
+		{"LastShares", row.LastShares, &er.LastShares},
+		{"LastPx", row.LastPx, &er.LastPx},
+		{"LeavesQty", row.LeavesQty, &er.LeavesQty},
+		{"CumQty", row.CumQty, &er.CumQty},
+		{"AvgPx", row.AvgPx, &er.AvgPx},
+	} {
+		d, err := pgNumericToDecimal(f.num)
+		if err != nil {
+			return model.ExecutionReport{}, fmt.Errorf("conversion failed for %s (exec=%s): %w", f.name, row.ExecID, err)
+		}
+		*f.dst = d
+	}
+	return er, nil
+}
+
 func stringToPgText(s string) pgtype.Text {
 	return pgtype.Text{String: s, Valid: true}
 }
 
+// optionalPgText stores an empty s as NULL.
+func optionalPgText(s string) pgtype.Text {
+	return pgtype.Text{String: s, Valid: s != ""}
+}
+
+// tradeDate is the day a report is partitioned under: the UTC day of its
+// TransactTime, which a trade shares with its fills.
+func tradeDate(transactTime int64) pgtype.Date {
+	return pgtype.Date{Time: utcDay(time.Unix(0, transactTime)), Valid: true}
+}
+
+// formatTradeDate renders a stored trade date as FIX TradeDate <75>.
+func formatTradeDate(d pgtype.Date) string {
+	if !d.Valid {
+		return ""
+	}
+	return d.Time.Format("20060102") // YYYYMMDD
+}
+
 func decimalToPgNumericOrZero(d decimal.Decimal) pgtype.Numeric {
 	num, err := decimalToPgNumeric(d)
 	if err != nil {
@@ -2,9 +2,11 @@ package repository
 
 import (
 	"context"
+	"math"
 	"testing"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -17,36 +19,69 @@ type MockQueries struct {
 	mock.Mock
 }
 
+func (m *MockQueries) BackdateOrderCreatedTime(ctx context.Context, params sqlc.BackdateOrderCreatedTimeParams) error {
+	args := m.Called(ctx, params)
+	return args.Error(0)
+}
+
 func (m *MockQueries) CreateExecution(ctx context.Context, params sqlc.CreateExecutionParams) error {
 	args := m.Called(ctx, params)
 	return args.Error(0)
 }
 
+func (m *MockQueries) GetExecution(ctx context.Context, execID string) (sqlc.Execution, error) {
+	args := m.Called(ctx, execID)
+	return args.Get(0).(sqlc.Execution), args.Error(1)
+}
+
+func (m *MockQueries) ListExecutions(ctx context.Context, params sqlc.ListExecutionsParams) ([]sqlc.Execution, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).([]sqlc.Execution), args.Error(1)
+}
+
+func (m *MockQueries) ListExecutionsByOrderID(ctx context.Context, orderID string) ([]sqlc.Execution, error) {
+	args := m.Called(ctx, orderID)
+	return args.Get(0).([]sqlc.Execution), args.Error(1)
+}
+
 func TestSaveExecution(t *testing.T) {
 	mockQueries := new(MockQueries)
 	repo := NewPostgresExecutionRepository(mockQueries)
 
 	execReport := model.ExecutionReport{
-		MsgType:      "8",
-		ExecID:       "exec-123",
-		OrderID:      "order-1",
-		ClOrdID:      "CL001",
-		ExecType:     model.ExecTypeFill,
-		OrdStatus:    model.OrderStatusPartialFill,
-		Symbol:       "BTC/USDT",
-		Side:         model.Buy,
-		OrderQty:     decimal.NewFromInt(10),
-		LastShares:   decimal.NewFromInt(5),
-		LastPx:       decimal.NewFromInt(100),
-		LeavesQty:    decimal.NewFromInt(5),
-		CumQty:       decimal.NewFromInt(5),
-		AvgPx:        decimal.NewFromInt(100),
-		TransactTime: time.Now().UnixNano(),
-		Text:         "Trade executed",
+		MsgType:          "8",
+		ExecID:           "exec-123",
+		OrderID:          "order-1",
+		ClOrdID:          "CL001",
+		Account:          "acct-1",
+		ExecType:         model.ExecTypeFill,
+		OrdStatus:        model.OrderStatusPartialFill,
+		Symbol:           "BTC/USDT",
+		Side:             model.Buy,
+		OrderQty:         decimal.NewFromInt(10),
+		LastShares:       decimal.NewFromInt(5),
+		LastPx:           decimal.NewFromInt(100),
+		LeavesQty:        decimal.NewFromInt(5),
+		CumQty:           decimal.NewFromInt(5),
+		AvgPx:            decimal.NewFromInt(100),
+		TransactTime:     time.Now().UnixNano(),
+		ClientTime:       42,
+		Text:             "Trade executed",
+		OrdType:          model.OrdTypeLimit,
+		TimeInForce:      model.TimeInForceGTC,
+		LastLiquidityInd: model.LiquidityRemoved,
+		ReportSeq:        7,
 	}
 
 	mockQueries.
-		On("CreateExecution", mock.Anything, mock.Anything).
+		On("CreateExecution", mock.Anything, mock.MatchedBy(func(p sqlc.CreateExecutionParams) bool {
+			return p.ExecID == "exec-123" && p.OrderID == "order-1" && p.Account.String == "acct-1" &&
+				p.OrdType.String == "2" && p.TimeInForce.String == "1" && p.LastLiquidityInd.String == "2" && !p.TradeID.Valid &&
+				p.ReportSeq == 7 && p.ClientTransactTime == 42
+		})).
+		Return(nil)
+	mockQueries.
+		On("BackdateOrderCreatedTime", mock.Anything, sqlc.BackdateOrderCreatedTimeParams{OrderID: "order-1", CreatedTime: execReport.TransactTime}).
 		Return(nil)
 
 	err := repo.SaveExecution(context.Background(), execReport)
@@ -54,3 +89,59 @@ func TestSaveExecution(t *testing.T) {
 
 	mockQueries.AssertExpectations(t)
 }
+
+func TestGetExecution(t *testing.T) {
+	mockQueries := new(MockQueries)
+	repo := NewPostgresExecutionRepository(mockQueries)
+
+	leavesQty, _ := decimalToPgNumeric(decimal.RequireFromString("2.5"))
+	mockQueries.On("GetExecution", mock.Anything, "execution-1").Return(sqlc.Execution{
+		MsgType:   "8",
+		ExecID:    "execution-1",
+		OrderID:   "order-1",
+		ClOrdID:   stringToPgText("CL001"),
+		ExecType:  string(model.ExecTypeNew),
+		OrdStatus: string(model.OrderStatusNew),
+		Symbol:    "BTC/USDT",
+		Side:      string(model.Sell),
+		LeavesQty: leavesQty,
+	}, nil)
+	mockQueries.On("GetExecution", mock.Anything, "missing").Return(sqlc.Execution{}, pgx.ErrNoRows)
+
+	er, err := repo.GetExecution(context.Background(), "execution-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "CL001", er.ClOrdID)
+	assert.Equal(t, model.Sell, er.Side)
+	assert.True(t, er.LeavesQty.Equal(decimal.RequireFromString("2.5")))
+	assert.True(t, er.OrderQty.IsZero())
+
+	_, err = repo.GetExecution(context.Background(), "missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestListExecutions(t *testing.T) {
+	mockQueries := new(MockQueries)
+	repo := NewPostgresExecutionRepository(mockQueries)
+
+	from := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC).UnixNano()
+	mockQueries.On("ListExecutions", mock.Anything, mock.MatchedBy(func(p sqlc.ListExecutionsParams) bool {
+		// Without an end the range runs to the last representable day
+		return p.FromTime == from && p.ToTime == math.MaxInt64 &&
+			p.FromDate.Time.Equal(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)) && p.ToDate.Time.Year() == 2262 &&
+			p.AfterTime == from+5 && p.AfterID == "execution-1" &&
+			p.Symbol == stringToPgText("BTC/USDT") && !p.OrderID.Valid && p.Account == stringToPgText("acct-1") &&
+			p.MaxRows == 2
+	})).Return([]sqlc.Execution{
+		{MsgType: "8", ExecID: "execution-2", OrderID: "order-1", Symbol: "BTC/USDT", TransactTime: from + 5},
+		{MsgType: "8", ExecID: "execution-3", OrderID: "order-2", Symbol: "BTC/USDT", TransactTime: from + 9},
+	}, nil)
+
+	reports, err := repo.ListExecutions(context.Background(),
+		model.HistoryFilter{Symbol: "BTC/USDT", Account: "acct-1", From: from},
+		HistoryKey{TransactTime: from + 5, ID: "execution-1"}, 2)
+	assert.NoError(t, err)
+	assert.Len(t, reports, 2)
+	assert.Equal(t, "execution-3", reports[1].ExecID)
+
+	mockQueries.AssertExpectations(t)
+}
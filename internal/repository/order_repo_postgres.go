@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/shopspring/decimal"
+
+	sqlc "MatchingEngine/internal/db/sqlc"
+	"MatchingEngine/internal/model"
+	"MatchingEngine/orderBook"
+)
+
+type OrderStateQueries interface {
+	GetOrderByClOrdID(ctx context.Context, clOrdID pgtype.Text) (sqlc.Order, error)
+	ListOpenOrders(ctx context.Context) ([]sqlc.Order, error)
+}
+
+// PostgresOrderRepository reads the orders table, the current state of every
+// order as of the last report saved.
+type PostgresOrderRepository struct {
+	queries OrderStateQueries
+}
+
+func NewPostgresOrderRepository(queries OrderStateQueries) *PostgresOrderRepository {
+	return &PostgresOrderRepository{queries: queries}
+}
+
+// ListOpenOrders returns the orders of every symbol that are not done, grouped
+// by symbol and in time priority within each.
+func (r *PostgresOrderRepository) ListOpenOrders(ctx context.Context) ([]orderBook.Order, error) {
+	rows, err := r.queries.ListOpenOrders(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list open orders: %w", err)
+	}
+	orders := make([]orderBook.Order, 0, len(rows))
+	for _, row := range rows {
+		order, err := orderFromRow(row)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+	return orders, nil
+}
+
+// OrderByClOrdID returns the order that last went by clOrdID, as its last
+// report saved left it.
+func (r *PostgresOrderRepository) OrderByClOrdID(ctx context.Context, clOrdID string) (orderBook.Order, error) {
+	row, err := r.queries.GetOrderByClOrdID(ctx, stringToPgText(clOrdID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return orderBook.Order{}, fmt.Errorf("order %s: %w", clOrdID, ErrNotFound)
+	}
+	if err != nil {
+		return orderBook.Order{}, fmt.Errorf("failed to get order %s: %w", clOrdID, err)
+	}
+	return orderFromRow(row)
+}
+
+func orderFromRow(row sqlc.Order) (orderBook.Order, error) {
+	order := orderBook.Order{
+		ClOrdID:     row.ClOrdID.String,
+		OrderID:     row.OrderID,
+		Account:     row.Account.String,
+		Symbol:      row.Symbol,
+		Side:        model.Side(row.Side),
+		ReceivedAt:  row.CreatedTime,
+		EngineSeq:   uint64(row.EngineSeq),
+		ReportSeq:   uint64(row.ReportSeq),
+		OrderStatus: model.OrderStatus(row.OrdStatus),
+	}
+	for _, f := range []struct {
+		name string
+		num  pgtype.Numeric
+		dst  *decimal.Decimal
+	}{
+		{"Price", row.Price, &order.Price},
+		{"OrderQty", row.OrderQty, &order.OrderQty},
+		{"LeavesQty", row.LeavesQty, &order.LeavesQty},
+		{"CumQty", row.CumQty, &order.CumQty},
+		{"AvgPx", row.AvgPx, &order.AvgPx},
+	} {
+		d, err := pgNumericToDecimal(f.num)
+		if err != nil {
+			return orderBook.Order{}, fmt.Errorf("conversion failed for %s (order=%s): %w", f.name, row.OrderID, err)
+		}
+		*f.dst = d
+	}
+	return order, nil
+}
@@ -0,0 +1,257 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// PartitionedTables are range-partitioned by trade date, one partition per
+// UTC day named <table>_pYYYYMMDD. A day's partitions are created in this
+// order and retired in the reverse one, so that a foreign key never points at
+// a partition that is gone.
+var PartitionedTables = []string{"executions", "trade_capture_reports", "trade_sides"}
+
+// RetentionMode is what becomes of the partitions of a day past retention.
+type RetentionMode string
+
+const (
+	RetentionArchive RetentionMode = "archive" // Detached and moved to the archive schema
+	RetentionDrop    RetentionMode = "drop"    // Detached and dropped
+)
+
+func (m RetentionMode) IsValid() bool {
+	return m == RetentionArchive || m == RetentionDrop
+}
+
+type PartitionOpts struct {
+	DaysAhead     int           // Days after today to create partitions for
+	RetentionDays int           // Days before today to keep attached; 0 keeps every day
+	Mode          RetentionMode // Defaults to RetentionArchive
+	ArchiveSchema string        // Defaults to "archive"
+}
+
+// PartitionPlan is the work a maintenance run does, in order.
+type PartitionPlan struct {
+	Create []time.Time // Days missing a partition of at least one table
+	Retire []time.Time // Days past retention
+}
+
+// PartitionDB is satisfied by *pgxpool.Pool and *pgx.Conn.
+type PartitionDB interface {
+	TxBeginner
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// PostgresPartitionManager creates the trade date partitions of the days
+// ahead and retires those older than the retention. Each day is one
+// transaction; retiring a day detaches its partitions, which briefly locks
+// the partitioned tables against writes.
+type PostgresPartitionManager struct {
+	db   PartitionDB
+	opts PartitionOpts
+}
+
+func NewPostgresPartitionManager(db PartitionDB, opts PartitionOpts) (*PostgresPartitionManager, error) {
+	if opts.Mode == "" {
+		opts.Mode = RetentionArchive
+	}
+	if opts.ArchiveSchema == "" {
+		opts.ArchiveSchema = "archive"
+	}
+	switch {
+	case !opts.Mode.IsValid():
+		return nil, fmt.Errorf("unknown retention mode %q", opts.Mode)
+	case opts.DaysAhead < 0 || opts.RetentionDays < 0:
+		return nil, fmt.Errorf("days ahead and retention days cannot be negative")
+	}
+	return &PostgresPartitionManager{db: db, opts: opts}, nil
+}
+
+// Plan lists what Apply would do on the given day.
+func (m *PostgresPartitionManager) Plan(ctx context.Context, today time.Time) (PartitionPlan, error) {
+	attached, err := m.attachedDays(ctx)
+	if err != nil {
+		return PartitionPlan{}, err
+	}
+	return planPartitions(attached, utcDay(today), m.opts), nil
+}
+
+// Ensure creates the partitions missing from today through DaysAhead and
+// retires nothing, so that a process writing reports can make sure they have
+// somewhere to go.
+func (m *PostgresPartitionManager) Ensure(ctx context.Context, today time.Time) error {
+	plan, err := m.Plan(ctx, today)
+	if err != nil {
+		return err
+	}
+	return m.Apply(ctx, PartitionPlan{Create: plan.Create})
+}
+
+// Apply creates and retires the partitions of plan.
+func (m *PostgresPartitionManager) Apply(ctx context.Context, plan PartitionPlan) error {
+	for _, day := range plan.Create {
+		if err := m.inTx(ctx, func(tx pgx.Tx) error { return createPartitions(ctx, tx, day) }); err != nil {
+			return fmt.Errorf("failed to create the partitions of %s: %w", day.Format(time.DateOnly), err)
+		}
+	}
+	for _, day := range plan.Retire {
+		if err := m.inTx(ctx, func(tx pgx.Tx) error { return m.retirePartitions(ctx, tx, day) }); err != nil {
+			return fmt.Errorf("failed to retire the partitions of %s: %w", day.Format(time.DateOnly), err)
+		}
+	}
+	return nil
+}
+
+func (m *PostgresPartitionManager) inTx(ctx context.Context, fn func(pgx.Tx) error) error {
+	tx, err := m.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// attachedDays returns the days each partitioned table has a partition for.
+// Partitions not named by partitionName are not managed and left out.
+func (m *PostgresPartitionManager) attachedDays(ctx context.Context) (map[string][]time.Time, error) {
+	rows, err := m.db.Query(ctx, `
+SELECT parent.relname, child.relname
+FROM pg_inherits
+         JOIN pg_class parent ON parent.oid = pg_inherits.inhparent
+         JOIN pg_class child ON child.oid = pg_inherits.inhrelid
+         JOIN pg_namespace ns ON ns.oid = parent.relnamespace
+WHERE ns.nspname = current_schema()
+  AND parent.relname = ANY ($1)`, PartitionedTables)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list partitions: %w", err)
+	}
+	defer rows.Close()
+
+	attached := make(map[string][]time.Time)
+	for rows.Next() {
+		var parent, child string
+		if err := rows.Scan(&parent, &child); err != nil {
+			return nil, fmt.Errorf("failed to list partitions: %w", err)
+		}
+		day, err := time.Parse("20060102", strings.TrimPrefix(child, parent+"_p"))
+		if err != nil || child != partitionName(parent, day) {
+			continue
+		}
+		attached[parent] = append(attached[parent], day)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list partitions: %w", err)
+	}
+	return attached, nil
+}
+
+// planPartitions creates every missing partition from today through
+// DaysAhead, and retires every day before today minus RetentionDays, oldest
+// first.
+func planPartitions(attached map[string][]time.Time, today time.Time, opts PartitionOpts) PartitionPlan {
+	var plan PartitionPlan
+	for i := 0; i <= opts.DaysAhead; i++ {
+		day := today.AddDate(0, 0, i)
+		for _, table := range PartitionedTables {
+			if !slices.ContainsFunc(attached[table], day.Equal) {
+				plan.Create = append(plan.Create, day)
+				break
+			}
+		}
+	}
+	if opts.RetentionDays == 0 {
+		return plan
+	}
+
+	cutoff := today.AddDate(0, 0, -opts.RetentionDays)
+	for _, table := range PartitionedTables {
+		for _, day := range attached[table] {
+			if day.Before(cutoff) && !slices.ContainsFunc(plan.Retire, day.Equal) {
+				plan.Retire = append(plan.Retire, day)
+			}
+		}
+	}
+	slices.SortFunc(plan.Retire, time.Time.Compare)
+	return plan
+}
+
+func createPartitions(ctx context.Context, tx pgx.Tx, day time.Time) error {
+	for _, table := range PartitionedTables {
+		stmt := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s PARTITION OF %s FOR VALUES FROM ('%s') TO ('%s')`,
+			pgx.Identifier{partitionName(table, day)}.Sanitize(), pgx.Identifier{table}.Sanitize(),
+			day.Format(time.DateOnly), day.AddDate(0, 0, 1).Format(time.DateOnly))
+		if _, err := tx.Exec(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// retirePartitions detaches the partitions of day and archives or drops them.
+// A detached partition keeps its foreign keys, now to the whole referenced
+// table, so they are dropped before the partitions they point at go.
+func (m *PostgresPartitionManager) retirePartitions(ctx context.Context, tx pgx.Tx, day time.Time) error {
+	if m.opts.Mode == RetentionArchive {
+		if _, err := tx.Exec(ctx, `CREATE SCHEMA IF NOT EXISTS `+pgx.Identifier{m.opts.ArchiveSchema}.Sanitize()); err != nil {
+			return err
+		}
+	}
+	for _, table := range slices.Backward(PartitionedTables) {
+		partition := pgx.Identifier{partitionName(table, day)}.Sanitize()
+		var exists bool
+		if err := tx.QueryRow(ctx, `SELECT to_regclass($1) IS NOT NULL`, partition).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			continue
+		}
+		if _, err := tx.Exec(ctx, fmt.Sprintf(`ALTER TABLE %s DETACH PARTITION %s`, pgx.Identifier{table}.Sanitize(), partition)); err != nil {
+			return err
+		}
+		if err := dropForeignKeys(ctx, tx, partition); err != nil {
+			return err
+		}
+		stmt := `DROP TABLE ` + partition
+		if m.opts.Mode == RetentionArchive {
+			stmt = fmt.Sprintf(`ALTER TABLE %s SET SCHEMA %s`, partition, pgx.Identifier{m.opts.ArchiveSchema}.Sanitize())
+		}
+		if _, err := tx.Exec(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func dropForeignKeys(ctx context.Context, tx pgx.Tx, table string) error {
+	rows, err := tx.Query(ctx, `SELECT conname FROM pg_constraint WHERE conrelid = $1::regclass AND contype = 'f'`, table)
+	if err != nil {
+		return err
+	}
+	names, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if _, err := tx.Exec(ctx, fmt.Sprintf(`ALTER TABLE %s DROP CONSTRAINT %s`, table, pgx.Identifier{name}.Sanitize())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func partitionName(table string, day time.Time) string {
+	return table + "_p" + day.Format("20060102")
+}
+
+func utcDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func day(s string) time.Time {
+	d, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func TestPlanPartitions_CreatesMissingDaysAhead(t *testing.T) {
+	attached := map[string][]time.Time{
+		"executions":            {day("2025-03-01"), day("2025-03-02")},
+		"trade_capture_reports": {day("2025-03-01"), day("2025-03-02")},
+		"trade_sides":           {day("2025-03-01")},
+	}
+
+	plan := planPartitions(attached, day("2025-03-01"), PartitionOpts{DaysAhead: 2})
+
+	// A day missing a partition of any table is created again; the others
+	// are skipped with IF NOT EXISTS.
+	assert.Equal(t, []time.Time{day("2025-03-02"), day("2025-03-03")}, plan.Create)
+	assert.Empty(t, plan.Retire)
+}
+
+func TestPlanPartitions_RetiresDaysPastRetention(t *testing.T) {
+	attached := map[string][]time.Time{
+		"executions":            {day("2025-02-20"), day("2025-02-24"), day("2025-02-25"), day("2025-03-01")},
+		"trade_capture_reports": {day("2025-02-24"), day("2025-02-20"), day("2025-03-01")},
+		"trade_sides":           {day("2025-02-24"), day("2025-03-01")},
+	}
+
+	plan := planPartitions(attached, day("2025-03-01"), PartitionOpts{RetentionDays: 4})
+
+	assert.Empty(t, plan.Create)
+	assert.Equal(t, []time.Time{day("2025-02-20"), day("2025-02-24")}, plan.Retire)
+}
+
+func TestNewPostgresPartitionManager_RejectsUnknownMode(t *testing.T) {
+	_, err := NewPostgresPartitionManager(nil, PartitionOpts{Mode: "truncate"})
+	assert.Error(t, err)
+
+	m, err := NewPostgresPartitionManager(nil, PartitionOpts{})
+	require.NoError(t, err)
+	assert.Equal(t, RetentionArchive, m.opts.Mode)
+}
@@ -0,0 +1,186 @@
+package repository
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"MatchingEngine/internal/model"
+)
+
+const (
+	spillFileName  = "spill.jsonl"
+	replayFileName = "replay.jsonl"
+)
+
+// spillRecord is a task on disk; exactly one field is set.
+type spillRecord struct {
+	Execution *model.ExecutionReport    `json:"execution,omitempty"`
+	Trade     *model.TradeCaptureReport `json:"trade,omitempty"`
+	Batch     *Batch                    `json:"batch,omitempty"`
+}
+
+// spillFile holds the tasks the database kept refusing, one JSON record per
+// line. Replay takes the whole file over by renaming it to replay.jsonl, so
+// tasks spilled meanwhile start a new file; a replay file left by a crash is
+// replayed again, which the idempotent writes make harmless.
+type spillFile struct {
+	dir string
+
+	mu      sync.Mutex
+	f       *os.File
+	pending int64
+}
+
+func openSpillFile(dir string) (*spillFile, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create spill dir: %w", err)
+	}
+	s := &spillFile{dir: dir}
+	for _, name := range []string{spillFileName, replayFileName} {
+		tasks, err := readSpill(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		s.pending += int64(len(tasks))
+	}
+	return s, nil
+}
+
+// append spills the task.
+func (s *spillFile) append(task DBTask) error {
+	return s.write(task, 1)
+}
+
+// requeue spills again a task taken for replay.
+func (s *spillFile) requeue(task DBTask) error {
+	return s.write(task, 0)
+}
+
+// write adds the task to the spill file and syncs it to disk.
+func (s *spillFile) write(task DBTask, added int64) error {
+	var rec spillRecord
+	switch t := task.(type) {
+	case SaveExecutionTask:
+		rec.Execution = &t.Execution
+	case SaveTradeTask:
+		rec.Trade = &t.Trade
+	case SaveBatchTask:
+		rec.Batch = &t.Batch
+	default:
+		return fmt.Errorf("cannot spill task of type %T", task)
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to encode spilled task: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		f, err := os.OpenFile(filepath.Join(s.dir, spillFileName), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return fmt.Errorf("failed to open spill file: %w", err)
+		}
+		s.f = f
+	}
+	if _, err := s.f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write spill file: %w", err)
+	}
+	if err := s.f.Sync(); err != nil {
+		return fmt.Errorf("failed to sync spill file: %w", err)
+	}
+	s.pending += added
+	return nil
+}
+
+// take returns the file to replay, or "" when nothing is spilled.
+func (s *spillFile) take() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	replay := filepath.Join(s.dir, replayFileName)
+	if _, err := os.Stat(replay); err == nil {
+		return replay, nil
+	}
+	spill := filepath.Join(s.dir, spillFileName)
+	info, err := os.Stat(spill)
+	if errors.Is(err, os.ErrNotExist) || (err == nil && info.Size() == 0) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	if s.f != nil {
+		if err := s.f.Close(); err != nil {
+			return "", err
+		}
+		s.f = nil
+	}
+	if err := os.Rename(spill, replay); err != nil {
+		return "", fmt.Errorf("failed to take spill file: %w", err)
+	}
+	return replay, nil
+}
+
+// replayed marks n spilled tasks as handled.
+func (s *spillFile) replayed(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending -= int64(n)
+}
+
+func (s *spillFile) count() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pending
+}
+
+func (s *spillFile) close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return nil
+	}
+	err := s.f.Close()
+	s.f = nil
+	return err
+}
+
+// readSpill reads the tasks in path. A line that does not decode, such as one
+// cut short by a crash, is logged and skipped.
+func readSpill(path string) ([]DBTask, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to open spill file: %w", err)
+	}
+	defer f.Close()
+
+	var tasks []DBTask
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 64<<20)
+	for scanner.Scan() {
+		var rec spillRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			log.Printf("Skipping unreadable spilled task in %s: %v", path, err)
+			continue
+		}
+		switch {
+		case rec.Execution != nil:
+			tasks = append(tasks, SaveExecutionTask{Execution: *rec.Execution})
+		case rec.Trade != nil:
+			tasks = append(tasks, SaveTradeTask{Trade: *rec.Trade})
+		case rec.Batch != nil:
+			tasks = append(tasks, SaveBatchTask{Batch: *rec.Batch})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read spill file: %w", err)
+	}
+	return tasks, nil
+}
@@ -4,7 +4,7 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 
 	sqlc "MatchingEngine/internal/db/sqlc"
 	"MatchingEngine/internal/model"
@@ -13,6 +13,10 @@ import (
 type TradeQueries interface {
 	CreateTrade(ctx context.Context, params sqlc.CreateTradeParams) error
 	CreateTradeSide(ctx context.Context, params sqlc.CreateTradeSideParams) error
+	ListTradesSince(ctx context.Context, transactTime int64) ([]sqlc.TradeCaptureReport, error)
+	ListRecentTradesWithSides(ctx context.Context, params sqlc.ListRecentTradesWithSidesParams) ([]sqlc.ListRecentTradesWithSidesRow, error)
+	ListTradeWithSides(ctx context.Context, params sqlc.ListTradeWithSidesParams) ([]sqlc.ListTradeWithSidesRow, error)
+	GetTradeWithFills(ctx context.Context, tradeID pgtype.Text) ([]sqlc.GetTradeWithFillsRow, error)
 }
 
 type PostgresTradeRepository struct {
@@ -23,8 +27,10 @@ func NewPostgresTradeRepository(queries TradeQueries) *PostgresTradeRepository {
 	return &PostgresTradeRepository{queries: queries}
 }
 
+// SaveTrade stores the report and its sides under the engine's
+// TradeReportID. Saving a report again is a no-op, and completes the sides of
+// one saved halfway.
 func (r *PostgresTradeRepository) SaveTrade(ctx context.Context, trade model.TradeCaptureReport) error {
-	tradeId := uuid.NewString()
 	price, err := decimalToPgNumeric(trade.LastPx)
 	if err != nil {
 		return fmt.Errorf("failed to convert LastPx to pgtype.Numeric: %w", err)
@@ -36,14 +42,15 @@ func (r *PostgresTradeRepository) SaveTrade(ctx context.Context, trade model.Tra
 	}
 
 	err = r.queries.CreateTrade(ctx, sqlc.CreateTradeParams{
-		TradeReportID: tradeId,
+		TradeReportID: trade.TradeReportID,
 		MsgType:       trade.MsgType,
 		ExecID:        trade.ExecID,
 		Symbol:        trade.Symbol,
 		LastQty:       quantity,
 		LastPx:        price,
-		TradeDate:     trade.TradeDate,
+		TradeDate:     tradeDate(trade.TransactTime),
 		TransactTime:  trade.TransactTime,
+		TradeID:       optionalPgText(trade.TradeID),
 	})
 	if err != nil {
 		return fmt.Errorf("failed to insert trade record: %w", err)
@@ -52,9 +59,12 @@ func (r *PostgresTradeRepository) SaveTrade(ctx context.Context, trade model.Tra
 	// Insert trade sides (552)
 	for _, side := range trade.NoSides {
 		err = r.queries.CreateTradeSide(ctx, sqlc.CreateTradeSideParams{
-			TradeReportID: tradeId,
+			TradeReportID: trade.TradeReportID,
 			Side:          mapSideToInt16(side.Side),
 			OrderID:       side.OrderID,
+			ExecID:        optionalPgText(side.ExecID),
+			ClOrdID:       optionalPgText(side.ClOrdID),
+			TradeDate:     tradeDate(trade.TransactTime),
 		})
 		if err != nil {
 			return fmt.Errorf("failed to insert trade side (orderID=%s): %w", side.OrderID, err)
@@ -64,6 +74,166 @@ func (r *PostgresTradeRepository) SaveTrade(ctx context.Context, trade model.Tra
 	return nil
 }
 
+// ListTradesSince returns the trade capture reports executed at or after the
+// given TransactTime, oldest first. Sides are not loaded.
+func (r *PostgresTradeRepository) ListTradesSince(ctx context.Context, since int64) ([]model.TradeCaptureReport, error) {
+	rows, err := r.queries.ListTradesSince(ctx, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trades since %d: %w", since, err)
+	}
+
+	trades := make([]model.TradeCaptureReport, 0, len(rows))
+	for _, row := range rows {
+		trade, err := tradeFromRow(row)
+		if err != nil {
+			return nil, err
+		}
+		trades = append(trades, trade)
+	}
+	return trades, nil
+}
+
+// GetTradeWithFills returns the trade known by tradeID, with its sides, and
+// the fill execution report of each side, in one query.
+func (r *PostgresTradeRepository) GetTradeWithFills(ctx context.Context, tradeID string) (model.TradeCaptureReport, []model.ExecutionReport, error) {
+	rows, err := r.queries.GetTradeWithFills(ctx, stringToPgText(tradeID))
+	if err != nil {
+		return model.TradeCaptureReport{}, nil, fmt.Errorf("failed to get trade %s: %w", tradeID, err)
+	}
+	if len(rows) == 0 {
+		return model.TradeCaptureReport{}, nil, fmt.Errorf("trade %s: %w", tradeID, ErrNotFound)
+	}
+
+	trade, err := tradeFromRow(rows[0].TradeCaptureReport)
+	if err != nil {
+		return model.TradeCaptureReport{}, nil, err
+	}
+	fills := make([]model.ExecutionReport, 0, len(rows))
+	for _, row := range rows {
+		fill, err := executionFromRow(row.Execution)
+		if err != nil {
+			return model.TradeCaptureReport{}, nil, err
+		}
+		fills = append(fills, fill)
+		trade.NoSides = append(trade.NoSides, model.NoSides{
+			Side:    mapInt16ToSide(row.Side),
+			OrderID: fill.OrderID,
+			ClOrdID: fill.ClOrdID,
+			ExecID:  fill.ExecID,
+		})
+	}
+	return trade, fills, nil
+}
+
+func tradeFromRow(row sqlc.TradeCaptureReport) (model.TradeCaptureReport, error) {
+	lastQty, err := pgNumericToDecimal(row.LastQty)
+	if err != nil {
+		return model.TradeCaptureReport{}, fmt.Errorf("conversion failed for LastQty (trade=%s): %w", row.TradeReportID, err)
+	}
+	lastPx, err := pgNumericToDecimal(row.LastPx)
+	if err != nil {
+		return model.TradeCaptureReport{}, fmt.Errorf("conversion failed for LastPx (trade=%s): %w", row.TradeReportID, err)
+	}
+	return model.TradeCaptureReport{
+		MsgType:       row.MsgType,
+		TradeReportID: row.TradeReportID,
+		ExecID:        row.ExecID,
+		TradeID:       row.TradeID.String,
+		Symbol:        row.Symbol,
+		LastQty:       lastQty,
+		LastPx:        lastPx,
+		TradeDate:     formatTradeDate(row.TradeDate),
+		TransactTime:  row.TransactTime,
+	}, nil
+}
+
+// ListRecentTrades returns the last limit trades of a symbol with their
+// sides, newest first.
+func (r *PostgresTradeRepository) ListRecentTrades(ctx context.Context, symbol string, limit int32) ([]model.TradeCaptureReport, error) {
+	rows, err := r.queries.ListRecentTradesWithSides(ctx, sqlc.ListRecentTradesWithSidesParams{Symbol: symbol, Limit: limit})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list recent trades of %s: %w", symbol, err)
+	}
+
+	var trades []model.TradeCaptureReport
+	for _, row := range rows {
+		if trades, err = appendTradeSide(trades, sqlc.ListTradeWithSidesRow(row)); err != nil {
+			return nil, err
+		}
+	}
+	return trades, nil
+}
+
+// ListTrades returns up to limit trades matching filter with their sides, in
+// history order from just after the given key.
+func (r *PostgresTradeRepository) ListTrades(ctx context.Context, filter model.HistoryFilter, after HistoryKey, limit int32) ([]model.TradeCaptureReport, error) {
+	to := historyEnd(filter)
+	rows, err := r.queries.ListTradeWithSides(ctx, sqlc.ListTradeWithSidesParams{
+		FromDate:  tradeDate(filter.From),
+		ToDate:    tradeDate(to - 1),
+		FromTime:  filter.From,
+		ToTime:    to,
+		AfterTime: after.TransactTime,
+		AfterID:   after.ID,
+		Symbol:    optionalPgText(filter.Symbol),
+		OrderID:   optionalPgText(filter.OrderID),
+		Account:   optionalPgText(filter.Account),
+		MaxRows:   limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trades: %w", err)
+	}
+
+	trades := make([]model.TradeCaptureReport, 0, len(rows)/2)
+	for _, row := range rows {
+		if trades, err = appendTradeSide(trades, row); err != nil {
+			return nil, err
+		}
+	}
+	return trades, nil
+}
+
+// appendTradeSide adds the side of a trade-with-sides row to the last trade,
+// or to a new one when the row starts the next trade.
+func appendTradeSide(trades []model.TradeCaptureReport, row sqlc.ListTradeWithSidesRow) ([]model.TradeCaptureReport, error) {
+	if n := len(trades); n == 0 || trades[n-1].TradeReportID != row.TradeReportID {
+		trade, err := tradeFromRow(sqlc.TradeCaptureReport{
+			TradeReportID: row.TradeReportID,
+			MsgType:       row.MsgType,
+			ExecID:        row.ExecID,
+			Symbol:        row.Symbol,
+			LastQty:       row.LastQty,
+			LastPx:        row.LastPx,
+			TradeDate:     row.TradeDate,
+			TransactTime:  row.TransactTime,
+			TradeID:       row.TradeID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		trades = append(trades, trade)
+	}
+	last := &trades[len(trades)-1]
+	last.NoSides = append(last.NoSides, model.NoSides{
+		Side:    mapInt16ToSide(row.Side),
+		OrderID: row.OrderID,
+		ClOrdID: row.SideClOrdID.String,
+		ExecID:  row.SideExecID.String,
+	})
+	return trades, nil
+}
+
+func mapInt16ToSide(side int16) model.Side {
+	switch side {
+	case 1:
+		return model.Buy
+	case 2:
+		return model.Sell
+	default:
+		return ""
+	}
+}
+
 func mapSideToInt16(side model.Side) int16 {
 	switch side {
 	case model.Buy:
@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -28,18 +29,39 @@ func (m *MockTradeQueries) CreateTradeSide(ctx context.Context, params sqlc.Crea
 	return args.Error(0)
 }
 
+func (m *MockTradeQueries) ListTradesSince(ctx context.Context, transactTime int64) ([]sqlc.TradeCaptureReport, error) {
+	args := m.Called(ctx, transactTime)
+	return args.Get(0).([]sqlc.TradeCaptureReport), args.Error(1)
+}
+
+func (m *MockTradeQueries) ListRecentTradesWithSides(ctx context.Context, params sqlc.ListRecentTradesWithSidesParams) ([]sqlc.ListRecentTradesWithSidesRow, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).([]sqlc.ListRecentTradesWithSidesRow), args.Error(1)
+}
+
+func (m *MockTradeQueries) ListTradeWithSides(ctx context.Context, params sqlc.ListTradeWithSidesParams) ([]sqlc.ListTradeWithSidesRow, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).([]sqlc.ListTradeWithSidesRow), args.Error(1)
+}
+
+func (m *MockTradeQueries) GetTradeWithFills(ctx context.Context, tradeID pgtype.Text) ([]sqlc.GetTradeWithFillsRow, error) {
+	args := m.Called(ctx, tradeID)
+	return args.Get(0).([]sqlc.GetTradeWithFillsRow), args.Error(1)
+}
+
 func TestSaveTrade(t *testing.T) {
 	mockQueries := new(MockTradeQueries)
 	repo := NewPostgresTradeRepository(mockQueries)
 
 	trade := model.TradeCaptureReport{
-		MsgType:      "AE",
-		ExecID:       "EXEC123",
-		Symbol:       "BTC/USDT",
-		LastQty:      decimal.NewFromInt(5),
-		LastPx:       decimal.NewFromInt(100),
-		TradeDate:    time.Now().Format("2006-01-02"),
-		TransactTime: time.Now().UnixNano(),
+		MsgType:       "AE",
+		TradeReportID: "TRADE123",
+		ExecID:        "EXEC123",
+		Symbol:        "BTC/USDT",
+		LastQty:       decimal.NewFromInt(5),
+		LastPx:        decimal.NewFromInt(100),
+		TradeDate:     "20250101",
+		TransactTime:  time.Date(2025, 1, 1, 23, 59, 0, 0, time.UTC).UnixNano(),
 		NoSides: []model.NoSides{
 			{
 				Side:    model.Buy,
@@ -52,14 +74,17 @@ func TestSaveTrade(t *testing.T) {
 		},
 	}
 
+	// The trade and its sides are filed under the UTC day of TransactTime
+	day := pgtype.Date{Time: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), Valid: true}
+
 	// Match CreateTrade
 	mockQueries.On("CreateTrade", mock.Anything, mock.MatchedBy(func(p sqlc.CreateTradeParams) bool {
-		return p.ExecID == trade.ExecID && p.Symbol == trade.Symbol
+		return p.TradeReportID == "TRADE123" && p.ExecID == trade.ExecID && p.Symbol == trade.Symbol && p.TradeDate == day
 	})).Return(nil)
 
 	// Match CreateTradeSide for each side
 	mockQueries.On("CreateTradeSide", mock.Anything, mock.MatchedBy(func(p sqlc.CreateTradeSideParams) bool {
-		return p.OrderID == "order-001" || p.OrderID == "order-002"
+		return p.TradeReportID == "TRADE123" && (p.OrderID == "order-001" || p.OrderID == "order-002") && p.TradeDate == day
 	})).Return(nil).Twice()
 
 	err := repo.SaveTrade(context.Background(), trade)
@@ -67,3 +92,141 @@ func TestSaveTrade(t *testing.T) {
 
 	mockQueries.AssertExpectations(t)
 }
+
+func TestListTradesSince(t *testing.T) {
+	mockQueries := new(MockTradeQueries)
+	repo := NewPostgresTradeRepository(mockQueries)
+
+	lastPx, _ := decimalToPgNumeric(decimal.RequireFromString("100.25"))
+	lastQty, _ := decimalToPgNumeric(decimal.NewFromInt(3))
+	mockQueries.On("ListTradesSince", mock.Anything, int64(1000)).Return([]sqlc.TradeCaptureReport{
+		{
+			TradeReportID: "tradeReport-1",
+			MsgType:       "AE",
+			ExecID:        "execution-1",
+			Symbol:        "BTC/USDT",
+			LastQty:       lastQty,
+			LastPx:        lastPx,
+			TradeDate:     pgtype.Date{Time: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), Valid: true},
+			TransactTime:  2000,
+		},
+	}, nil)
+
+	trades, err := repo.ListTradesSince(context.Background(), 1000)
+	assert.NoError(t, err)
+	assert.Len(t, trades, 1)
+	assert.Equal(t, "BTC/USDT", trades[0].Symbol)
+	assert.True(t, trades[0].LastPx.Equal(decimal.RequireFromString("100.25")))
+	assert.True(t, trades[0].LastQty.Equal(decimal.NewFromInt(3)))
+
+	mockQueries.AssertExpectations(t)
+}
+
+func TestListRecentTrades(t *testing.T) {
+	mockQueries := new(MockTradeQueries)
+	repo := NewPostgresTradeRepository(mockQueries)
+
+	px, _ := decimalToPgNumeric(decimal.NewFromInt(100))
+	qty, _ := decimalToPgNumeric(decimal.NewFromInt(1))
+	row := func(id string, ts int64, side int16, orderID string) sqlc.ListRecentTradesWithSidesRow {
+		return sqlc.ListRecentTradesWithSidesRow{
+			TradeReportID: id, MsgType: "AE", Symbol: "BTC/USDT", LastQty: qty, LastPx: px,
+			TransactTime: ts, Side: side, OrderID: orderID,
+		}
+	}
+	mockQueries.On("ListRecentTradesWithSides", mock.Anything,
+		sqlc.ListRecentTradesWithSidesParams{Symbol: "BTC/USDT", Limit: 2}).
+		Return([]sqlc.ListRecentTradesWithSidesRow{
+			row("tradeReport-2", 2000, 1, "order-3"),
+			row("tradeReport-2", 2000, 2, "order-1"),
+			row("tradeReport-1", 1000, 1, "order-2"),
+			row("tradeReport-1", 1000, 2, "order-1"),
+		}, nil)
+
+	trades, err := repo.ListRecentTrades(context.Background(), "BTC/USDT", 2)
+	assert.NoError(t, err)
+	assert.Len(t, trades, 2)
+	assert.Equal(t, "tradeReport-2", trades[0].TradeReportID)
+	assert.Equal(t, []model.NoSides{{Side: model.Buy, OrderID: "order-3"}, {Side: model.Sell, OrderID: "order-1"}}, trades[0].NoSides)
+	assert.Equal(t, "tradeReport-1", trades[1].TradeReportID)
+	assert.Len(t, trades[1].NoSides, 2)
+
+	mockQueries.AssertExpectations(t)
+}
+
+func TestGetTradeWithFills(t *testing.T) {
+	mockQueries := new(MockTradeQueries)
+	repo := NewPostgresTradeRepository(mockQueries)
+
+	px, _ := decimalToPgNumeric(decimal.NewFromInt(100))
+	qty, _ := decimalToPgNumeric(decimal.NewFromInt(1))
+	trade := sqlc.TradeCaptureReport{
+		TradeReportID: "tradeReport-1", MsgType: "AE", Symbol: "BTC/USDT", LastQty: qty, LastPx: px,
+		TradeID: pgtype.Text{String: "trade-1", Valid: true},
+	}
+	fill := func(execID, orderID string) sqlc.Execution {
+		return sqlc.Execution{
+			MsgType: "8", ExecID: execID, OrderID: orderID, ClOrdID: pgtype.Text{String: "cl-" + orderID, Valid: true},
+			ExecType: "F", OrdStatus: "2", Symbol: "BTC/USDT", LastShares: qty, LastPx: px,
+			TradeID: pgtype.Text{String: "trade-1", Valid: true},
+		}
+	}
+	mockQueries.On("GetTradeWithFills", mock.Anything, pgtype.Text{String: "trade-1", Valid: true}).
+		Return([]sqlc.GetTradeWithFillsRow{
+			{TradeCaptureReport: trade, Side: 1, Execution: fill("execution-1", "order-1")},
+			{TradeCaptureReport: trade, Side: 2, Execution: fill("execution-2", "order-2")},
+		}, nil)
+	mockQueries.On("GetTradeWithFills", mock.Anything, pgtype.Text{String: "trade-2", Valid: true}).
+		Return([]sqlc.GetTradeWithFillsRow{}, nil)
+
+	got, fills, err := repo.GetTradeWithFills(context.Background(), "trade-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "trade-1", got.TradeID)
+	assert.Equal(t, []model.NoSides{
+		{Side: model.Buy, OrderID: "order-1", ClOrdID: "cl-order-1", ExecID: "execution-1"},
+		{Side: model.Sell, OrderID: "order-2", ClOrdID: "cl-order-2", ExecID: "execution-2"},
+	}, got.NoSides)
+	assert.Len(t, fills, 2)
+	assert.Equal(t, "trade-1", fills[1].TradeID)
+
+	_, _, err = repo.GetTradeWithFills(context.Background(), "trade-2")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestListTrades(t *testing.T) {
+	mockQueries := new(MockTradeQueries)
+	repo := NewPostgresTradeRepository(mockQueries)
+
+	px, _ := decimalToPgNumeric(decimal.NewFromInt(100))
+	qty, _ := decimalToPgNumeric(decimal.NewFromInt(1))
+	row := func(id string, ts int64, side int16, orderID string) sqlc.ListTradeWithSidesRow {
+		return sqlc.ListTradeWithSidesRow{
+			TradeReportID: id, MsgType: "AE", Symbol: "BTC/USDT", LastQty: qty, LastPx: px,
+			TransactTime: ts, Side: side, OrderID: orderID,
+		}
+	}
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC).UnixNano()
+	to := time.Date(2025, 1, 3, 0, 0, 0, 0, time.UTC).UnixNano()
+	mockQueries.On("ListTradeWithSides", mock.Anything, sqlc.ListTradeWithSidesParams{
+		// The end is exclusive, so the 3rd is not searched
+		FromDate: pgtype.Date{Time: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), Valid: true},
+		ToDate:   pgtype.Date{Time: time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC), Valid: true},
+		FromTime: from,
+		ToTime:   to,
+		OrderID:  pgtype.Text{String: "order-1", Valid: true},
+		MaxRows:  10,
+	}).Return([]sqlc.ListTradeWithSidesRow{
+		row("tradeReport-1", from+1000, 1, "order-2"),
+		row("tradeReport-1", from+1000, 2, "order-1"),
+		row("tradeReport-2", from+2000, 1, "order-1"),
+		row("tradeReport-2", from+2000, 2, "order-3"),
+	}, nil)
+
+	trades, err := repo.ListTrades(context.Background(), model.HistoryFilter{OrderID: "order-1", From: from, To: to}, HistoryKey{}, 10)
+	assert.NoError(t, err)
+	assert.Len(t, trades, 2)
+	assert.Equal(t, "tradeReport-1", trades[0].TradeReportID)
+	assert.Equal(t, []model.NoSides{{Side: model.Buy, OrderID: "order-1"}, {Side: model.Sell, OrderID: "order-3"}}, trades[1].NoSides)
+
+	mockQueries.AssertExpectations(t)
+}
@@ -0,0 +1,192 @@
+package rmq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/streadway/amqp"
+
+	"MatchingEngine/internal/model"
+)
+
+const defaultClientTimeout = 5 * time.Second
+
+// ErrNoReply is a request whose reply did not arrive in time. The engine may
+// still process it.
+var ErrNoReply = errors.New("no reply before the deadline")
+
+// RejectError is a request the engine refused with a BusinessMessageReject.
+type RejectError struct {
+	Reject model.BusinessMessageReject
+}
+
+func (e *RejectError) Error() string {
+	return fmt.Sprintf("request rejected (reason %s): %s", e.Reject.BusinessRejectReason, e.Reject.Text)
+}
+
+type ClientOpts struct {
+	RabbitMQURL string
+	// Requests are published to Exchange with RoutingKey; with no exchange,
+	// RoutingKey is the engine's queue. Router, when set, routes each
+	// request to the shard of its symbol instead.
+	Exchange   string
+	RoutingKey string
+	Router     ShardRouter
+	Timeout    time.Duration // Applied to every Submit; 5s when zero
+}
+
+// Client submits order requests over AMQP and waits for the engine's
+// answer, correlated through a reply queue of its own.
+type Client struct {
+	opts       ClientOpts
+	publisher  Publisher
+	replyQueue string
+	conn       *amqp.Connection
+
+	mu      sync.Mutex
+	pending map[string]chan []byte // by correlation ID
+}
+
+// DialClient connects, declares an exclusive reply queue and starts
+// listening on it.
+func DialClient(opts ClientOpts) (*Client, error) {
+	conn, err := amqp.Dial(opts.RabbitMQURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to RabbitMQ: %w", err)
+	}
+	ch, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to open channel: %w", err)
+	}
+	q, err := ch.QueueDeclare("", false, true, true, false, nil)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to declare reply queue: %w", err)
+	}
+	replies, err := ch.Consume(q.Name, "", true, true, false, false, nil)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to consume reply queue: %w", err)
+	}
+
+	c := NewClient(ch, q.Name, opts)
+	c.conn = conn
+	go c.Listen(replies)
+	return c, nil
+}
+
+// NewClient builds a client that publishes with publisher and expects its
+// replies on replyQueue, to be fed to Listen.
+func NewClient(publisher Publisher, replyQueue string, opts ClientOpts) *Client {
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultClientTimeout
+	}
+	return &Client{
+		opts:       opts,
+		publisher:  publisher,
+		replyQueue: replyQueue,
+		pending:    make(map[string]chan []byte),
+	}
+}
+
+// Listen hands each reply to the Submit waiting for it until replies is
+// closed. Replies nobody waits for any more are dropped.
+func (c *Client) Listen(replies <-chan amqp.Delivery) {
+	for d := range replies {
+		c.mu.Lock()
+		wait, ok := c.pending[d.CorrelationId]
+		c.mu.Unlock()
+		if !ok {
+			continue
+		}
+		select {
+		case wait <- d.Body:
+		default:
+		}
+	}
+}
+
+// Submit publishes req and returns the engine's first execution report for
+// it, which may be a rejection. It returns a *RejectError when the engine
+// refused the request, and ErrNoReply when neither came before the timeout
+// or the end of ctx.
+func (c *Client) Submit(ctx context.Context, req model.OrderRequest) (model.ExecutionReport, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.opts.Timeout)
+	defer cancel()
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return model.ExecutionReport{}, fmt.Errorf("failed to marshal order request: %w", err)
+	}
+	key := c.opts.RoutingKey
+	if c.opts.Router != nil {
+		key = c.opts.Router.Owner(req.Symbol())
+	}
+
+	id := uuid.NewString()
+	reply := make(chan []byte, 1)
+	c.mu.Lock()
+	c.pending[id] = reply
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	err = c.publisher.Publish(c.opts.Exchange, key, false, false, amqp.Publishing{
+		ContentType:   "application/json",
+		DeliveryMode:  amqp.Persistent,
+		ReplyTo:       c.replyQueue,
+		CorrelationId: id,
+		Body:          body,
+	})
+	if err != nil {
+		return model.ExecutionReport{}, fmt.Errorf("failed to publish order request: %w", err)
+	}
+
+	select {
+	case body := <-reply:
+		return decodeReply(body)
+	case <-ctx.Done():
+		return model.ExecutionReport{}, ErrNoReply
+	}
+}
+
+func decodeReply(body []byte) (model.ExecutionReport, error) {
+	var head struct {
+		MsgType model.MsgType `json:"35"`
+	}
+	if err := json.Unmarshal(body, &head); err != nil {
+		return model.ExecutionReport{}, fmt.Errorf("failed to decode reply: %w", err)
+	}
+	switch head.MsgType {
+	case model.MsgTypeExecRpt:
+		var er model.ExecutionReport
+		if err := json.Unmarshal(body, &er); err != nil {
+			return model.ExecutionReport{}, fmt.Errorf("failed to decode execution report: %w", err)
+		}
+		return er, nil
+	case model.MsgTypeBusinessRej:
+		var rej model.BusinessMessageReject
+		if err := json.Unmarshal(body, &rej); err != nil {
+			return model.ExecutionReport{}, fmt.Errorf("failed to decode business reject: %w", err)
+		}
+		return model.ExecutionReport{}, &RejectError{Reject: rej}
+	}
+	return model.ExecutionReport{}, fmt.Errorf("unexpected reply MsgType %q", head.MsgType)
+}
+
+// Close closes the connection DialClient opened.
+func (c *Client) Close() error {
+	if c.conn == nil {
+		return nil
+	}
+	return c.conn.Close()
+}
@@ -0,0 +1,121 @@
+package rmq
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"MatchingEngine/internal/handler"
+	"MatchingEngine/internal/model"
+	"MatchingEngine/internal/service"
+	"MatchingEngine/orderBook"
+)
+
+type nopAcknowledger struct{}
+
+func (nopAcknowledger) Ack(uint64, bool) error        { return nil }
+func (nopAcknowledger) Nack(uint64, bool, bool) error { return nil }
+func (nopAcknowledger) Reject(uint64, bool) error     { return nil }
+
+type nopNotifier struct{}
+
+func (nopNotifier) NotifyEventAndTrade(model.MsgType, string, json.RawMessage) error { return nil }
+
+// loopback delivers each request straight to an engine's request handler,
+// and its replies back to the client.
+type loopback struct {
+	engine  *handler.OrderRequestHandler
+	replies chan amqp.Delivery
+}
+
+func (l *loopback) Publish(_, _ string, _, _ bool, msg amqp.Publishing) error {
+	l.engine.HandleOrderMessage(amqp.Delivery{
+		Acknowledger:  nopAcknowledger{},
+		ReplyTo:       msg.ReplyTo,
+		CorrelationId: msg.CorrelationId,
+		Body:          msg.Body,
+	})
+	return nil
+}
+
+func (l *loopback) Reply(_, correlationID string, body json.RawMessage) error {
+	l.replies <- amqp.Delivery{CorrelationId: correlationID, Body: body}
+	return nil
+}
+
+func newLoopbackClient() *Client {
+	waiter := service.NewReportWaiter()
+	orders := service.NewOrderService(waiter.Notifier(nopNotifier{}), orderBook.BookOpts{})
+	waiter.Orders = orders
+
+	engine := handler.NewOrderRequestHandler(orders)
+	engine.Reports = waiter
+	l := &loopback{engine: engine, replies: make(chan amqp.Delivery, 16)}
+	engine.Replies = l
+
+	client := NewClient(l, "replies", ClientOpts{RoutingKey: "orders"})
+	go client.Listen(l.replies)
+	return client
+}
+
+func clientOrder(clOrdID string, side model.Side, qty int64, symbol string) model.OrderRequest {
+	return model.OrderRequest{
+		MsgType: model.MsgTypeNew,
+		NewOrderReq: model.NewOrderRequest{
+			BaseOrderRequest: model.BaseOrderRequest{MsgType: model.MsgTypeNew, ClOrdID: clOrdID, Side: side, Symbol: symbol},
+			OrderQty:         decimal.NewFromInt(qty),
+			Price:            decimal.NewFromInt(100),
+		},
+	}
+}
+
+func TestClient_SubmitReturnsFirstReport(t *testing.T) {
+	client := newLoopbackClient()
+	ctx := context.Background()
+
+	er, err := client.Submit(ctx, clientOrder("S1", model.Sell, 1, "BTC/USDT"))
+	require.NoError(t, err)
+	assert.Equal(t, "S1", er.ClOrdID)
+	assert.Equal(t, model.ExecTypeNew, er.ExecType)
+
+	er, err = client.Submit(ctx, clientOrder("B1", model.Buy, 1, "BTC/USDT"))
+	require.NoError(t, err)
+	assert.Equal(t, model.ExecTypeFill, er.ExecType)
+
+	// An invalid order is answered by its rejection report.
+	er, err = client.Submit(ctx, clientOrder("B2", model.Buy, 0, "BTC/USDT"))
+	require.NoError(t, err)
+	assert.Equal(t, model.ExecTypeRejected, er.ExecType)
+}
+
+func TestClient_SubmitReturnsBusinessReject(t *testing.T) {
+	client := newLoopbackClient()
+
+	_, err := client.Submit(context.Background(), clientOrder("B1", model.Buy, 1, ""))
+	var rej *RejectError
+	require.ErrorAs(t, err, &rej)
+	assert.Equal(t, model.BusinessRejectMissingField, rej.Reject.BusinessRejectReason)
+	assert.Equal(t, "B1", rej.Reject.BusinessRejectRefID)
+	assert.Equal(t, string(model.MsgTypeNew), rej.Reject.RefMsgType)
+}
+
+func TestClient_SubmitTimesOutWithoutReply(t *testing.T) {
+	pub := &recordingPublisher{}
+	client := NewClient(pub, "replies", ClientOpts{Router: mapRouter{"BTC/USDT": "shard-0"}, Timeout: 20 * time.Millisecond})
+
+	_, err := client.Submit(context.Background(), clientOrder("B1", model.Buy, 1, "BTC/USDT"))
+	assert.ErrorIs(t, err, ErrNoReply)
+
+	require.Len(t, pub.messages, 1)
+	msg := pub.messages[0]
+	assert.Equal(t, "shard-0", msg.key)
+	assert.Equal(t, "replies", msg.msg.ReplyTo)
+	assert.NotEmpty(t, msg.msg.CorrelationId)
+	assert.Empty(t, client.pending)
+}
@@ -2,8 +2,12 @@ package rmq
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"math/rand/v2"
+	"sync"
+	"time"
 
 	"github.com/streadway/amqp"
 )
@@ -12,68 +16,242 @@ type ConsumerOpts struct {
 	RabbitMQURL string
 	QueueName   string
 	Prefetch    int
+	// Exchange and RoutingKey bind the queue to a direct exchange, as each
+	// shard does with its own queue. Without an exchange the queue is fed
+	// through the default exchange.
+	Exchange   string
+	RoutingKey string
+	// DeadLetterExchange, when set, receives the messages the handler gives
+	// up on, routed to the queue's DLQ (see DeadLetterQueue).
+	DeadLetterExchange string
+	// ReconnectMin and ReconnectMax bound the backoff between reconnects.
+	ReconnectMin time.Duration
+	ReconnectMax time.Duration
+	Dial         Dialer // Dial when nil
 }
 
 type MessageHandler interface {
 	HandleOrderMessage(msg amqp.Delivery)
 }
 
+const (
+	defaultReconnectMin = 500 * time.Millisecond
+	defaultReconnectMax = 30 * time.Second
+)
+
+// Consumer feeds the deliveries of a queue to a handler. When the connection
+// or the channel is lost it reconnects, waiting a jittered backoff that
+// doubles from ReconnectMin up to ReconnectMax, and declares its topology
+// again.
 type Consumer struct {
 	opts           ConsumerOpts
 	requestHandler MessageHandler
+
+	mu         sync.Mutex
+	ch         Channel // nil while disconnected
+	since      time.Time
+	reconnects int
+	lastErr    error
+}
+
+// ConsumerHealth is a consumer's connection state.
+type ConsumerHealth struct {
+	Connected  bool      `json:"connected"`
+	Since      time.Time `json:"since"` // Of the current state
+	Reconnects int       `json:"reconnects"`
+	LastError  string    `json:"last_error,omitempty"`
 }
 
 func NewConsumer(opts ConsumerOpts, requestHandler MessageHandler) *Consumer {
+	if opts.Dial == nil {
+		opts.Dial = Dial
+	}
+	if opts.ReconnectMin <= 0 {
+		opts.ReconnectMin = defaultReconnectMin
+	}
+	if opts.ReconnectMax < opts.ReconnectMin {
+		opts.ReconnectMax = max(defaultReconnectMax, opts.ReconnectMin)
+	}
 	return &Consumer{
 		opts:           opts,
 		requestHandler: requestHandler,
+		since:          time.Now(),
 	}
 }
 
+// Start consumes until ctx ends, reconnecting as often as it takes.
 func (c *Consumer) Start(ctx context.Context) error {
-	conn, err := amqp.Dial(c.opts.RabbitMQURL)
+	backoff := c.opts.ReconnectMin
+	for {
+		connected, err := c.consume(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if connected {
+			backoff = c.opts.ReconnectMin
+		}
+		wait := jitter(backoff)
+		log.Printf("Consumer for queue %s lost: %v; reconnecting in %s", c.opts.QueueName, err, wait)
+		c.setDisconnected(err)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(wait):
+		}
+		backoff = min(backoff*2, c.opts.ReconnectMax)
+	}
+}
+
+// jitter spreads a wait over [d/2, d) so consumers cut off together do not
+// come back together.
+func jitter(d time.Duration) time.Duration {
+	half := d / 2
+	return half + rand.N(d-half)
+}
+
+// consume runs one session: it connects, declares the topology and hands
+// deliveries to the handler until the connection or the channel closes. It
+// tells whether it got as far as consuming.
+func (c *Consumer) consume(ctx context.Context) (bool, error) {
+	conn, err := c.opts.Dial(c.opts.RabbitMQURL)
 	if err != nil {
-		return fmt.Errorf("failed to connect to RabbitMQ: %w", err)
+		return false, fmt.Errorf("failed to connect to RabbitMQ: %w", err)
 	}
 	defer conn.Close()
+	connClosed := conn.NotifyClose(make(chan *amqp.Error, 1))
 
 	ch, err := conn.Channel()
 	if err != nil {
-		return fmt.Errorf("failed to open channel: %w", err)
+		return false, fmt.Errorf("failed to open channel: %w", err)
 	}
 	defer ch.Close()
+	chClosed := ch.NotifyClose(make(chan *amqp.Error, 1))
 
-	_, err = ch.QueueDeclare(
-		c.opts.QueueName, true, false, false, false, nil,
-	)
-	if err != nil {
-		return fmt.Errorf("failed to declare queue: %w", err)
+	if err := c.declare(ch); err != nil {
+		return false, err
 	}
 
 	err = ch.Qos(c.opts.Prefetch, 0, false)
 	if err != nil {
-		return fmt.Errorf("failed to set QoS: %w", err)
+		return false, fmt.Errorf("failed to set QoS: %w", err)
 	}
 
 	msgs, err := ch.Consume(
 		c.opts.QueueName, "", false, false, false, false, nil,
 	)
 	if err != nil {
-		return fmt.Errorf("failed to start consuming: %w", err)
+		return false, fmt.Errorf("failed to start consuming: %w", err)
 	}
 
-	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case msg := <-msgs:
-				c.requestHandler.HandleOrderMessage(msg)
+	c.setConnected(ch)
+	defer c.clearChannel()
+	log.Printf("Consumer started for queue: %s", c.opts.QueueName)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return true, nil
+		case amqpErr := <-connClosed:
+			return true, fmt.Errorf("connection closed: %v", amqpErr)
+		case amqpErr := <-chClosed:
+			return true, fmt.Errorf("channel closed: %v", amqpErr)
+		case msg, ok := <-msgs:
+			// A closed delivery channel yields zero deliveries forever.
+			if !ok {
+				return true, errors.New("delivery channel closed")
 			}
+			c.requestHandler.HandleOrderMessage(msg)
 		}
-	}()
+	}
+}
 
-	log.Printf("Consumer started for queue: %s", c.opts.QueueName)
-	<-ctx.Done()
+// declare declares the queue with its dead-letter and shard bindings.
+func (c *Consumer) declare(ch Channel) error {
+	if c.opts.DeadLetterExchange != "" {
+		if err := declareDeadLetter(ch, c.opts.DeadLetterExchange, c.opts.QueueName); err != nil {
+			return err
+		}
+	}
+
+	_, err := ch.QueueDeclare(
+		c.opts.QueueName, true, false, false, false, queueArgs(c.opts.DeadLetterExchange, c.opts.QueueName),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to declare queue: %w", err)
+	}
+
+	if c.opts.Exchange != "" {
+		if err := declareShardBinding(ch, c.opts.Exchange, c.opts.QueueName, c.opts.RoutingKey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Consumer) setConnected(ch Channel) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ch = ch
+	c.since = time.Now()
+	c.lastErr = nil
+}
+
+func (c *Consumer) clearChannel() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ch = nil
+}
+
+func (c *Consumer) setDisconnected(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.lastErr == nil {
+		c.since = time.Now()
+	}
+	c.reconnects++
+	c.lastErr = err
+}
+
+// Health returns the consumer's connection state.
+func (c *Consumer) Health() ConsumerHealth {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	h := ConsumerHealth{Connected: c.ch != nil, Since: c.since, Reconnects: c.reconnects}
+	if c.lastErr != nil {
+		h.LastError = c.lastErr.Error()
+	}
+	return h
+}
+
+// Ready returns nil while the consumer is consuming, and why not otherwise.
+func (c *Consumer) Ready() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ch != nil {
+		return nil
+	}
+	if c.lastErr != nil {
+		return fmt.Errorf("not consuming from %s: %w", c.opts.QueueName, c.lastErr)
+	}
+	return fmt.Errorf("not consuming from %s yet", c.opts.QueueName)
+}
+
+// DeadLetter publishes msg to the dead-letter exchange with the reason it
+// failed and the retries it went through. The caller still acks msg.
+func (c *Consumer) DeadLetter(msg amqp.Delivery, reason string, cause error, retries int) error {
+	if c.opts.DeadLetterExchange == "" {
+		return errors.New("no dead-letter exchange configured")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ch == nil {
+		return errors.New("not connected to RabbitMQ")
+	}
+	err := c.ch.Publish(c.opts.DeadLetterExchange, c.opts.QueueName, false, false,
+		deadLetterPublishing(msg, c.opts.QueueName, reason, cause, retries))
+	if err != nil {
+		return fmt.Errorf("failed to publish dead letter: %w", err)
+	}
 	return nil
 }
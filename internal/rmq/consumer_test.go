@@ -0,0 +1,267 @@
+package rmq
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeBroker stands in for RabbitMQ: it hands out connections, counts the
+// declarations made on them, and can sever the live connection.
+type fakeBroker struct {
+	mu         sync.Mutex
+	failDials  int
+	dials      int
+	declared   int
+	deliveries chan amqp.Delivery
+	conn       *fakeConn
+}
+
+func newFakeBroker() *fakeBroker {
+	return &fakeBroker{deliveries: make(chan amqp.Delivery)}
+}
+
+func (b *fakeBroker) Dial(string) (Connection, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.dials++
+	if b.failDials > 0 {
+		b.failDials--
+		return nil, errors.New("connection refused")
+	}
+	b.conn = &fakeConn{broker: b}
+	return b.conn, nil
+}
+
+// sever drops the live connection the way a broker restart does.
+func (b *fakeBroker) sever() {
+	b.mu.Lock()
+	conn := b.conn
+	b.conn = nil
+	b.mu.Unlock()
+	conn.close(&amqp.Error{Code: amqp.ConnectionForced, Reason: "CONNECTION_FORCED"})
+}
+
+func (b *fakeBroker) live() *fakeConn {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.conn
+}
+
+type fakeConn struct {
+	broker *fakeBroker
+
+	mu       sync.Mutex
+	closed   bool
+	notify   []chan *amqp.Error
+	channels []*fakeChannel
+}
+
+func (c *fakeConn) Channel() (Channel, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := &fakeChannel{conn: c, msgs: make(chan amqp.Delivery)}
+	c.channels = append(c.channels, ch)
+	return ch, nil
+}
+
+func (c *fakeConn) NotifyClose(receiver chan *amqp.Error) chan *amqp.Error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.notify = append(c.notify, receiver)
+	return receiver
+}
+
+func (c *fakeConn) Close() error {
+	c.close(nil)
+	return nil
+}
+
+// close closes the channels, then tells the listeners, as amqp does.
+func (c *fakeConn) close(err *amqp.Error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	c.closed = true
+	for _, ch := range c.channels {
+		ch.close()
+	}
+	for _, n := range c.notify {
+		if err != nil {
+			n <- err
+		}
+		close(n)
+	}
+}
+
+type fakeChannel struct {
+	conn *fakeConn
+	msgs chan amqp.Delivery
+
+	once   sync.Once
+	notify []chan *amqp.Error
+}
+
+func (ch *fakeChannel) Publish(string, string, bool, bool, amqp.Publishing) error { return nil }
+
+func (ch *fakeChannel) ExchangeDeclare(string, string, bool, bool, bool, bool, amqp.Table) error {
+	return nil
+}
+
+func (ch *fakeChannel) QueueDeclare(name string, _, _, _, _ bool, _ amqp.Table) (amqp.Queue, error) {
+	ch.conn.broker.mu.Lock()
+	ch.conn.broker.declared++
+	ch.conn.broker.mu.Unlock()
+	return amqp.Queue{Name: name}, nil
+}
+
+func (ch *fakeChannel) QueueBind(string, string, string, bool, amqp.Table) error { return nil }
+
+func (ch *fakeChannel) Qos(int, int, bool) error { return nil }
+
+// Consume forwards the broker's deliveries until the channel closes.
+func (ch *fakeChannel) Consume(string, string, bool, bool, bool, bool, amqp.Table) (<-chan amqp.Delivery, error) {
+	out := make(chan amqp.Delivery)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ch.msgs:
+				return
+			case d := <-ch.conn.broker.deliveries:
+				out <- d
+			}
+		}
+	}()
+	return out, nil
+}
+
+func (ch *fakeChannel) NotifyClose(receiver chan *amqp.Error) chan *amqp.Error {
+	ch.notify = append(ch.notify, receiver)
+	return receiver
+}
+
+func (ch *fakeChannel) Close() error {
+	ch.close()
+	return nil
+}
+
+func (ch *fakeChannel) close() {
+	ch.once.Do(func() {
+		close(ch.msgs)
+		for _, n := range ch.notify {
+			close(n)
+		}
+	})
+}
+
+type recordingHandler struct {
+	mu     sync.Mutex
+	bodies []string
+}
+
+func (h *recordingHandler) HandleOrderMessage(msg amqp.Delivery) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.bodies = append(h.bodies, string(msg.Body))
+}
+
+func (h *recordingHandler) received() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.bodies...)
+}
+
+func startConsumer(t *testing.T, broker *fakeBroker, handler MessageHandler) *Consumer {
+	consumer := NewConsumer(ConsumerOpts{
+		QueueName:    "orderRequests",
+		Prefetch:     1,
+		ReconnectMin: time.Millisecond,
+		ReconnectMax: 4 * time.Millisecond,
+		Dial:         broker.Dial,
+	}, handler)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- consumer.Start(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		assert.NoError(t, <-done)
+	})
+	return consumer
+}
+
+func deliver(t *testing.T, broker *fakeBroker, body string) {
+	t.Helper()
+	select {
+	case broker.deliveries <- amqp.Delivery{Body: []byte(body)}:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("nobody consumed %s", body)
+	}
+}
+
+func TestConsumer_ReconnectsAfterConnectionLoss(t *testing.T) {
+	broker := newFakeBroker()
+	handler := &recordingHandler{}
+	consumer := startConsumer(t, broker, handler)
+
+	deliver(t, broker, "first")
+	require.Eventually(t, func() bool { return consumer.Ready() == nil }, 5*time.Second, time.Millisecond)
+
+	broker.sever()
+	require.Eventually(t, func() bool { return consumer.Health().Reconnects == 1 }, 5*time.Second, time.Millisecond)
+	require.Eventually(t, func() bool { return broker.live() != nil && consumer.Ready() == nil }, 5*time.Second, time.Millisecond)
+
+	deliver(t, broker, "second")
+	require.Eventually(t, func() bool { return len(handler.received()) == 2 }, 5*time.Second, time.Millisecond)
+
+	// No zero deliveries from the closed channel, and the queue was declared
+	// again on the new connection.
+	assert.Equal(t, []string{"first", "second"}, handler.received())
+	broker.mu.Lock()
+	assert.Equal(t, 2, broker.declared)
+	broker.mu.Unlock()
+	health := consumer.Health()
+	assert.True(t, health.Connected)
+	assert.Empty(t, health.LastError)
+}
+
+func TestConsumer_RetriesUntilBrokerIsUp(t *testing.T) {
+	broker := newFakeBroker()
+	broker.failDials = 3
+	consumer := NewConsumer(ConsumerOpts{
+		QueueName:    "orderRequests",
+		ReconnectMin: time.Millisecond,
+		ReconnectMax: 2 * time.Millisecond,
+		Dial:         broker.Dial,
+	}, &recordingHandler{})
+	assert.ErrorContains(t, consumer.Ready(), "not consuming from orderRequests yet")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- consumer.Start(ctx) }()
+	require.Eventually(t, func() bool { return consumer.Ready() == nil }, 5*time.Second, time.Millisecond)
+	cancel()
+	require.NoError(t, <-done)
+
+	broker.mu.Lock()
+	assert.Equal(t, 4, broker.dials)
+	broker.mu.Unlock()
+	assert.Equal(t, 3, consumer.Health().Reconnects)
+	assert.Error(t, consumer.Ready())
+}
+
+func TestJitterStaysWithinBackoff(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		d := jitter(100 * time.Millisecond)
+		assert.GreaterOrEqual(t, d, 50*time.Millisecond)
+		assert.Less(t, d, 100*time.Millisecond)
+	}
+}
@@ -0,0 +1,238 @@
+package rmq
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/streadway/amqp"
+)
+
+// Headers set on a dead-lettered message.
+const (
+	HeaderDeadLetterID  = "x-dead-letter-id"
+	HeaderFailureReason = "x-failure-reason"
+	HeaderFailureError  = "x-failure-error"
+	HeaderFailedAt      = "x-failed-at"
+	HeaderRetryCount    = "x-retry-count"
+	HeaderOriginalQueue = "x-original-queue"
+)
+
+// DeadLetterQueue names the queue the failed messages of queueName end up in.
+func DeadLetterQueue(queueName string) string {
+	return queueName + ".dlq"
+}
+
+// declareDeadLetter declares the dead-letter exchange and the queue's DLQ,
+// bound with the queue's name as routing key.
+func declareDeadLetter(ch Channel, exchange, queueName string) error {
+	if err := ch.ExchangeDeclare(exchange, amqp.ExchangeDirect, true, false, false, false, nil); err != nil {
+		return fmt.Errorf("failed to declare dead-letter exchange: %w", err)
+	}
+	dlq := DeadLetterQueue(queueName)
+	if _, err := ch.QueueDeclare(dlq, true, false, false, false, nil); err != nil {
+		return fmt.Errorf("failed to declare dead-letter queue: %w", err)
+	}
+	if err := ch.QueueBind(dlq, queueName, exchange, false, nil); err != nil {
+		return fmt.Errorf("failed to bind dead-letter queue: %w", err)
+	}
+	return nil
+}
+
+// queueArgs makes the broker dead-letter what is nacked without requeue, for
+// the messages that cannot be published to the DLX with their reason.
+func queueArgs(exchange, queueName string) amqp.Table {
+	if exchange == "" {
+		return nil
+	}
+	return amqp.Table{
+		"x-dead-letter-exchange":    exchange,
+		"x-dead-letter-routing-key": queueName,
+	}
+}
+
+// deadLetterPublishing copies msg with the failure headers added.
+func deadLetterPublishing(msg amqp.Delivery, queueName, reason string, cause error, retries int) amqp.Publishing {
+	headers := amqp.Table{}
+	for k, v := range msg.Headers {
+		headers[k] = v
+	}
+	headers[HeaderDeadLetterID] = uuid.NewString()
+	headers[HeaderFailureReason] = reason
+	if cause != nil {
+		headers[HeaderFailureError] = cause.Error()
+	}
+	headers[HeaderFailedAt] = time.Now().UTC().Format(time.RFC3339Nano)
+	headers[HeaderRetryCount] = int32(RetryCount(msg.Headers) + retries)
+	headers[HeaderOriginalQueue] = queueName
+
+	return amqp.Publishing{
+		Headers:       headers,
+		ContentType:   msg.ContentType,
+		DeliveryMode:  amqp.Persistent,
+		CorrelationId: msg.CorrelationId,
+		ReplyTo:       msg.ReplyTo,
+		MessageId:     msg.MessageId,
+		Timestamp:     msg.Timestamp,
+		Body:          msg.Body,
+	}
+}
+
+// RetryCount returns the retries a message has been through, as recorded
+// when it was dead-lettered.
+func RetryCount(headers amqp.Table) int {
+	switch n := headers[HeaderRetryCount].(type) {
+	case int32:
+		return int(n)
+	case int64:
+		return int(n)
+	case int:
+		return n
+	}
+	return 0
+}
+
+func headerString(headers amqp.Table, key string) string {
+	s, _ := headers[key].(string)
+	return s
+}
+
+// DeadLetter is a message in a DLQ.
+type DeadLetter struct {
+	ID            string
+	Reason        string
+	Error         string
+	FailedAt      string
+	RetryCount    int
+	OriginalQueue string
+	Body          []byte
+
+	delivery amqp.Delivery
+}
+
+// DLQChannel is the part of *amqp.Channel used to go through a DLQ.
+type DLQChannel interface {
+	Publisher
+	Get(queue string, autoAck bool) (amqp.Delivery, bool, error)
+}
+
+// DLQ inspects and replays the messages of a dead-letter queue. It takes the
+// messages without acking them, so whatever it does not replay is put back.
+type DLQ struct {
+	ch   DLQChannel
+	name string
+}
+
+func NewDLQ(ch DLQChannel, queueName string) *DLQ {
+	return &DLQ{ch: ch, name: DeadLetterQueue(queueName)}
+}
+
+// Inspect returns up to limit messages from the head of the queue and leaves
+// them there.
+func (q *DLQ) Inspect(limit int) ([]DeadLetter, error) {
+	letters, err := q.take(limit)
+	q.putBack(letters)
+	return letters, err
+}
+
+// Replay publishes the messages whose ID is in ids, or every message when
+// ids is empty, back to the queue they failed on, with their retry count.
+// It returns the messages replayed.
+func (q *DLQ) Replay(ids []string) ([]DeadLetter, error) {
+	letters, err := q.take(0)
+	if err != nil {
+		q.putBack(letters)
+		return nil, err
+	}
+	selected := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		selected[id] = true
+	}
+
+	var replayed, kept []DeadLetter
+	for i, l := range letters {
+		if len(ids) > 0 && !selected[l.ID] {
+			kept = append(kept, l)
+			continue
+		}
+		if err := q.republish(l); err != nil {
+			q.putBack(append(kept, letters[i:]...))
+			return replayed, err
+		}
+		if err := l.delivery.Ack(false); err != nil {
+			q.putBack(append(kept, letters[i+1:]...))
+			return replayed, fmt.Errorf("replayed %s but failed to remove it from %s: %w", l.ID, q.name, err)
+		}
+		replayed = append(replayed, l)
+	}
+	q.putBack(kept)
+	return replayed, nil
+}
+
+// take gets up to limit messages, or all of them when limit is zero.
+func (q *DLQ) take(limit int) ([]DeadLetter, error) {
+	var letters []DeadLetter
+	for limit <= 0 || len(letters) < limit {
+		d, ok, err := q.ch.Get(q.name, false)
+		if err != nil {
+			return letters, fmt.Errorf("failed to get from %s: %w", q.name, err)
+		}
+		if !ok {
+			break
+		}
+		letters = append(letters, DeadLetter{
+			ID:            headerString(d.Headers, HeaderDeadLetterID),
+			Reason:        headerString(d.Headers, HeaderFailureReason),
+			Error:         headerString(d.Headers, HeaderFailureError),
+			FailedAt:      headerString(d.Headers, HeaderFailedAt),
+			RetryCount:    RetryCount(d.Headers),
+			OriginalQueue: headerString(d.Headers, HeaderOriginalQueue),
+			Body:          d.Body,
+			delivery:      d,
+		})
+		if l := &letters[len(letters)-1]; l.OriginalQueue == "" {
+			// Nacked when the DLX could not be published to: only the
+			// broker's x-death record says where it came from.
+			l.OriginalQueue, l.Reason = xDeath(d.Headers)
+		}
+	}
+	return letters, nil
+}
+
+// xDeath returns the queue and reason of the broker's latest dead-lettering.
+func xDeath(headers amqp.Table) (queue, reason string) {
+	deaths, _ := headers["x-death"].([]interface{})
+	if len(deaths) == 0 {
+		return "", ""
+	}
+	death, _ := deaths[0].(amqp.Table)
+	return headerString(death, "queue"), headerString(death, "reason")
+}
+
+func (q *DLQ) putBack(letters []DeadLetter) {
+	for _, l := range letters {
+		l.delivery.Nack(false, true)
+	}
+}
+
+func (q *DLQ) republish(l DeadLetter) error {
+	if l.OriginalQueue == "" {
+		return fmt.Errorf("message %s does not name the queue it failed on", l.ID)
+	}
+	headers := amqp.Table{HeaderRetryCount: int32(l.RetryCount)}
+	d := l.delivery
+	err := q.ch.Publish("", l.OriginalQueue, false, false, amqp.Publishing{
+		Headers:       headers,
+		ContentType:   d.ContentType,
+		DeliveryMode:  amqp.Persistent,
+		CorrelationId: d.CorrelationId,
+		ReplyTo:       d.ReplyTo,
+		MessageId:     d.MessageId,
+		Timestamp:     d.Timestamp,
+		Body:          d.Body,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to replay %s to %s: %w", l.ID, l.OriginalQueue, err)
+	}
+	return nil
+}
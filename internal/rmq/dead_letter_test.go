@@ -0,0 +1,117 @@
+package rmq
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeQueue is a queue read with basic.get: a message taken is out of the
+// queue until it is acked, or nacked back in place.
+type fakeQueue struct {
+	messages []amqp.Delivery
+	taken    map[uint64]bool
+	nextTag  uint64
+	replayed []published
+}
+
+func (q *fakeQueue) add(headers amqp.Table, body string) {
+	q.nextTag++
+	q.messages = append(q.messages, amqp.Delivery{Acknowledger: q, DeliveryTag: q.nextTag, Headers: headers, Body: []byte(body)})
+}
+
+func (q *fakeQueue) Get(string, bool) (amqp.Delivery, bool, error) {
+	for _, d := range q.messages {
+		if !q.taken[d.DeliveryTag] {
+			q.taken[d.DeliveryTag] = true
+			return d, true, nil
+		}
+	}
+	return amqp.Delivery{}, false, nil
+}
+
+func (q *fakeQueue) Publish(exchange, key string, _, _ bool, msg amqp.Publishing) error {
+	q.replayed = append(q.replayed, published{exchange: exchange, key: key, msg: msg})
+	return nil
+}
+
+func (q *fakeQueue) Ack(tag uint64, _ bool) error {
+	for i, d := range q.messages {
+		if d.DeliveryTag == tag {
+			q.messages = append(q.messages[:i], q.messages[i+1:]...)
+			break
+		}
+	}
+	delete(q.taken, tag)
+	return nil
+}
+
+func (q *fakeQueue) Nack(tag uint64, _, requeue bool) error {
+	if !requeue {
+		return q.Ack(tag, false)
+	}
+	delete(q.taken, tag)
+	return nil
+}
+
+func (q *fakeQueue) Reject(tag uint64, requeue bool) error { return q.Nack(tag, false, requeue) }
+
+func TestDeadLetterPublishing_AddsFailureHeaders(t *testing.T) {
+	msg := amqp.Delivery{
+		Headers:       amqp.Table{"trace": "abc", HeaderRetryCount: int32(1)},
+		ContentType:   "application/json",
+		CorrelationId: "req-1",
+		ReplyTo:       "replies",
+		Body:          []byte(`{"35":"D"}`),
+	}
+	p := deadLetterPublishing(msg, "orderRequests", "order processing error", errors.New("timeout"), 3)
+
+	assert.Equal(t, "abc", p.Headers["trace"])
+	assert.NotEmpty(t, p.Headers[HeaderDeadLetterID])
+	assert.Equal(t, "order processing error", p.Headers[HeaderFailureReason])
+	assert.Equal(t, "timeout", p.Headers[HeaderFailureError])
+	assert.Equal(t, int32(4), p.Headers[HeaderRetryCount])
+	assert.Equal(t, "orderRequests", p.Headers[HeaderOriginalQueue])
+	_, err := time.Parse(time.RFC3339Nano, p.Headers[HeaderFailedAt].(string))
+	assert.NoError(t, err)
+	assert.Equal(t, "req-1", p.CorrelationId)
+	assert.Equal(t, msg.Body, p.Body)
+	assert.NoError(t, p.Headers.Validate())
+}
+
+func TestDLQ_InspectLeavesMessagesAndReplaySelects(t *testing.T) {
+	q := &fakeQueue{taken: map[uint64]bool{}}
+	q.add(amqp.Table{HeaderDeadLetterID: "a", HeaderFailureReason: "invalid JSON format", HeaderOriginalQueue: "orderRequests"}, "{bad")
+	q.add(amqp.Table{HeaderDeadLetterID: "b", HeaderRetryCount: int32(3), HeaderOriginalQueue: "orderRequests.shard-0"}, `{"35":"D"}`)
+	q.add(amqp.Table{"x-death": []interface{}{amqp.Table{"queue": "orderRequests", "reason": "rejected"}}}, `{"35":"F"}`)
+	dlq := NewDLQ(q, "orderRequests")
+
+	letters, err := dlq.Inspect(2)
+	require.NoError(t, err)
+	require.Len(t, letters, 2)
+	assert.Equal(t, "invalid JSON format", letters[0].Reason)
+	assert.Equal(t, 3, letters[1].RetryCount)
+	assert.Len(t, q.messages, 3)
+	assert.Empty(t, q.taken)
+
+	replayed, err := dlq.Replay([]string{"b"})
+	require.NoError(t, err)
+	require.Len(t, replayed, 1)
+	require.Len(t, q.replayed, 1)
+	assert.Equal(t, "orderRequests.shard-0", q.replayed[0].key)
+	assert.Equal(t, int32(3), q.replayed[0].msg.Headers[HeaderRetryCount])
+	assert.Len(t, q.messages, 2)
+
+	// Without IDs everything goes back, including what the broker
+	// dead-lettered on its own.
+	replayed, err = dlq.Replay(nil)
+	require.NoError(t, err)
+	assert.Len(t, replayed, 2)
+	assert.Equal(t, "orderRequests", q.replayed[2].key)
+	assert.Equal(t, "rejected", replayed[1].Reason)
+	assert.Empty(t, q.messages)
+}
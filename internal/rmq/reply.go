@@ -0,0 +1,68 @@
+package rmq
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/streadway/amqp"
+)
+
+// ReplyPublisher publishes the answers to requests on the queue each one
+// named in reply_to, through the default exchange. It connects on first use
+// and again after a failed publish.
+type ReplyPublisher struct {
+	url string
+
+	mu   sync.Mutex
+	conn *amqp.Connection
+	ch   *amqp.Channel
+}
+
+func NewReplyPublisher(url string) *ReplyPublisher {
+	return &ReplyPublisher{url: url}
+}
+
+func (p *ReplyPublisher) Reply(replyTo, correlationID string, body json.RawMessage) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.ch == nil {
+		conn, err := amqp.Dial(p.url)
+		if err != nil {
+			return fmt.Errorf("failed to connect to RabbitMQ: %w", err)
+		}
+		ch, err := conn.Channel()
+		if err != nil {
+			conn.Close()
+			return fmt.Errorf("failed to open channel: %w", err)
+		}
+		p.conn, p.ch = conn, ch
+	}
+
+	err := p.ch.Publish("", replyTo, false, false, amqp.Publishing{
+		ContentType:   "application/json",
+		CorrelationId: correlationID,
+		Body:          body,
+	})
+	if err != nil {
+		p.closeLocked()
+		return fmt.Errorf("failed to publish reply: %w", err)
+	}
+	return nil
+}
+
+func (p *ReplyPublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.closeLocked()
+}
+
+func (p *ReplyPublisher) closeLocked() error {
+	if p.conn == nil {
+		return nil
+	}
+	err := p.conn.Close()
+	p.conn, p.ch = nil, nil
+	return err
+}
@@ -0,0 +1,72 @@
+package rmq
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/streadway/amqp"
+
+	"MatchingEngine/internal/model"
+)
+
+// ShardQueue names the queue of one shard.
+func ShardQueue(queueName, shardID string) string {
+	return queueName + "." + shardID
+}
+
+func declareShardBinding(ch Channel, exchange, queueName, routingKey string) error {
+	if err := ch.ExchangeDeclare(exchange, amqp.ExchangeDirect, true, false, false, false, nil); err != nil {
+		return fmt.Errorf("failed to declare exchange: %w", err)
+	}
+	if err := ch.QueueBind(queueName, routingKey, exchange, false, nil); err != nil {
+		return fmt.Errorf("failed to bind queue: %w", err)
+	}
+	return nil
+}
+
+// Publisher is the part of *amqp.Channel used to publish.
+type Publisher interface {
+	Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error
+}
+
+type ShardRouter interface {
+	Owner(symbol string) string
+}
+
+// OrderPublisher is the ingress side of a sharded deployment: it publishes
+// each request with its symbol's shard as routing key, so it lands on that
+// shard's queue.
+type OrderPublisher struct {
+	publisher Publisher
+	exchange  string
+	router    ShardRouter
+}
+
+func NewOrderPublisher(publisher Publisher, exchange string, router ShardRouter) *OrderPublisher {
+	return &OrderPublisher{
+		publisher: publisher,
+		exchange:  exchange,
+		router:    router,
+	}
+}
+
+func (p *OrderPublisher) Publish(req model.OrderRequest) error {
+	symbol := req.Symbol()
+	if symbol == "" {
+		return errors.New("symbol not specified in order request")
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to marshal order request: %w", err)
+	}
+	err = p.publisher.Publish(p.exchange, p.router.Owner(symbol), false, false, amqp.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		Body:         body,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish order request for %s: %w", symbol, err)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package rmq
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"MatchingEngine/internal/model"
+	"MatchingEngine/internal/shard"
+)
+
+type published struct {
+	exchange, key string
+	msg           amqp.Publishing
+}
+
+type recordingPublisher struct {
+	messages []published
+}
+
+func (p *recordingPublisher) Publish(exchange, key string, _, _ bool, msg amqp.Publishing) error {
+	p.messages = append(p.messages, published{exchange: exchange, key: key, msg: msg})
+	return nil
+}
+
+type mapRouter map[string]string
+
+func (r mapRouter) Owner(symbol string) string { return r[symbol] }
+
+func TestOrderPublisher_RoutesBySymbolOwner(t *testing.T) {
+	pub := &recordingPublisher{}
+	p := NewOrderPublisher(pub, "orders", mapRouter{"BTC/USDT": "shard-0", "ETH/USDT": "shard-1"})
+
+	cancel := model.OrderRequest{
+		MsgType: model.MsgTypeCancel,
+		CancelOrderReq: model.OrderCancelRequest{
+			BaseOrderRequest: model.BaseOrderRequest{MsgType: model.MsgTypeCancel, ClOrdID: "C1", Symbol: "ETH/USDT"},
+			OrigClOrdID:      "B1",
+		},
+	}
+	require.NoError(t, p.Publish(cancel))
+	assert.Error(t, p.Publish(model.OrderRequest{MsgType: model.MsgTypeNew}))
+
+	require.Len(t, pub.messages, 1)
+	assert.Equal(t, "orders", pub.messages[0].exchange)
+	assert.Equal(t, "shard-1", pub.messages[0].key)
+
+	var decoded model.OrderRequest
+	require.NoError(t, json.Unmarshal(pub.messages[0].msg.Body, &decoded))
+	assert.Equal(t, "B1", decoded.CancelOrderReq.OrigClOrdID)
+	assert.Equal(t, "orders.shard-1", ShardQueue("orders", "shard-1"))
+}
+
+func TestOrderPublisher_FollowsHandoffs(t *testing.T) {
+	overrides := filepath.Join(t.TempDir(), "overrides.json")
+	assignment := func() *shard.Assignment {
+		a, err := shard.NewAssignment([]string{"shard-0", "shard-1"}, map[string]string{"BTC/USDT": "shard-0"})
+		require.NoError(t, err)
+		require.NoError(t, a.LoadOverrides(overrides))
+		return a
+	}
+	router, releasing := assignment(), assignment()
+	pub := &recordingPublisher{}
+	p := NewOrderPublisher(pub, "orders", router)
+	order := model.OrderRequest{
+		MsgType: model.MsgTypeNew,
+		NewOrderReq: model.NewOrderRequest{
+			BaseOrderRequest: model.BaseOrderRequest{MsgType: model.MsgTypeNew, ClOrdID: "B1", Symbol: "BTC/USDT"},
+		},
+	}
+
+	require.NoError(t, p.Publish(order))
+	// The old shard releases the symbol in another process.
+	require.NoError(t, releasing.Handoff("BTC/USDT", "shard-1"))
+	require.NoError(t, p.Publish(order))
+
+	require.Len(t, pub.messages, 2)
+	assert.Equal(t, "shard-0", pub.messages[0].key)
+	assert.Equal(t, "shard-1", pub.messages[1].key)
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"MatchingEngine/internal/model"
+	"MatchingEngine/orderBook"
+)
+
+type OpenOrderStore interface {
+	// ListOpenOrders returns the orders of every symbol that are not done,
+	// in time priority within each symbol.
+	ListOpenOrders(ctx context.Context) ([]orderBook.Order, error)
+}
+
+// SideSummary counts the resting orders of one side of a book.
+type SideSummary struct {
+	Orders    int
+	LeavesQty decimal.Decimal
+}
+
+// BookSummary is what was recovered for one symbol.
+type BookSummary struct {
+	Symbol string
+	Bids   SideSummary
+	Asks   SideSummary
+}
+
+// RecoverySummary reconciles the books rebuilt from the database with the
+// open orders found there.
+type RecoverySummary struct {
+	Books []BookSummary // Sorted by symbol
+}
+
+func (s RecoverySummary) Orders() int {
+	n := 0
+	for _, book := range s.Books {
+		n += book.Bids.Orders + book.Asks.Orders
+	}
+	return n
+}
+
+func (s RecoverySummary) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Recovered %d resting orders in %d order books from the database", s.Orders(), len(s.Books))
+	for _, book := range s.Books {
+		fmt.Fprintf(&b, "\n  %s: %d bids for %s, %d asks for %s", book.Symbol,
+			book.Bids.Orders, book.Bids.LeavesQty, book.Asks.Orders, book.Asks.LeavesQty)
+	}
+	return b.String()
+}
+
+// RecoverFromDatabase rebuilds the order books from the open orders in the
+// database, for an engine starting with neither snapshots nor a journal. It
+// must run before any request is processed. Any order whose state does not
+// add up fails the recovery, and the engine must not start: matching against
+// a book that is wrong is worse than not matching at all.
+func RecoverFromDatabase(ctx context.Context, store OpenOrderStore, orders *OrderService) (RecoverySummary, error) {
+	open, err := store.ListOpenOrders(ctx)
+	if err != nil {
+		return RecoverySummary{}, err
+	}
+
+	bySymbol := make(map[string][]orderBook.Order)
+	clOrdIDs := make(map[string]map[string]bool)
+	var problems []error
+	for _, order := range open {
+		if err := checkOpenOrder(order); err != nil {
+			problems = append(problems, err)
+			continue
+		}
+		if clOrdIDs[order.Symbol] == nil {
+			clOrdIDs[order.Symbol] = make(map[string]bool)
+		}
+		if clOrdIDs[order.Symbol][order.ClOrdID] {
+			problems = append(problems, fmt.Errorf("order %s: ClOrdID %s is open more than once in %s", order.OrderID, order.ClOrdID, order.Symbol))
+			continue
+		}
+		clOrdIDs[order.Symbol][order.ClOrdID] = true
+		bySymbol[order.Symbol] = append(bySymbol[order.Symbol], order)
+	}
+	if len(problems) > 0 {
+		return RecoverySummary{}, fmt.Errorf("%d of %d open orders in the database are inconsistent: %w", len(problems), len(open), errors.Join(problems...))
+	}
+
+	var summary RecoverySummary
+	snapshots := make([]orderBook.BookSnapshot, 0, len(bySymbol))
+	takenAt := time.Now().UnixNano()
+	for symbol, bookOrders := range bySymbol {
+		if orders.checkOwner(symbol) != nil {
+			// Another shard recovers it.
+			continue
+		}
+		snapshots = append(snapshots, orderBook.SnapshotFromOrders(symbol, bookOrders, takenAt))
+		summary.Books = append(summary.Books, summarizeBook(symbol, bookOrders))
+	}
+	sort.Slice(summary.Books, func(i, j int) bool { return summary.Books[i].Symbol < summary.Books[j].Symbol })
+	if err := orders.RestoreBooks(snapshots); err != nil {
+		return RecoverySummary{}, err
+	}
+	return summary, nil
+}
+
+// checkOpenOrder reports an order that cannot rest as stored.
+func checkOpenOrder(order orderBook.Order) error {
+	switch {
+	case order.OrderStatus != model.OrderStatusNew && order.OrderStatus != model.OrderStatusPartialFill:
+		return fmt.Errorf("order %s is not done but has status %q, which cannot rest", order.OrderID, order.OrderStatus)
+	case order.ClOrdID == "":
+		return fmt.Errorf("order %s has no ClOrdID", order.OrderID)
+	case order.Side != model.Buy && order.Side != model.Sell:
+		return fmt.Errorf("order %s has unknown side %q", order.OrderID, order.Side)
+	case !order.Price.IsPositive():
+		return fmt.Errorf("order %s has no price", order.OrderID)
+	case !order.LeavesQty.IsPositive():
+		return fmt.Errorf("order %s is open with LeavesQty %s", order.OrderID, order.LeavesQty)
+	case !order.LeavesQty.Equal(order.OrderQty.Sub(order.CumQty)):
+		return fmt.Errorf("order %s has LeavesQty %s, but OrderQty %s - CumQty %s is %s", order.OrderID,
+			order.LeavesQty, order.OrderQty, order.CumQty, order.OrderQty.Sub(order.CumQty))
+	case order.OrderStatus == model.OrderStatusNew && !order.CumQty.IsZero():
+		return fmt.Errorf("order %s is new with CumQty %s", order.OrderID, order.CumQty)
+	case order.OrderStatus == model.OrderStatusPartialFill && !order.CumQty.IsPositive():
+		return fmt.Errorf("order %s is partially filled with CumQty %s", order.OrderID, order.CumQty)
+	}
+	return nil
+}
+
+func summarizeBook(symbol string, orders []orderBook.Order) BookSummary {
+	summary := BookSummary{Symbol: symbol}
+	for _, order := range orders {
+		side := &summary.Asks
+		if order.Side == model.Buy {
+			side = &summary.Bids
+		}
+		side.Orders++
+		side.LeavesQty = side.LeavesQty.Add(order.LeavesQty)
+	}
+	return summary
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"MatchingEngine/internal/model"
+	"MatchingEngine/orderBook"
+)
+
+type fakeOpenOrders []orderBook.Order
+
+func (f fakeOpenOrders) ListOpenOrders(context.Context) ([]orderBook.Order, error) {
+	return f, nil
+}
+
+func storedOrder(clOrdID, symbol string, side model.Side, price, qty, cum int64) orderBook.Order {
+	status := model.OrderStatusNew
+	if cum > 0 {
+		status = model.OrderStatusPartialFill
+	}
+	return orderBook.Order{
+		ClOrdID:     clOrdID,
+		OrderID:     "O-" + clOrdID,
+		Symbol:      symbol,
+		Side:        side,
+		Price:       decimal.NewFromInt(price),
+		OrderQty:    decimal.NewFromInt(qty),
+		CumQty:      decimal.NewFromInt(cum),
+		LeavesQty:   decimal.NewFromInt(qty - cum),
+		OrderStatus: status,
+	}
+}
+
+func TestRecoverFromDatabase_RebuildsBooksInTimePriority(t *testing.T) {
+	store := fakeOpenOrders{
+		storedOrder("B1", "BTC/USDT", model.Buy, 100, 5, 2),
+		storedOrder("S1", "BTC/USDT", model.Sell, 105, 1, 0),
+		storedOrder("B2", "BTC/USDT", model.Buy, 100, 4, 0),
+		storedOrder("B3", "ETH/USDT", model.Buy, 10, 7, 0),
+	}
+	orders := NewOrderService(&MockNotifier{}, orderBook.BookOpts{})
+
+	summary, err := RecoverFromDatabase(context.Background(), store, orders)
+	require.NoError(t, err)
+
+	require.Len(t, summary.Books, 2)
+	assert.Equal(t, "BTC/USDT", summary.Books[0].Symbol)
+	assert.Equal(t, 2, summary.Books[0].Bids.Orders)
+	assert.True(t, summary.Books[0].Bids.LeavesQty.Equal(decimal.NewFromInt(7)))
+	assert.Equal(t, 1, summary.Books[0].Asks.Orders)
+	assert.Equal(t, 4, summary.Orders())
+
+	var clOrdIDs []string
+	for _, order := range orders.OpenOrders("BTC/USDT") {
+		if order.Side == model.Buy {
+			clOrdIDs = append(clOrdIDs, order.ClOrdID)
+		}
+	}
+	assert.Equal(t, []string{"B1", "B2"}, clOrdIDs)
+	_, ok := orders.OpenOrder("B3")
+	assert.True(t, ok)
+}
+
+func TestRecoverFromDatabase_RefusesInconsistentOrders(t *testing.T) {
+	broken := storedOrder("B2", "BTC/USDT", model.Buy, 100, 5, 2)
+	broken.LeavesQty = decimal.NewFromInt(5)
+	pending := storedOrder("B3", "BTC/USDT", model.Buy, 98, 1, 0)
+	pending.OrderStatus = model.OrderStatusPendingNew
+	store := fakeOpenOrders{
+		storedOrder("B1", "BTC/USDT", model.Buy, 100, 5, 0),
+		broken,
+		storedOrder("B1", "BTC/USDT", model.Buy, 99, 1, 0),
+		pending,
+	}
+	orders := NewOrderService(&MockNotifier{}, orderBook.BookOpts{})
+
+	_, err := RecoverFromDatabase(context.Background(), store, orders)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "3 of 4 open orders")
+	assert.Contains(t, err.Error(), "LeavesQty 5")
+	assert.Contains(t, err.Error(), `status "A"`)
+	assert.Empty(t, orders.OpenOrders(""))
+}
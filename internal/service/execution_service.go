@@ -1,6 +1,8 @@
 package service
 
 import (
+	"context"
+
 	"MatchingEngine/internal/model"
 	"MatchingEngine/internal/repository"
 )
@@ -17,8 +19,11 @@ func NewExecutionService(asyncWriter repository.AsyncDBWriterInterface, notifier
 	}
 }
 
-func (e *ExecutionService) SaveExecutionAsync(execution model.ExecutionReport) {
-	e.asyncWriter.EnqueueTask(repository.SaveExecutionTask{
+// SaveExecutionAsync queues the report for writing; done, when set, is called
+// with the outcome.
+func (e *ExecutionService) SaveExecutionAsync(execution model.ExecutionReport, done func(error)) {
+	e.asyncWriter.EnqueueTask(context.Background(), repository.SaveExecutionTask{
 		Execution: execution,
+		Done:      done,
 	})
 }
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -15,7 +16,7 @@ type MockAsyncDBWriter struct {
 	mock.Mock
 }
 
-func (m *MockAsyncDBWriter) EnqueueTask(task repository.DBTask) {
+func (m *MockAsyncDBWriter) EnqueueTask(_ context.Context, task repository.DBTask) {
 	m.Called(task)
 }
 
@@ -44,7 +45,7 @@ func TestSaveExecutionAsync(t *testing.T) {
 
 	mockWriter.On("EnqueueTask", repository.SaveExecutionTask{Execution: execution}).Return()
 
-	executionService.SaveExecutionAsync(execution)
+	executionService.SaveExecutionAsync(execution, nil)
 
 	mockWriter.AssertCalled(t, "EnqueueTask", repository.SaveExecutionTask{Execution: execution})
 }
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"MatchingEngine/internal/model"
+	"MatchingEngine/internal/repository"
+)
+
+const (
+	DefaultHistoryLimit = 100
+	MaxHistoryLimit     = 1000
+)
+
+// ErrInvalidHistoryQuery is a history query that cannot be answered as asked.
+var ErrInvalidHistoryQuery = errors.New("invalid history query")
+
+type ExecutionHistoryStore interface {
+	ListExecutions(ctx context.Context, filter model.HistoryFilter, after repository.HistoryKey, limit int32) ([]model.ExecutionReport, error)
+}
+
+type TradeHistoryStore interface {
+	ListTrades(ctx context.Context, filter model.HistoryFilter, after repository.HistoryKey, limit int32) ([]model.TradeCaptureReport, error)
+}
+
+// HistoryService pages through the stored executions and trades, oldest
+// first. Each page comes with the cursor of the next one, empty after the
+// last. A cursor is the (TransactTime, ID) key of the last report of its
+// page rather than an offset, so reports written while a client pages
+// through neither shift nor repeat the pages.
+type HistoryService struct {
+	executions ExecutionHistoryStore
+	trades     TradeHistoryStore
+}
+
+func NewHistoryService(executions ExecutionHistoryStore, trades TradeHistoryStore) *HistoryService {
+	return &HistoryService{executions: executions, trades: trades}
+}
+
+// ListExecutions returns up to limit execution reports matching filter from
+// where cursor left off, and the cursor of the next page. A limit of 0 is
+// DefaultHistoryLimit.
+func (s *HistoryService) ListExecutions(ctx context.Context, filter model.HistoryFilter, cursor string, limit int) ([]model.ExecutionReport, string, error) {
+	after, limit, err := historyPage(filter, cursor, limit)
+	if err != nil {
+		return nil, "", err
+	}
+	// One more than asked tells whether there is a next page.
+	reports, err := s.executions.ListExecutions(ctx, filter, after, int32(limit+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(reports) <= limit {
+		return reports, "", nil
+	}
+	last := reports[limit-1]
+	return reports[:limit], encodeCursor(repository.HistoryKey{TransactTime: last.TransactTime, ID: last.ExecID}), nil
+}
+
+// ListTrades returns up to limit trades matching filter, with their sides,
+// from where cursor left off, and the cursor of the next page. A limit of 0
+// is DefaultHistoryLimit.
+func (s *HistoryService) ListTrades(ctx context.Context, filter model.HistoryFilter, cursor string, limit int) ([]model.TradeCaptureReport, string, error) {
+	after, limit, err := historyPage(filter, cursor, limit)
+	if err != nil {
+		return nil, "", err
+	}
+	trades, err := s.trades.ListTrades(ctx, filter, after, int32(limit+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(trades) <= limit {
+		return trades, "", nil
+	}
+	last := trades[limit-1]
+	return trades[:limit], encodeCursor(repository.HistoryKey{TransactTime: last.TransactTime, ID: last.TradeReportID}), nil
+}
+
+// historyPage checks a query and returns the key its page starts after and
+// its size.
+func historyPage(filter model.HistoryFilter, cursor string, limit int) (repository.HistoryKey, int, error) {
+	switch {
+	case limit == 0:
+		limit = DefaultHistoryLimit
+	case limit < 0 || limit > MaxHistoryLimit:
+		return repository.HistoryKey{}, 0, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidHistoryQuery, MaxHistoryLimit)
+	}
+	if filter.From < 0 || filter.To < 0 {
+		return repository.HistoryKey{}, 0, fmt.Errorf("%w: times cannot be before 1970", ErrInvalidHistoryQuery)
+	}
+	if filter.To != 0 && filter.To <= filter.From {
+		return repository.HistoryKey{}, 0, fmt.Errorf("%w: the range ends before it starts", ErrInvalidHistoryQuery)
+	}
+	if cursor == "" {
+		return repository.HistoryKey{}, limit, nil
+	}
+	after, err := decodeCursor(cursor)
+	if err != nil {
+		return repository.HistoryKey{}, 0, fmt.Errorf("%w: bad cursor %q", ErrInvalidHistoryQuery, cursor)
+	}
+	return after, limit, nil
+}
+
+// encodeCursor renders a key as an opaque, URL-safe token.
+func encodeCursor(key repository.HistoryKey) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(key.TransactTime, 10) + ":" + key.ID))
+}
+
+func decodeCursor(cursor string) (repository.HistoryKey, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return repository.HistoryKey{}, err
+	}
+	ts, id, ok := strings.Cut(string(raw), ":")
+	if !ok || id == "" {
+		return repository.HistoryKey{}, errors.New("no ID")
+	}
+	transactTime, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return repository.HistoryKey{}, err
+	}
+	return repository.HistoryKey{TransactTime: transactTime, ID: id}, nil
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"MatchingEngine/internal/model"
+	"MatchingEngine/internal/repository"
+)
+
+// fakeTradeStore answers from a list of trades in history order.
+type fakeTradeStore []model.TradeCaptureReport
+
+func (f fakeTradeStore) ListTrades(_ context.Context, filter model.HistoryFilter, after repository.HistoryKey, limit int32) ([]model.TradeCaptureReport, error) {
+	var out []model.TradeCaptureReport
+	for _, trade := range f {
+		if (trade.TransactTime < after.TransactTime) ||
+			(trade.TransactTime == after.TransactTime && trade.TradeReportID <= after.ID) ||
+			(filter.Symbol != "" && trade.Symbol != filter.Symbol) {
+			continue
+		}
+		if len(out) == int(limit) {
+			break
+		}
+		out = append(out, trade)
+	}
+	return out, nil
+}
+
+type fakeExecutionStore struct {
+	after repository.HistoryKey
+	limit int32
+}
+
+func (f *fakeExecutionStore) ListExecutions(_ context.Context, _ model.HistoryFilter, after repository.HistoryKey, limit int32) ([]model.ExecutionReport, error) {
+	f.after, f.limit = after, limit
+	return nil, nil
+}
+
+func TestHistoryService_PagesThroughTrades(t *testing.T) {
+	store := fakeTradeStore{
+		{TradeReportID: "T1", Symbol: "BTC/USDT", TransactTime: 100},
+		{TradeReportID: "T2", Symbol: "ETH/USDT", TransactTime: 100},
+		{TradeReportID: "T3", Symbol: "BTC/USDT", TransactTime: 100},
+		{TradeReportID: "T4", Symbol: "BTC/USDT", TransactTime: 200},
+		{TradeReportID: "T5", Symbol: "BTC/USDT", TransactTime: 300},
+	}
+	s := NewHistoryService(&fakeExecutionStore{}, store)
+	filter := model.HistoryFilter{Symbol: "BTC/USDT"}
+
+	var ids []string
+	var pages int
+	cursor := ""
+	for {
+		trades, next, err := s.ListTrades(context.Background(), filter, cursor, 2)
+		require.NoError(t, err)
+		pages++
+		for _, trade := range trades {
+			ids = append(ids, trade.TradeReportID)
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	assert.Equal(t, []string{"T1", "T3", "T4", "T5"}, ids)
+	// A full last page does not leave an empty one behind
+	assert.Equal(t, 2, pages)
+}
+
+func TestHistoryService_ChecksQueries(t *testing.T) {
+	executions := &fakeExecutionStore{}
+	s := NewHistoryService(executions, fakeTradeStore{})
+	ctx := context.Background()
+
+	_, _, err := s.ListExecutions(ctx, model.HistoryFilter{}, "", 0)
+	require.NoError(t, err)
+	assert.Equal(t, int32(DefaultHistoryLimit+1), executions.limit)
+	assert.Equal(t, repository.HistoryKey{}, executions.after)
+
+	cursor := encodeCursor(repository.HistoryKey{TransactTime: 42, ID: "exec:1"})
+	_, _, err = s.ListExecutions(ctx, model.HistoryFilter{}, cursor, 10)
+	require.NoError(t, err)
+	assert.Equal(t, repository.HistoryKey{TransactTime: 42, ID: "exec:1"}, executions.after)
+
+	for name, query := range map[string]func() error{
+		"limit too large": func() error {
+			_, _, err := s.ListExecutions(ctx, model.HistoryFilter{}, "", MaxHistoryLimit+1)
+			return err
+		},
+		"bad cursor": func() error {
+			_, _, err := s.ListTrades(ctx, model.HistoryFilter{}, "not a cursor", 10)
+			return err
+		},
+		"empty range": func() error {
+			_, _, err := s.ListTrades(ctx, model.HistoryFilter{From: 200, To: 100}, "", 10)
+			return err
+		},
+	} {
+		assert.ErrorIs(t, query(), ErrInvalidHistoryQuery, name)
+	}
+}
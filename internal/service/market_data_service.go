@@ -0,0 +1,122 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+
+	"MatchingEngine/internal/model"
+	"MatchingEngine/mdclient"
+)
+
+type MarketDataPublisher interface {
+	PublishMarketData(symbol string, value json.RawMessage) error
+}
+
+// MarketDataService publishes the depth updates of every order book. It also
+// follows the updates into a local copy of each book, the same way a consumer
+// does, so that consumers can fetch a snapshot to resync from.
+type MarketDataService struct {
+	publisher MarketDataPublisher
+	mu        sync.Mutex
+	books     map[string]*mdclient.LocalBook
+}
+
+func NewMarketDataService(publisher MarketDataPublisher) *MarketDataService {
+	return &MarketDataService{
+		publisher: publisher,
+		books:     make(map[string]*mdclient.LocalBook),
+	}
+}
+
+func (s *MarketDataService) NotifyDepth(update model.MarketDataIncrementalRefresh) error {
+	payload, err := json.Marshal(update)
+	if err != nil {
+		return fmt.Errorf("failed to marshal depth update: %w", err)
+	}
+
+	// Apply before publishing so a snapshot is never behind a published update.
+	s.apply(update.Symbol, update.Depth, payload)
+
+	return s.publisher.PublishMarketData(update.Symbol, payload)
+}
+
+// ResetDepth replaces the local copy of a book and publishes the snapshot so
+// consumers can reset theirs.
+func (s *MarketDataService) ResetDepth(snapshot model.MarketDataSnapshotFullRefresh) error {
+	payload, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to marshal depth snapshot: %w", err)
+	}
+
+	var snap mdclient.Snapshot
+	if err := json.Unmarshal(payload, &snap); err != nil {
+		return fmt.Errorf("failed to decode depth snapshot: %w", err)
+	}
+	book := mdclient.NewLocalBook(snapshot.Symbol)
+	if err := book.Reset(snap); err != nil {
+		log.Printf("depth snapshot for %s does not match its checksum: %v", snapshot.Symbol, err)
+	}
+
+	s.mu.Lock()
+	s.books[snapshot.Symbol] = book
+	s.mu.Unlock()
+
+	return s.publisher.PublishMarketData(snapshot.Symbol, payload)
+}
+
+func (s *MarketDataService) apply(symbol string, depth int, payload []byte) {
+	var u mdclient.Update
+	if err := json.Unmarshal(payload, &u); err != nil {
+		log.Printf("failed to decode depth update for %s: %v", symbol, err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	book, ok := s.books[symbol]
+	if !ok {
+		book = mdclient.NewLocalBook(symbol)
+		empty := mdclient.Snapshot{Symbol: symbol, Depth: depth, Checksum: mdclient.Checksum(nil, nil, depth)}
+		if err := book.Reset(empty); err != nil {
+			log.Printf("failed to initialise depth book for %s: %v", symbol, err)
+		}
+		s.books[symbol] = book
+	}
+	if err := book.Apply(u); err != nil {
+		log.Printf("depth book for %s diverged from engine: %v", symbol, err)
+	}
+}
+
+// GetDepthSnapshot returns every price level of a symbol as of the last
+// published depth update.
+func (s *MarketDataService) GetDepthSnapshot(symbol string) (model.MarketDataSnapshotFullRefresh, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	book, ok := s.books[symbol]
+	if !ok {
+		return model.MarketDataSnapshotFullRefresh{}, false
+	}
+	snapshot := book.Snapshot()
+
+	entries := make([]model.MDEntry, 0, len(snapshot.Entries))
+	for _, e := range snapshot.Entries {
+		entries = append(entries, model.MDEntry{
+			EntryType: model.MDEntryType(e.EntryType),
+			Px:        e.Px,
+			Size:      e.Size,
+		})
+	}
+	return model.MarketDataSnapshotFullRefresh{
+		MsgType:      string(model.MsgTypeMDSnapshot),
+		Symbol:       snapshot.Symbol,
+		SeqNum:       snapshot.SeqNum,
+		TransactTime: snapshot.TransactTime,
+		Depth:        snapshot.Depth,
+		Checksum:     snapshot.Checksum,
+		Entries:      entries,
+	}, true
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"MatchingEngine/internal/marketdata"
+	"MatchingEngine/internal/model"
+)
+
+const StatsWindow = 24 * time.Hour
+
+type TradeHistory interface {
+	ListTradesSince(ctx context.Context, since int64) ([]model.TradeCaptureReport, error)
+}
+
+type StatsPublisher interface {
+	PublishStatistics(symbol string, value json.RawMessage) error
+}
+
+// MarketStatsService maintains rolling per-symbol trade statistics. It sits in
+// front of the engine's Notifier so it sees every trade report the order books
+// publish, and forwards all events unchanged.
+type MarketStatsService struct {
+	Notifier  Notifier
+	stats     *marketdata.Statistics
+	history   TradeHistory
+	publisher StatsPublisher
+	window    time.Duration
+	now       func() time.Time
+}
+
+func NewMarketStatsService(notifier Notifier, history TradeHistory, publisher StatsPublisher) *MarketStatsService {
+	return &MarketStatsService{
+		Notifier:  notifier,
+		stats:     marketdata.NewStatistics(StatsWindow),
+		history:   history,
+		publisher: publisher,
+		window:    StatsWindow,
+		now:       time.Now,
+	}
+}
+
+func (s *MarketStatsService) NotifyEventAndTrade(msgType model.MsgType, key string, value json.RawMessage) error {
+	err := s.Notifier.NotifyEventAndTrade(msgType, key, value)
+	// The trade has happened in the book whether or not it was published.
+	if msgType == model.MsgTypeTradeReport {
+		s.record(value)
+	}
+	return err
+}
+
+func (s *MarketStatsService) record(value json.RawMessage) {
+	var trade model.TradeCaptureReport
+	if err := trade.FromJSON(value); err != nil {
+		log.Printf("failed to decode trade report for statistics: %v", err)
+		return
+	}
+	s.stats.Record(trade)
+}
+
+// Rebuild reloads the trades of the current window from the trade history.
+// It must run before any order is processed.
+func (s *MarketStatsService) Rebuild(ctx context.Context) error {
+	since := s.now().Add(-s.window).UnixNano()
+	trades, err := s.history.ListTradesSince(ctx, since)
+	if err != nil {
+		return fmt.Errorf("failed to rebuild trade statistics: %w", err)
+	}
+	for _, trade := range trades {
+		s.stats.Record(trade)
+	}
+	log.Printf("Rebuilt trade statistics from %d trades since %d", len(trades), since)
+	return nil
+}
+
+func (s *MarketStatsService) GetStatistics(symbol string) (model.TradeStatistics, bool) {
+	return s.stats.Get(symbol, s.now().UnixNano())
+}
+
+func (s *MarketStatsService) ListStatistics() []model.TradeStatistics {
+	return s.stats.All(s.now().UnixNano())
+}
+
+// StartPublishing publishes the statistics of every symbol each interval until
+// the context is cancelled.
+func (s *MarketStatsService) StartPublishing(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		log.Println("Statistics publishing disabled: no publish interval configured")
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Stopping statistics publishing due to context cancellation")
+			return
+		case <-ticker.C:
+			s.publishAll()
+		}
+	}
+}
+
+func (s *MarketStatsService) publishAll() {
+	for _, stats := range s.ListStatistics() {
+		payload, err := json.Marshal(stats)
+		if err != nil {
+			log.Printf("Error marshaling statistics for %s: %v", stats.Symbol, err)
+			continue
+		}
+		if err := s.publisher.PublishStatistics(stats.Symbol, payload); err != nil {
+			log.Printf("Error publishing statistics for %s: %v", stats.Symbol, err)
+		}
+	}
+}
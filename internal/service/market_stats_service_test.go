@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"MatchingEngine/internal/model"
+)
+
+type MockTradeHistory struct {
+	mock.Mock
+}
+
+func (m *MockTradeHistory) ListTradesSince(ctx context.Context, since int64) ([]model.TradeCaptureReport, error) {
+	args := m.Called(ctx, since)
+	return args.Get(0).([]model.TradeCaptureReport), args.Error(1)
+}
+
+type MockStatsPublisher struct {
+	mock.Mock
+}
+
+func (m *MockStatsPublisher) PublishStatistics(symbol string, value json.RawMessage) error {
+	args := m.Called(symbol, value)
+	return args.Error(0)
+}
+
+type failingNotifier struct{}
+
+func (f *failingNotifier) NotifyEventAndTrade(model.MsgType, string, json.RawMessage) error {
+	return errors.New("broker down")
+}
+
+func newTestStatsService(notifier Notifier, history TradeHistory, publisher StatsPublisher, now time.Time) *MarketStatsService {
+	s := NewMarketStatsService(notifier, history, publisher)
+	s.now = func() time.Time { return now }
+	return s
+}
+
+func testTrade(symbol string, px, qty int64, ts time.Time) model.TradeCaptureReport {
+	return model.TradeCaptureReport{
+		MsgType:      string(model.MsgTypeTradeReport),
+		Symbol:       symbol,
+		LastPx:       decimal.NewFromInt(px),
+		LastQty:      decimal.NewFromInt(qty),
+		TransactTime: ts.UnixNano(),
+	}
+}
+
+func TestMarketStats_RecordsPublishedTrades(t *testing.T) {
+	now := time.Now()
+	s := newTestStatsService(&MockNotifier{}, nil, nil, now)
+
+	trade := testTrade("BTC/USDT", 100, 2, now.Add(-time.Minute))
+	assert.NoError(t, s.NotifyEventAndTrade(model.MsgTypeTradeReport, "trade-1", trade.ToJSON()))
+
+	execReport, _ := json.Marshal(model.ExecutionReport{MsgType: "8", Symbol: "BTC/USDT", LastPx: decimal.NewFromInt(1)})
+	assert.NoError(t, s.NotifyEventAndTrade(model.MsgTypeExecRpt, "exec-1", execReport))
+
+	stats, ok := s.GetStatistics("BTC/USDT")
+	require.True(t, ok)
+	assert.Equal(t, 1, stats.TradeCount)
+	assert.True(t, stats.Volume.Equal(decimal.NewFromInt(2)))
+}
+
+func TestMarketStats_RecordsTradeWhenNotifierFails(t *testing.T) {
+	now := time.Now()
+	s := newTestStatsService(&failingNotifier{}, nil, nil, now)
+
+	trade := testTrade("BTC/USDT", 100, 1, now)
+	assert.Error(t, s.NotifyEventAndTrade(model.MsgTypeTradeReport, "trade-1", trade.ToJSON()))
+
+	_, ok := s.GetStatistics("BTC/USDT")
+	assert.True(t, ok)
+}
+
+func TestMarketStats_Rebuild(t *testing.T) {
+	now := time.Now()
+	history := new(MockTradeHistory)
+	history.On("ListTradesSince", mock.Anything, now.Add(-StatsWindow).UnixNano()).Return([]model.TradeCaptureReport{
+		testTrade("BTC/USDT", 100, 1, now.Add(-2*time.Hour)),
+		testTrade("BTC/USDT", 110, 1, now.Add(-time.Hour)),
+	}, nil)
+	s := newTestStatsService(&MockNotifier{}, history, nil, now)
+
+	require.NoError(t, s.Rebuild(context.Background()))
+
+	stats, ok := s.GetStatistics("BTC/USDT")
+	require.True(t, ok)
+	assert.Equal(t, 2, stats.TradeCount)
+	assert.True(t, stats.OpenPx.Equal(decimal.NewFromInt(100)))
+	assert.True(t, stats.LastPx.Equal(decimal.NewFromInt(110)))
+	history.AssertExpectations(t)
+}
+
+func TestMarketStats_PublishAll(t *testing.T) {
+	now := time.Now()
+	publisher := new(MockStatsPublisher)
+	publisher.On("PublishStatistics", "BTC/USDT", mock.Anything).Return(nil).Once()
+	s := newTestStatsService(&MockNotifier{}, nil, publisher, now)
+	s.stats.Record(testTrade("BTC/USDT", 100, 1, now))
+
+	s.publishAll()
+
+	publisher.AssertExpectations(t)
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
 	"sync"
 	"time"
@@ -14,22 +15,43 @@ import (
 var (
 	ErrSymbolNotSpecified = errors.New("symbol not specified in order request")
 	ErrChannelTimeout     = errors.New("timeout while sending order to processing channel")
+	ErrSymbolNotOwned     = errors.New("symbol is not owned by this shard")
+	ErrNotAccepting       = errors.New("engine is not accepting requests")
 )
 
 type Notifier interface {
-	NotifyEventAndTrade(orderID string, value json.RawMessage) error
+	NotifyEventAndTrade(msgType model.MsgType, orderID string, value json.RawMessage) error
+}
+
+// Ownership tells a sharded engine which symbols it serves, and which are
+// being handed over to it.
+type Ownership interface {
+	Owns(symbol string) bool
+	Acquiring(symbol string) bool
+	Owner(symbol string) string
 }
 
 type OrderService struct {
 	Notifier Notifier
+	// Ownership restricts the service to one shard's symbols; nil serves all.
+	Ownership     Ownership
+	// Gate refuses new requests while it is closed: on a standby, and once
+	// the lease is lost. Nil accepts them all along.
+	Gate          interface{ IsOpen() bool }
+	bookOpts      orderBook.BookOpts
 	orderChannels map[string]chan model.OrderRequest
+	books         map[string]*orderBook.OrderBook
 	mu            sync.Mutex
 }
 
-func NewOrderService(notifier Notifier) *OrderService {
+// NewOrderService creates books on demand; bookOpts is the template for every
+// book, with Symbol filled in per book.
+func NewOrderService(notifier Notifier, bookOpts orderBook.BookOpts) *OrderService {
 	return &OrderService{
 		orderChannels: make(map[string]chan model.OrderRequest),
+		books:         make(map[string]*orderBook.OrderBook),
 		Notifier: notifier,
+		bookOpts:      bookOpts,
 	}
 }
 
@@ -39,15 +61,22 @@ func (s *OrderService) ProcessOrderRequest(req model.OrderRequest) error {
 		log.Printf("empty symbol in order request: %+v", req)
 		return ErrSymbolNotSpecified
 	}
+	if s.Gate != nil && !s.Gate.IsOpen() {
+		return ErrNotAccepting
+	}
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if err := s.checkOwner(symbol); err != nil {
+		return err
+	}
+
 	ch, exists := s.orderChannels[symbol]
 	if !exists {
-		newCh := orderBook.NewOrderBook(s.Notifier)
-		s.orderChannels[symbol] = newCh
-		ch = newCh
+		opts := s.bookOpts
+		opts.Symbol = symbol
+		ch = s.startBook(symbol, orderBook.NewOrderBook(s.Notifier, opts))
 		log.Printf("created new order book and channel for symbol %s, channel addr: %p", symbol, ch)
 	} else {
 		log.Printf("using existing order channel for symbol %s, channel addr: %p", symbol, ch)
@@ -63,14 +92,217 @@ func (s *OrderService) ProcessOrderRequest(req model.OrderRequest) error {
 
 }
 
+// Apply applies a request sequenced by another engine, such as the primary a
+// standby follows, to the book of its symbol and waits for it. Entries a book
+// has already applied are ignored, and so are entries for symbols handed over
+// to another shard since they were journaled.
+func (s *OrderService) Apply(seq uint64, ts int64, req model.OrderRequest) error {
+	symbol := extractSymbol(req)
+	if symbol == "" {
+		return ErrSymbolNotSpecified
+	}
+
+	s.mu.Lock()
+	if s.checkOwner(symbol) != nil {
+		s.mu.Unlock()
+		return nil
+	}
+	book, exists := s.books[symbol]
+	if !exists {
+		opts := s.bookOpts
+		opts.Symbol = symbol
+		book = orderBook.NewOrderBook(s.Notifier, opts)
+		s.startBook(symbol, book)
+	}
+	s.mu.Unlock()
+
+	book.Exec(func(b *orderBook.OrderBook) {
+		b.Apply(seq, ts, req)
+	})
+	return nil
+}
+
+// JournalRange returns the lowest and highest journal sequence applied by any
+// book, both 0 without books.
+func (s *OrderService) JournalRange() (lowest, highest uint64) {
+	s.mu.Lock()
+	books := make([]*orderBook.OrderBook, 0, len(s.books))
+	for _, book := range s.books {
+		books = append(books, book)
+	}
+	s.mu.Unlock()
+
+	for i, book := range books {
+		var seq uint64
+		book.Exec(func(b *orderBook.OrderBook) {
+			seq = b.JournalSeq()
+		})
+		if i == 0 || seq < lowest {
+			lowest = seq
+		}
+		if seq > highest {
+			highest = seq
+		}
+	}
+	return lowest, highest
+}
+
+// checkOwner must be called with s.mu held, so that a book is never created
+// or fed after its symbol has been released. A symbol being handed over to
+// this shard is not served before its book is taken over, but its requests
+// are refused as if the engine were not accepting, to be offered again.
+func (s *OrderService) checkOwner(symbol string) error {
+	if s.Ownership == nil || s.Ownership.Owns(symbol) {
+		return nil
+	}
+	if s.Ownership.Acquiring(symbol) {
+		return fmt.Errorf("%w: %s is being handed over to this shard", ErrNotAccepting, symbol)
+	}
+	return fmt.Errorf("%w: %s belongs to shard %s", ErrSymbolNotOwned, symbol, s.Ownership.Owner(symbol))
+}
+
+// startBook must be called with s.mu held.
+func (s *OrderService) startBook(symbol string, book *orderBook.OrderBook) chan model.OrderRequest {
+	ch := book.Start()
+	s.books[symbol] = book
+	s.orderChannels[symbol] = ch
+	return ch
+}
+
+// Snapshots returns a snapshot of every book. Each one is taken on the book's
+// goroutine after the requests already queued to it.
+func (s *OrderService) Snapshots(takenAt int64) []orderBook.BookSnapshot {
+	s.mu.Lock()
+	books := make([]*orderBook.OrderBook, 0, len(s.books))
+	for _, book := range s.books {
+		books = append(books, book)
+	}
+	s.mu.Unlock()
+
+	snapshots := make([]orderBook.BookSnapshot, 0, len(books))
+	for _, book := range books {
+		book.Exec(func(b *orderBook.OrderBook) {
+			snapshots = append(snapshots, b.Snapshot(takenAt))
+		})
+	}
+	return snapshots
+}
+
+// RestoreBooks starts one book per snapshot. It must run before any request
+// is processed. Snapshots of symbols this shard does not own are skipped.
+func (s *OrderService) RestoreBooks(snapshots []orderBook.BookSnapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, snapshot := range snapshots {
+		if err := s.checkOwner(snapshot.Symbol); err != nil {
+			log.Printf("not restoring order book: %v", err)
+			continue
+		}
+		if err := s.restoreBook(snapshot); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AdoptBook starts a book handed over by another shard. The caller takes
+// ownership of the symbol once it returns.
+func (s *OrderService) AdoptBook(snapshot orderBook.BookSnapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.restoreBook(snapshot)
+}
+
+// OpenOrders returns the resting orders of a symbol, or of every book when
+// symbol is empty.
+func (s *OrderService) OpenOrders(symbol string) []orderBook.Order {
+	orders := []orderBook.Order{}
+	for _, book := range s.booksOf(symbol) {
+		book.Exec(func(b *orderBook.OrderBook) {
+			orders = append(orders, b.OpenOrders()...)
+		})
+	}
+	return orders
+}
+
+// OpenOrder finds a resting order by ClOrdID in any book.
+func (s *OrderService) OpenOrder(clOrdID string) (orderBook.Order, bool) {
+	for _, book := range s.booksOf("") {
+		var order orderBook.Order
+		var ok bool
+		book.Exec(func(b *orderBook.OrderBook) {
+			order, ok = b.RestingOrder(clOrdID)
+		})
+		if ok {
+			return order, true
+		}
+	}
+	return orderBook.Order{}, false
+}
+
+func (s *OrderService) booksOf(symbol string) []*orderBook.OrderBook {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if symbol != "" {
+		if book, ok := s.books[symbol]; ok {
+			return []*orderBook.OrderBook{book}
+		}
+		return nil
+	}
+	books := make([]*orderBook.OrderBook, 0, len(s.books))
+	for _, book := range s.books {
+		books = append(books, book)
+	}
+	return books
+}
+
+// ReleaseBook stops the book of a symbol being handed over to another shard
+// and returns its final snapshot, taken after every request queued to it. The
+// caller must give up ownership of the symbol first. A symbol without a book
+// yields an empty snapshot.
+func (s *OrderService) ReleaseBook(symbol string, takenAt int64) orderBook.BookSnapshot {
+	s.mu.Lock()
+	book, exists := s.books[symbol]
+	ch := s.orderChannels[symbol]
+	delete(s.books, symbol)
+	delete(s.orderChannels, symbol)
+	s.mu.Unlock()
+
+	if !exists {
+		return orderBook.BookSnapshot{Version: orderBook.SnapshotVersion, Symbol: symbol, TakenAt: takenAt}
+	}
+
+	var snapshot orderBook.BookSnapshot
+	book.Exec(func(b *orderBook.OrderBook) {
+		snapshot = b.Snapshot(takenAt)
+	})
+	// Nothing sends to the channel once it is out of the map.
+	close(ch)
+	log.Printf("released order book for symbol %s with %d bid and %d ask levels", symbol, len(snapshot.Bids), len(snapshot.Asks))
+	return snapshot
+}
+
+// restoreBook must be called with s.mu held.
+func (s *OrderService) restoreBook(snapshot orderBook.BookSnapshot) error {
+	if _, exists := s.books[snapshot.Symbol]; exists {
+		return fmt.Errorf("order book for %s already exists", snapshot.Symbol)
+	}
+	opts := s.bookOpts
+	opts.Symbol = snapshot.Symbol
+	book, err := orderBook.RestoreOrderBook(s.Notifier, opts, snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to restore order book for %s: %w", snapshot.Symbol, err)
+	}
+	s.startBook(snapshot.Symbol, book)
+	log.Printf("restored order book for symbol %s with %d bid and %d ask levels", snapshot.Symbol, len(snapshot.Bids), len(snapshot.Asks))
+	return nil
+}
+
 func extractSymbol(req model.OrderRequest) string {
-	switch req.MsgType {
-	case model.MsgTypeNew:
-		return req.NewOrderReq.Symbol
-	case model.MsgTypeCancel:
-		return req.CancelOrderReq.Symbol
-	default:
+	symbol := req.Symbol()
+	if req.MsgType != model.MsgTypeNew && req.MsgType != model.MsgTypeCancel && req.MsgType != model.MsgTypeReplace {
 		log.Printf("invalid message type: %s", req.MsgType)
-		return ""
 	}
+	return symbol
 }
@@ -2,24 +2,29 @@ package service
 
 import (
 	"encoding/json"
+	"fmt"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	"MatchingEngine/internal/idgen"
 	"MatchingEngine/internal/model"
+	"MatchingEngine/orderBook"
 )
 
 type MockNotifier struct{}
 
-func (m *MockNotifier) NotifyEventAndTrade(orderID string, value json.RawMessage) error {
+func (m *MockNotifier) NotifyEventAndTrade(_ model.MsgType, orderID string, value json.RawMessage) error {
 	return nil
 }
 
 func TestProcessOrderRequest_NewOrder(t *testing.T) {
 	notifier := &MockNotifier{}
-	orderService := NewOrderService(notifier)
+	orderService := NewOrderService(notifier, orderBook.BookOpts{})
 
 	req := model.OrderRequest{
 		MsgType: model.MsgTypeNew,
@@ -55,7 +60,7 @@ func TestProcessOrderRequest_NewOrder(t *testing.T) {
 
 func TestProcessOrderRequest_CancelOrder(t *testing.T) {
 	notifier := &MockNotifier{}
-	orderService := NewOrderService(notifier)
+	orderService := NewOrderService(notifier, orderBook.BookOpts{})
 
 	req := model.OrderRequest{
 		MsgType: model.MsgTypeCancel,
@@ -83,7 +88,7 @@ func TestProcessOrderRequest_CancelOrder(t *testing.T) {
 
 func TestProcessOrderRequest_InvalidMessageType(t *testing.T) {
 	notifier := &MockNotifier{}
-	orderService := NewOrderService(notifier)
+	orderService := NewOrderService(notifier, orderBook.BookOpts{})
 
 	req := model.OrderRequest{
 		MsgType: "X",
@@ -108,3 +113,135 @@ func TestProcessOrderRequest_InvalidMessageType(t *testing.T) {
 
 	assert.Equal(t, 0, len(orderService.orderChannels))
 }
+
+type fakeGate struct{ open bool }
+
+func (g *fakeGate) IsOpen() bool { return g.open }
+
+func TestProcessOrderRequest_RefusedWhileGateClosed(t *testing.T) {
+	gate := &fakeGate{}
+	orderService := NewOrderService(&MockNotifier{}, orderBook.BookOpts{})
+	orderService.Gate = gate
+
+	req := newOrderReq("CL001", model.Buy, 1, 100)
+	assert.ErrorIs(t, orderService.ProcessOrderRequest(req), ErrNotAccepting)
+	assert.Empty(t, orderService.OpenOrders(""))
+
+	gate.open = true
+	assert.NoError(t, orderService.ProcessOrderRequest(req))
+}
+
+func TestOrderService_SnapshotsAndRestore(t *testing.T) {
+	orderService := NewOrderService(&MockNotifier{}, orderBook.BookOpts{})
+
+	req := model.OrderRequest{
+		MsgType: model.MsgTypeNew,
+		NewOrderReq: model.NewOrderRequest{
+			BaseOrderRequest: model.BaseOrderRequest{
+				MsgType:      model.MsgTypeNew,
+				ClOrdID:      "CL001",
+				Side:         model.Buy,
+				Symbol:       "BTC/USDT",
+				TransactTime: time.Now().UnixNano(),
+			},
+			OrderQty: decimal.NewFromInt(10),
+			Price:    decimal.NewFromInt(100),
+		},
+	}
+	assert.NoError(t, orderService.ProcessOrderRequest(req))
+
+	snapshots := orderService.Snapshots(time.Now().UnixNano())
+	assert.Len(t, snapshots, 1)
+	assert.Equal(t, "BTC/USDT", snapshots[0].Symbol)
+	assert.Contains(t, snapshots[0].OrderIndex, "CL001")
+
+	restored := NewOrderService(&MockNotifier{}, orderBook.BookOpts{})
+	assert.NoError(t, restored.RestoreBooks(snapshots))
+	assert.Error(t, restored.RestoreBooks(snapshots))
+
+	again := restored.Snapshots(snapshots[0].TakenAt)
+	assert.Equal(t, snapshots, again)
+}
+
+type idCollector struct {
+	mu       sync.Mutex
+	keys     []string
+	orderIDs map[string]map[string]bool // OrderID -> ClOrdIDs
+}
+
+func (c *idCollector) NotifyEventAndTrade(_ model.MsgType, key string, value json.RawMessage) error {
+	var msg struct {
+		MsgType string `json:"35"`
+		ExecID  string `json:"17"`
+		OrderID string `json:"37"`
+		ClOrdID string `json:"11"`
+	}
+	if err := json.Unmarshal(value, &msg); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.keys = append(c.keys, key)
+	if msg.MsgType == string(model.MsgTypeTradeReport) {
+		c.keys = append(c.keys, msg.ExecID)
+		return nil
+	}
+	if c.orderIDs[msg.OrderID] == nil {
+		c.orderIDs[msg.OrderID] = make(map[string]bool)
+	}
+	c.orderIDs[msg.OrderID][msg.ClOrdID] = true
+	return nil
+}
+
+func TestOrderService_IDsUniqueAcrossSymbols(t *testing.T) {
+	ids, err := idgen.New(idgen.Opts{Instance: "me1", BlockSize: 16})
+	require.NoError(t, err)
+	collector := &idCollector{orderIDs: make(map[string]map[string]bool)}
+	orderService := NewOrderService(collector, orderBook.BookOpts{IDs: ids})
+
+	const symbols, pairs = 32, 50
+	var wg sync.WaitGroup
+	for s := 0; s < symbols; s++ {
+		wg.Add(1)
+		go func(symbol string) {
+			defer wg.Done()
+			for i := 0; i < pairs; i++ {
+				for _, side := range []model.Side{model.Sell, model.Buy} {
+					req := model.OrderRequest{
+						MsgType: model.MsgTypeNew,
+						NewOrderReq: model.NewOrderRequest{
+							BaseOrderRequest: model.BaseOrderRequest{
+								MsgType:      model.MsgTypeNew,
+								ClOrdID:      fmt.Sprintf("%s-%s-%d", symbol, side, i),
+								Side:         side,
+								Symbol:       symbol,
+								TransactTime: time.Now().UnixNano(),
+							},
+							OrderQty: decimal.NewFromInt(1),
+							Price:    decimal.NewFromInt(100),
+						},
+					}
+					assert.NoError(t, orderService.ProcessOrderRequest(req))
+				}
+			}
+		}(fmt.Sprintf("SYM%02d/USDT", s))
+	}
+	wg.Wait()
+	orderService.Snapshots(0) // Waits for every book to drain its queue
+
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+
+	// Per pair: one New, two fills and one trade report carrying its own ExecID.
+	assert.Len(t, collector.keys, symbols*pairs*5)
+	seen := make(map[string]bool, len(collector.keys))
+	for _, id := range collector.keys {
+		assert.False(t, seen[id], "duplicate ID %s", id)
+		seen[id] = true
+	}
+	assert.Len(t, collector.orderIDs, symbols*pairs*2)
+	for orderID, clOrdIDs := range collector.orderIDs {
+		assert.Len(t, clOrdIDs, 1, "OrderID %s shared by several orders", orderID)
+	}
+}
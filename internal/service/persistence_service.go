@@ -0,0 +1,28 @@
+package service
+
+import (
+	"context"
+
+	"MatchingEngine/internal/repository"
+)
+
+// PersistenceService queues the batches the persistence consumer flushes.
+type PersistenceService struct {
+	asyncWriter repository.AsyncDBWriterInterface
+}
+
+func NewPersistenceService(asyncWriter repository.AsyncDBWriterInterface) *PersistenceService {
+	return &PersistenceService{
+		asyncWriter: asyncWriter,
+	}
+}
+
+// SaveBatchAsync queues the batch for writing in one transaction, blocking
+// while the writer's queue is full; done, when set, is called with the
+// outcome.
+func (s *PersistenceService) SaveBatchAsync(ctx context.Context, batch repository.Batch, done func(error)) {
+	s.asyncWriter.EnqueueTask(ctx, repository.SaveBatchTask{
+		Batch: batch,
+		Done:  done,
+	})
+}
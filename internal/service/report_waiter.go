@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"MatchingEngine/internal/model"
+)
+
+// ErrNoReport is a submitted request whose execution report did not arrive in
+// time. The request may still be processed.
+var ErrNoReport = errors.New("no execution report before the deadline")
+
+type RequestProcessor interface {
+	ProcessOrderRequest(req model.OrderRequest) error
+}
+
+// ReportWaiter submits order requests and waits for the execution report that
+// answers each one: the first report of a new order, the Canceled or Rejected
+// report of a cancel, and the Replaced or Rejected report of a replace.
+type ReportWaiter struct {
+	// Orders is set once the order service exists, as the service's notifier
+	// must be built from the waiter first.
+	Orders RequestProcessor
+
+	mu      sync.Mutex
+	waiters map[reportKey][]*reportWait
+}
+
+// reportKey names the order a report is about. ClOrdIDs are unique within a
+// book only, so the symbol is part of it.
+type reportKey struct {
+	symbol  string
+	clOrdID string
+}
+
+type reportWait struct {
+	match   func(er model.ExecutionReport) bool
+	reports chan model.ExecutionReport
+}
+
+func NewReportWaiter() *ReportWaiter {
+	return &ReportWaiter{waiters: make(map[reportKey][]*reportWait)}
+}
+
+// Submit hands req to the order service and returns its execution report,
+// ErrNoReport when ctx ends first, or the error the service refused it with.
+func (w *ReportWaiter) Submit(ctx context.Context, req model.OrderRequest) (model.ExecutionReport, error) {
+	pending, err := w.Enter(req)
+	if err != nil {
+		return model.ExecutionReport{}, err
+	}
+	return pending.Wait(ctx)
+}
+
+// PendingReport is a request handed to the order service whose execution
+// report has yet to be collected with Wait.
+type PendingReport struct {
+	waiter  *ReportWaiter
+	keys    []reportKey
+	reports chan model.ExecutionReport
+}
+
+// Enter hands req to the order service like Submit but returns without
+// waiting, so that a caller taking requests in order can wait for their
+// reports elsewhere. Wait must be called on the result.
+func (w *ReportWaiter) Enter(req model.OrderRequest) (*PendingReport, error) {
+	reports := make(chan model.ExecutionReport, 1)
+	p := &PendingReport{waiter: w, keys: w.register(req, reports), reports: reports}
+	if err := w.Orders.ProcessOrderRequest(req); err != nil {
+		w.unregister(p.keys, reports)
+		return nil, err
+	}
+	return p, nil
+}
+
+// Wait returns the report of the request, or ErrNoReport when ctx ends first.
+func (p *PendingReport) Wait(ctx context.Context) (model.ExecutionReport, error) {
+	defer p.waiter.unregister(p.keys, p.reports)
+	select {
+	case er := <-p.reports:
+		return er, nil
+	case <-ctx.Done():
+		return model.ExecutionReport{}, ErrNoReport
+	}
+}
+
+// register sets up the waits for the ClOrdIDs the answer to req may carry.
+func (w *ReportWaiter) register(req model.OrderRequest, reports chan model.ExecutionReport) []reportKey {
+	anyReport := func(model.ExecutionReport) bool { return true }
+	execTypes := func(types ...model.ExecType) func(model.ExecutionReport) bool {
+		return func(er model.ExecutionReport) bool {
+			for _, t := range types {
+				if er.ExecType == t {
+					return true
+				}
+			}
+			return false
+		}
+	}
+
+	symbol := req.Symbol()
+	waits := map[reportKey]func(model.ExecutionReport) bool{}
+	switch req.MsgType {
+	case model.MsgTypeNew:
+		waits[reportKey{symbol, req.NewOrderReq.ClOrdID}] = anyReport
+	case model.MsgTypeCancel:
+		// Reports on the order itself, a fill say, are not the answer.
+		waits[reportKey{symbol, req.CancelOrderReq.OrigClOrdID}] = execTypes(model.ExecTypeCanceled, model.ExecTypeRejected)
+	case model.MsgTypeReplace:
+		if req.ReplaceOrderReq != nil {
+			waits[reportKey{symbol, req.ReplaceOrderReq.OrigClOrdID}] = execTypes(model.ExecTypeRejected)
+			waits[reportKey{symbol, req.ReplaceOrderReq.ClOrdID}] = anyReport
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	keys := make([]reportKey, 0, len(waits))
+	for key, match := range waits {
+		w.waiters[key] = append(w.waiters[key], &reportWait{match: match, reports: reports})
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func (w *ReportWaiter) unregister(keys []reportKey, reports chan model.ExecutionReport) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, key := range keys {
+		waits := w.waiters[key][:0]
+		for _, wait := range w.waiters[key] {
+			if wait.reports != reports {
+				waits = append(waits, wait)
+			}
+		}
+		if len(waits) == 0 {
+			delete(w.waiters, key)
+		} else {
+			w.waiters[key] = waits
+		}
+	}
+}
+
+// Notifier returns a notifier that forwards everything to next and hands
+// execution reports to the submissions waiting for them.
+func (w *ReportWaiter) Notifier(next Notifier) Notifier {
+	return &waiterNotifier{next: next, waiter: w}
+}
+
+type waiterNotifier struct {
+	next   Notifier
+	waiter *ReportWaiter
+}
+
+func (n *waiterNotifier) NotifyEventAndTrade(msgType model.MsgType, key string, value json.RawMessage) error {
+	err := n.next.NotifyEventAndTrade(msgType, key, value)
+	if msgType != model.MsgTypeExecRpt || n.waiter.idle() {
+		return err
+	}
+
+	var er model.ExecutionReport
+	if json.Unmarshal(value, &er) != nil {
+		return err
+	}
+	n.waiter.deliver(er)
+	return err
+}
+
+func (w *ReportWaiter) idle() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.waiters) == 0
+}
+
+func (w *ReportWaiter) deliver(er model.ExecutionReport) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, wait := range w.waiters[reportKey{er.Symbol, er.ClOrdID}] {
+		if !wait.match(er) {
+			continue
+		}
+		// The first report is the answer; later ones are not.
+		select {
+		case wait.reports <- er:
+		default:
+		}
+	}
+}
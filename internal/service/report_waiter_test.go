@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"MatchingEngine/internal/model"
+	"MatchingEngine/orderBook"
+)
+
+func newOrderReq(clOrdID string, side model.Side, qty, px int64) model.OrderRequest {
+	return model.OrderRequest{
+		MsgType: model.MsgTypeNew,
+		NewOrderReq: model.NewOrderRequest{
+			BaseOrderRequest: model.BaseOrderRequest{MsgType: model.MsgTypeNew, ClOrdID: clOrdID, Side: side, Symbol: "BTC/USDT"},
+			OrderQty:         decimal.NewFromInt(qty),
+			Price:            decimal.NewFromInt(px),
+		},
+	}
+}
+
+func newWaiterService() *ReportWaiter {
+	waiter := NewReportWaiter()
+	waiter.Orders = NewOrderService(waiter.Notifier(&MockNotifier{}), orderBook.BookOpts{})
+	return waiter
+}
+
+func submit(t *testing.T, waiter *ReportWaiter, req model.OrderRequest) model.ExecutionReport {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	er, err := waiter.Submit(ctx, req)
+	require.NoError(t, err)
+	return er
+}
+
+func TestReportWaiter_AnswersEachRequest(t *testing.T) {
+	waiter := newWaiterService()
+
+	er := submit(t, waiter, newOrderReq("S1", model.Sell, 5, 100))
+	assert.Equal(t, "S1", er.ClOrdID)
+	assert.Equal(t, model.ExecTypeNew, er.ExecType)
+
+	// An order that fills at once is answered by its first fill.
+	er = submit(t, waiter, newOrderReq("B1", model.Buy, 2, 100))
+	assert.Equal(t, "B1", er.ClOrdID)
+	assert.Equal(t, model.ExecTypeFill, er.ExecType)
+
+	er = submit(t, waiter, newOrderReq("B2", model.Buy, 0, 100))
+	assert.Equal(t, model.ExecTypeRejected, er.ExecType)
+
+	er = submit(t, waiter, model.OrderRequest{
+		MsgType: model.MsgTypeReplace,
+		ReplaceOrderReq: &model.OrderCancelReplaceRequest{
+			BaseOrderRequest: model.BaseOrderRequest{MsgType: model.MsgTypeReplace, ClOrdID: "S1R", Side: model.Sell, Symbol: "BTC/USDT"},
+			OrigClOrdID:      "S1",
+			OrderQty:         decimal.NewFromInt(4),
+			Price:            decimal.NewFromInt(100),
+		},
+	})
+	assert.Equal(t, "S1R", er.ClOrdID)
+	assert.Equal(t, model.ExecTypeReplaced, er.ExecType)
+
+	cancel := model.OrderRequest{
+		MsgType: model.MsgTypeCancel,
+		CancelOrderReq: model.OrderCancelRequest{
+			BaseOrderRequest: model.BaseOrderRequest{MsgType: model.MsgTypeCancel, ClOrdID: "C1", Side: model.Sell, Symbol: "BTC/USDT"},
+			OrigClOrdID:      "S1R",
+		},
+	}
+	er = submit(t, waiter, cancel)
+	assert.Equal(t, model.ExecTypeCanceled, er.ExecType)
+
+	// The order is gone now.
+	er = submit(t, waiter, cancel)
+	assert.Equal(t, model.ExecTypeRejected, er.ExecType)
+	assert.True(t, waiter.idle())
+}
+
+func TestReportWaiter_KeepsClOrdIDsOfSymbolsApart(t *testing.T) {
+	waiter := newWaiterService()
+
+	btc, err := waiter.Enter(newOrderReq("B1", model.Buy, 1, 100))
+	require.NoError(t, err)
+	eth := newOrderReq("B1", model.Buy, 0, 100)
+	eth.NewOrderReq.Symbol = "ETH/USDT"
+	ethPending, err := waiter.Enter(eth)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	er, err := btc.Wait(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "BTC/USDT", er.Symbol)
+	assert.Equal(t, model.ExecTypeNew, er.ExecType)
+	er, err = ethPending.Wait(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "ETH/USDT", er.Symbol)
+	assert.Equal(t, model.ExecTypeRejected, er.ExecType)
+}
+
+func TestReportWaiter_TimesOutWithoutReport(t *testing.T) {
+	waiter := NewReportWaiter()
+	waiter.Orders = NewOrderService(&MockNotifier{}, orderBook.BookOpts{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := waiter.Submit(ctx, newOrderReq("S1", model.Sell, 1, 100))
+	assert.ErrorIs(t, err, ErrNoReport)
+	assert.True(t, waiter.idle())
+
+	_, err = waiter.Submit(context.Background(), model.OrderRequest{MsgType: model.MsgTypeNew})
+	assert.ErrorIs(t, err, ErrSymbolNotSpecified)
+}
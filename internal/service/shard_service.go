@@ -0,0 +1,117 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"MatchingEngine/orderBook"
+)
+
+// ShardMap is the symbol-to-shard assignment as one shard sees it.
+type ShardMap interface {
+	Ownership
+	Assign(symbol, shardID string) error
+	Handoff(symbol, shardID string) error
+}
+
+// BookStore keeps book snapshots by symbol.
+type BookStore interface {
+	SaveAll(snapshots []orderBook.BookSnapshot) error
+	Load(symbol string) (orderBook.BookSnapshot, error)
+	Delete(symbol string) error
+}
+
+// JournalPosition reports the last sequence written to the engine's journal.
+type JournalPosition interface {
+	LastSeq() uint64
+}
+
+// ShardService moves symbols between shards. The releasing shard stops the
+// book and leaves its final snapshot in a handoff store both shards can
+// reach; the acquiring shard restores it from there.
+type ShardService struct {
+	shardID string
+	shards  ShardMap
+	orders  *OrderService
+	local   BookStore
+	handoff BookStore
+	journal JournalPosition
+}
+
+// NewShardService needs the shard's own snapshot store so that a moved book
+// is neither restored on the old shard nor lost on the new one after a
+// restart.
+func NewShardService(shardID string, shards ShardMap, orders *OrderService, local, handoff BookStore, journal JournalPosition) *ShardService {
+	return &ShardService{
+		shardID: shardID,
+		shards:  shards,
+		orders:  orders,
+		local:   local,
+		handoff: handoff,
+		journal: journal,
+	}
+}
+
+// Release hands a symbol over to another shard. From the moment ownership
+// moves, requests for the symbol are rejected here and routed to the other
+// shard, which holds them back until it has acquired the book; requests
+// already queued to the book are processed before its final snapshot is
+// taken.
+func (s *ShardService) Release(symbol, to string) error {
+	if !s.shards.Owns(symbol) {
+		return fmt.Errorf("%w: %s belongs to shard %s", ErrSymbolNotOwned, symbol, s.shards.Owner(symbol))
+	}
+	if to == s.shardID {
+		return fmt.Errorf("%s is already owned by shard %s", symbol, to)
+	}
+	if err := s.shards.Handoff(symbol, to); err != nil {
+		return fmt.Errorf("failed to reassign %s: %w", symbol, err)
+	}
+
+	snapshot := s.orders.ReleaseBook(symbol, time.Now().UnixNano())
+	if err := s.handoff.SaveAll([]orderBook.BookSnapshot{snapshot}); err != nil {
+		// Keep serving the symbol rather than lose the book.
+		if restoreErr := s.orders.AdoptBook(snapshot); restoreErr != nil {
+			return fmt.Errorf("failed to hand over %s: %w; also failed to take it back: %v", symbol, err, restoreErr)
+		}
+		if assignErr := s.shards.Assign(symbol, s.shardID); assignErr != nil {
+			return fmt.Errorf("failed to hand over %s: %w; also failed to take it back: %v", symbol, err, assignErr)
+		}
+		return fmt.Errorf("failed to hand over %s: %w", symbol, err)
+	}
+	if err := s.local.Delete(symbol); err != nil {
+		return err
+	}
+	log.Printf("Released %s to shard %s", symbol, to)
+	return nil
+}
+
+// Acquire takes over a symbol released by another shard. The book's journal
+// position is moved onto this engine's journal, whose sequence the old
+// shard's snapshot knows nothing about.
+func (s *ShardService) Acquire(symbol string) error {
+	if s.shards.Owns(symbol) {
+		return fmt.Errorf("%s is already owned by shard %s", symbol, s.shardID)
+	}
+	snapshot, err := s.handoff.Load(symbol)
+	if err != nil {
+		return fmt.Errorf("%s has not been released: %w", symbol, err)
+	}
+	snapshot.JournalSeq = s.journal.LastSeq()
+
+	if err := s.orders.AdoptBook(snapshot); err != nil {
+		return err
+	}
+	if err := s.local.SaveAll([]orderBook.BookSnapshot{snapshot}); err != nil {
+		return fmt.Errorf("failed to save snapshot of %s: %w", symbol, err)
+	}
+	if err := s.shards.Assign(symbol, s.shardID); err != nil {
+		return fmt.Errorf("failed to reassign %s: %w", symbol, err)
+	}
+	if err := s.handoff.Delete(symbol); err != nil {
+		log.Printf("Error removing handoff snapshot of %s: %v", symbol, err)
+	}
+	log.Printf("Acquired %s on shard %s", symbol, s.shardID)
+	return nil
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"MatchingEngine/internal/model"
+	"MatchingEngine/internal/shard"
+	"MatchingEngine/internal/snapshot"
+	"MatchingEngine/orderBook"
+)
+
+type fixedPosition uint64
+
+func (p fixedPosition) LastSeq() uint64 { return uint64(p) }
+
+func newShardOrder(clOrdID, symbol string) model.OrderRequest {
+	return model.OrderRequest{
+		MsgType: model.MsgTypeNew,
+		NewOrderReq: model.NewOrderRequest{
+			BaseOrderRequest: model.BaseOrderRequest{
+				MsgType:      model.MsgTypeNew,
+				ClOrdID:      clOrdID,
+				Side:         model.Buy,
+				Symbol:       symbol,
+				TransactTime: time.Now().UnixNano(),
+			},
+			OrderQty: decimal.NewFromInt(10),
+			Price:    decimal.NewFromInt(100),
+		},
+	}
+}
+
+func newShard(t *testing.T, id string, static map[string]string, overridesPath ...string) (*OrderService, *shard.Ownership) {
+	assignment, err := shard.NewAssignment([]string{"shard-0", "shard-1"}, static)
+	require.NoError(t, err)
+	for _, path := range overridesPath {
+		require.NoError(t, assignment.LoadOverrides(path))
+	}
+	ownership := &shard.Ownership{ShardID: id, Assignment: assignment}
+	orders := NewOrderService(&MockNotifier{}, orderBook.BookOpts{})
+	orders.Ownership = ownership
+	return orders, ownership
+}
+
+func TestOrderService_RejectsSymbolsOfOtherShards(t *testing.T) {
+	orders, _ := newShard(t, "shard-0", map[string]string{"BTC/USDT": "shard-0", "ETH/USDT": "shard-1"})
+
+	require.NoError(t, orders.ProcessOrderRequest(newShardOrder("B1", "BTC/USDT")))
+	err := orders.ProcessOrderRequest(newShardOrder("E1", "ETH/USDT"))
+	assert.ErrorIs(t, err, ErrSymbolNotOwned)
+	assert.Contains(t, err.Error(), "shard-1")
+
+	// Neither intake nor replay creates a ghost book.
+	require.NoError(t, orders.Apply(1, 1, newShardOrder("E2", "ETH/USDT")))
+	snapshots := orders.Snapshots(0)
+	require.Len(t, snapshots, 1)
+	assert.Equal(t, "BTC/USDT", snapshots[0].Symbol)
+}
+
+func TestShardService_HandsBookOver(t *testing.T) {
+	static := map[string]string{"BTC/USDT": "shard-0"}
+	handoff := snapshot.NewFileStore(t.TempDir())
+	overrides := filepath.Join(t.TempDir(), "overrides.json")
+
+	oldOrders, oldMap := newShard(t, "shard-0", static, overrides)
+	oldLocal := snapshot.NewFileStore(t.TempDir())
+	oldShard := NewShardService("shard-0", oldMap, oldOrders, oldLocal, handoff, fixedPosition(0))
+
+	newOrders, newMap := newShard(t, "shard-1", static, overrides)
+	newLocal := snapshot.NewFileStore(t.TempDir())
+	newShard := NewShardService("shard-1", newMap, newOrders, newLocal, handoff, fixedPosition(7))
+
+	require.NoError(t, oldOrders.ProcessOrderRequest(newShardOrder("B1", "BTC/USDT")))
+	require.NoError(t, oldLocal.SaveAll(oldOrders.Snapshots(1)))
+
+	assert.Error(t, newShard.Acquire("BTC/USDT"), "nothing was released yet")
+	require.NoError(t, oldShard.Release("BTC/USDT", "shard-1"))
+	assert.ErrorIs(t, oldOrders.ProcessOrderRequest(newShardOrder("B2", "BTC/USDT")), ErrSymbolNotOwned)
+	assert.Empty(t, oldOrders.Snapshots(0))
+	_, err := oldLocal.Load("BTC/USDT")
+	assert.Error(t, err)
+
+	// Requests follow the symbol to the new shard, which holds them back
+	// until it has the book.
+	assert.Equal(t, "shard-1", newMap.Owner("BTC/USDT"))
+	assert.ErrorIs(t, newOrders.ProcessOrderRequest(newShardOrder("B3", "BTC/USDT")), ErrNotAccepting)
+	assert.Empty(t, newOrders.Snapshots(0))
+
+	require.NoError(t, newShard.Acquire("BTC/USDT"))
+	assert.True(t, oldMap.Owner("BTC/USDT") == "shard-1" && !oldMap.Acquiring("BTC/USDT"))
+	require.NoError(t, newOrders.ProcessOrderRequest(newShardOrder("B3", "BTC/USDT")))
+
+	snapshots := newOrders.Snapshots(0)
+	require.Len(t, snapshots, 1)
+	assert.Contains(t, snapshots[0].OrderIndex, "B1")
+	assert.Contains(t, snapshots[0].OrderIndex, "B3")
+	assert.Equal(t, uint64(7), snapshots[0].JournalSeq)
+
+	saved, err := newLocal.Load("BTC/USDT")
+	require.NoError(t, err)
+	assert.Contains(t, saved.OrderIndex, "B1")
+	_, err = handoff.Load("BTC/USDT")
+	assert.Error(t, err)
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"MatchingEngine/orderBook"
+)
+
+type SnapshotStore interface {
+	SaveAll(snapshots []orderBook.BookSnapshot) error
+	LoadAll() ([]orderBook.BookSnapshot, error)
+}
+
+// SnapshotService persists the order books so that resting orders survive a
+// restart.
+type SnapshotService struct {
+	orders *OrderService
+	store  SnapshotStore
+}
+
+func NewSnapshotService(orders *OrderService, store SnapshotStore) *SnapshotService {
+	return &SnapshotService{
+		orders: orders,
+		store:  store,
+	}
+}
+
+// Restore loads the stored snapshots into the order service and returns how
+// many there were. It must run before any order request is consumed.
+func (s *SnapshotService) Restore() (int, error) {
+	snapshots, err := s.store.LoadAll()
+	if err != nil {
+		return 0, fmt.Errorf("failed to load order book snapshots: %w", err)
+	}
+	return len(snapshots), s.orders.RestoreBooks(snapshots)
+}
+
+func (s *SnapshotService) TakeSnapshot() error {
+	snapshots := s.orders.Snapshots(time.Now().UnixNano())
+	if err := s.store.SaveAll(snapshots); err != nil {
+		return fmt.Errorf("failed to save order book snapshots: %w", err)
+	}
+	log.Printf("Saved snapshots of %d order books", len(snapshots))
+	return nil
+}
+
+// Start takes a snapshot each interval until the context is cancelled. The
+// final on-shutdown snapshot is left to the caller, once intake has stopped.
+func (s *SnapshotService) Start(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		log.Println("Periodic order book snapshots disabled: no snapshot interval configured")
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.TakeSnapshot(); err != nil {
+				log.Printf("Error taking order book snapshot: %v", err)
+			}
+		}
+	}
+}
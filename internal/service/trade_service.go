@@ -1,6 +1,8 @@
 package service
 
 import (
+	"context"
+
 	"MatchingEngine/internal/model"
 	"MatchingEngine/internal/repository"
 )
@@ -17,8 +19,11 @@ func NewTradeService(asyncWriter repository.AsyncDBWriterInterface, notifier Not
 	}
 }
 
-func (s *TradeService) SaveTradeAsync(trade model.TradeCaptureReport) {
-	s.asyncWriter.EnqueueTask(repository.SaveTradeTask{
+// SaveTradeAsync queues the report for writing; done, when set, is called
+// with the outcome.
+func (s *TradeService) SaveTradeAsync(trade model.TradeCaptureReport, done func(error)) {
+	s.asyncWriter.EnqueueTask(context.Background(), repository.SaveTradeTask{
 		Trade: trade,
+		Done:  done,
 	})
 }
@@ -0,0 +1,356 @@
+// Package shard decides which engine instance owns a symbol when the symbols
+// are spread over several engines.
+package shard
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultVirtualNodes is the number of ring positions per shard.
+const DefaultVirtualNodes = 64
+
+// Ring assigns symbols to shards by consistent hashing, so adding or removing
+// a shard only moves the symbols of the ring segments it gains or loses.
+type Ring struct {
+	points []uint64
+	owners map[uint64]string
+}
+
+func NewRing(shards []string, virtualNodes int) (*Ring, error) {
+	if len(shards) == 0 {
+		return nil, fmt.Errorf("shard ring needs at least one shard")
+	}
+	if virtualNodes <= 0 {
+		virtualNodes = DefaultVirtualNodes
+	}
+
+	r := &Ring{owners: make(map[uint64]string, len(shards)*virtualNodes)}
+	for _, s := range shards {
+		if s == "" {
+			return nil, fmt.Errorf("shard ring has an empty shard ID")
+		}
+		for i := 0; i < virtualNodes; i++ {
+			point := hash(s + "#" + strconv.Itoa(i))
+			other, taken := r.owners[point]
+			if !taken {
+				r.points = append(r.points, point)
+			} else if other <= s {
+				// A collision keeps the smaller ID so every engine builds the
+				// same ring whatever order the shards are listed in.
+				continue
+			}
+			r.owners[point] = s
+		}
+	}
+	sort.Slice(r.points, func(i, j int) bool { return r.points[i] < r.points[j] })
+	return r, nil
+}
+
+// Owner returns the shard of the first ring position at or after the
+// symbol's hash.
+func (r *Ring) Owner(symbol string) string {
+	h := hash(symbol)
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= h })
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.owners[r.points[i]]
+}
+
+// hash is FNV-1a followed by the splitmix64 finalizer, which spreads the
+// near-identical keys of the ring ("shard-0#1", "shard-0#2") evenly.
+func hash(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// override is a handoff made at runtime. It is pending from the release until
+// the shard it names has taken the book over: the symbol is routed there but
+// not served yet.
+type override struct {
+	Shard   string `json:"shard"`
+	Pending bool   `json:"pending,omitempty"`
+}
+
+// Assignment maps symbols to shards: the static map first, then the ring.
+// Overrides record handoffs made at runtime and win over both. They are kept
+// in a file every engine and router of the deployment shares, so a handoff is
+// followed everywhere, and an engine restarted before the static map is
+// updated still agrees with it. The file is read again whenever it changes.
+type Assignment struct {
+	static map[string]string
+	ring   *Ring
+	known  map[string]bool
+
+	mu            sync.RWMutex
+	overrides     map[string]override
+	overridesPath string
+	// loaded is the overrides file as last read; every write replaces the
+	// file, so another one means another process assigned a symbol.
+	loaded os.FileInfo
+}
+
+func NewAssignment(shards []string, static map[string]string) (*Assignment, error) {
+	ring, err := NewRing(shards, DefaultVirtualNodes)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool, len(shards))
+	for _, s := range shards {
+		known[s] = true
+	}
+	for symbol, s := range static {
+		if !known[s] {
+			return nil, fmt.Errorf("symbol %s is mapped to unknown shard %s", symbol, s)
+		}
+	}
+	return &Assignment{static: static, ring: ring, known: known, overrides: make(map[string]override)}, nil
+}
+
+// LoadOverrides reads the overrides kept at path, if any, and makes Owner
+// follow them and Assign write them back there.
+func (a *Assignment) LoadOverrides(path string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	overrides, info, err := a.readOverrides(path)
+	if err != nil {
+		return err
+	}
+	a.overrides, a.loaded = overrides, info
+	a.overridesPath = path
+	return nil
+}
+
+// readOverrides returns the overrides kept at path, none if there is no file.
+func (a *Assignment) readOverrides(path string) (map[string]override, os.FileInfo, error) {
+	overrides := make(map[string]override)
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return overrides, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read shard overrides: %w", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read shard overrides: %w", err)
+	}
+	if err := json.NewDecoder(f).Decode(&overrides); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode shard overrides %s: %w", path, err)
+	}
+	for symbol, o := range overrides {
+		if !a.known[o.Shard] {
+			return nil, nil, fmt.Errorf("shard overrides map %s to unknown shard %s", symbol, o.Shard)
+		}
+	}
+	return overrides, info, nil
+}
+
+// refresh reads the overrides file again if another process replaced it. A
+// file that cannot be read leaves the overrides as they were.
+func (a *Assignment) refresh() {
+	a.mu.RLock()
+	path, loaded := a.overridesPath, a.loaded
+	a.mu.RUnlock()
+	if path == "" {
+		return
+	}
+	info, err := os.Stat(path)
+	if err != nil || (loaded != nil && os.SameFile(loaded, info) && loaded.ModTime().Equal(info.ModTime())) {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	overrides, info, err := a.readOverrides(path)
+	if err != nil {
+		log.Printf("Keeping the shard overrides last read: %v", err)
+		return
+	}
+	a.overrides, a.loaded = overrides, info
+}
+
+// Owner returns the shard a symbol is routed to, which may still be taking
+// it over.
+func (a *Assignment) Owner(symbol string) string {
+	owner, _ := a.lookup(symbol)
+	return owner
+}
+
+// lookup returns the shard of a symbol and whether it is still taking the
+// symbol over.
+func (a *Assignment) lookup(symbol string) (string, bool) {
+	a.refresh()
+	a.mu.RLock()
+	o, ok := a.overrides[symbol]
+	a.mu.RUnlock()
+	if ok {
+		return o.Shard, o.Pending
+	}
+	if owner, ok := a.static[symbol]; ok {
+		return owner, false
+	}
+	return a.ring.Owner(symbol), false
+}
+
+// Assign moves a symbol to a shard, which serves it from then on.
+func (a *Assignment) Assign(symbol, shardID string) error {
+	return a.set(symbol, override{Shard: shardID})
+}
+
+// Handoff routes a symbol to a shard that has yet to take the book over; it
+// serves the symbol once it is assigned it.
+func (a *Assignment) Handoff(symbol, shardID string) error {
+	return a.set(symbol, override{Shard: shardID, Pending: true})
+}
+
+// set records an override. It is merged into the overrides file under a
+// lock, so handoffs made by other processes are kept, and is written out
+// before it takes effect.
+func (a *Assignment) set(symbol string, o override) error {
+	if !a.known[o.Shard] {
+		return fmt.Errorf("unknown shard %s", o.Shard)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.overridesPath == "" {
+		overrides := maps.Clone(a.overrides)
+		overrides[symbol] = o
+		a.overrides = overrides
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(a.overridesPath), 0o755); err != nil {
+		return fmt.Errorf("failed to create shard overrides directory: %w", err)
+	}
+	unlock, err := lockFile(a.overridesPath + ".lock")
+	if err != nil {
+		return fmt.Errorf("failed to lock shard overrides: %w", err)
+	}
+	defer unlock()
+
+	overrides, _, err := a.readOverrides(a.overridesPath)
+	if err != nil {
+		return err
+	}
+	overrides[symbol] = o
+	if err := writeOverrides(a.overridesPath, overrides); err != nil {
+		return err
+	}
+	info, err := os.Stat(a.overridesPath)
+	if err != nil {
+		return fmt.Errorf("failed to read shard overrides: %w", err)
+	}
+	a.overrides, a.loaded = overrides, info
+	return nil
+}
+
+func writeOverrides(path string, overrides map[string]override) error {
+	data, err := json.MarshalIndent(overrides, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode shard overrides: %w", err)
+	}
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, ".overrides-*")
+	if err != nil {
+		return fmt.Errorf("failed to create shard overrides file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write shard overrides: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync shard overrides: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close shard overrides: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace shard overrides: %w", err)
+	}
+	return nil
+}
+
+// ParseIDs parses a comma-separated list of shard IDs.
+func ParseIDs(s string) []string {
+	var ids []string
+	for _, id := range strings.Split(s, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// ParseStaticMap parses "SYMBOL=shard,SYMBOL=shard".
+func ParseStaticMap(s string) (map[string]string, error) {
+	static := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		symbol, shardID, ok := strings.Cut(pair, "=")
+		if !ok || symbol == "" || shardID == "" {
+			return nil, fmt.Errorf("invalid shard map entry %q, expected SYMBOL=shard", pair)
+		}
+		static[strings.TrimSpace(symbol)] = strings.TrimSpace(shardID)
+	}
+	return static, nil
+}
+
+// Ownership is one shard's view of an Assignment.
+type Ownership struct {
+	ShardID    string
+	Assignment *Assignment
+}
+
+// Owns tells whether the shard serves a symbol, one it has taken over
+// included.
+func (o *Ownership) Owns(symbol string) bool {
+	owner, pending := o.Assignment.lookup(symbol)
+	return owner == o.ShardID && !pending
+}
+
+// Acquiring tells whether a symbol is being handed over to the shard, which
+// has yet to take its book over.
+func (o *Ownership) Acquiring(symbol string) bool {
+	owner, pending := o.Assignment.lookup(symbol)
+	return owner == o.ShardID && pending
+}
+
+func (o *Ownership) Owner(symbol string) string {
+	return o.Assignment.Owner(symbol)
+}
+
+func (o *Ownership) Assign(symbol, shardID string) error {
+	return o.Assignment.Assign(symbol, shardID)
+}
+
+func (o *Ownership) Handoff(symbol, shardID string) error {
+	return o.Assignment.Handoff(symbol, shardID)
+}
@@ -0,0 +1,124 @@
+package shard
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func symbols(n int) []string {
+	s := make([]string, n)
+	for i := range s {
+		s[i] = fmt.Sprintf("SYM%d/USDT", i)
+	}
+	return s
+}
+
+func TestRing_SpreadsAndIgnoresShardOrder(t *testing.T) {
+	a, err := NewRing([]string{"shard-0", "shard-1", "shard-2"}, 0)
+	require.NoError(t, err)
+	b, err := NewRing([]string{"shard-2", "shard-0", "shard-1"}, 0)
+	require.NoError(t, err)
+
+	counts := map[string]int{}
+	for _, symbol := range symbols(3000) {
+		owner := a.Owner(symbol)
+		assert.Equal(t, owner, b.Owner(symbol))
+		counts[owner]++
+	}
+	require.Len(t, counts, 3)
+	for shard, n := range counts {
+		assert.Greater(t, n, 500, "shard %s owns too few symbols", shard)
+	}
+}
+
+func TestRing_AddingShardOnlyMovesSymbolsToIt(t *testing.T) {
+	before, err := NewRing([]string{"shard-0", "shard-1", "shard-2"}, 0)
+	require.NoError(t, err)
+	after, err := NewRing([]string{"shard-0", "shard-1", "shard-2", "shard-3"}, 0)
+	require.NoError(t, err)
+
+	moved := 0
+	for _, symbol := range symbols(3000) {
+		if was, is := before.Owner(symbol), after.Owner(symbol); was != is {
+			assert.Equal(t, "shard-3", is)
+			moved++
+		}
+	}
+	assert.Greater(t, moved, 0)
+	assert.Less(t, moved, 1500)
+}
+
+func TestAssignment_StaticMapThenOverrides(t *testing.T) {
+	static, err := ParseStaticMap("BTC/USDT=shard-1, ETH/USDT=shard-0")
+	require.NoError(t, err)
+	assignment, err := NewAssignment([]string{"shard-0", "shard-1"}, static)
+	require.NoError(t, err)
+
+	assert.Equal(t, "shard-1", assignment.Owner("BTC/USDT"))
+	assert.Equal(t, "shard-0", assignment.Owner("ETH/USDT"))
+
+	path := filepath.Join(t.TempDir(), "overrides.json")
+	require.NoError(t, assignment.LoadOverrides(path))
+	require.NoError(t, assignment.Assign("BTC/USDT", "shard-0"))
+	assert.Error(t, assignment.Assign("BTC/USDT", "shard-9"))
+	assert.Equal(t, "shard-0", assignment.Owner("BTC/USDT"))
+
+	// A restarted engine sees the handoff.
+	restarted, err := NewAssignment([]string{"shard-0", "shard-1"}, static)
+	require.NoError(t, err)
+	require.NoError(t, restarted.LoadOverrides(path))
+	assert.Equal(t, "shard-0", restarted.Owner("BTC/USDT"))
+
+	ownership := &Ownership{ShardID: "shard-1", Assignment: restarted}
+	assert.False(t, ownership.Owns("BTC/USDT"))
+}
+
+func TestAssignment_SharesOverridesBetweenProcesses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "overrides.json")
+	ids := []string{"shard-0", "shard-1", "shard-2"}
+	static := map[string]string{"BTC/USDT": "shard-0", "ETH/USDT": "shard-1"}
+	shards := make([]*Ownership, len(ids))
+	for i, id := range ids {
+		assignment, err := NewAssignment(ids, static)
+		require.NoError(t, err)
+		require.NoError(t, assignment.LoadOverrides(path))
+		shards[i] = &Ownership{ShardID: id, Assignment: assignment}
+	}
+
+	// A handoff is seen by every process, the uninvolved shard-2 included,
+	// and the receiving shard serves the symbol only once it takes it.
+	require.NoError(t, shards[0].Handoff("BTC/USDT", "shard-1"))
+	for _, o := range shards {
+		assert.Equal(t, "shard-1", o.Owner("BTC/USDT"), o.ShardID)
+	}
+	assert.False(t, shards[0].Owns("BTC/USDT"))
+	assert.False(t, shards[1].Owns("BTC/USDT"))
+	assert.True(t, shards[1].Acquiring("BTC/USDT"))
+	require.NoError(t, shards[1].Assign("BTC/USDT", "shard-1"))
+	assert.True(t, shards[1].Owns("BTC/USDT"))
+	assert.False(t, shards[2].Acquiring("BTC/USDT"))
+
+	// Writers merge: a handoff made elsewhere since a process last looked
+	// is kept.
+	require.NoError(t, shards[0].Assign("SOL/USDT", "shard-0"))
+	require.NoError(t, shards[2].Assign("ETH/USDT", "shard-2"))
+	for _, o := range shards {
+		assert.Equal(t, "shard-1", o.Owner("BTC/USDT"), o.ShardID)
+		assert.Equal(t, "shard-2", o.Owner("ETH/USDT"), o.ShardID)
+		assert.Equal(t, "shard-0", o.Owner("SOL/USDT"), o.ShardID)
+	}
+}
+
+func TestAssignment_RejectsUnknownShards(t *testing.T) {
+	_, err := NewAssignment([]string{"shard-0"}, map[string]string{"BTC/USDT": "shard-1"})
+	assert.Error(t, err)
+
+	_, err = ParseStaticMap("BTC/USDT")
+	assert.Error(t, err)
+
+	assert.Equal(t, []string{"shard-0", "shard-1"}, ParseIDs(" shard-0, ,shard-1"))
+}
@@ -0,0 +1,181 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"MatchingEngine/orderBook"
+)
+
+const (
+	fileFormat = "matching-engine-book-snapshot"
+	fileSuffix = ".snapshot.json"
+)
+
+// envelope is the on-disk layout of one snapshot file. Book holds the
+// orderBook.BookSnapshot bytes covered by CRC32.
+type envelope struct {
+	Format  string          `json:"format"`
+	Version int             `json:"version"`
+	CRC32   uint32          `json:"crc32"`
+	Book    json.RawMessage `json:"book"`
+}
+
+// FileStore keeps the latest snapshot of each book in its own file under dir.
+type FileStore struct {
+	dir string
+}
+
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{dir: dir}
+}
+
+func (s *FileStore) SaveAll(snapshots []orderBook.BookSnapshot) error {
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+	for _, snapshot := range snapshots {
+		if err := s.save(snapshot); err != nil {
+			return err
+		}
+	}
+	return syncDir(s.dir)
+}
+
+// save writes the snapshot to a temporary file, syncs it and renames it over
+// the previous snapshot so a crash never leaves a partial file behind.
+func (s *FileStore) save(snapshot orderBook.BookSnapshot) error {
+	book, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to marshal snapshot of %s: %w", snapshot.Symbol, err)
+	}
+	data, err := json.Marshal(envelope{
+		Format:  fileFormat,
+		Version: snapshot.Version,
+		CRC32:   crc32.ChecksumIEEE(book),
+		Book:    book,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal snapshot of %s: %w", snapshot.Symbol, err)
+	}
+
+	path := s.path(snapshot.Symbol)
+	tmp, err := os.CreateTemp(s.dir, ".snapshot-*")
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot file for %s: %w", snapshot.Symbol, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write snapshot of %s: %w", snapshot.Symbol, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync snapshot of %s: %w", snapshot.Symbol, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close snapshot of %s: %w", snapshot.Symbol, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace snapshot of %s: %w", snapshot.Symbol, err)
+	}
+	return nil
+}
+
+// LoadAll reads every snapshot under dir. A missing directory means there is
+// nothing to restore; a corrupt file is an error.
+func (s *FileStore) LoadAll() ([]orderBook.BookSnapshot, error) {
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot directory: %w", err)
+	}
+
+	var snapshots []orderBook.BookSnapshot
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), fileSuffix) {
+			continue
+		}
+		snapshot, err := readSnapshot(filepath.Join(s.dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	log.Printf("Loaded %d order book snapshots from %s", len(snapshots), s.dir)
+	return snapshots, nil
+}
+
+// Load reads the snapshot of one symbol. The error wraps os.ErrNotExist when
+// there is none.
+func (s *FileStore) Load(symbol string) (orderBook.BookSnapshot, error) {
+	path := s.path(symbol)
+	if _, err := os.Stat(path); err != nil {
+		return orderBook.BookSnapshot{}, fmt.Errorf("no snapshot of %s: %w", symbol, err)
+	}
+	return readSnapshot(path)
+}
+
+// Delete removes the snapshot of one symbol, if any.
+func (s *FileStore) Delete(symbol string) error {
+	err := os.Remove(s.path(symbol))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete snapshot of %s: %w", symbol, err)
+	}
+	return syncDir(s.dir)
+}
+
+func (s *FileStore) path(symbol string) string {
+	return filepath.Join(s.dir, url.PathEscape(symbol)+fileSuffix)
+}
+
+func readSnapshot(path string) (orderBook.BookSnapshot, error) {
+	var snapshot orderBook.BookSnapshot
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return snapshot, fmt.Errorf("failed to read snapshot %s: %w", path, err)
+	}
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return snapshot, fmt.Errorf("failed to decode snapshot %s: %w", path, err)
+	}
+	if env.Format != fileFormat {
+		return snapshot, fmt.Errorf("snapshot %s has unknown format %q", path, env.Format)
+	}
+	if env.Version != orderBook.SnapshotVersion {
+		return snapshot, fmt.Errorf("snapshot %s has unsupported version %d", path, env.Version)
+	}
+	if crc := crc32.ChecksumIEEE(env.Book); crc != env.CRC32 {
+		return snapshot, fmt.Errorf("snapshot %s is corrupt: crc32 %d, expected %d", path, crc, env.CRC32)
+	}
+	if err := json.Unmarshal(env.Book, &snapshot); err != nil {
+		return snapshot, fmt.Errorf("failed to decode snapshot %s: %w", path, err)
+	}
+	return snapshot, nil
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot directory: %w", err)
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("failed to sync snapshot directory: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package snapshot
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"MatchingEngine/internal/model"
+	"MatchingEngine/orderBook"
+)
+
+func testSnapshot(symbol string) orderBook.BookSnapshot {
+	order := orderBook.Order{
+		ClOrdID:     "CL001",
+		OrderID:     "order-000001",
+		Symbol:      symbol,
+		Side:        model.Buy,
+		Price:       decimal.NewFromInt(100),
+		OrderQty:    decimal.NewFromInt(10),
+		LeavesQty:   decimal.NewFromInt(4),
+		CumQty:      decimal.NewFromInt(6),
+		AvgPx:       decimal.NewFromInt(100),
+		Timestamp:   1729811234567890,
+		OrderStatus: model.OrderStatusPartialFill,
+		Text:        "resting",
+	}
+	return orderBook.BookSnapshot{
+		Version:  orderBook.SnapshotVersion,
+		Symbol:   symbol,
+		TakenAt:  1,
+		DepthSeq: 7,
+		Bids:     []orderBook.LevelSnapshot{{Price: order.Price, Orders: []orderBook.Order{order}}},
+		Asks:     []orderBook.LevelSnapshot{},
+		OrderIndex: map[string]orderBook.OrderRef{
+			"CL001": {PriceLevel: order.Price, Side: string(model.Buy), Index: 0},
+		},
+	}
+}
+
+func TestFileStore_RoundTrip(t *testing.T) {
+	store := NewFileStore(filepath.Join(t.TempDir(), "snapshots"))
+	saved := []orderBook.BookSnapshot{testSnapshot("BTC/USDT"), testSnapshot("ETH/USDT")}
+
+	require.NoError(t, store.SaveAll(saved))
+	// Saving again replaces the previous files.
+	require.NoError(t, store.SaveAll(saved))
+
+	loaded, err := store.LoadAll()
+	require.NoError(t, err)
+	require.Len(t, loaded, 2)
+	assert.Equal(t, "BTC/USDT", loaded[0].Symbol)
+	assert.Equal(t, "ETH/USDT", loaded[1].Symbol)
+
+	order := loaded[0].Bids[0].Orders[0]
+	assert.Equal(t, "order-000001", order.OrderID)
+	assert.True(t, order.CumQty.Equal(decimal.NewFromInt(6)))
+	assert.Equal(t, model.OrderStatusPartialFill, order.OrderStatus)
+	assert.Equal(t, 0, loaded[0].OrderIndex["CL001"].Index)
+
+	_, err = orderBook.RestoreOrderBook(nil, orderBook.BookOpts{}, loaded[0])
+	assert.NoError(t, err)
+}
+
+func TestFileStore_MissingDirectory(t *testing.T) {
+	store := NewFileStore(filepath.Join(t.TempDir(), "absent"))
+
+	loaded, err := store.LoadAll()
+
+	assert.NoError(t, err)
+	assert.Empty(t, loaded)
+}
+
+func TestFileStore_DetectsCorruption(t *testing.T) {
+	dir := t.TempDir()
+	store := NewFileStore(dir)
+	require.NoError(t, store.SaveAll([]orderBook.BookSnapshot{testSnapshot("BTC/USDT")}))
+
+	path := filepath.Join(dir, "BTC%2FUSDT"+fileSuffix)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	tampered := strings.Replace(string(data), `"resting"`, `"tampered"`, 1)
+	require.NotEqual(t, string(data), tampered)
+	require.NoError(t, os.WriteFile(path, []byte(tampered), 0o644))
+
+	_, err = store.LoadAll()
+	assert.Error(t, err)
+}
+
+func TestFileStore_LoadAndDeleteOneSymbol(t *testing.T) {
+	store := NewFileStore(t.TempDir())
+	require.NoError(t, store.SaveAll([]orderBook.BookSnapshot{testSnapshot("BTC/USDT"), testSnapshot("ETH/USDT")}))
+
+	loaded, err := store.Load("ETH/USDT")
+	require.NoError(t, err)
+	assert.Equal(t, "ETH/USDT", loaded.Symbol)
+
+	require.NoError(t, store.Delete("ETH/USDT"))
+	require.NoError(t, store.Delete("ETH/USDT"))
+	_, err = store.Load("ETH/USDT")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	all, err := store.LoadAll()
+	require.NoError(t, err)
+	require.Len(t, all, 1)
+	assert.Equal(t, "BTC/USDT", all[0].Symbol)
+}
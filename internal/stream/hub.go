@@ -0,0 +1,422 @@
+package stream
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"MatchingEngine/internal/model"
+)
+
+// Channels a client can subscribe to. Executions is private to the account
+// the client authenticated as; trades and depth are public and per symbol.
+const (
+	ChannelExecutions = "executions"
+	ChannelTrades     = "trades"
+	ChannelDepth      = "depth"
+)
+
+var errUnauthorized = errors.New("unauthorized")
+
+type Notifier interface {
+	NotifyEventAndTrade(msgType model.MsgType, key string, value json.RawMessage) error
+}
+
+type MarketDataPublisher interface {
+	PublishMarketData(symbol string, value json.RawMessage) error
+}
+
+// DepthSnapshots gives new depth subscribers the book to apply updates to.
+type DepthSnapshots interface {
+	GetDepthSnapshot(symbol string) (model.MarketDataSnapshotFullRefresh, bool)
+}
+
+type Authenticator interface {
+	Authenticate(token string) (account string, ok bool)
+}
+
+// Tokens authenticates against a fixed table of token to account.
+type Tokens map[string]string
+
+// ParseTokens reads a comma-separated list of token=account pairs.
+func ParseTokens(s string) (Tokens, error) {
+	tokens := Tokens{}
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		token, account, ok := strings.Cut(pair, "=")
+		if !ok || token == "" || account == "" {
+			return nil, fmt.Errorf("invalid token entry %q, expected token=account", pair)
+		}
+		tokens[token] = account
+	}
+	return tokens, nil
+}
+
+func (t Tokens) Authenticate(token string) (string, bool) {
+	account, ok := t[token]
+	return account, ok
+}
+
+type Opts struct {
+	Auth Authenticator
+
+	BufferSize     int           // Messages queued per client before it is disconnected
+	AuthTimeout    time.Duration // How long a new connection has to authenticate
+	WriteTimeout   time.Duration
+	MaxMessageSize int // Largest client message accepted
+}
+
+// Message is what a subscriber receives for each event. Seq counts the
+// events of the channel (of the account, for executions) and goes up by one
+// from the Seq of the subscription's reply, so a gap means a lost message.
+type Message struct {
+	Channel string          `json:"channel"`
+	Symbol  string          `json:"symbol,omitempty"`
+	Seq     uint64          `json:"seq"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// request is a client message: {"op":"auth","token":...} first, then
+// {"op":"subscribe"|"unsubscribe","channel":...,"symbol":...}.
+type request struct {
+	Op      string `json:"op"`
+	Token   string `json:"token,omitempty"`
+	Channel string `json:"channel,omitempty"`
+	Symbol  string `json:"symbol,omitempty"`
+}
+
+type reply struct {
+	Op       string          `json:"op"`
+	Account  string          `json:"account,omitempty"`
+	Channel  string          `json:"channel,omitempty"`
+	Symbol   string          `json:"symbol,omitempty"`
+	Seq      uint64          `json:"seq,omitempty"`
+	Snapshot json.RawMessage `json:"snapshot,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// Hub streams the engine's execution reports, trades and depth updates to
+// websocket clients. It sees the events by wrapping the notifier and the
+// market data publisher, so clients get exactly what Kafka gets.
+//
+// Each client has a bounded queue; one that does not keep up is
+// disconnected rather than slowing the engine down.
+type Hub struct {
+	Depth DepthSnapshots // Optional; depth subscribers get no snapshot without it
+
+	opts Opts
+
+	mu      sync.Mutex
+	seqs    map[string]uint64
+	subs    map[string]map[*client]struct{}
+	clients map[*client]struct{}
+}
+
+type client struct {
+	conn    *Conn
+	send    chan []byte
+	account string // Set once authenticated; read loop only
+
+	// Guarded by Hub.mu.
+	subs        map[string]struct{}
+	dropped     bool
+	closeCode   int
+	closeReason string
+}
+
+func NewHub(opts Opts) *Hub {
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = 256
+	}
+	if opts.AuthTimeout <= 0 {
+		opts.AuthTimeout = 10 * time.Second
+	}
+	if opts.WriteTimeout <= 0 {
+		opts.WriteTimeout = 10 * time.Second
+	}
+	if opts.MaxMessageSize <= 0 {
+		opts.MaxMessageSize = 4096
+	}
+	return &Hub{
+		opts:    opts,
+		seqs:    make(map[string]uint64),
+		subs:    make(map[string]map[*client]struct{}),
+		clients: make(map[*client]struct{}),
+	}
+}
+
+// ServeHTTP upgrades the request to a websocket and serves the client until
+// either side closes.
+func (h *Hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := Upgrade(w, r, h.opts.MaxMessageSize)
+	if err != nil {
+		return
+	}
+	c := &client{
+		conn: conn,
+		send: make(chan []byte, h.opts.BufferSize),
+		subs: make(map[string]struct{}),
+	}
+	h.mu.Lock()
+	h.clients[c] = struct{}{}
+	h.mu.Unlock()
+
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		h.writeLoop(c)
+	}()
+
+	err = h.readLoop(c)
+	code, reason := CloseNormal, ""
+	if errors.Is(err, errUnauthorized) {
+		code, reason = ClosePolicyViolation, err.Error()
+	}
+	h.mu.Lock()
+	h.dropLocked(c, code, reason)
+	h.mu.Unlock()
+	<-written
+}
+
+func (h *Hub) readLoop(c *client) error {
+	c.conn.SetReadDeadline(time.Now().Add(h.opts.AuthTimeout))
+	for {
+		raw, err := c.conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		var req request
+		if err := json.Unmarshal(raw, &req); err != nil {
+			h.reply(c, reply{Op: "error", Error: "invalid JSON: " + err.Error()})
+			continue
+		}
+
+		if c.account == "" {
+			if req.Op != "auth" {
+				h.reply(c, reply{Op: "error", Error: "authenticate first"})
+				return errUnauthorized
+			}
+			account, ok := h.opts.Auth.Authenticate(req.Token)
+			if !ok {
+				h.reply(c, reply{Op: "error", Error: "invalid token"})
+				return errUnauthorized
+			}
+			c.account = account
+			c.conn.SetReadDeadline(time.Time{})
+			h.reply(c, reply{Op: "authenticated", Account: account})
+			continue
+		}
+
+		switch req.Op {
+		case "subscribe":
+			h.subscribe(c, req)
+		case "unsubscribe":
+			h.unsubscribe(c, req)
+		default:
+			h.reply(c, reply{Op: "error", Error: fmt.Sprintf("unknown op %q", req.Op)})
+		}
+	}
+}
+
+// writeLoop sends the client's queue until the hub drops the client, then
+// closes the connection with the reason it was dropped for.
+func (h *Hub) writeLoop(c *client) {
+	for msg := range c.send {
+		if err := c.conn.WriteText(msg, time.Now().Add(h.opts.WriteTimeout)); err != nil {
+			c.conn.Close(CloseNormal, "")
+			return
+		}
+	}
+	h.mu.Lock()
+	code, reason := c.closeCode, c.closeReason
+	h.mu.Unlock()
+	c.conn.Close(code, reason)
+}
+
+// channelKey names the sequence a subscription follows.
+func (c *client) channelKey(req request) (string, error) {
+	switch req.Channel {
+	case ChannelExecutions:
+		return ChannelExecutions + ":" + c.account, nil
+	case ChannelTrades, ChannelDepth:
+		if req.Symbol == "" {
+			return "", errors.New("missing symbol")
+		}
+		return req.Channel + ":" + req.Symbol, nil
+	}
+	return "", fmt.Errorf("unknown channel %q", req.Channel)
+}
+
+func (h *Hub) subscribe(c *client, req request) {
+	key, err := c.channelKey(req)
+	if err != nil {
+		h.reply(c, reply{Op: "error", Channel: req.Channel, Symbol: req.Symbol, Error: err.Error()})
+		return
+	}
+	resp := reply{Op: "subscribed", Channel: req.Channel, Symbol: req.Symbol}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	// Registering, numbering and queueing the reply under one lock puts every
+	// later message of the channel after the reply, one Seq apart.
+	if req.Channel == ChannelDepth && h.Depth != nil {
+		if snapshot, ok := h.Depth.GetDepthSnapshot(req.Symbol); ok {
+			resp.Snapshot, _ = json.Marshal(snapshot)
+		}
+	}
+	resp.Seq = h.seqs[key]
+	if h.subs[key] == nil {
+		h.subs[key] = make(map[*client]struct{})
+	}
+	h.subs[key][c] = struct{}{}
+	c.subs[key] = struct{}{}
+	h.sendLocked(c, marshalReply(resp))
+}
+
+func (h *Hub) unsubscribe(c *client, req request) {
+	key, err := c.channelKey(req)
+	if err != nil {
+		h.reply(c, reply{Op: "error", Channel: req.Channel, Symbol: req.Symbol, Error: err.Error()})
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.removeSubLocked(c, key)
+	h.sendLocked(c, marshalReply(reply{Op: "unsubscribed", Channel: req.Channel, Symbol: req.Symbol}))
+}
+
+func (h *Hub) reply(c *client, r reply) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.sendLocked(c, marshalReply(r))
+}
+
+func marshalReply(r reply) []byte {
+	b, _ := json.Marshal(r)
+	return b
+}
+
+// publish numbers an event of a channel and queues it for the subscribers.
+func (h *Hub) publish(key, channel, symbol string, data json.RawMessage) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.seqs[key]++
+	subs := h.subs[key]
+	if len(subs) == 0 {
+		return
+	}
+	msg, err := json.Marshal(Message{Channel: channel, Symbol: symbol, Seq: h.seqs[key], Data: data})
+	if err != nil {
+		log.Printf("stream: cannot encode %s message: %v", key, err)
+		return
+	}
+	for c := range subs {
+		h.sendLocked(c, msg)
+	}
+}
+
+func (h *Hub) sendLocked(c *client, msg []byte) {
+	if c.dropped {
+		return
+	}
+	select {
+	case c.send <- msg:
+	default:
+		log.Printf("stream: disconnecting slow consumer %s", c.account)
+		h.dropLocked(c, CloseTryAgainLater, "slow consumer")
+	}
+}
+
+// dropLocked forgets a client and ends its queue; the write loop then closes
+// the connection with code and reason.
+func (h *Hub) dropLocked(c *client, code int, reason string) {
+	if c.dropped {
+		return
+	}
+	c.dropped = true
+	c.closeCode, c.closeReason = code, reason
+	for key := range c.subs {
+		h.removeSubLocked(c, key)
+	}
+	delete(h.clients, c)
+	close(c.send)
+}
+
+func (h *Hub) removeSubLocked(c *client, key string) {
+	delete(c.subs, key)
+	if subs := h.subs[key]; subs != nil {
+		delete(subs, c)
+		if len(subs) == 0 {
+			delete(h.subs, key)
+		}
+	}
+}
+
+// Close disconnects every client.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for c := range h.clients {
+		h.dropLocked(c, CloseGoingAway, "server shutting down")
+	}
+}
+
+// Notifier returns a notifier that forwards everything to next and also
+// streams execution reports to their account and trades to their symbol.
+func (h *Hub) Notifier(next Notifier) Notifier {
+	return &hubNotifier{hub: h, next: next}
+}
+
+type hubNotifier struct {
+	hub  *Hub
+	next Notifier
+}
+
+func (n *hubNotifier) NotifyEventAndTrade(msgType model.MsgType, key string, value json.RawMessage) error {
+	err := n.next.NotifyEventAndTrade(msgType, key, value)
+	if msgType != model.MsgTypeExecRpt && msgType != model.MsgTypeTradeReport {
+		return err
+	}
+
+	var head struct {
+		Account string `json:"1"`
+		Symbol  string `json:"55"`
+	}
+	if json.Unmarshal(value, &head) != nil {
+		return err
+	}
+	switch msgType {
+	case model.MsgTypeExecRpt:
+		if head.Account != "" {
+			n.hub.publish(ChannelExecutions+":"+head.Account, ChannelExecutions, head.Symbol, value)
+		}
+	case model.MsgTypeTradeReport:
+		n.hub.publish(ChannelTrades+":"+head.Symbol, ChannelTrades, head.Symbol, value)
+	}
+	return err
+}
+
+// MarketDataPublisher returns a publisher that forwards to next and also
+// streams each depth message to its symbol.
+func (h *Hub) MarketDataPublisher(next MarketDataPublisher) MarketDataPublisher {
+	return &hubMarketDataPublisher{hub: h, next: next}
+}
+
+type hubMarketDataPublisher struct {
+	hub  *Hub
+	next MarketDataPublisher
+}
+
+func (p *hubMarketDataPublisher) PublishMarketData(symbol string, value json.RawMessage) error {
+	err := p.next.PublishMarketData(symbol, value)
+	p.hub.publish(ChannelDepth+":"+symbol, ChannelDepth, symbol, value)
+	return err
+}
@@ -0,0 +1,197 @@
+package stream
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"MatchingEngine/internal/model"
+	"MatchingEngine/internal/service"
+	"MatchingEngine/orderBook"
+)
+
+type nopNotifier struct{}
+
+func (nopNotifier) NotifyEventAndTrade(model.MsgType, string, json.RawMessage) error { return nil }
+
+type nopPublisher struct{}
+
+func (nopPublisher) PublishMarketData(string, json.RawMessage) error { return nil }
+
+func startHub(t *testing.T, opts Opts) (*Hub, *httptest.Server) {
+	hub := NewHub(opts)
+	srv := httptest.NewServer(hub)
+	t.Cleanup(func() {
+		hub.Close()
+		srv.Close()
+	})
+	return hub, srv
+}
+
+func send(t *testing.T, conn *Conn, v any) {
+	t.Helper()
+	b, err := json.Marshal(v)
+	require.NoError(t, err)
+	require.NoError(t, conn.WriteText(b, time.Time{}))
+}
+
+func receive(t *testing.T, conn *Conn, v any) {
+	t.Helper()
+	b, err := conn.ReadMessage()
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(b, v))
+}
+
+func newOrder(account, clOrdID string, side model.Side, qty int64) model.OrderRequest {
+	return model.OrderRequest{
+		MsgType: model.MsgTypeNew,
+		NewOrderReq: model.NewOrderRequest{
+			BaseOrderRequest: model.BaseOrderRequest{
+				MsgType: model.MsgTypeNew, Account: account, ClOrdID: clOrdID, Side: side, Symbol: "BTC/USDT",
+			},
+			OrderQty: decimal.NewFromInt(qty),
+			Price:    decimal.NewFromInt(100),
+		},
+	}
+}
+
+func TestHub_StreamsOwnReportsAndPublicChannelsInOrder(t *testing.T) {
+	hub := NewHub(Opts{Auth: Tokens{"t1": "ACC1", "t2": "ACC2"}})
+	marketData := service.NewMarketDataService(hub.MarketDataPublisher(nopPublisher{}))
+	hub.Depth = marketData
+	orders := service.NewOrderService(hub.Notifier(nopNotifier{}), orderBook.BookOpts{MarketData: marketData, DepthLevels: 5})
+	srv := httptest.NewServer(hub)
+	t.Cleanup(func() {
+		hub.Close()
+		srv.Close()
+	})
+
+	// A resting order from before the subscription shows in the snapshot.
+	require.NoError(t, orders.ProcessOrderRequest(newOrder("ACC1", "S0", model.Sell, 1)))
+	require.Eventually(t, func() bool {
+		_, ok := marketData.GetDepthSnapshot("BTC/USDT")
+		return ok
+	}, 5*time.Second, 10*time.Millisecond)
+
+	conn := dial(t, srv)
+	var r reply
+	send(t, conn, request{Op: "auth", Token: "t1"})
+	receive(t, conn, &r)
+	require.Equal(t, "authenticated", r.Op)
+	assert.Equal(t, "ACC1", r.Account)
+
+	seqs := map[string]uint64{}
+	for _, sub := range []request{
+		{Op: "subscribe", Channel: ChannelExecutions},
+		{Op: "subscribe", Channel: ChannelTrades, Symbol: "BTC/USDT"},
+		{Op: "subscribe", Channel: ChannelDepth, Symbol: "BTC/USDT"},
+	} {
+		send(t, conn, sub)
+		r = reply{}
+		receive(t, conn, &r)
+		require.Equal(t, "subscribed", r.Op, r.Error)
+		seqs[r.Channel] = r.Seq
+		if r.Channel == ChannelDepth {
+			var snapshot model.MarketDataSnapshotFullRefresh
+			require.NoError(t, json.Unmarshal(r.Snapshot, &snapshot))
+			assert.Len(t, snapshot.Entries, 1)
+		}
+	}
+	assert.Equal(t, uint64(1), seqs[ChannelExecutions])
+
+	require.NoError(t, orders.ProcessOrderRequest(newOrder("ACC1", "S1", model.Sell, 2)))
+	require.NoError(t, orders.ProcessOrderRequest(newOrder("ACC2", "B1", model.Buy, 3)))
+
+	var reports []model.ExecutionReport
+	trades := 0
+	for len(reports) < 3 || trades < 2 {
+		var msg Message
+		receive(t, conn, &msg)
+		require.Equal(t, seqs[msg.Channel]+1, msg.Seq, "gap on %s", msg.Channel)
+		seqs[msg.Channel] = msg.Seq
+		switch msg.Channel {
+		case ChannelExecutions:
+			var er model.ExecutionReport
+			require.NoError(t, json.Unmarshal(msg.Data, &er))
+			assert.Equal(t, "ACC1", er.Account)
+			reports = append(reports, er)
+		case ChannelTrades:
+			assert.Equal(t, "BTC/USDT", msg.Symbol)
+			trades++
+		}
+	}
+	// S1 rests, then B1 takes S0 and S1 in time priority.
+	assert.Equal(t, []string{"S1", "S0", "S1"}, []string{reports[0].ClOrdID, reports[1].ClOrdID, reports[2].ClOrdID})
+	assert.Equal(t, model.ExecTypeNew, reports[0].ExecType)
+	assert.Equal(t, model.ExecTypeFill, reports[2].ExecType)
+}
+
+func TestHub_RequiresAuthentication(t *testing.T) {
+	_, srv := startHub(t, Opts{Auth: Tokens{"t1": "ACC1"}})
+
+	conn := dial(t, srv)
+	send(t, conn, request{Op: "subscribe", Channel: ChannelTrades, Symbol: "BTC/USDT"})
+	var r reply
+	receive(t, conn, &r)
+	assert.Equal(t, "error", r.Op)
+	_, err := conn.ReadMessage()
+	var ce *CloseError
+	require.ErrorAs(t, err, &ce)
+	assert.Equal(t, ClosePolicyViolation, ce.Code)
+
+	conn = dial(t, srv)
+	send(t, conn, request{Op: "auth", Token: "wrong"})
+	receive(t, conn, &r)
+	assert.Equal(t, "error", r.Op)
+	_, err = conn.ReadMessage()
+	require.ErrorAs(t, err, &ce)
+	assert.Equal(t, ClosePolicyViolation, ce.Code)
+
+	conn = dial(t, srv)
+	send(t, conn, request{Op: "auth", Token: "t1"})
+	receive(t, conn, &r)
+	send(t, conn, request{Op: "subscribe", Channel: ChannelTrades})
+	receive(t, conn, &r)
+	assert.Equal(t, "error", r.Op)
+	assert.Equal(t, "missing symbol", r.Error)
+}
+
+func TestHub_DisconnectsSlowConsumer(t *testing.T) {
+	hub := NewHub(Opts{BufferSize: 2})
+	c := &client{send: make(chan []byte, 2), subs: make(map[string]struct{})}
+	hub.clients[c] = struct{}{}
+	hub.subscribe(c, request{Op: "subscribe", Channel: ChannelTrades, Symbol: "BTC/USDT"})
+
+	notifier := hub.Notifier(nopNotifier{})
+	trade := json.RawMessage(`{"35":"AE","55":"BTC/USDT"}`)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, notifier.NotifyEventAndTrade(model.MsgTypeTradeReport, "t", trade))
+	}
+
+	// The reply and the first trade fill the queue; the second trade drops
+	// the client and the third is numbered without it.
+	var queued []string
+	for msg := range c.send {
+		queued = append(queued, string(msg))
+	}
+	assert.Len(t, queued, 2)
+	assert.True(t, c.dropped)
+	assert.Equal(t, CloseTryAgainLater, c.closeCode)
+	assert.Empty(t, hub.subs)
+	assert.Empty(t, hub.clients)
+	assert.Equal(t, uint64(3), hub.seqs[ChannelTrades+":BTC/USDT"])
+}
+
+func TestParseTokens(t *testing.T) {
+	tokens, err := ParseTokens(" t1=ACC1, t2=ACC2,")
+	require.NoError(t, err)
+	assert.Equal(t, Tokens{"t1": "ACC1", "t2": "ACC2"}, tokens)
+
+	_, err = ParseTokens("t1")
+	assert.Error(t, err)
+}
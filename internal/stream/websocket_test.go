@@ -0,0 +1,102 @@
+package stream
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dial opens a client websocket to the test server.
+func dial(t *testing.T, srv *httptest.Server) *Conn {
+	t.Helper()
+	addr := strings.TrimPrefix(srv.URL, "http://")
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	var nonce [16]byte
+	rand.Read(nonce[:])
+	key := base64.StdEncoding.EncodeToString(nonce[:])
+	fmt.Fprintf(conn, "GET /ws HTTP/1.1\r\nHost: %s\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+		"Sec-WebSocket-Key: %s\r\nSec-WebSocket-Version: 13\r\n\r\n", addr, key)
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	require.Equal(t, acceptKey(key), resp.Header.Get("Sec-WebSocket-Accept"))
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	return newClientConn(conn, br, 1<<20)
+}
+
+func echoServer(t *testing.T, maxSize int) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r, maxSize)
+		if err != nil {
+			return
+		}
+		for {
+			msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.WriteText(msg, time.Time{}); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestWebSocket_EchoesMessagesOfEverySize(t *testing.T) {
+	srv := echoServer(t, 1<<17)
+	conn := dial(t, srv)
+
+	for _, n := range []int{0, 5, 125, 126, 70000} {
+		msg := []byte(strings.Repeat("x", n))
+		require.NoError(t, conn.WriteText(msg, time.Time{}))
+		got, err := conn.ReadMessage()
+		require.NoError(t, err)
+		assert.Len(t, got, n)
+	}
+
+	// A ping is answered without disturbing the message stream.
+	require.NoError(t, conn.writeFrame(opPing, []byte("p"), time.Time{}))
+	require.NoError(t, conn.WriteText([]byte("after ping"), time.Time{}))
+	got, err := conn.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, "after ping", string(got))
+
+	require.NoError(t, conn.writeFrame(opClose, []byte{0x03, 0xE8}, time.Time{}))
+	_, err = conn.ReadMessage()
+	var ce *CloseError
+	require.ErrorAs(t, err, &ce)
+	assert.Equal(t, CloseNormal, ce.Code)
+}
+
+func TestWebSocket_RefusesOversizedMessagesAndPlainRequests(t *testing.T) {
+	srv := echoServer(t, 16)
+	conn := dial(t, srv)
+
+	require.NoError(t, conn.WriteText([]byte(strings.Repeat("x", 17)), time.Time{}))
+	_, err := conn.ReadMessage()
+	var ce *CloseError
+	require.ErrorAs(t, err, &ce)
+	assert.Equal(t, CloseTooBig, ce.Code)
+
+	resp, err := http.Get(srv.URL + "/ws")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
@@ -1,21 +1,70 @@
 package util
 
 import (
+	"time"
+
 	"github.com/spf13/viper"
 )
 
 // Config stores all configuration of the application.
 // The values are read by viper from a config file or environment variable.
 type Config struct {
-	Environment         string `mapstructure:"ENVIRONMENT"`
-	DBSource            string `mapstructure:"DB_SOURCE"`
-	DBDriver            string `mapstructure:"DB_DRIVER"`
-	KafkaBroker         string `mapstructure:"KAFKA_BROKER"`
-	KafkaDBUpdateTopic  string `mapstructure:"KAFKA_DB_UPDATE_TOPIC"`
-	KafkaExecutionTopic string `mapstructure:"KAFKA_EXECUTION_TOPIC"`
-	KafkaConsumerGroup  string `mapstructure:"KAFKA_CONSUMER_GROUP"`
-	RmqHost             string `mapstructure:"RMQ_URL"`
-	RmqQueueName        string `mapstructure:"RMQ_QUEUE_NAME"`
+	Environment          string        `mapstructure:"ENVIRONMENT"`
+	DBSource             string        `mapstructure:"DB_SOURCE"`
+	DBDriver             string        `mapstructure:"DB_DRIVER"`
+	KafkaBroker          string        `mapstructure:"KAFKA_BROKER"`
+	KafkaDBUpdateTopic   string        `mapstructure:"KAFKA_DB_UPDATE_TOPIC"`
+	KafkaExecutionTopic  string        `mapstructure:"KAFKA_EXECUTION_TOPIC"`
+	KafkaConsumerGroup   string        `mapstructure:"KAFKA_CONSUMER_GROUP"`
+	RmqHost              string        `mapstructure:"RMQ_URL"`
+	RmqQueueName         string        `mapstructure:"RMQ_QUEUE_NAME"`
+	KafkaStatsTopic      string        `mapstructure:"KAFKA_STATS_TOPIC"`
+	StatsPublishInterval time.Duration `mapstructure:"STATS_PUBLISH_INTERVAL"`
+	HTTPServerAddress    string        `mapstructure:"HTTP_SERVER_ADDRESS"`
+	KafkaMarketDataTopic string        `mapstructure:"KAFKA_MARKET_DATA_TOPIC"`
+	MarketDataDepth      int           `mapstructure:"MARKET_DATA_DEPTH"`
+	SnapshotDir          string        `mapstructure:"SNAPSHOT_DIR"`
+	SnapshotInterval     time.Duration `mapstructure:"SNAPSHOT_INTERVAL"`
+	JournalDir           string        `mapstructure:"JOURNAL_DIR"`
+	JournalSegmentBytes  int64         `mapstructure:"JOURNAL_SEGMENT_BYTES"`
+	EngineInstanceID     string        `mapstructure:"ENGINE_INSTANCE_ID"`
+	IDLayout             string        `mapstructure:"ID_LAYOUT"`
+	IDStatePath          string        `mapstructure:"ID_STATE_PATH"`
+	EngineMode           string        `mapstructure:"ENGINE_MODE"`
+	KafkaJournalTopic    string        `mapstructure:"KAFKA_JOURNAL_TOPIC"`
+	ReplicaSource        string        `mapstructure:"REPLICA_SOURCE"`
+	ReplicaSourceDir     string        `mapstructure:"REPLICA_SOURCE_DIR"`
+	ReplicaPollInterval  time.Duration `mapstructure:"REPLICA_POLL_INTERVAL"`
+	LeaseLockID          int64         `mapstructure:"LEASE_LOCK_ID"`
+	LeaseInterval        time.Duration `mapstructure:"LEASE_INTERVAL"`
+	ShardID              string        `mapstructure:"SHARD_ID"`
+	ShardIDs             string        `mapstructure:"SHARD_IDS"`
+	ShardMap             string        `mapstructure:"SHARD_MAP"`
+	ShardOverridesPath   string        `mapstructure:"SHARD_OVERRIDES_PATH"`
+	ShardHandoffDir      string        `mapstructure:"SHARD_HANDOFF_DIR"`
+	RmqExchange          string        `mapstructure:"RMQ_EXCHANGE"`
+	FixAddress           string        `mapstructure:"FIX_ADDRESS"`
+	FixSenderCompID      string        `mapstructure:"FIX_SENDER_COMP_ID"`
+	FixStoreDir          string        `mapstructure:"FIX_STORE_DIR"`
+	FixTargetCompIDs     string        `mapstructure:"FIX_TARGET_COMP_IDS"`
+	FixAccounts          string        `mapstructure:"FIX_ACCOUNTS"`
+	OrderAckTimeout      time.Duration `mapstructure:"ORDER_ACK_TIMEOUT"`
+	RmqDLX               string        `mapstructure:"RMQ_DEAD_LETTER_EXCHANGE"`
+	RmqRetryAttempts     int           `mapstructure:"RMQ_RETRY_ATTEMPTS"`
+	RmqRetryBackoff      time.Duration `mapstructure:"RMQ_RETRY_BACKOFF"`
+	StreamTokens         string        `mapstructure:"STREAM_TOKENS"`
+	StreamBufferSize     int           `mapstructure:"STREAM_BUFFER_SIZE"`
+	RmqReconnectMin      time.Duration `mapstructure:"RMQ_RECONNECT_MIN"`
+	RmqReconnectMax      time.Duration `mapstructure:"RMQ_RECONNECT_MAX"`
+	OutboxDir            string        `mapstructure:"OUTBOX_DIR"`
+	KafkaBatchSize       int           `mapstructure:"KAFKA_BATCH_SIZE"`
+	KafkaFlushInterval   time.Duration `mapstructure:"KAFKA_FLUSH_INTERVAL"`
+	DBWriterQueueSize    int           `mapstructure:"DB_WRITER_QUEUE_SIZE"`
+	DBWriteAttempts      int           `mapstructure:"DB_WRITE_ATTEMPTS"`
+	DBSpillDir           string        `mapstructure:"DB_SPILL_DIR"`
+	PartitionDaysAhead   int           `mapstructure:"PARTITION_DAYS_AHEAD"`
+	PartitionRetention   int           `mapstructure:"PARTITION_RETENTION_DAYS"`
+	PartitionMode        string        `mapstructure:"PARTITION_RETENTION_MODE"`
 }
 
 // LoadConfig reads configuration from file or environment variables.
@@ -1,17 +1,11 @@
 package util
 
 import (
-	"fmt"
-	"math/rand"
 	"time"
 )
 
-var rng = rand.New(rand.NewSource(time.Now().UnixNano()))
-
-func GeneratePrefixedID(prefix string) string {
-	return fmt.Sprintf("%s-%06d", prefix, rng.Intn(1000000))
-}
-
+// FormatDate renders ts as a FIX TradeDate <75>. Trade dates are UTC days,
+// the day the database files a report under.
 func FormatDate(ts int64) string {
-	return time.Unix(0, ts).Format("20060102") // YYYYMMDD
+	return time.Unix(0, ts).UTC().Format("20060102") // YYYYMMDD
 }
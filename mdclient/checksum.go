@@ -0,0 +1,45 @@
+// Package mdclient helps market data consumers keep a local copy of an engine
+// order book in sync from its depth updates. It has no dependency on the
+// engine internals so it can be vendored by external consumers.
+package mdclient
+
+import (
+	"hash/crc32"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// DefaultDepth is the number of levels per side covered by the checksum when
+// an update does not say otherwise.
+const DefaultDepth = 10
+
+type Level struct {
+	Price decimal.Decimal
+	Size  decimal.Decimal
+}
+
+// Checksum returns the CRC32 (IEEE) of the canonical string of the top depth
+// levels. bids must be ordered best (highest) first and asks best (lowest)
+// first. The canonical string interleaves the levels as
+//
+//	bidPx:bidSize:askPx:askSize:...
+//
+// level by level, skipping a side once it runs out. Prices and sizes use
+// decimal.Decimal.String, so trailing zeros never change the checksum.
+func Checksum(bids, asks []Level, depth int) uint32 {
+	return crc32.ChecksumIEEE([]byte(CanonicalString(bids, asks, depth)))
+}
+
+func CanonicalString(bids, asks []Level, depth int) string {
+	var parts []string
+	for i := 0; i < depth; i++ {
+		if i < len(bids) {
+			parts = append(parts, bids[i].Price.String(), bids[i].Size.String())
+		}
+		if i < len(asks) {
+			parts = append(parts, asks[i].Price.String(), asks[i].Size.String())
+		}
+	}
+	return strings.Join(parts, ":")
+}
@@ -0,0 +1,88 @@
+package mdclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// SnapshotSource fetches the current full snapshot of a symbol.
+type SnapshotSource func(ctx context.Context, symbol string) (Snapshot, error)
+
+// Follower keeps a LocalBook in sync with a stream of depth updates and
+// resyncs it from a snapshot whenever an update cannot be applied cleanly.
+type Follower struct {
+	Book    *LocalBook
+	Resyncs int
+	source  SnapshotSource
+}
+
+func NewFollower(symbol string, source SnapshotSource) *Follower {
+	return &Follower{
+		Book:   NewLocalBook(symbol),
+		source: source,
+	}
+}
+
+// Handle applies one update. On a sequence gap, checksum mismatch or an
+// unsynced book it fetches a snapshot and then re-applies the update, which
+// is a no-op when the snapshot already includes it.
+func (f *Follower) Handle(ctx context.Context, u Update) error {
+	err := f.Book.Apply(u)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, ErrNotSynced) {
+		log.Printf("depth update for %s rejected, resyncing: %v", f.Book.Symbol, err)
+	}
+
+	if err := f.Resync(ctx); err != nil {
+		return err
+	}
+	return f.Book.Apply(u)
+}
+
+func (f *Follower) Resync(ctx context.Context) error {
+	snapshot, err := f.source(ctx, f.Book.Symbol)
+	if err != nil {
+		return fmt.Errorf("failed to fetch snapshot for %s: %w", f.Book.Symbol, err)
+	}
+	f.Resyncs++
+	return f.Book.Reset(snapshot)
+}
+
+// HTTPSnapshotSource fetches snapshots from the engine's depth endpoint,
+// e.g. HTTPSnapshotSource("http://engine:8080", nil).
+func HTTPSnapshotSource(baseURL string, client *http.Client) SnapshotSource {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	baseURL = strings.TrimRight(baseURL, "/")
+
+	return func(ctx context.Context, symbol string) (Snapshot, error) {
+		endpoint := baseURL + "/api/v1/depth?symbol=" + url.QueryEscape(symbol)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return Snapshot{}, err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return Snapshot{}, err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return Snapshot{}, fmt.Errorf("unexpected status %s from %s", resp.Status, endpoint)
+		}
+		var snapshot Snapshot
+		if err := json.NewDecoder(resp.Body).Decode(&snapshot); err != nil {
+			return Snapshot{}, fmt.Errorf("failed to decode snapshot: %w", err)
+		}
+		return snapshot, nil
+	}
+}
@@ -0,0 +1,191 @@
+package mdclient
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrNotSynced        = errors.New("local book has not been synced from a snapshot")
+	ErrSequenceGap      = errors.New("depth update sequence gap")
+	ErrChecksumMismatch = errors.New("depth checksum mismatch")
+)
+
+const (
+	actionDelete = "2"
+
+	entryTypeBid   = "0"
+	entryTypeOffer = "1"
+)
+
+// Entry mirrors the engine's MDEntry wire format.
+type Entry struct {
+	UpdateAction string          `json:"279,omitempty"`
+	EntryType    string          `json:"269"`
+	Px           decimal.Decimal `json:"270"`
+	Size         decimal.Decimal `json:"271"`
+}
+
+// Update mirrors the engine's MarketDataIncrementalRefresh (35=X).
+type Update struct {
+	MsgType      string  `json:"35"`
+	Symbol       string  `json:"55"`
+	SeqNum       uint64  `json:"seq_num"`
+	TransactTime int64   `json:"60"`
+	Depth        int     `json:"depth"`
+	Checksum     uint32  `json:"checksum"`
+	Entries      []Entry `json:"268"`
+}
+
+// Snapshot mirrors the engine's MarketDataSnapshotFullRefresh (35=W).
+type Snapshot struct {
+	MsgType      string  `json:"35"`
+	Symbol       string  `json:"55"`
+	SeqNum       uint64  `json:"seq_num"`
+	TransactTime int64   `json:"60"`
+	Depth        int     `json:"depth"`
+	Checksum     uint32  `json:"checksum"`
+	Entries      []Entry `json:"268"`
+}
+
+// LocalBook is a consumer-side copy of one symbol's price levels. It is not
+// safe for concurrent use.
+type LocalBook struct {
+	Symbol       string
+	SeqNum       uint64
+	TransactTime int64
+	Depth        int
+	synced       bool
+	bids         map[string]Level
+	asks         map[string]Level
+}
+
+func NewLocalBook(symbol string) *LocalBook {
+	return &LocalBook{
+		Symbol: symbol,
+		Depth:  DefaultDepth,
+		bids:   make(map[string]Level),
+		asks:   make(map[string]Level),
+	}
+}
+
+// Reset replaces the book content with a snapshot after verifying its checksum.
+func (b *LocalBook) Reset(s Snapshot) error {
+	b.bids = make(map[string]Level)
+	b.asks = make(map[string]Level)
+	b.synced = false
+	for _, e := range s.Entries {
+		b.set(e.EntryType, e.Px, e.Size)
+	}
+	b.SeqNum = s.SeqNum
+	b.TransactTime = s.TransactTime
+	if s.Depth > 0 {
+		b.Depth = s.Depth
+	}
+	if got := b.Checksum(); got != s.Checksum {
+		return fmt.Errorf("%w: snapshot seq %d expected %d got %d", ErrChecksumMismatch, s.SeqNum, s.Checksum, got)
+	}
+	b.synced = true
+	return nil
+}
+
+// Apply applies an incremental update and verifies the resulting checksum.
+// Updates at or below the current sequence are ignored, so replaying updates
+// buffered during a resync is harmless. After an error the book is out of
+// sync until the next successful Reset.
+func (b *LocalBook) Apply(u Update) error {
+	if !b.synced {
+		return ErrNotSynced
+	}
+	if u.SeqNum <= b.SeqNum {
+		return nil
+	}
+	if u.SeqNum != b.SeqNum+1 {
+		b.synced = false
+		return fmt.Errorf("%w: expected %d got %d", ErrSequenceGap, b.SeqNum+1, u.SeqNum)
+	}
+
+	for _, e := range u.Entries {
+		size := e.Size
+		if e.UpdateAction == actionDelete {
+			size = decimal.Zero
+		}
+		b.set(e.EntryType, e.Px, size)
+	}
+	b.SeqNum = u.SeqNum
+	b.TransactTime = u.TransactTime
+	if u.Depth > 0 {
+		b.Depth = u.Depth
+	}
+
+	if got := b.Checksum(); got != u.Checksum {
+		b.synced = false
+		return fmt.Errorf("%w: seq %d expected %d got %d", ErrChecksumMismatch, u.SeqNum, u.Checksum, got)
+	}
+	return nil
+}
+
+func (b *LocalBook) set(entryType string, px, size decimal.Decimal) {
+	levels := b.bids
+	if entryType == entryTypeOffer {
+		levels = b.asks
+	}
+	key := px.String()
+	if !size.IsPositive() {
+		delete(levels, key)
+		return
+	}
+	levels[key] = Level{Price: px, Size: size}
+}
+
+func (b *LocalBook) Synced() bool {
+	return b.synced
+}
+
+// Bids returns every bid level, best first.
+func (b *LocalBook) Bids() []Level {
+	return sortedLevels(b.bids, func(x, y decimal.Decimal) bool { return x.GreaterThan(y) })
+}
+
+// Asks returns every ask level, best first.
+func (b *LocalBook) Asks() []Level {
+	return sortedLevels(b.asks, func(x, y decimal.Decimal) bool { return x.LessThan(y) })
+}
+
+func (b *LocalBook) Checksum() uint32 {
+	return Checksum(b.Bids(), b.Asks(), b.Depth)
+}
+
+// Snapshot returns the full content of the book in snapshot form.
+func (b *LocalBook) Snapshot() Snapshot {
+	s := Snapshot{
+		MsgType:      "W",
+		Symbol:       b.Symbol,
+		SeqNum:       b.SeqNum,
+		TransactTime: b.TransactTime,
+		Depth:        b.Depth,
+		Checksum:     b.Checksum(),
+		Entries:      make([]Entry, 0, len(b.bids)+len(b.asks)),
+	}
+	for _, l := range b.Bids() {
+		s.Entries = append(s.Entries, Entry{EntryType: entryTypeBid, Px: l.Price, Size: l.Size})
+	}
+	for _, l := range b.Asks() {
+		s.Entries = append(s.Entries, Entry{EntryType: entryTypeOffer, Px: l.Price, Size: l.Size})
+	}
+	return s
+}
+
+func sortedLevels(levels map[string]Level, better func(x, y decimal.Decimal) bool) []Level {
+	out := make([]Level, 0, len(levels))
+	for _, l := range levels {
+		out = append(out, l)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return better(out[i].Price, out[j].Price)
+	})
+	return out
+}
@@ -0,0 +1,109 @@
+package mdclient
+
+import (
+	"context"
+	"errors"
+	"hash/crc32"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func lvl(px, size string) Level {
+	return Level{Price: decimal.RequireFromString(px), Size: decimal.RequireFromString(size)}
+}
+
+func entry(action, entryType, px, size string) Entry {
+	return Entry{UpdateAction: action, EntryType: entryType, Px: decimal.RequireFromString(px), Size: decimal.RequireFromString(size)}
+}
+
+func TestCanonicalString(t *testing.T) {
+	bids := []Level{lvl("100.50", "2"), lvl("100", "1.0")}
+	asks := []Level{lvl("101", "3")}
+
+	assert.Equal(t, "100.5:2:101:3:100:1", CanonicalString(bids, asks, 10))
+	assert.Equal(t, "100.5:2:101:3", CanonicalString(bids, asks, 1))
+	assert.Equal(t, crc32.ChecksumIEEE([]byte("100.5:2:101:3")), Checksum(bids, asks, 1))
+}
+
+func syncedBook(t *testing.T) *LocalBook {
+	b := NewLocalBook("BTC/USDT")
+	snapshot := Snapshot{
+		SeqNum:  5,
+		Depth:   10,
+		Entries: []Entry{entry("", entryTypeBid, "100", "2"), entry("", entryTypeOffer, "101", "3")},
+	}
+	snapshot.Checksum = Checksum([]Level{lvl("100", "2")}, []Level{lvl("101", "3")}, 10)
+	require.NoError(t, b.Reset(snapshot))
+	return b
+}
+
+func TestLocalBook_Apply(t *testing.T) {
+	b := syncedBook(t)
+
+	u := Update{SeqNum: 6, Entries: []Entry{
+		entry("1", entryTypeBid, "100", "5"),
+		entry("2", entryTypeOffer, "101", "3"),
+		entry("0", entryTypeOffer, "102", "1"),
+	}}
+	u.Checksum = Checksum([]Level{lvl("100", "5")}, []Level{lvl("102", "1")}, 10)
+
+	require.NoError(t, b.Apply(u))
+	assert.Equal(t, uint64(6), b.SeqNum)
+	assert.Equal(t, []Level{lvl("102", "1")}, b.Asks())
+
+	// Replays are ignored.
+	assert.NoError(t, b.Apply(u))
+}
+
+func TestLocalBook_ChecksumMismatch(t *testing.T) {
+	b := syncedBook(t)
+
+	err := b.Apply(Update{SeqNum: 6, Checksum: 42, Entries: []Entry{entry("1", entryTypeBid, "100", "1")}})
+
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+	assert.False(t, b.Synced())
+	assert.ErrorIs(t, b.Apply(Update{SeqNum: 7}), ErrNotSynced)
+}
+
+func TestLocalBook_SequenceGap(t *testing.T) {
+	b := syncedBook(t)
+
+	err := b.Apply(Update{SeqNum: 8})
+
+	assert.ErrorIs(t, err, ErrSequenceGap)
+	assert.False(t, b.Synced())
+}
+
+func TestFollower_ResyncsOnMismatch(t *testing.T) {
+	good := syncedBook(t).Snapshot()
+	good.SeqNum = 7
+	fetches := 0
+	f := NewFollower("BTC/USDT", func(ctx context.Context, symbol string) (Snapshot, error) {
+		fetches++
+		return good, nil
+	})
+
+	// Unsynced: the first update triggers the initial snapshot fetch.
+	require.NoError(t, f.Handle(context.Background(), Update{SeqNum: 7, Checksum: good.Checksum}))
+	assert.Equal(t, 1, fetches)
+
+	// A drifted update is detected and the book is resynced from a snapshot
+	// that already includes it.
+	good.SeqNum = 8
+	require.NoError(t, f.Handle(context.Background(), Update{SeqNum: 8, Checksum: 1}))
+	assert.Equal(t, 2, f.Resyncs)
+	assert.True(t, f.Book.Synced())
+	assert.Equal(t, uint64(8), f.Book.SeqNum)
+}
+
+func TestFollower_SnapshotSourceError(t *testing.T) {
+	f := NewFollower("BTC/USDT", func(ctx context.Context, symbol string) (Snapshot, error) {
+		return Snapshot{}, errors.New("unavailable")
+	})
+
+	assert.Error(t, f.Handle(context.Background(), Update{SeqNum: 1}))
+	assert.False(t, f.Book.Synced())
+}
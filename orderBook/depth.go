@@ -0,0 +1,154 @@
+package orderBook
+
+import (
+	"log"
+	"sort"
+
+	"github.com/emirpasic/gods/maps/treemap"
+	"github.com/shopspring/decimal"
+
+	"MatchingEngine/internal/model"
+	"MatchingEngine/mdclient"
+)
+
+type MarketDataNotifier interface {
+	NotifyDepth(update model.MarketDataIncrementalRefresh) error
+	// ResetDepth replaces the consumers' view of a book, e.g. after a restore.
+	ResetDepth(snapshot model.MarketDataSnapshotFullRefresh) error
+}
+
+type levelKey struct {
+	side  model.Side
+	price string
+}
+
+// markLevelDirty records that the aggregated size at a price level may have
+// changed during the current event.
+func (book *OrderBook) markLevelDirty(side model.Side, price decimal.Decimal) {
+	if book.MarketData == nil {
+		return
+	}
+	if book.dirtyLevels == nil {
+		book.dirtyLevels = make(map[levelKey]decimal.Decimal)
+		book.publishedLevels = make(map[levelKey]decimal.Decimal)
+	}
+	book.dirtyLevels[levelKey{side: side, price: price.String()}] = price
+}
+
+// publishDepth emits one incremental refresh with every level changed by the
+// current event and the checksum of the resulting top levels.
+func (book *OrderBook) publishDepth() {
+	if book.MarketData == nil || len(book.dirtyLevels) == 0 {
+		return
+	}
+
+	keys := make([]levelKey, 0, len(book.dirtyLevels))
+	for k := range book.dirtyLevels {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].side != keys[j].side {
+			return keys[i].side == model.Buy
+		}
+		cmp := book.dirtyLevels[keys[i]].Cmp(book.dirtyLevels[keys[j]])
+		if keys[i].side == model.Buy {
+			return cmp > 0
+		}
+		return cmp < 0
+	})
+
+	var entries []model.MDEntry
+	for _, k := range keys {
+		price := book.dirtyLevels[k]
+		size := book.levelSize(k.side, price)
+		prev, had := book.publishedLevels[k]
+
+		entry := model.MDEntry{EntryType: mdEntryType(k.side), Px: price, Size: size}
+		switch {
+		case !had && size.IsPositive():
+			entry.UpdateAction = model.MDUpdateActionNew
+			book.publishedLevels[k] = size
+		case had && !size.IsPositive():
+			entry.UpdateAction = model.MDUpdateActionDelete
+			delete(book.publishedLevels, k)
+		case had && !size.Equal(prev):
+			entry.UpdateAction = model.MDUpdateActionChange
+			book.publishedLevels[k] = size
+		default:
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	clear(book.dirtyLevels)
+
+	if len(entries) == 0 {
+		return
+	}
+
+	book.depthSeq++
+	update := model.MarketDataIncrementalRefresh{
+		MsgType:      string(model.MsgTypeMDIncRefresh),
+		Symbol:       book.Symbol,
+		SeqNum:       book.depthSeq,
+		TransactTime: book.env.now(),
+		Depth:        book.depthLevels(),
+		Checksum:     book.Checksum(),
+		Entries:      entries,
+	}
+	if err := book.MarketData.NotifyDepth(update); err != nil {
+		log.Printf("Error publishing depth update %d for %s: %v", update.SeqNum, book.Symbol, err)
+	}
+}
+
+// Checksum returns the CRC32 of the book's top levels as defined by
+// mdclient.Checksum.
+func (book *OrderBook) Checksum() uint32 {
+	depth := book.depthLevels()
+	return mdclient.Checksum(topLevels(book.Bids, depth), topLevels(book.Asks, depth), depth)
+}
+
+func (book *OrderBook) depthLevels() int {
+	if book.DepthLevels <= 0 {
+		return mdclient.DefaultDepth
+	}
+	return book.DepthLevels
+}
+
+func (book *OrderBook) levelSize(side model.Side, price decimal.Decimal) decimal.Decimal {
+	levels := book.Asks
+	if side == model.Buy {
+		levels = book.Bids
+	}
+	val, ok := levels.Get(price)
+	if !ok {
+		return decimal.Zero
+	}
+	return val.(*OrderList).totalLeavesQty()
+}
+
+func topLevels(levels *treemap.Map, depth int) []mdclient.Level {
+	out := make([]mdclient.Level, 0, depth)
+	it := levels.Iterator()
+	for it.Next() && len(out) < depth {
+		out = append(out, mdclient.Level{
+			Price: it.Key().(decimal.Decimal),
+			Size:  it.Value().(*OrderList).totalLeavesQty(),
+		})
+	}
+	return out
+}
+
+func (list *OrderList) totalLeavesQty() decimal.Decimal {
+	total := decimal.Zero
+	for _, o := range list.Orders {
+		total = total.Add(o.LeavesQty)
+	}
+	return total
+}
+
+func mdEntryType(side model.Side) model.MDEntryType {
+	if side == model.Buy {
+		return model.MDEntryTypeBid
+	}
+	return model.MDEntryTypeOffer
+}
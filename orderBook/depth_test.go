@@ -0,0 +1,110 @@
+package orderBook
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"MatchingEngine/internal/model"
+	"MatchingEngine/mdclient"
+)
+
+type recordingMarketData struct {
+	updates   []model.MarketDataIncrementalRefresh
+	snapshots []model.MarketDataSnapshotFullRefresh
+}
+
+func (r *recordingMarketData) ResetDepth(snapshot model.MarketDataSnapshotFullRefresh) error {
+	r.snapshots = append(r.snapshots, snapshot)
+	return nil
+}
+
+func (r *recordingMarketData) NotifyDepth(update model.MarketDataIncrementalRefresh) error {
+	r.updates = append(r.updates, update)
+	return nil
+}
+
+func depthOrderReq(clOrdID string, side model.Side, price, qty int64) model.NewOrderRequest {
+	req := validNewOrderReq(clOrdID)
+	req.Side = side
+	req.Price = decimal.NewFromInt(price)
+	req.OrderQty = decimal.NewFromInt(qty)
+	return req
+}
+
+func TestPublishDepth_Entries(t *testing.T) {
+	ob := setupOrderBook()
+	md := &recordingMarketData{}
+	ob.Symbol = "BTC/USDT"
+	ob.MarketData = md
+
+	ob.OnNewOrder(depthOrderReq("B1", model.Buy, 100, 5))
+	ob.OnNewOrder(depthOrderReq("B2", model.Buy, 100, 3))
+	ob.OnNewOrder(depthOrderReq("S1", model.Sell, 100, 8))
+
+	require.Len(t, md.updates, 3)
+
+	first := md.updates[0]
+	assert.Equal(t, uint64(1), first.SeqNum)
+	assert.Equal(t, "BTC/USDT", first.Symbol)
+	require.Len(t, first.Entries, 1)
+	assert.Equal(t, model.MDUpdateActionNew, first.Entries[0].UpdateAction)
+	assert.Equal(t, model.MDEntryTypeBid, first.Entries[0].EntryType)
+	assert.True(t, first.Entries[0].Size.Equal(decimal.NewFromInt(5)))
+
+	second := md.updates[1]
+	assert.Equal(t, model.MDUpdateActionChange, second.Entries[0].UpdateAction)
+	assert.True(t, second.Entries[0].Size.Equal(decimal.NewFromInt(8)))
+
+	// The sell fully consumes the bid level and never rests.
+	third := md.updates[2]
+	require.Len(t, third.Entries, 1)
+	assert.Equal(t, model.MDUpdateActionDelete, third.Entries[0].UpdateAction)
+	assert.Equal(t, mdclient.Checksum(nil, nil, mdclient.DefaultDepth), third.Checksum)
+}
+
+func TestPublishDepth_NoUpdateForRejectedOrder(t *testing.T) {
+	ob := setupOrderBook()
+	md := &recordingMarketData{}
+	ob.MarketData = md
+
+	req := depthOrderReq("B1", model.Buy, 100, 5)
+	req.OrderQty = decimal.Zero
+	ob.OnNewOrder(req)
+	ob.CancelOrder("UNKNOWN")
+
+	assert.Empty(t, md.updates)
+}
+
+func TestPublishDepth_ConsumerChecksumMatches(t *testing.T) {
+	ob := setupOrderBook()
+	md := &recordingMarketData{}
+	ob.Symbol = "BTC/USDT"
+	ob.MarketData = md
+	ob.DepthLevels = 2
+
+	ob.OnNewOrder(depthOrderReq("B1", model.Buy, 99, 5))
+	ob.OnNewOrder(depthOrderReq("B2", model.Buy, 98, 5))
+	ob.OnNewOrder(depthOrderReq("B3", model.Buy, 97, 5))
+	ob.OnNewOrder(depthOrderReq("S1", model.Sell, 101, 4))
+	ob.OnNewOrder(depthOrderReq("S2", model.Sell, 102, 4))
+	ob.OnNewOrder(depthOrderReq("S3", model.Sell, 98, 7))
+	ob.CancelOrder("B3")
+	ob.OnNewOrder(depthOrderReq("B4", model.Buy, 102, 6))
+
+	local := mdclient.NewLocalBook("BTC/USDT")
+	require.NoError(t, local.Reset(mdclient.Snapshot{Depth: 2, Checksum: mdclient.Checksum(nil, nil, 2)}))
+	for _, update := range md.updates {
+		payload, err := json.Marshal(update)
+		require.NoError(t, err)
+		var u mdclient.Update
+		require.NoError(t, json.Unmarshal(payload, &u))
+		require.NoError(t, local.Apply(u), "seq %d", update.SeqNum)
+	}
+
+	assert.Equal(t, ob.Checksum(), local.Checksum())
+	assert.Equal(t, uint64(len(md.updates)), local.SeqNum)
+}
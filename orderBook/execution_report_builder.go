@@ -1,30 +1,35 @@
 package orderBook
 
 import (
-	"time"
-
 	"github.com/shopspring/decimal"
 
 	"MatchingEngine/internal/model"
-	"MatchingEngine/internal/util"
 )
 
 func newExecutionReport(order *Order, execType model.ExecType) model.ExecutionReport {
+	order.ReportSeq = order.env.nextReportSeq()
 	return model.ExecutionReport{
 		MsgType:      "8",
-		ExecID:       util.GeneratePrefixedID("execution"),
+		ExecID:       order.env.nextID("execution"),
 		OrderID:      order.OrderID,
 		ClOrdID:      order.ClOrdID,
+		Account:      order.Account,
 		ExecType:     execType,
 		OrdStatus:    order.OrderStatus,
 		Symbol:       order.Symbol,
 		Side:         order.Side,
+		Price:        order.Price,
+		OrdType:      order.OrdType,
+		TimeInForce:  order.TimeInForce,
 		OrderQty:     order.OrderQty,
 		LastShares:   decimal.Zero,
 		LastPx:       decimal.Zero,
 		LeavesQty:    order.LeavesQty,
 		CumQty:       order.CumQty,
 		AvgPx:        order.AvgPx,
-		TransactTime: time.Now().UnixNano(),
+		TransactTime: order.env.now(),
+		ClientTime:   order.env.clientTime(),
+		EngineSeq:    order.EngineSeq,
+		ReportSeq:    order.ReportSeq,
 	}
 }
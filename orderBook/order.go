@@ -7,27 +7,45 @@ import (
 	"github.com/shopspring/decimal"
 
 	"MatchingEngine/internal/model"
-	"MatchingEngine/internal/util"
 )
 
 type Order struct {
-	ClOrdID     string            `json:"cl_ord_id"` // from FIX <11>
-	OrderID     string            `json:"order_id"`  // from FIX <37>
-	Symbol      string            `json:"symbol"`    // from FIX <55>
-	Side        model.Side        `json:"side"`      // from FIX <54>
-	Price       decimal.Decimal   `json:"price"`     // from FIX <44>`
-	OrderQty    decimal.Decimal   `json:"order_qty"` // from FIX <38>
+	ClOrdID     string            `json:"cl_ord_id"`               // from FIX <11>
+	OrderID     string            `json:"order_id"`                // from FIX <37>
+	Account     string            `json:"account,omitempty"`       // from FIX <1>
+	Symbol      string            `json:"symbol"`                  // from FIX <55>
+	Side        model.Side        `json:"side"`                    // from FIX <54>
+	OrdType     model.OrdType     `json:"ord_type,omitempty"`      // from FIX <40>
+	TimeInForce model.TimeInForce `json:"time_in_force,omitempty"` // from FIX <59>
+	Price       decimal.Decimal   `json:"price"`                   // from FIX <44>`
+	OrderQty    decimal.Decimal   `json:"order_qty"`               // from FIX <38>
 	LeavesQty   decimal.Decimal   `json:"leaves_qty"`
 	CumQty      decimal.Decimal   `json:"cum_qty"`
 	AvgPx       decimal.Decimal   `json:"avg_px"`
-	Timestamp   int64             `json:"transact_time"` // from FIX <60>
+	Timestamp   int64             `json:"transact_time"` // from FIX <60>, client-supplied; reports carry it as ClientTime
+	ReceivedAt  int64             `json:"received_at"`   // Engine receive time, epoch ns
+	EngineSeq   uint64            `json:"engine_seq"`    // Engine sequence of the request that placed the order
+	ReportSeq   uint64            `json:"report_seq"`    // Sequence of the order's last execution report
 	OrderStatus model.OrderStatus `json:"order_status"`
 	Text        string            `json:"text,omitempty"` // from FIX <58>
-	Notifier    Notifier
+	Notifier    Notifier          `json:"-"`
+	env         *bookEnv
+}
+
+// defaultTerms fills in the OrdType and TimeInForce of an order restored from
+// state that predates them. The engine has only ever accepted limit orders
+// resting until canceled.
+func (o *Order) defaultTerms() {
+	if o.OrdType == "" {
+		o.OrdType = model.OrdTypeLimit
+	}
+	if o.TimeInForce == "" {
+		o.TimeInForce = model.TimeInForceGTC
+	}
 }
 
 func (o *Order) AssignOrderID() {
-	o.OrderID = util.GeneratePrefixedID("order")
+	o.OrderID = o.env.nextID("order")
 }
 
 func (o *Order) NewOrderEvent() {
@@ -37,7 +55,10 @@ func (o *Order) NewOrderEvent() {
 	o.publishExecutionReport(er)
 }
 
-func (o *Order) newFillEvent(price, qty decimal.Decimal) {
+// fill applies a fill of trade tradeID and returns its report, which the
+// caller publishes. liquidity tells whether the order was resting or
+// aggressing.
+func (o *Order) fill(price, qty decimal.Decimal, tradeID string, liquidity model.LastLiquidityInd) model.ExecutionReport {
 	o.CumQty = o.CumQty.Add(qty)
 	o.LeavesQty = o.OrderQty.Sub(o.CumQty)
 
@@ -53,12 +74,20 @@ func (o *Order) newFillEvent(price, qty decimal.Decimal) {
 	er := newExecutionReport(o, execType)
 	er.LastShares = qty
 	er.LastPx = price
-	o.publishExecutionReport(er)
+	er.TradeID = tradeID
+	er.LastLiquidityInd = liquidity
+	return er
 }
 
-func NewFillOrderEvent(order, matchOrder *Order, qty decimal.Decimal) {
-	order.newFillEvent(matchOrder.Price, qty)
-	matchOrder.newFillEvent(matchOrder.Price, qty)
+// NewFillOrderEvent fills both orders of a trade at the resting order's
+// price: order is the aggressor and removes liquidity, matchOrder rested and
+// added it. Both reports carry the same TransactTime, and are returned rather
+// than published so that the trade capture report can name them.
+func NewFillOrderEvent(order, matchOrder *Order, qty decimal.Decimal, tradeID string) (model.ExecutionReport, model.ExecutionReport) {
+	orderFill := order.fill(matchOrder.Price, qty, tradeID, model.LiquidityRemoved)
+	matchFill := matchOrder.fill(matchOrder.Price, qty, tradeID, model.LiquidityAdded)
+	matchFill.TransactTime = orderFill.TransactTime
+	return orderFill, matchFill
 }
 
 func (o *Order) NewCanceledOrderEvent() {
@@ -77,6 +106,35 @@ func (o *Order) NewCanceledRejectOrderEvent() {
 	o.publishExecutionReport(er)
 }
 
+// amend applies a validated replace request.
+func (o *Order) amend(or model.OrderCancelReplaceRequest) {
+	o.ClOrdID = or.ClOrdID
+	o.OrderQty = or.OrderQty
+	o.Price = or.Price
+	o.LeavesQty = or.OrderQty.Sub(o.CumQty)
+	o.Timestamp = or.TransactTime
+	o.Text = or.Text
+	o.OrderStatus = model.OrderStatusNew
+	if o.CumQty.IsPositive() {
+		o.OrderStatus = model.OrderStatusPartialFill
+	}
+}
+
+func (o *Order) NewReplacedOrderEvent() {
+	log.Printf("Creating replaced event for order: %s", o.OrderID)
+	er := newExecutionReport(o, model.ExecTypeReplaced)
+	o.publishExecutionReport(er)
+}
+
+// NewReplaceRejectedEvent reports a replace the order could not take. The
+// order itself is unchanged.
+func (o *Order) NewReplaceRejectedEvent(reason string) {
+	log.Printf("Creating replace reject event for order: %s", o.OrderID)
+	er := newExecutionReport(o, model.ExecTypeRejected)
+	er.Text = reason
+	o.publishExecutionReport(er)
+}
+
 func (o *Order) NewRejectedOrderEvent() {
 	log.Printf("Creating rejected event for order: %s", o.OrderID)
 	o.OrderStatus = model.OrderStatusRejected
@@ -106,7 +164,7 @@ func (o *Order) publishExecutionReport(er model.ExecutionReport) {
 		return
 	}
 
-	if err := o.Notifier.NotifyEventAndTrade(er.ExecID, payload); err != nil {
+	if err := o.Notifier.NotifyEventAndTrade(model.MsgTypeExecRpt, er.ExecID, payload); err != nil {
 		log.Printf("Error publishing execution report: %v", err)
 	} else {
 		log.Printf("Execution report published for event %s [%s]", er.ExecID, er.OrdStatus)
@@ -11,43 +11,83 @@ import (
 	"MatchingEngine/internal/util"
 )
 
+// Notifier publishes the execution and trade reports of a book. msgType tells
+// them apart, so notifiers need not decode value to find out.
 type Notifier interface {
-	NotifyEventAndTrade(orderID string, value json.RawMessage) error
+	NotifyEventAndTrade(msgType model.MsgType, orderID string, value json.RawMessage) error
 }
 
 type OrderRef struct {
-	PriceLevel decimal.Decimal
-	Side       string
-	Index      int
+	PriceLevel decimal.Decimal `json:"price_level"`
+	Side       string          `json:"side"`
+	Index      int             `json:"index"` // Position in the level's time priority queue
 }
 
 type OrderBook struct {
-	Bids       *treemap.Map // sorted descending // descending prices
-	Asks       *treemap.Map // ascending prices
-	Notifier   Notifier
-	orderIndex map[string]*OrderRef
+	Symbol          string
+	Bids            *treemap.Map // sorted descending // descending prices
+	Asks            *treemap.Map // ascending prices
+	Notifier        Notifier
+	MarketData      MarketDataNotifier
+	DepthLevels     int // levels per side covered by the depth checksum
+	Journal         Journal
+	orderIndex      map[string]*OrderRef
+	orderChan       chan model.OrderRequest
+	control         chan func(*OrderBook)
+	depthSeq        uint64
+	dirtyLevels     map[levelKey]decimal.Decimal
+	publishedLevels map[levelKey]decimal.Decimal
+	env             *bookEnv
+	journalSeq      uint64 // Last journal entry applied
+	localSeq        uint64 // Last sequence stamped on a request without a journal
+}
+
+// BookOpts holds the optional collaborators of an order book.
+type BookOpts struct {
+	Symbol      string
+	MarketData  MarketDataNotifier
+	DepthLevels int
+	Journal     Journal
+	IDs         IDGenerator
+	Clock       Clock
 }
 
 type OrderList struct {
 	Orders []Order
 }
 
-func NewOrderBook(Notifier Notifier) chan model.OrderRequest {
-	ob := &OrderBook{
-		Bids:       treemap.NewWith(util.DecimalDescComparator),
-		Asks:       treemap.NewWith(util.DecimalAscComparator),
-		Notifier:   Notifier,
-		orderIndex: make(map[string]*OrderRef),
+func NewOrderBook(Notifier Notifier, opts BookOpts) *OrderBook {
+	return &OrderBook{
+		Symbol:      opts.Symbol,
+		Bids:        treemap.NewWith(util.DecimalDescComparator),
+		Asks:        treemap.NewWith(util.DecimalAscComparator),
+		Notifier:    Notifier,
+		MarketData:  opts.MarketData,
+		DepthLevels: opts.DepthLevels,
+		Journal:     opts.Journal,
+		orderIndex:  make(map[string]*OrderRef),
+		control:     make(chan func(*OrderBook)),
+		env:         &bookEnv{ids: opts.IDs, clock: opts.Clock},
 	}
+}
+
+// Start runs the book's event loop on its own goroutine and returns the
+// channel the book consumes requests from.
+func (book *OrderBook) Start() chan model.OrderRequest {
 	orderChan := make(chan model.OrderRequest, 100)
+	book.orderChan = orderChan
 
 	go func() {
-		for req := range orderChan {
-			switch req.MsgType {
-			case model.MsgTypeNew:
-				ob.OnNewOrder(req.NewOrderReq)
-			case model.MsgTypeCancel:
-				ob.CancelOrder(req.CancelOrderReq.OrigClOrdID)
+		for {
+			select {
+			case req, ok := <-orderChan:
+				if !ok {
+					return
+				}
+				book.handleRequest(req)
+			case fn := <-book.control:
+				book.drainRequests()
+				fn(book)
 			}
 		}
 	}()
@@ -55,10 +95,131 @@ func NewOrderBook(Notifier Notifier) chan model.OrderRequest {
 	return orderChan
 }
 
+// Exec runs fn on the book's goroutine once every request queued so far has
+// been processed, and waits for it to return. The book must be started.
+func (book *OrderBook) Exec(fn func(*OrderBook)) {
+	done := make(chan struct{})
+	book.control <- func(b *OrderBook) {
+		fn(b)
+		close(done)
+	}
+	<-done
+}
+
+func (book *OrderBook) drainRequests() {
+	for {
+		select {
+		case req, ok := <-book.orderChan:
+			if !ok {
+				return
+			}
+			book.handleRequest(req)
+		default:
+			return
+		}
+	}
+}
+
+// handleRequest stamps the request with an engine sequence and receive time
+// and processes it. With a journal both come from the journal, which records
+// the request first; a request that cannot be journaled is rejected
+// unprocessed. Without one the sequence is local to the book and restarts
+// with the process.
+func (book *OrderBook) handleRequest(req model.OrderRequest) {
+	if book.Journal == nil {
+		book.localSeq++
+		book.process(&commandSequence{seq: book.localSeq, ts: book.env.clockNow()}, req)
+		return
+	}
+	seq, ts, err := book.Journal.Append(req)
+	if err != nil {
+		log.Printf("Rejecting request for %s, journal append failed: %v", book.Symbol, err)
+		book.rejectRequest(req)
+		return
+	}
+	book.Apply(seq, ts, req)
+}
+
+// Apply processes a journaled request. IDs and timestamps of the reports it
+// produces derive from seq and ts, so applying the same entries to the same
+// state yields identical reports. Entries at or below the last applied
+// sequence are ignored.
+func (book *OrderBook) Apply(seq uint64, ts int64, req model.OrderRequest) {
+	if seq <= book.journalSeq {
+		return
+	}
+	book.journalSeq = seq
+	book.process(&commandSequence{seq: seq, ts: ts, journaled: true}, req)
+}
+
+func (book *OrderBook) process(cmd *commandSequence, req model.OrderRequest) {
+	cmd.clientTime = req.TransactTime()
+	book.env.cmd = cmd
+	defer func() { book.env.cmd = nil }()
+	book.processRequest(req)
+}
+
+// JournalSeq returns the sequence of the last journal entry applied.
+func (book *OrderBook) JournalSeq() uint64 {
+	return book.journalSeq
+}
+
+func (book *OrderBook) processRequest(req model.OrderRequest) {
+	switch req.MsgType {
+	case model.MsgTypeNew:
+		book.OnNewOrder(req.NewOrderReq)
+	case model.MsgTypeCancel:
+		book.CancelOrder(req.CancelOrderReq.OrigClOrdID)
+	case model.MsgTypeReplace:
+		if req.ReplaceOrderReq == nil {
+			book.rejectRequest(req)
+			return
+		}
+		book.ReplaceOrder(*req.ReplaceOrderReq)
+	}
+}
+
+func (book *OrderBook) rejectRequest(req model.OrderRequest) {
+	switch req.MsgType {
+	case model.MsgTypeNew:
+		order := convertOrderRequestToOrder(req.NewOrderReq)
+		book.attach(&order)
+		order.ReceivedAt = book.env.now()
+		order.AssignOrderID()
+		order.NewRejectedOrderEvent()
+	case model.MsgTypeCancel:
+		order := book.unknownOrder(req.CancelOrderReq.OrigClOrdID)
+		order.NewCanceledRejectOrderEvent()
+	case model.MsgTypeReplace:
+		var origClOrdID string
+		if req.ReplaceOrderReq != nil {
+			origClOrdID = req.ReplaceOrderReq.OrigClOrdID
+		}
+		order := book.unknownOrder(origClOrdID)
+		order.NewCanceledRejectOrderEvent()
+	}
+}
+
+// attach wires an order to the book's notifier and ID and time sources.
+func (book *OrderBook) attach(order *Order) {
+	order.Notifier = book.Notifier
+	order.env = book.env
+}
+
+// unknownOrder stands in for an order a request names but the book does not
+// hold, to report the request's reject on.
+func (book *OrderBook) unknownOrder(clOrdID string) Order {
+	order := Order{ClOrdID: clOrdID, Symbol: book.Symbol}
+	book.attach(&order)
+	return order
+}
+
 func (book *OrderBook) OnNewOrder(or model.NewOrderRequest) {
+	defer book.publishDepth()
 	log.Printf("Received new order: %+v", or)
 	order := convertOrderRequestToOrder(or)
-	order.Notifier = book.Notifier
+	book.attach(&order)
+	order.EngineSeq, order.ReceivedAt = book.env.stamp()
 	order.AssignOrderID()
 	err := or.ValidateNewOrder()
 	if err != nil {
@@ -70,11 +231,11 @@ func (book *OrderBook) OnNewOrder(or model.NewOrderRequest) {
 }
 
 func (book *OrderBook) CancelOrder(origClOrdID string) {
+	defer book.publishDepth()
 	ref, ok := book.orderIndex[origClOrdID]
 	if !ok {
 		log.Printf("Order with ID %s not found", origClOrdID)
-		order := Order{ClOrdID: origClOrdID}
-		order.Notifier = book.Notifier
+		order := book.unknownOrder(origClOrdID)
 		order.NewCanceledRejectOrderEvent()
 		return
 	}
@@ -84,23 +245,15 @@ func (book *OrderBook) CancelOrder(origClOrdID string) {
 	if !exists || list == nil || ref.Index >= len(list.Orders) || list.Orders[ref.Index].ClOrdID != origClOrdID {
 		log.Printf("Order with ID %s inconsistent in index", origClOrdID)
 		delete(book.orderIndex, origClOrdID)
-		order := Order{ClOrdID: origClOrdID}
-		order.Notifier = book.Notifier
+		order := book.unknownOrder(origClOrdID)
 		order.NewCanceledRejectOrderEvent()
 		return
 	}
 
 	order := list.Orders[ref.Index]
-	order.Notifier = book.Notifier
+	book.attach(&order)
 
-	// Remove order from list (swap with last, then pop)
-	last := len(list.Orders) - 1
-	if ref.Index != last {
-		list.Orders[ref.Index] = list.Orders[last]
-		// Update moved order's index
-		book.orderIndex[list.Orders[ref.Index].ClOrdID].Index = ref.Index
-	}
-	list.Orders = list.Orders[:last]
+	book.removeOrderAt(list, ref.Index)
 
 	if len(list.Orders) == 0 {
 		if ref.Side == string(model.Buy) {
@@ -110,12 +263,92 @@ func (book *OrderBook) CancelOrder(origClOrdID string) {
 		}
 	}
 
-	delete(book.orderIndex, origClOrdID)
+	book.markLevelDirty(order.Side, ref.PriceLevel)
 
 	log.Printf("Canceled order %s from %s at price %s", origClOrdID, ref.Side, ref.PriceLevel)
 	order.NewCanceledOrderEvent()
 }
 
+// ReplaceOrder amends the quantity and price of a resting order. The order
+// keeps its time priority when only its quantity goes down; otherwise it
+// leaves the book and is matched again as if it had just arrived. A request
+// the order cannot take is rejected and leaves it untouched.
+func (book *OrderBook) ReplaceOrder(or model.OrderCancelReplaceRequest) {
+	defer book.publishDepth()
+	ref, ok := book.orderIndex[or.OrigClOrdID]
+	if !ok {
+		log.Printf("Order with ID %s not found", or.OrigClOrdID)
+		order := book.unknownOrder(or.OrigClOrdID)
+		order.NewCanceledRejectOrderEvent()
+		return
+	}
+
+	isBid := ref.Side == string(model.Buy)
+	list, exists := book.getOrderListAndRemoveFromBook(ref.PriceLevel, isBid)
+	if !exists || list == nil || ref.Index >= len(list.Orders) || list.Orders[ref.Index].ClOrdID != or.OrigClOrdID {
+		log.Printf("Order with ID %s inconsistent in index", or.OrigClOrdID)
+		delete(book.orderIndex, or.OrigClOrdID)
+		order := book.unknownOrder(or.OrigClOrdID)
+		order.NewCanceledRejectOrderEvent()
+		return
+	}
+
+	resting := &list.Orders[ref.Index]
+	reason := replaceRejectReason(resting, or)
+	if _, taken := book.orderIndex[or.ClOrdID]; taken && or.ClOrdID != or.OrigClOrdID {
+		reason = "duplicate client order ID"
+	}
+	if reason != "" {
+		log.Printf("Rejecting replace of order %s: %s", or.OrigClOrdID, reason)
+		book.attach(resting)
+		resting.NewReplaceRejectedEvent(reason)
+		return
+	}
+
+	if or.Price.Equal(resting.Price) && or.OrderQty.LessThanOrEqual(resting.OrderQty) {
+		delete(book.orderIndex, resting.ClOrdID)
+		book.orderIndex[or.ClOrdID] = ref
+		resting.amend(or)
+		book.markLevelDirty(resting.Side, ref.PriceLevel)
+		book.attach(resting)
+		resting.NewReplacedOrderEvent()
+		return
+	}
+
+	order := *resting
+	book.attach(&order)
+	book.removeOrderAt(list, ref.Index)
+	if len(list.Orders) == 0 {
+		if isBid {
+			book.Bids.Remove(ref.PriceLevel)
+		} else {
+			book.Asks.Remove(ref.PriceLevel)
+		}
+	}
+	book.markLevelDirty(order.Side, ref.PriceLevel)
+
+	order.amend(or)
+	order.EngineSeq, order.ReceivedAt = book.env.stamp()
+	order.NewReplacedOrderEvent()
+	book.matchOrder(&order)
+	book.rest(&order)
+}
+
+func replaceRejectReason(order *Order, or model.OrderCancelReplaceRequest) string {
+	if err := or.ValidateReplace(); err != nil {
+		return err.Error()
+	}
+	switch {
+	case or.Side != order.Side:
+		return "side cannot be changed"
+	case or.Symbol != order.Symbol:
+		return "symbol cannot be changed"
+	case !or.OrderQty.GreaterThan(order.CumQty):
+		return "order quantity must exceed the filled quantity"
+	}
+	return ""
+}
+
 func (book *OrderBook) getOrderListAndRemoveFromBook(priceLevel decimal.Decimal, isBid bool) (*OrderList, bool) {
 	var list *OrderList
 	var ok bool
@@ -164,6 +397,7 @@ func (book *OrderBook) addOrderToBook(order Order) {
 	}
 
 	list.Orders = append(list.Orders, order)
+	book.markLevelDirty(order.Side, priceKey)
 	book.orderIndex[order.ClOrdID] = &OrderRef{
 		PriceLevel: priceKey,
 		Side:       string(order.Side),
@@ -171,11 +405,26 @@ func (book *OrderBook) addOrderToBook(order Order) {
 	}
 }
 
+// removeOrderAt removes the i-th order of a price level, keeping the time
+// priority of the remaining orders and their index entries in step.
+func (book *OrderBook) removeOrderAt(list *OrderList, i int) {
+	delete(book.orderIndex, list.Orders[i].ClOrdID)
+	list.Orders = append(list.Orders[:i], list.Orders[i+1:]...)
+	for j := i; j < len(list.Orders); j++ {
+		if ref, ok := book.orderIndex[list.Orders[j].ClOrdID]; ok {
+			ref.Index = j
+		}
+	}
+}
+
 func convertOrderRequestToOrder(or model.NewOrderRequest) Order {
 	return Order{
 		ClOrdID:     or.ClOrdID,
+		Account:     or.Account,
 		Symbol:      or.Symbol,
 		Side:        or.Side,
+		OrdType:     model.OrdTypeLimit,
+		TimeInForce: model.TimeInForceGTC,
 		Price:       or.Price,
 		OrderQty:    or.OrderQty,
 		LeavesQty:   or.OrderQty,
@@ -185,3 +434,44 @@ func convertOrderRequestToOrder(or model.NewOrderRequest) Order {
 		CumQty:      decimal.Zero,
 	}
 }
+
+// OpenOrders copies the resting orders, bids then asks, each best price first
+// and in time priority within a level. It must run on the book's goroutine,
+// e.g. through Exec.
+func (book *OrderBook) OpenOrders() []Order {
+	var orders []Order
+	for _, levels := range []*treemap.Map{book.Bids, book.Asks} {
+		it := levels.Iterator()
+		for it.Next() {
+			for _, order := range it.Value().(*OrderList).Orders {
+				order.Notifier = nil
+				orders = append(orders, order)
+			}
+		}
+	}
+	return orders
+}
+
+// RestingOrder copies the resting order known by clOrdID. It must run on the
+// book's goroutine.
+func (book *OrderBook) RestingOrder(clOrdID string) (Order, bool) {
+	ref, ok := book.orderIndex[clOrdID]
+	if !ok {
+		return Order{}, false
+	}
+	levels := book.Asks
+	if ref.Side == string(model.Buy) {
+		levels = book.Bids
+	}
+	val, found := levels.Get(ref.PriceLevel)
+	if !found {
+		return Order{}, false
+	}
+	list := val.(*OrderList)
+	if ref.Index >= len(list.Orders) || list.Orders[ref.Index].ClOrdID != clOrdID {
+		return Order{}, false
+	}
+	order := list.Orders[ref.Index]
+	order.Notifier = nil
+	return order, true
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/emirpasic/gods/maps/treemap"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"MatchingEngine/internal/model"
 	"MatchingEngine/internal/util"
@@ -15,7 +16,7 @@ import (
 
 type MockTradeNotifier struct{}
 
-func (m *MockTradeNotifier) NotifyEventAndTrade(string, json.RawMessage) error {
+func (m *MockTradeNotifier) NotifyEventAndTrade(model.MsgType, string, json.RawMessage) error {
 	return nil
 }
 
@@ -44,7 +45,7 @@ func validNewOrderReq(clOrdID string) model.NewOrderRequest {
 }
 
 func TestNewOrderBook(t *testing.T) {
-	orderChan := NewOrderBook(&MockTradeNotifier{})
+	orderChan := NewOrderBook(&MockTradeNotifier{}, BookOpts{Symbol: "BTC/USDT"}).Start()
 	assert.NotNil(t, orderChan)
 }
 
@@ -130,3 +131,184 @@ func TestAddOrderToBook_SellOrder(t *testing.T) {
 	assert.Equal(t, order.ClOrdID, orderList.Orders[0].ClOrdID)
 	assert.Contains(t, ob.orderIndex, order.ClOrdID)
 }
+
+func TestCancelOrder_KeepsQueueAndIndexConsistent(t *testing.T) {
+	ob := setupOrderBook()
+	ob.OnNewOrder(validNewOrderReq("CLORD010"))
+	ob.OnNewOrder(validNewOrderReq("CLORD011"))
+	ob.OnNewOrder(validNewOrderReq("CLORD012"))
+
+	ob.CancelOrder("CLORD010")
+
+	val, ok := ob.Bids.Get(decimal.NewFromFloat(100.50))
+	assert.True(t, ok)
+	orderList := val.(*OrderList)
+	assert.Equal(t, "CLORD011", orderList.Orders[0].ClOrdID)
+	assert.Equal(t, "CLORD012", orderList.Orders[1].ClOrdID)
+	assert.Equal(t, 0, ob.orderIndex["CLORD011"].Index)
+	assert.Equal(t, 1, ob.orderIndex["CLORD012"].Index)
+
+	ob.CancelOrder("CLORD012")
+	assert.NotContains(t, ob.orderIndex, "CLORD012")
+	assert.Len(t, orderList.Orders, 1)
+}
+
+func replaceReq(origClOrdID, clOrdID string, qty int64, px float64) model.OrderCancelReplaceRequest {
+	return model.OrderCancelReplaceRequest{
+		BaseOrderRequest: model.BaseOrderRequest{
+			MsgType: model.MsgTypeReplace,
+			ClOrdID: clOrdID,
+			Side:    model.Buy,
+			Symbol:  "BTC/USDT",
+		},
+		OrigClOrdID: origClOrdID,
+		OrderQty:    decimal.NewFromInt(qty),
+		Price:       decimal.NewFromFloat(px),
+	}
+}
+
+func reportsOf(t *testing.T, n *payloadNotifier) []model.ExecutionReport {
+	var reports []model.ExecutionReport
+	for _, p := range n.payloads {
+		var er model.ExecutionReport
+		if json.Unmarshal(p, &er) == nil && er.MsgType == "8" {
+			reports = append(reports, er)
+		}
+	}
+	return reports
+}
+
+func TestReplaceOrder_QuantityDownKeepsPriority(t *testing.T) {
+	ob := setupOrderBook()
+	notifier := &payloadNotifier{}
+	ob.Notifier = notifier
+	ob.OnNewOrder(validNewOrderReq("CLORD020"))
+	ob.OnNewOrder(validNewOrderReq("CLORD021"))
+
+	ob.ReplaceOrder(replaceReq("CLORD020", "CLORD020R", 4, 100.50))
+
+	val, _ := ob.Bids.Get(decimal.NewFromFloat(100.50))
+	orderList := val.(*OrderList)
+	assert.Equal(t, "CLORD020R", orderList.Orders[0].ClOrdID)
+	assert.True(t, orderList.Orders[0].LeavesQty.Equal(decimal.NewFromInt(4)))
+	assert.NotContains(t, ob.orderIndex, "CLORD020")
+	assert.Equal(t, 0, ob.orderIndex["CLORD020R"].Index)
+
+	reports := reportsOf(t, notifier)
+	last := reports[len(reports)-1]
+	assert.Equal(t, model.ExecTypeReplaced, last.ExecType)
+	assert.Equal(t, model.OrderStatusNew, last.OrdStatus)
+	assert.Equal(t, "CLORD020R", last.ClOrdID)
+}
+
+func TestReplaceOrder_PriceChangeLosesPriorityAndMatches(t *testing.T) {
+	ob := setupOrderBook()
+	notifier := &payloadNotifier{}
+	ob.Notifier = notifier
+	ob.OnNewOrder(validNewOrderReq("CLORD030"))
+	sell := validNewOrderReq("SELL030")
+	sell.Side = model.Sell
+	sell.Price = decimal.NewFromInt(101)
+	sell.OrderQty = decimal.NewFromInt(3)
+	ob.OnNewOrder(sell)
+
+	ob.ReplaceOrder(replaceReq("CLORD030", "CLORD030R", 10, 101))
+
+	assert.Equal(t, 0, ob.Asks.Size())
+	val, ok := ob.Bids.Get(decimal.NewFromInt(101))
+	assert.True(t, ok)
+	resting := val.(*OrderList).Orders[0]
+	assert.Equal(t, "CLORD030R", resting.ClOrdID)
+	assert.True(t, resting.LeavesQty.Equal(decimal.NewFromInt(7)))
+	_, ok = ob.Bids.Get(decimal.NewFromFloat(100.50))
+	assert.False(t, ok)
+
+	var execTypes []model.ExecType
+	for _, er := range reportsOf(t, notifier) {
+		if er.ClOrdID == "CLORD030R" {
+			execTypes = append(execTypes, er.ExecType)
+		}
+	}
+	assert.Equal(t, []model.ExecType{model.ExecTypeReplaced, model.ExecTypeFill}, execTypes)
+}
+
+func TestReplaceOrder_RejectsWithoutTouchingOrder(t *testing.T) {
+	ob := setupOrderBook()
+	notifier := &payloadNotifier{}
+	ob.Notifier = notifier
+	ob.OnNewOrder(validNewOrderReq("CLORD040"))
+	ob.OnNewOrder(validNewOrderReq("CLORD041"))
+
+	ob.ReplaceOrder(replaceReq("CLORD040", "CLORD041", 5, 100.50))
+	wrongSide := replaceReq("CLORD040", "CLORD040R", 5, 100.50)
+	wrongSide.Side = model.Sell
+	ob.ReplaceOrder(wrongSide)
+	ob.ReplaceOrder(replaceReq("UNKNOWN", "CLORD040R", 5, 100.50))
+
+	assert.Contains(t, ob.orderIndex, "CLORD040")
+	val, _ := ob.Bids.Get(decimal.NewFromFloat(100.50))
+	assert.True(t, val.(*OrderList).Orders[0].LeavesQty.Equal(decimal.NewFromInt(10)))
+
+	reports := reportsOf(t, notifier)
+	for _, er := range reports[len(reports)-3:] {
+		assert.Equal(t, model.ExecTypeRejected, er.ExecType)
+	}
+	assert.Equal(t, "duplicate client order ID", reports[len(reports)-3].Text)
+}
+
+func TestReplaceOrder_RejectReportsTheLiveOrdersStatus(t *testing.T) {
+	notifier := &payloadNotifier{}
+	ob := NewOrderBook(notifier, BookOpts{Symbol: "BTC/USDT"})
+	ob.OnNewOrder(validNewOrderReq("CLORD050"))
+	sell := validNewOrderReq("SELL050")
+	sell.Side = model.Sell
+	sell.OrderQty = decimal.NewFromInt(4)
+	ob.OnNewOrder(validNewOrderReq("CLORD051"))
+
+	wrongSide := replaceReq("CLORD050", "CLORD050R", 5, 100.50)
+	wrongSide.Side = model.Sell
+	ob.ReplaceOrder(wrongSide)
+	ob.OnNewOrder(sell)
+	ob.ReplaceOrder(replaceReq("CLORD050", "CLORD050R", 4, 100.50))
+
+	reports := reportsOf(t, notifier)
+	require.Len(t, reports, 6)
+	newReport, unfilledReject := reports[0], reports[2]
+	assert.Equal(t, model.OrderStatusNew, newReport.OrdStatus)
+	assert.Equal(t, model.ExecTypeRejected, unfilledReject.ExecType)
+	assert.Equal(t, model.OrderStatusNew, unfilledReject.OrdStatus)
+	assert.Greater(t, unfilledReject.ReportSeq, newReport.ReportSeq)
+	filledReject := reports[5]
+	assert.Equal(t, model.ExecTypeRejected, filledReject.ExecType)
+	assert.Equal(t, model.OrderStatusPartialFill, filledReject.OrdStatus)
+
+	order, ok := ob.RestingOrder("CLORD050")
+	require.True(t, ok)
+	assert.Equal(t, model.OrderStatusPartialFill, order.OrderStatus)
+	assert.Equal(t, filledReject.ReportSeq, order.ReportSeq)
+}
+
+func TestOpenOrdersAndRestingOrder(t *testing.T) {
+	ob := setupOrderBook()
+	ob.OnNewOrder(validNewOrderReq("B1"))
+	better := validNewOrderReq("B2")
+	better.Price = decimal.NewFromInt(101)
+	ob.OnNewOrder(better)
+	ask := validNewOrderReq("S1")
+	ask.Side = model.Sell
+	ask.Price = decimal.NewFromInt(105)
+	ob.OnNewOrder(ask)
+
+	var ids []string
+	for _, o := range ob.OpenOrders() {
+		assert.Nil(t, o.Notifier)
+		ids = append(ids, o.ClOrdID)
+	}
+	assert.Equal(t, []string{"B2", "B1", "S1"}, ids)
+
+	order, ok := ob.RestingOrder("S1")
+	assert.True(t, ok)
+	assert.True(t, order.Price.Equal(decimal.NewFromInt(105)))
+	_, ok = ob.RestingOrder("NOPE")
+	assert.False(t, ok)
+}
@@ -11,6 +11,24 @@ import (
 )
 
 func (book *OrderBook) processOrder(order *Order) {
+	if !book.matchOrder(order) && order.LeavesQty.IsPositive() {
+		order.NewOrderEvent()
+	}
+	book.rest(order)
+}
+
+// rest puts what is left of the order in the book. It runs once the order's
+// reports are out, so the book's copy has the status and report sequence of
+// the last of them.
+func (book *OrderBook) rest(order *Order) {
+	if order.LeavesQty.IsPositive() {
+		book.addOrderToBook(*order)
+	}
+}
+
+// matchOrder fills the order against the opposite side. It reports whether
+// anything was filled.
+func (book *OrderBook) matchOrder(order *Order) bool {
 	var (
 		matchingBook *treemap.Map
 		isBuy        = order.Side == model.Buy
@@ -29,7 +47,7 @@ func (book *OrderBook) processOrder(order *Order) {
 		}
 	}
 
-	order.LeavesQty = order.OrderQty
+	order.LeavesQty = order.OrderQty.Sub(order.CumQty)
 	it := matchingBook.Iterator()
 	it.Begin()
 
@@ -47,14 +65,19 @@ func (book *OrderBook) processOrder(order *Order) {
 			match := &orderList.Orders[i]
 			matchQty := decimal.Min(order.LeavesQty, match.LeavesQty)
 
-			book.publishTrade(order, match, matchQty)
-
-			NewFillOrderEvent(order, match, matchQty)
+			// The fills go out before the trade capture report that refers
+			// to them.
+			tradeID := book.env.nextID("trade")
+			orderFill, matchFill := NewFillOrderEvent(order, match, matchQty, tradeID)
+			order.publishExecutionReport(orderFill)
+			match.publishExecutionReport(matchFill)
+			book.publishTrade(order, match, matchQty, orderFill, matchFill)
+			book.markLevelDirty(match.Side, price)
 
 			orderMatched = true
 
 			if match.LeavesQty.IsZero() {
-				orderList.Orders = append(orderList.Orders[:i], orderList.Orders[i+1:]...)
+				book.removeOrderAt(orderList, i)
 			} else {
 				i++
 			}
@@ -69,43 +92,48 @@ func (book *OrderBook) processOrder(order *Order) {
 		}
 	}
 
-	if order.LeavesQty.IsPositive() {
-		book.addOrderToBook(*order)
-		if !orderMatched {
-			order.NewOrderEvent()
-		}
-	}
+	return orderMatched
 }
 
-func (book *OrderBook) publishTrade(order, match *Order, qty decimal.Decimal) {
+// publishTrade reports the trade between order and match, filled by
+// orderFill and matchFill.
+func (book *OrderBook) publishTrade(order, match *Order, qty decimal.Decimal, orderFill, matchFill model.ExecutionReport) {
 	price := match.Price
 	if price.IsZero() {
 		price = order.Price
 	}
 
+	// The trade happens when its fills do, which also files it under the
+	// same trade date.
+	now := orderFill.TransactTime
 	tradeReport := model.TradeCaptureReport{
-		MsgType:       "AE",                                   // FIX MsgType = AE (Trade Capture Report)
-		TradeReportID: util.GeneratePrefixedID("tradeReport"), // Unique trade report ID
-		ExecID:        util.GeneratePrefixedID("execution"),   // Unique execution ID
+		MsgType:       "AE",                           // FIX MsgType = AE (Trade Capture Report)
+		TradeReportID: book.env.nextID("tradeReport"), // Unique trade report ID
+		ExecID:        book.env.nextID("execution"),   // Unique execution ID
+		TradeID:       orderFill.TradeID,
 		Symbol:        order.Symbol,
 		LastQty:       qty,
 		LastPx:        price,
-		TradeDate:     util.FormatDate(order.Timestamp), // Format: YYYYMMDD
-		TransactTime:  order.Timestamp,
+		TradeDate:     util.FormatDate(now), // Format: YYYYMMDD
+		TransactTime:  now,
 		NoSides: []model.NoSides{
 			{
 				Side:    order.Side,
 				OrderID: order.OrderID,
+				ClOrdID: orderFill.ClOrdID,
+				ExecID:  orderFill.ExecID,
 			},
 			{
 				Side:    match.Side,
 				OrderID: match.OrderID,
+				ClOrdID: matchFill.ClOrdID,
+				ExecID:  matchFill.ExecID,
 			},
 		},
 	}
 
 	if book.Notifier != nil {
-		if err := book.Notifier.NotifyEventAndTrade(tradeReport.TradeReportID, tradeReport.ToJSON()); err != nil {
+		if err := book.Notifier.NotifyEventAndTrade(model.MsgTypeTradeReport, tradeReport.TradeReportID, tradeReport.ToJSON()); err != nil {
 			log.Printf("Error publishing trade report: %v", err)
 		} else {
 			//log.Printf("Trade report sent: ExecID %s, Buy=%s Sell=%s Qty=%s",
@@ -7,6 +7,7 @@ import (
 	"github.com/emirpasic/gods/maps/treemap"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"MatchingEngine/internal/model"
 	"MatchingEngine/internal/util"
@@ -106,3 +107,87 @@ func TestProcessOrder_NoMatch(t *testing.T) {
 	assert.Equal(t, 1, book.Bids.Size())
 	assert.True(t, buyOrder.LeavesQty.Equal(decimal.NewFromInt(10)))
 }
+
+func TestProcessOrder_FilledOrdersLeaveIndex(t *testing.T) {
+	book := newTestOrderBook()
+	for _, id := range []string{"S1", "S2", "S3"} {
+		book.addOrderToBook(Order{
+			ClOrdID:   id,
+			Side:      model.Sell,
+			Price:     decimal.NewFromInt(100),
+			OrderQty:  decimal.NewFromInt(5),
+			LeavesQty: decimal.NewFromInt(5),
+		})
+	}
+
+	buyOrder := Order{
+		ClOrdID:   "B1",
+		Side:      model.Buy,
+		Price:     decimal.NewFromInt(100),
+		OrderQty:  decimal.NewFromInt(7),
+		LeavesQty: decimal.NewFromInt(7),
+	}
+	book.processOrder(&buyOrder)
+
+	assert.NotContains(t, book.orderIndex, "S1")
+	assert.Equal(t, 0, book.orderIndex["S2"].Index)
+	assert.Equal(t, 1, book.orderIndex["S3"].Index)
+
+	book.CancelOrder("S3")
+	assert.NotContains(t, book.orderIndex, "S3")
+}
+
+func TestMatchOrder_TradeLinksBothFills(t *testing.T) {
+	ob := setupOrderBook()
+	notifier := &payloadNotifier{}
+	ob.Notifier = notifier
+	ob.OnNewOrder(depthOrderReq("B1", model.Buy, 100, 5))
+	ob.OnNewOrder(depthOrderReq("S1", model.Sell, 100, 3))
+
+	fills := map[string]model.ExecutionReport{}
+	for _, er := range reportsOf(t, notifier) {
+		if er.ExecType == model.ExecTypeFill {
+			fills[er.ClOrdID] = er
+		}
+	}
+	require.Len(t, fills, 2)
+
+	// The trade capture report follows the fills it names.
+	var trade model.TradeCaptureReport
+	require.NoError(t, trade.FromJSON(notifier.payloads[len(notifier.payloads)-1]))
+	require.Equal(t, string(model.MsgTypeTradeReport), trade.MsgType)
+	assert.NotEmpty(t, trade.TradeID)
+	require.Len(t, trade.NoSides, 2)
+	for _, side := range trade.NoSides {
+		fill := fills[side.ClOrdID]
+		assert.Equal(t, trade.TradeID, fill.TradeID)
+		assert.Equal(t, fill.ExecID, side.ExecID)
+		assert.Equal(t, fill.OrderID, side.OrderID)
+		assert.Equal(t, trade.TransactTime, fill.TransactTime)
+	}
+}
+
+func TestMatchOrder_FillsCarryOrderTermsAndLiquidity(t *testing.T) {
+	ob := setupOrderBook()
+	notifier := &payloadNotifier{}
+	ob.Notifier = notifier
+	ob.OnNewOrder(depthOrderReq("B1", model.Buy, 101, 5))
+	ob.OnNewOrder(depthOrderReq("S1", model.Sell, 100, 3))
+
+	fills := map[string]model.ExecutionReport{}
+	for _, er := range reportsOf(t, notifier) {
+		assert.Equal(t, model.OrdTypeLimit, er.OrdType)
+		assert.Equal(t, model.TimeInForceGTC, er.TimeInForce)
+		if er.ExecType == model.ExecTypeFill {
+			fills[er.ClOrdID] = er
+		}
+	}
+	require.Len(t, fills, 2)
+
+	// The resting buy made the market; the sell took it at the buy's price
+	// but keeps its own limit.
+	assert.Equal(t, model.LiquidityAdded, fills["B1"].LastLiquidityInd)
+	assert.Equal(t, model.LiquidityRemoved, fills["S1"].LastLiquidityInd)
+	assert.True(t, fills["S1"].LastPx.Equal(decimal.NewFromInt(101)))
+	assert.True(t, fills["S1"].Price.Equal(decimal.NewFromInt(100)))
+}
@@ -17,7 +17,7 @@ type MockNotifier struct {
 	LastData  json.RawMessage
 }
 
-func (m *MockNotifier) NotifyEventAndTrade(id string, data json.RawMessage) error {
+func (m *MockNotifier) NotifyEventAndTrade(_ model.MsgType, id string, data json.RawMessage) error {
 	m.Published = true
 	m.LastID = id
 	m.LastData = data
@@ -90,36 +90,34 @@ func TestNewCanceledRejectOrderEvent(t *testing.T) {
 	assert.Equal(t, model.OrderStatusRejected, order.OrderStatus)
 }
 
-func TestNewFillEvent_FullFill(t *testing.T) {
+func TestFill_FullFill(t *testing.T) {
 	order := newTestOrder()
-	notifier := &MockNotifier{}
-	order.Notifier = notifier
 
 	price := decimal.NewFromInt(100)
 	qty := decimal.NewFromInt(10)
 
-	order.newFillEvent(price, qty)
+	er := order.fill(price, qty, "trade-1", model.LiquidityAdded)
 
 	assert.Equal(t, model.OrderStatusFill, order.OrderStatus)
 	assert.True(t, order.LeavesQty.IsZero())
-	assert.True(t, notifier.Published)
 	assert.True(t, order.AvgPx.Equal(price))
+	assert.Equal(t, model.ExecTypeFill, er.ExecType)
+	assert.Equal(t, "trade-1", er.TradeID)
+	assert.True(t, er.LastShares.Equal(qty))
 }
 
-func TestNewFillEvent_PartialFill(t *testing.T) {
+func TestFill_PartialFill(t *testing.T) {
 	order := newTestOrder()
-	notifier := &MockNotifier{}
-	order.Notifier = notifier
 
 	price := decimal.NewFromInt(100)
 	qty := decimal.NewFromInt(5)
 
-	order.newFillEvent(price, qty)
+	er := order.fill(price, qty, "trade-1", model.LiquidityRemoved)
 
 	assert.Equal(t, model.OrderStatusPartialFill, order.OrderStatus)
 	assert.True(t, order.LeavesQty.Equal(decimal.NewFromInt(5)))
 	assert.True(t, order.CumQty.Equal(qty))
-	assert.True(t, notifier.Published)
+	assert.Equal(t, model.OrderStatusPartialFill, er.OrdStatus)
 }
 
 func TestNewFillOrderEvent(t *testing.T) {
@@ -129,8 +127,12 @@ func TestNewFillOrderEvent(t *testing.T) {
 	sell.Notifier = &MockNotifier{}
 
 	qty := decimal.NewFromInt(5)
-	NewFillOrderEvent(buy, sell, qty)
+	buyFill, sellFill := NewFillOrderEvent(buy, sell, qty, "trade-1")
 
 	assert.Equal(t, decimal.NewFromInt(5), buy.CumQty)
 	assert.Equal(t, decimal.NewFromInt(5), sell.CumQty)
+	assert.Equal(t, "trade-1", buyFill.TradeID)
+	assert.Equal(t, "trade-1", sellFill.TradeID)
+	assert.Equal(t, model.LiquidityRemoved, buyFill.LastLiquidityInd)
+	assert.Equal(t, model.LiquidityAdded, sellFill.LastLiquidityInd)
 }
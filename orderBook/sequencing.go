@@ -0,0 +1,138 @@
+package orderBook
+
+import (
+	"fmt"
+	"time"
+
+	"MatchingEngine/internal/idgen"
+	"MatchingEngine/internal/model"
+)
+
+// IDGenerator creates the OrderID, ExecID and TradeReportID values of a book.
+type IDGenerator interface {
+	NextID(prefix string) string
+}
+
+// CommandIDGenerator is implemented by generators that can derive the IDs of
+// a journaled command from its sequence and timestamp alone. Without it such
+// IDs are "<prefix>-<seq>-<n>".
+type CommandIDGenerator interface {
+	CommandID(prefix string, seq uint64, ts int64, n int) string
+}
+
+// fallbackIDs serves books that were not given an IDGenerator.
+var fallbackIDs = idgen.NewEphemeral()
+
+// Clock supplies engine time: the receive time stamped on requests that are
+// not journaled and the TransactTime of reports published outside a request.
+type Clock interface {
+	Now() time.Time
+}
+
+// Journal durably records a request, stamped with an engine sequence number
+// and timestamp, before the book processes it.
+type Journal interface {
+	Append(req model.OrderRequest) (seq uint64, ts int64, err error)
+}
+
+// bookEnv is shared by a book and every order it holds. While a request is
+// processed, engine time is the receive time stamped on it; a journaled
+// request also derives its IDs from its journal entry, through ids when it
+// supports that. Outside a request, or for IDs of requests that were not
+// journaled, ids (or fallbackIDs) and the clock are used. reportSeq numbers
+// the book's execution reports, so the reports of an order can be ranked
+// however they are stored.
+type bookEnv struct {
+	ids       IDGenerator
+	clock     Clock
+	cmd       *commandSequence
+	reportSeq uint64
+}
+
+func (e *bookEnv) nextID(prefix string) string {
+	switch {
+	case e == nil:
+		return fallbackIDs.NextID(prefix)
+	case e.cmd != nil && e.cmd.journaled:
+		return e.cmd.nextID(e.ids, prefix)
+	case e.ids != nil:
+		return e.ids.NextID(prefix)
+	default:
+		return fallbackIDs.NextID(prefix)
+	}
+}
+
+// now returns the engine time in epoch ns.
+func (e *bookEnv) now() int64 {
+	switch {
+	case e == nil:
+		return time.Now().UnixNano()
+	case e.cmd != nil:
+		return e.cmd.ts
+	default:
+		return e.clockNow()
+	}
+}
+
+func (e *bookEnv) clockNow() int64 {
+	if e.clock == nil {
+		return time.Now().UnixNano()
+	}
+	return e.clock.Now().UnixNano()
+}
+
+// nextReportSeq returns the sequence of the next execution report, or 0 for
+// an order that was never attached to a book.
+func (e *bookEnv) nextReportSeq() uint64 {
+	if e == nil {
+		return 0
+	}
+	e.reportSeq++
+	return e.reportSeq
+}
+
+// lastReportSeq returns the sequence of the last execution report.
+func (e *bookEnv) lastReportSeq() uint64 {
+	if e == nil {
+		return 0
+	}
+	return e.reportSeq
+}
+
+// clientTime returns the TransactTime the client sent with the request being
+// processed, or 0 outside a request.
+func (e *bookEnv) clientTime() int64 {
+	if e == nil || e.cmd == nil {
+		return 0
+	}
+	return e.cmd.clientTime
+}
+
+// stamp returns the engine sequence and receive time of the request being
+// processed, or no sequence and the current time outside a request.
+func (e *bookEnv) stamp() (uint64, int64) {
+	if e == nil || e.cmd == nil {
+		return 0, e.now()
+	}
+	return e.cmd.seq, e.cmd.ts
+}
+
+// commandSequence is the request being processed, stamped with its engine
+// sequence and receive time. IDs of a journaled request are numbered within
+// its entry, so replaying the journal reproduces them exactly. clientTime is
+// the TransactTime the client sent with the request.
+type commandSequence struct {
+	seq        uint64
+	ts         int64
+	clientTime int64
+	journaled  bool
+	n          int
+}
+
+func (c *commandSequence) nextID(ids IDGenerator, prefix string) string {
+	c.n++
+	if gen, ok := ids.(CommandIDGenerator); ok {
+		return gen.CommandID(prefix, c.seq, c.ts, c.n)
+	}
+	return fmt.Sprintf("%s-%d-%d", prefix, c.seq, c.n)
+}
@@ -0,0 +1,127 @@
+package orderBook
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"MatchingEngine/internal/model"
+)
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(time.Second)
+	return c.now
+}
+
+type payloadNotifier struct {
+	mu       sync.Mutex
+	payloads []json.RawMessage
+}
+
+func (p *payloadNotifier) NotifyEventAndTrade(_ model.MsgType, _ string, value json.RawMessage) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.payloads = append(p.payloads, value)
+	return nil
+}
+
+func TestEngineTime_StampsRequestsAndReports(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2025, 3, 1, 23, 59, 59, 0, time.UTC)}
+	notifier := &payloadNotifier{}
+	book := NewOrderBook(notifier, BookOpts{Symbol: "BTC/USDT", Clock: clock})
+	ch := book.Start()
+
+	backDated := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC).UnixNano()
+	sell := depthOrderReq("S1", model.Sell, 100, 5)
+	sell.TransactTime = backDated
+	buy := depthOrderReq("B1", model.Buy, 100, 2)
+	buy.TransactTime = backDated
+	ch <- model.OrderRequest{MsgType: model.MsgTypeNew, NewOrderReq: sell}
+	ch <- model.OrderRequest{MsgType: model.MsgTypeNew, NewOrderReq: buy}
+
+	var snapshot BookSnapshot
+	book.Exec(func(b *OrderBook) { snapshot = b.Snapshot(0) })
+
+	firstReceive := time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC).UnixNano()
+	secondReceive := time.Date(2025, 3, 2, 0, 0, 1, 0, time.UTC).UnixNano()
+
+	// New for S1, then the fills for B1 and S1 and their trade report.
+	require.Len(t, notifier.payloads, 4)
+	var trades int
+	for i, payload := range notifier.payloads {
+		var msg struct {
+			MsgType      string `json:"35"`
+			TransactTime int64  `json:"60"`
+			ClientTime   int64  `json:"5003"`
+			TradeDate    string `json:"75"`
+		}
+		require.NoError(t, json.Unmarshal(payload, &msg))
+		if msg.MsgType == string(model.MsgTypeExecRpt) {
+			assert.Equal(t, backDated, msg.ClientTime) // Kept next to engine time for audit
+		}
+		if i == 0 {
+			assert.Equal(t, firstReceive, msg.TransactTime)
+			continue
+		}
+		assert.Equal(t, secondReceive, msg.TransactTime)
+		if msg.MsgType == string(model.MsgTypeTradeReport) {
+			trades++
+			assert.Equal(t, "20250302", msg.TradeDate) // The UTC day, wherever the engine runs
+
+		}
+	}
+	assert.Equal(t, 1, trades)
+
+	require.Len(t, snapshot.Asks, 1)
+	resting := snapshot.Asks[0].Orders[0]
+	assert.Equal(t, backDated, resting.Timestamp)
+	assert.Equal(t, firstReceive, resting.ReceivedAt)
+	assert.Equal(t, uint64(1), resting.EngineSeq)
+}
+
+func TestEngineTime_NoClockOutsideRequests(t *testing.T) {
+	var env *bookEnv
+	seq, ts := env.stamp()
+	assert.Zero(t, seq)
+	assert.InDelta(t, time.Now().UnixNano(), ts, float64(time.Minute))
+}
+
+func TestReportSeq_RanksReportsAcrossRestore(t *testing.T) {
+	notifier := &capturingNotifier{}
+	book := NewOrderBook(notifier, BookOpts{Symbol: "BTC/USDT"})
+	book.OnNewOrder(depthOrderReq("S1", model.Sell, 100, 5))
+	// Fills the buy and part of S1 within one request, at one TransactTime.
+	book.OnNewOrder(depthOrderReq("B1", model.Buy, 100, 2))
+	book.CancelOrder("S1")
+
+	require.Len(t, notifier.reports, 4)
+	for i, er := range notifier.reports {
+		assert.Equal(t, uint64(i+1), er.ReportSeq, er.ExecID)
+	}
+
+	snapshot := book.Snapshot(0)
+	assert.Equal(t, uint64(4), snapshot.ReportSeq)
+	restored, err := RestoreOrderBook(notifier, BookOpts{}, snapshot)
+	require.NoError(t, err)
+	restored.OnNewOrder(depthOrderReq("B2", model.Buy, 99, 1))
+	assert.Equal(t, uint64(5), notifier.reports[4].ReportSeq)
+
+	// A book rebuilt from stored orders continues after the last report of any.
+	fromOrders := SnapshotFromOrders("BTC/USDT", []Order{
+		{ClOrdID: "B3", Side: model.Buy, Price: decimal.NewFromInt(99), OrderQty: decimal.NewFromInt(1), LeavesQty: decimal.NewFromInt(1), ReportSeq: 9},
+		{ClOrdID: "B4", Side: model.Buy, Price: decimal.NewFromInt(98), OrderQty: decimal.NewFromInt(1), LeavesQty: decimal.NewFromInt(1), ReportSeq: 4},
+	}, 0)
+	assert.Equal(t, uint64(9), fromOrders.ReportSeq)
+}
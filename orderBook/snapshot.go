@@ -0,0 +1,217 @@
+package orderBook
+
+import (
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/emirpasic/gods/maps/treemap"
+	"github.com/shopspring/decimal"
+
+	"MatchingEngine/internal/model"
+)
+
+// SnapshotVersion is the version of the BookSnapshot layout. Bump it on any
+// incompatible change and keep RestoreOrderBook able to read older versions.
+const SnapshotVersion = 1
+
+type LevelSnapshot struct {
+	Price  decimal.Decimal `json:"price"`
+	Orders []Order         `json:"orders"` // In time priority
+}
+
+// BookSnapshot is the full state of one order book at a point in time.
+type BookSnapshot struct {
+	Version    int                 `json:"version"`
+	Symbol     string              `json:"symbol"`
+	TakenAt    int64               `json:"taken_at"`    // Epoch ns
+	DepthSeq   uint64              `json:"depth_seq"`   // Last published depth update
+	JournalSeq uint64              `json:"journal_seq"` // Last journal entry applied
+	ReportSeq  uint64              `json:"report_seq"`  // Last execution report sequence
+	Bids       []LevelSnapshot     `json:"bids"`        // Best price first
+	Asks       []LevelSnapshot     `json:"asks"`        // Best price first
+	OrderIndex map[string]OrderRef `json:"order_index"`
+}
+
+// Snapshot copies the state of the book. It must run on the book's goroutine,
+// e.g. through Exec, once the book is started.
+func (book *OrderBook) Snapshot(takenAt int64) BookSnapshot {
+	snapshot := BookSnapshot{
+		Version:    SnapshotVersion,
+		Symbol:     book.Symbol,
+		TakenAt:    takenAt,
+		DepthSeq:   book.depthSeq,
+		JournalSeq: book.journalSeq,
+		ReportSeq:  book.env.lastReportSeq(),
+		Bids:       snapshotLevels(book.Bids),
+		Asks:       snapshotLevels(book.Asks),
+		OrderIndex: make(map[string]OrderRef, len(book.orderIndex)),
+	}
+	for clOrdID, ref := range book.orderIndex {
+		snapshot.OrderIndex[clOrdID] = *ref
+	}
+	return snapshot
+}
+
+func snapshotLevels(levels *treemap.Map) []LevelSnapshot {
+	out := make([]LevelSnapshot, 0, levels.Size())
+	it := levels.Iterator()
+	for it.Next() {
+		list := it.Value().(*OrderList)
+		orders := make([]Order, len(list.Orders))
+		copy(orders, list.Orders)
+		for i := range orders {
+			orders[i].Notifier = nil
+		}
+		out = append(out, LevelSnapshot{Price: it.Key().(decimal.Decimal), Orders: orders})
+	}
+	return out
+}
+
+// SnapshotFromOrders builds the snapshot of a book holding the given resting
+// orders, which must all be of symbol and come in time priority. It lets a
+// book be rebuilt from order state kept elsewhere, such as the database.
+func SnapshotFromOrders(symbol string, orders []Order, takenAt int64) BookSnapshot {
+	snapshot := BookSnapshot{
+		Version:    SnapshotVersion,
+		Symbol:     symbol,
+		TakenAt:    takenAt,
+		OrderIndex: make(map[string]OrderRef, len(orders)),
+	}
+	var bids, asks []Order
+	for _, order := range orders {
+		order.Notifier = nil
+		// Reports continue from the last one stored for any of the orders.
+		snapshot.ReportSeq = max(snapshot.ReportSeq, order.ReportSeq)
+		if order.Side == model.Buy {
+			bids = append(bids, order)
+		} else {
+			asks = append(asks, order)
+		}
+	}
+	// A stable sort keeps the time priority within each level.
+	sort.SliceStable(bids, func(i, j int) bool { return bids[i].Price.GreaterThan(bids[j].Price) })
+	sort.SliceStable(asks, func(i, j int) bool { return asks[i].Price.LessThan(asks[j].Price) })
+	snapshot.Bids = levelsFromOrders(bids, snapshot.OrderIndex)
+	snapshot.Asks = levelsFromOrders(asks, snapshot.OrderIndex)
+	return snapshot
+}
+
+// levelsFromOrders groups orders sorted by price into levels and records
+// their positions in index.
+func levelsFromOrders(orders []Order, index map[string]OrderRef) []LevelSnapshot {
+	levels := []LevelSnapshot{}
+	for _, order := range orders {
+		if n := len(levels); n == 0 || !levels[n-1].Price.Equal(order.Price) {
+			levels = append(levels, LevelSnapshot{Price: order.Price})
+		}
+		level := &levels[len(levels)-1]
+		index[order.ClOrdID] = OrderRef{PriceLevel: level.Price, Side: string(order.Side), Index: len(level.Orders)}
+		level.Orders = append(level.Orders, order)
+	}
+	return levels
+}
+
+// RestoreOrderBook rebuilds a book from a snapshot, keeping the queue order of
+// every price level. The order index is rebuilt from the queues and must match
+// the one recorded in the snapshot.
+func RestoreOrderBook(Notifier Notifier, opts BookOpts, snapshot BookSnapshot) (*OrderBook, error) {
+	if snapshot.Version != SnapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d", snapshot.Version)
+	}
+	if opts.Symbol != "" && opts.Symbol != snapshot.Symbol {
+		return nil, fmt.Errorf("snapshot is for symbol %s, not %s", snapshot.Symbol, opts.Symbol)
+	}
+	opts.Symbol = snapshot.Symbol
+
+	book := NewOrderBook(Notifier, opts)
+	if err := book.restoreLevels(snapshot.Bids, model.Buy); err != nil {
+		return nil, err
+	}
+	if err := book.restoreLevels(snapshot.Asks, model.Sell); err != nil {
+		return nil, err
+	}
+
+	if len(book.orderIndex) != len(snapshot.OrderIndex) {
+		return nil, fmt.Errorf("snapshot of %s has %d resting orders but %d index entries",
+			snapshot.Symbol, len(book.orderIndex), len(snapshot.OrderIndex))
+	}
+	for clOrdID, ref := range book.orderIndex {
+		want, ok := snapshot.OrderIndex[clOrdID]
+		if !ok || !want.PriceLevel.Equal(ref.PriceLevel) || want.Side != ref.Side || want.Index != ref.Index {
+			return nil, fmt.Errorf("snapshot of %s has an inconsistent index entry for order %s", snapshot.Symbol, clOrdID)
+		}
+	}
+
+	book.depthSeq = snapshot.DepthSeq
+	book.journalSeq = snapshot.JournalSeq
+	book.env.reportSeq = snapshot.ReportSeq
+	book.resetDepth()
+	return book, nil
+}
+
+func (book *OrderBook) restoreLevels(levels []LevelSnapshot, side model.Side) error {
+	for i, level := range levels {
+		if i > 0 {
+			prev := levels[i-1].Price
+			if (side == model.Buy && !level.Price.LessThan(prev)) || (side == model.Sell && !level.Price.GreaterThan(prev)) {
+				return fmt.Errorf("snapshot of %s has %s levels out of order at %s", book.Symbol, side, level.Price)
+			}
+		}
+		if len(level.Orders) == 0 {
+			return fmt.Errorf("snapshot of %s has an empty level at %s", book.Symbol, level.Price)
+		}
+
+		for _, order := range level.Orders {
+			switch {
+			case order.Side != side || !order.Price.Equal(level.Price):
+				return fmt.Errorf("order %s does not belong to the %s level at %s", order.ClOrdID, side, level.Price)
+			case !order.LeavesQty.IsPositive():
+				return fmt.Errorf("order %s rests with no leaves quantity", order.ClOrdID)
+			}
+			if _, dup := book.orderIndex[order.ClOrdID]; dup {
+				return fmt.Errorf("order %s appears more than once in the snapshot", order.ClOrdID)
+			}
+			order.defaultTerms()
+			book.attach(&order)
+			book.addOrderToBook(order)
+		}
+	}
+	return nil
+}
+
+// resetDepth discards pending depth changes after a restore and hands the
+// restored levels to the market data notifier as the new baseline.
+func (book *OrderBook) resetDepth() {
+	if book.MarketData == nil {
+		return
+	}
+	clear(book.dirtyLevels)
+	book.publishedLevels = make(map[levelKey]decimal.Decimal)
+
+	snapshot := model.MarketDataSnapshotFullRefresh{
+		MsgType:      string(model.MsgTypeMDSnapshot),
+		Symbol:       book.Symbol,
+		SeqNum:       book.depthSeq,
+		TransactTime: book.env.now(),
+		Depth:        book.depthLevels(),
+		Checksum:     book.Checksum(),
+	}
+	for _, side := range []model.Side{model.Buy, model.Sell} {
+		levels := book.Asks
+		if side == model.Buy {
+			levels = book.Bids
+		}
+		it := levels.Iterator()
+		for it.Next() {
+			price := it.Key().(decimal.Decimal)
+			size := it.Value().(*OrderList).totalLeavesQty()
+			book.publishedLevels[levelKey{side: side, price: price.String()}] = size
+			snapshot.Entries = append(snapshot.Entries, model.MDEntry{EntryType: mdEntryType(side), Px: price, Size: size})
+		}
+	}
+
+	if err := book.MarketData.ResetDepth(snapshot); err != nil {
+		log.Printf("Error publishing depth snapshot for %s: %v", book.Symbol, err)
+	}
+}
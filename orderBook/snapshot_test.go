@@ -0,0 +1,213 @@
+package orderBook
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"MatchingEngine/internal/model"
+)
+
+type capturingNotifier struct {
+	reports []model.ExecutionReport
+}
+
+func (c *capturingNotifier) NotifyEventAndTrade(msgType model.MsgType, _ string, value json.RawMessage) error {
+	var er model.ExecutionReport
+	if err := json.Unmarshal(value, &er); err == nil && msgType == model.MsgTypeExecRpt {
+		c.reports = append(c.reports, er)
+	}
+	return nil
+}
+
+func populatedBook(t *testing.T) *OrderBook {
+	ob := setupOrderBook()
+	ob.Symbol = "BTC/USDT"
+	ob.OnNewOrder(depthOrderReq("B1", model.Buy, 100, 5))
+	ob.OnNewOrder(depthOrderReq("B2", model.Buy, 100, 3))
+	ob.OnNewOrder(depthOrderReq("B3", model.Buy, 100, 4))
+	ob.OnNewOrder(depthOrderReq("B4", model.Buy, 99, 2))
+	ob.OnNewOrder(depthOrderReq("S1", model.Sell, 105, 6))
+	ob.OnNewOrder(depthOrderReq("S2", model.Sell, 104, 1))
+	// Fills B1 and part of B2, then cancels the only order at 99.
+	ob.OnNewOrder(depthOrderReq("S3", model.Sell, 100, 6))
+	ob.CancelOrder("B4")
+	require.Len(t, ob.orderIndex, 4)
+	return ob
+}
+
+func TestSnapshot_RoundTrip(t *testing.T) {
+	ob := populatedBook(t)
+	snapshot := ob.Snapshot(42)
+
+	data, err := json.Marshal(snapshot)
+	require.NoError(t, err)
+	var decoded BookSnapshot
+	require.NoError(t, json.Unmarshal(data, &decoded))
+
+	restored, err := RestoreOrderBook(&MockTradeNotifier{}, BookOpts{}, decoded)
+	require.NoError(t, err)
+
+	assert.Equal(t, "BTC/USDT", restored.Symbol)
+	again, err := json.Marshal(restored.Snapshot(42))
+	require.NoError(t, err)
+	assert.JSONEq(t, string(data), string(again))
+
+	bids := decoded.Bids
+	require.Len(t, bids, 1)
+	require.Len(t, bids[0].Orders, 2)
+	assert.Equal(t, "B2", bids[0].Orders[0].ClOrdID)
+	assert.True(t, bids[0].Orders[0].LeavesQty.Equal(decimal.NewFromInt(2)))
+	assert.True(t, bids[0].Orders[0].CumQty.Equal(decimal.NewFromInt(1)))
+	assert.Equal(t, model.OrderStatusPartialFill, bids[0].Orders[0].OrderStatus)
+	assert.NotEmpty(t, bids[0].Orders[0].OrderID)
+}
+
+func TestSnapshot_RestingOrdersCarryTheirLastReport(t *testing.T) {
+	notifier := &capturingNotifier{}
+	ob := NewOrderBook(notifier, BookOpts{Symbol: "BTC/USDT"})
+	ob.OnNewOrder(depthOrderReq("B1", model.Buy, 100, 5))
+	ob.OnNewOrder(depthOrderReq("B2", model.Buy, 99, 3))
+	ob.OnNewOrder(depthOrderReq("S1", model.Sell, 100, 2))
+
+	last := make(map[string]model.ExecutionReport)
+	for _, er := range notifier.reports {
+		last[er.ClOrdID] = er
+	}
+	want := map[string]model.OrderStatus{"B1": model.OrderStatusPartialFill, "B2": model.OrderStatusNew}
+
+	open := ob.OpenOrders()
+	require.Len(t, open, 2)
+	snapshot := ob.Snapshot(0)
+	require.Len(t, snapshot.Bids, 2)
+	for i, order := range open {
+		assert.Equal(t, want[order.ClOrdID], order.OrderStatus, order.ClOrdID)
+		assert.Equal(t, last[order.ClOrdID].ReportSeq, order.ReportSeq, order.ClOrdID)
+		snapped := snapshot.Bids[i].Orders[0]
+		assert.Equal(t, order.OrderStatus, snapped.OrderStatus, order.ClOrdID)
+		assert.Equal(t, order.ReportSeq, snapped.ReportSeq, order.ClOrdID)
+	}
+}
+
+func TestSnapshot_RestoreKeepsTimePriority(t *testing.T) {
+	notifier := &capturingNotifier{}
+	restored, err := RestoreOrderBook(notifier, BookOpts{}, populatedBook(t).Snapshot(0))
+	require.NoError(t, err)
+
+	restored.OnNewOrder(depthOrderReq("S4", model.Sell, 100, 3))
+
+	var filled []string
+	for _, er := range notifier.reports {
+		if er.Side == model.Buy {
+			filled = append(filled, er.ClOrdID)
+		}
+	}
+	assert.Equal(t, []string{"B2", "B3"}, filled)
+
+	restored.CancelOrder("B3")
+	assert.Equal(t, 0, restored.Bids.Size())
+	assert.NotContains(t, restored.orderIndex, "B2")
+	assert.NotContains(t, restored.orderIndex, "B3")
+}
+
+func TestSnapshot_RestoreDefaultsOrderTerms(t *testing.T) {
+	snapshot := populatedBook(t).Snapshot(0)
+	for _, level := range snapshot.Bids {
+		for i := range level.Orders {
+			level.Orders[i].OrdType, level.Orders[i].TimeInForce = "", ""
+		}
+	}
+	notifier := &capturingNotifier{}
+	restored, err := RestoreOrderBook(notifier, BookOpts{}, snapshot)
+	require.NoError(t, err)
+
+	restored.OnNewOrder(depthOrderReq("S4", model.Sell, 100, 1))
+
+	require.NotEmpty(t, notifier.reports)
+	for _, er := range notifier.reports {
+		assert.Equal(t, model.OrdTypeLimit, er.OrdType)
+		assert.Equal(t, model.TimeInForceGTC, er.TimeInForce)
+	}
+}
+
+func TestSnapshot_RestoreRejectsInconsistentIndex(t *testing.T) {
+	snapshot := populatedBook(t).Snapshot(0)
+	ref := snapshot.OrderIndex["B3"]
+	ref.Index = 0
+	snapshot.OrderIndex["B3"] = ref
+
+	_, err := RestoreOrderBook(&MockTradeNotifier{}, BookOpts{}, snapshot)
+	assert.Error(t, err)
+}
+
+func TestSnapshot_RestoreRejectsUnknownVersion(t *testing.T) {
+	snapshot := populatedBook(t).Snapshot(0)
+	snapshot.Version = SnapshotVersion + 1
+
+	_, err := RestoreOrderBook(&MockTradeNotifier{}, BookOpts{}, snapshot)
+	assert.Error(t, err)
+}
+
+func TestSnapshot_RestoreResetsDepth(t *testing.T) {
+	ob := populatedBook(t)
+	md := &recordingMarketData{}
+	ob.MarketData = md
+	ob.OnNewOrder(depthOrderReq("B5", model.Buy, 98, 1))
+
+	restored, err := RestoreOrderBook(&MockTradeNotifier{}, BookOpts{MarketData: md}, ob.Snapshot(0))
+	require.NoError(t, err)
+
+	require.Len(t, md.snapshots, 1)
+	assert.Equal(t, ob.depthSeq, md.snapshots[0].SeqNum)
+	assert.Equal(t, ob.Checksum(), md.snapshots[0].Checksum)
+	assert.Len(t, md.snapshots[0].Entries, 4)
+
+	restored.CancelOrder("B5")
+	require.Len(t, md.updates, 2)
+	assert.Equal(t, ob.depthSeq+1, md.updates[1].SeqNum)
+	assert.Equal(t, model.MDUpdateActionDelete, md.updates[1].Entries[0].UpdateAction)
+}
+
+func TestSnapshotFromOrders_MatchesBookSnapshot(t *testing.T) {
+	want := populatedBook(t).Snapshot(42)
+	byID := map[string]Order{}
+	for _, levels := range [][]LevelSnapshot{want.Bids, want.Asks} {
+		for _, level := range levels {
+			for _, order := range level.Orders {
+				byID[order.ClOrdID] = order
+			}
+		}
+	}
+
+	// The orders in the sequence they were placed, sides and levels mixed.
+	var orders []Order
+	for _, id := range []string{"B2", "S1", "B3", "S2"} {
+		orders = append(orders, byID[id])
+	}
+	got := SnapshotFromOrders("BTC/USDT", orders, 42)
+
+	assert.Equal(t, want.Bids, got.Bids)
+	assert.Equal(t, want.Asks, got.Asks)
+	assert.Equal(t, want.OrderIndex, got.OrderIndex)
+	_, err := RestoreOrderBook(&MockTradeNotifier{}, BookOpts{}, got)
+	assert.NoError(t, err)
+}
+
+func TestExec_RunsAfterQueuedRequests(t *testing.T) {
+	ob := NewOrderBook(&MockTradeNotifier{}, BookOpts{Symbol: "BTC/USDT"})
+	ch := ob.Start()
+	for i, id := range []string{"B1", "B2", "B3"} {
+		ch <- model.OrderRequest{MsgType: model.MsgTypeNew, NewOrderReq: depthOrderReq(id, model.Buy, int64(100-i), 1)}
+	}
+
+	var snapshot BookSnapshot
+	ob.Exec(func(b *OrderBook) {
+		snapshot = b.Snapshot(0)
+	})
+
+	assert.Len(t, snapshot.Bids, 3)
+	assert.Len(t, snapshot.OrderIndex, 3)
+}